@@ -0,0 +1,332 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.1
+// 	protoc        (unknown)
+// source: canva/v1/draw.proto
+
+package canvav1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DrawEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type     string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Color    string `protobuf:"bytes,2,opt,name=color,proto3" json:"color,omitempty"`
+	Size     int32  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	PrevX    int32  `protobuf:"varint,4,opt,name=prev_x,json=prevX,proto3" json:"prev_x,omitempty"`
+	PrevY    int32  `protobuf:"varint,5,opt,name=prev_y,json=prevY,proto3" json:"prev_y,omitempty"`
+	CurrX    int32  `protobuf:"varint,6,opt,name=curr_x,json=currX,proto3" json:"curr_x,omitempty"`
+	CurrY    int32  `protobuf:"varint,7,opt,name=curr_y,json=currY,proto3" json:"curr_y,omitempty"`
+	ClientId string `protobuf:"bytes,8,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// The drawing tool, empty means pen.
+	Tool string `protobuf:"bytes,9,opt,name=tool,proto3" json:"tool,omitempty"`
+	// The color assigned to the client that drew the event.
+	ClientColor string `protobuf:"bytes,10,opt,name=client_color,json=clientColor,proto3" json:"client_color,omitempty"`
+	// When the event was drawn, in Unix milliseconds.
+	Time int64 `protobuf:"varint,11,opt,name=time,proto3" json:"time,omitempty"`
+	// The position of the event in its room's history, assigned by the server from 1.
+	// A gap in the sequence tells a client it missed events.
+	Seq int64 `protobuf:"varint,12,opt,name=seq,proto3" json:"seq,omitempty"`
+}
+
+func (x *DrawEvent) Reset() {
+	*x = DrawEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_canva_v1_draw_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DrawEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrawEvent) ProtoMessage() {}
+
+func (x *DrawEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_canva_v1_draw_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrawEvent.ProtoReflect.Descriptor instead.
+func (*DrawEvent) Descriptor() ([]byte, []int) {
+	return file_canva_v1_draw_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DrawEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *DrawEvent) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *DrawEvent) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *DrawEvent) GetPrevX() int32 {
+	if x != nil {
+		return x.PrevX
+	}
+	return 0
+}
+
+func (x *DrawEvent) GetPrevY() int32 {
+	if x != nil {
+		return x.PrevY
+	}
+	return 0
+}
+
+func (x *DrawEvent) GetCurrX() int32 {
+	if x != nil {
+		return x.CurrX
+	}
+	return 0
+}
+
+func (x *DrawEvent) GetCurrY() int32 {
+	if x != nil {
+		return x.CurrY
+	}
+	return 0
+}
+
+func (x *DrawEvent) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *DrawEvent) GetTool() string {
+	if x != nil {
+		return x.Tool
+	}
+	return ""
+}
+
+func (x *DrawEvent) GetClientColor() string {
+	if x != nil {
+		return x.ClientColor
+	}
+	return ""
+}
+
+func (x *DrawEvent) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *DrawEvent) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+type History struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Events []*DrawEvent `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+}
+
+func (x *History) Reset() {
+	*x = History{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_canva_v1_draw_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *History) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*History) ProtoMessage() {}
+
+func (x *History) ProtoReflect() protoreflect.Message {
+	mi := &file_canva_v1_draw_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use History.ProtoReflect.Descriptor instead.
+func (*History) Descriptor() ([]byte, []int) {
+	return file_canva_v1_draw_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *History) GetEvents() []*DrawEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+var File_canva_v1_draw_proto protoreflect.FileDescriptor
+
+var file_canva_v1_draw_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x72, 0x61, 0x77, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x22,
+	0x9f, 0x02, 0x0a, 0x09, 0x44, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x70,
+	0x72, 0x65, 0x76, 0x5f, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x70, 0x72, 0x65,
+	0x76, 0x58, 0x12, 0x15, 0x0a, 0x06, 0x70, 0x72, 0x65, 0x76, 0x5f, 0x79, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x05, 0x70, 0x72, 0x65, 0x76, 0x59, 0x12, 0x15, 0x0a, 0x06, 0x63, 0x75, 0x72,
+	0x72, 0x5f, 0x78, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x63, 0x75, 0x72, 0x72, 0x58,
+	0x12, 0x15, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x72, 0x5f, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x05, 0x63, 0x75, 0x72, 0x72, 0x59, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x5f, 0x69, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6c, 0x69, 0x65,
+	0x6e, 0x74, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x6f, 0x6f, 0x6c, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x74, 0x6f, 0x6f, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6c, 0x69, 0x65,
+	0x6e, 0x74, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x74,
+	0x69, 0x6d, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12,
+	0x10, 0x0a, 0x03, 0x73, 0x65, 0x71, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x73, 0x65,
+	0x71, 0x22, 0x36, 0x0a, 0x07, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x2b, 0x0a, 0x06,
+	0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x63,
+	0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x52, 0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x42, 0x3b, 0x5a, 0x39, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x66, 0x61, 0x77, 0x61, 0x2d, 0x69, 0x6f, 0x2f,
+	0x66, 0x61, 0x77, 0x61, 0x2f, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2f, 0x76, 0x31, 0x3b, 0x63,
+	0x61, 0x6e, 0x76, 0x61, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_canva_v1_draw_proto_rawDescOnce sync.Once
+	file_canva_v1_draw_proto_rawDescData = file_canva_v1_draw_proto_rawDesc
+)
+
+func file_canva_v1_draw_proto_rawDescGZIP() []byte {
+	file_canva_v1_draw_proto_rawDescOnce.Do(func() {
+		file_canva_v1_draw_proto_rawDescData = protoimpl.X.CompressGZIP(file_canva_v1_draw_proto_rawDescData)
+	})
+	return file_canva_v1_draw_proto_rawDescData
+}
+
+var file_canva_v1_draw_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_canva_v1_draw_proto_goTypes = []interface{}{
+	(*DrawEvent)(nil), // 0: canva.v1.DrawEvent
+	(*History)(nil),   // 1: canva.v1.History
+}
+var file_canva_v1_draw_proto_depIdxs = []int32{
+	0, // 0: canva.v1.History.events:type_name -> canva.v1.DrawEvent
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_canva_v1_draw_proto_init() }
+func file_canva_v1_draw_proto_init() {
+	if File_canva_v1_draw_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_canva_v1_draw_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DrawEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_canva_v1_draw_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*History); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_canva_v1_draw_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_canva_v1_draw_proto_goTypes,
+		DependencyIndexes: file_canva_v1_draw_proto_depIdxs,
+		MessageInfos:      file_canva_v1_draw_proto_msgTypes,
+	}.Build()
+	File_canva_v1_draw_proto = out.File
+	file_canva_v1_draw_proto_rawDesc = nil
+	file_canva_v1_draw_proto_goTypes = nil
+	file_canva_v1_draw_proto_depIdxs = nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// UnambiguousAlphabet holds the upper-case letters and digits except those easily confused
+// when read or typed by hand: 0 and O, 1, I and L.
+const UnambiguousAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// canvasCodeLength is the length of the codes users share to join a canvas
+const canvasCodeLength = 6
+
+// GenerateRandomStringFromAlphabet returns n characters drawn uniformly from alphabet with crypto/rand.
+// It panics if alphabet is empty.
+func GenerateRandomStringFromAlphabet(n int, alphabet string) string {
+	runes := []rune(alphabet)
+	if len(runes) == 0 {
+		panic("handler: empty alphabet")
+	}
+	max := big.NewInt(int64(len(runes)))
+
+	b := make([]rune, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = runes[idx.Int64()]
+	}
+	return string(b)
+}
+
+// newCanvasCode returns a code for a new canvas, drawn from UnambiguousAlphabet since users type it by hand
+func newCanvasCode() string {
+	return GenerateRandomStringFromAlphabet(canvasCodeLength, UnambiguousAlphabet)
+}
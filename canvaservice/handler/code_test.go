@@ -0,0 +1,76 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateRandomStringFromAlphabet(t *testing.T) {
+	testCases := []struct {
+		name     string
+		n        int
+		alphabet string
+	}{
+		{name: "unambiguous", n: 64, alphabet: UnambiguousAlphabet},
+		{name: "single character", n: 5, alphabet: "x"},
+		{name: "multi-byte runes", n: 16, alphabet: "αβγ"},
+		{name: "empty string", n: 0, alphabet: "ab"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := GenerateRandomStringFromAlphabet(tc.n, tc.alphabet)
+			if n := len([]rune(got)); n != tc.n {
+				t.Fatalf("got %d characters, want %d", n, tc.n)
+			}
+			for _, r := range got {
+				if !strings.ContainsRune(tc.alphabet, r) {
+					t.Fatalf("%q contains %q, which is not in %q", got, r, tc.alphabet)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRandomStringFromAlphabetEmpty(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("empty alphabet did not panic")
+		}
+	}()
+	GenerateRandomStringFromAlphabet(6, "")
+}
+
+func TestUnambiguousAlphabet(t *testing.T) {
+	for _, r := range "0O1IL" {
+		if strings.ContainsRune(UnambiguousAlphabet, r) {
+			t.Errorf("UnambiguousAlphabet contains the ambiguous %q", r)
+		}
+	}
+	seen := make(map[rune]bool)
+	for _, r := range UnambiguousAlphabet {
+		if seen[r] {
+			t.Errorf("UnambiguousAlphabet repeats %q, skewing the distribution", r)
+		}
+		seen[r] = true
+	}
+
+	code := newCanvasCode()
+	if len(code) != canvasCodeLength || strings.Trim(code, UnambiguousAlphabet) != "" {
+		t.Errorf("newCanvasCode() = %q, want %d characters of UnambiguousAlphabet", code, canvasCodeLength)
+	}
+}
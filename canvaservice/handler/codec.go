@@ -0,0 +1,106 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+// Codec encodes the canvas messages for a transport or a store
+type Codec interface {
+	// Name identifies the codec, e.g. in configuration
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSONCodec encodes any message as JSON, browsers only speak this one
+	JSONCodec Codec = jsonCodec{}
+	// ProtoCodec encodes draw events and histories in the compact canvav1 binary form
+	ProtoCodec Codec = protoCodec{}
+)
+
+// wireCodec encodes the WebSocket and WebTransport messages
+var wireCodec = JSONCodec
+
+// CodecByName returns the codec with the given name
+func CodecByName(name string) (Codec, error) {
+	for _, c := range []Codec{JSONCodec, ProtoCodec} {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown codec %q", name)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// protoCodec encodes DrawEvent and History through their canvav1 messages, and proto messages as is
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "proto" }
+
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	switch m := v.(type) {
+	case *DrawEvent:
+		return proto.Marshal(toProto(m))
+	case *History:
+		history := &canvav1.History{Events: make([]*canvav1.DrawEvent, len(m.Events))}
+		for i := range m.Events {
+			history.Events[i] = toProto(&m.Events[i])
+		}
+		return proto.Marshal(history)
+	case proto.Message:
+		return proto.Marshal(m)
+	}
+	return nil, fmt.Errorf("proto codec can't encode %T", v)
+}
+
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	switch m := v.(type) {
+	case *DrawEvent:
+		var event canvav1.DrawEvent
+		if err := proto.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		*m = fromProto(&event)
+		return nil
+	case *History:
+		var history canvav1.History
+		if err := proto.Unmarshal(data, &history); err != nil {
+			return err
+		}
+		m.Events = make([]DrawEvent, len(history.GetEvents()))
+		for i, e := range history.GetEvents() {
+			m.Events[i] = fromProto(e)
+		}
+		return nil
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	}
+	return fmt.Errorf("proto codec can't decode into %T", v)
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	event := DrawEvent{
+		Type:        "draw",
+		Tool:        ToolRect,
+		Color:       "#ff0000",
+		Size:        4,
+		PrevX:       -10,
+		PrevY:       20,
+		CurrX:       30,
+		CurrY:       40,
+		ClientID:    "client-1",
+		ClientColor: "#00ff00",
+		Time:        1700000000000,
+	}
+	history := History{Events: []DrawEvent{event, {Type: "clear", ClientID: "client-2", Time: 1700000000001}}}
+
+	for _, codec := range []Codec{JSONCodec, ProtoCodec} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			data, err := codec.Marshal(&event)
+			if err != nil {
+				t.Fatalf("Marshal(event) error = %v", err)
+			}
+			var gotEvent DrawEvent
+			if err := codec.Unmarshal(data, &gotEvent); err != nil {
+				t.Fatalf("Unmarshal(event) error = %v", err)
+			}
+			if !reflect.DeepEqual(gotEvent, event) {
+				t.Errorf("event round trip = %+v, want %+v", gotEvent, event)
+			}
+
+			data, err = codec.Marshal(&history)
+			if err != nil {
+				t.Fatalf("Marshal(history) error = %v", err)
+			}
+			var gotHistory History
+			if err := codec.Unmarshal(data, &gotHistory); err != nil {
+				t.Fatalf("Unmarshal(history) error = %v", err)
+			}
+			if !reflect.DeepEqual(gotHistory, history) {
+				t.Errorf("history round trip = %+v, want %+v", gotHistory, history)
+			}
+		})
+	}
+}
+
+func TestProtoCodecUnsupportedType(t *testing.T) {
+	if _, err := ProtoCodec.Marshal(&ClientDrawResponse{}); err == nil {
+		t.Error("Marshal() should reject a type without a proto form")
+	}
+	if err := ProtoCodec.Unmarshal(nil, &ClientDrawResponse{}); err == nil {
+		t.Error("Unmarshal() should reject a type without a proto form")
+	}
+}
+
+func TestCodecByName(t *testing.T) {
+	for _, name := range []string{"json", "proto"} {
+		c, err := CodecByName(name)
+		if err != nil || c.Name() != name {
+			t.Errorf("CodecByName(%q) = %v, %v", name, c, err)
+		}
+	}
+	if _, err := CodecByName("msgpack"); err == nil {
+		t.Error("CodecByName() should reject an unknown codec")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"sort"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+// toProto converts a draw event from its JSON wire form to the in-memory canvav1.DrawEvent.
+// The event must have passed Validate, which keeps the numbers within the int32 range.
+func toProto(e *DrawEvent) *canvav1.DrawEvent {
+	return &canvav1.DrawEvent{
+		Type:        e.Type,
+		Color:       e.Color,
+		Size:        int32(e.Size),
+		PrevX:       int32(e.PrevX),
+		PrevY:       int32(e.PrevY),
+		CurrX:       int32(e.CurrX),
+		CurrY:       int32(e.CurrY),
+		ClientId:    e.ClientID,
+		Tool:        e.Tool,
+		ClientColor: e.ClientColor,
+		Time:        e.Time,
+		Seq:         e.Seq,
+	}
+}
+
+// fromProto converts an in-memory canvav1.DrawEvent to its JSON wire form
+func fromProto(p *canvav1.DrawEvent) DrawEvent {
+	return DrawEvent{
+		Type:        p.GetType(),
+		Tool:        p.GetTool(),
+		Color:       p.GetColor(),
+		Size:        int(p.GetSize()),
+		PrevX:       int(p.GetPrevX()),
+		PrevY:       int(p.GetPrevY()),
+		CurrX:       int(p.GetCurrX()),
+		CurrY:       int(p.GetCurrY()),
+		ClientID:    p.GetClientId(),
+		ClientColor: p.GetClientColor(),
+		Time:        p.GetTime(),
+		Seq:         p.GetSeq(),
+	}
+}
+
+// historySnapshot returns the session history in its JSON wire form
+func (s *CanvasSession) historySnapshot() []DrawEvent {
+	s.HistoryMu.RLock()
+	defer s.HistoryMu.RUnlock()
+	events := make([]DrawEvent, len(s.History))
+	for i, e := range s.History {
+		events[i] = fromProto(e)
+	}
+	return events
+}
+
+// historySince returns the events of the session after fromSeq. It returns the whole history
+// and true when events after fromSeq are no longer in it or fromSeq is past its end.
+func (s *CanvasSession) historySince(fromSeq int64) ([]DrawEvent, bool) {
+	s.HistoryMu.RLock()
+	defer s.HistoryMu.RUnlock()
+	var truncated bool
+	if n := len(s.History); n == 0 {
+		truncated = fromSeq > 0
+	} else {
+		truncated = fromSeq < s.History[0].GetSeq()-1 || fromSeq > s.History[n-1].GetSeq()
+	}
+	start := 0
+	if !truncated {
+		start = sort.Search(len(s.History), func(i int) bool { return s.History[i].GetSeq() > fromSeq })
+	}
+	events := make([]DrawEvent, len(s.History)-start)
+	for i, e := range s.History[start:] {
+		events[i] = fromProto(e)
+	}
+	return events, truncated
+}
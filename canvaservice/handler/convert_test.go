@@ -0,0 +1,89 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+func TestDrawEventProtoRoundTrip(t *testing.T) {
+	event := DrawEvent{
+		Type:        "draw",
+		Tool:        ToolRect,
+		Color:       "#ff0000",
+		Size:        4,
+		PrevX:       1,
+		PrevY:       2,
+		CurrX:       30,
+		CurrY:       -40,
+		ClientID:    "client-a",
+		ClientColor: "#e6194b",
+		Time:        1700000000123,
+		Seq:         7,
+	}
+
+	p := toProto(&event)
+	if got := fromProto(p); got != event {
+		t.Errorf("fromProto(toProto(e)) = %+v, want %+v", got, event)
+	}
+
+	back := toProto(&event)
+	if !proto.Equal(back, p) {
+		t.Errorf("toProto(fromProto(p)) = %v, want %v", back, p)
+	}
+}
+
+// TestDrawEventFieldMapping checks that the JSON wire names of DrawEvent match the proto field names
+func TestDrawEventFieldMapping(t *testing.T) {
+	event := DrawEvent{
+		Type:        "draw",
+		Tool:        ToolLine,
+		Color:       "#000000",
+		Size:        1,
+		PrevX:       2,
+		PrevY:       3,
+		CurrX:       4,
+		CurrY:       5,
+		ClientID:    "client-b",
+		ClientColor: "#3cb44b",
+		Time:        42,
+		Seq:         3,
+	}
+	data, err := json.Marshal(&event)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var p canvav1.DrawEvent
+	if err := protojson.Unmarshal(data, &p); err != nil {
+		t.Fatalf("protojson.Unmarshal(%s) error = %v", data, err)
+	}
+	if !proto.Equal(&p, toProto(&event)) {
+		t.Errorf("protojson decoded %v, want %v", &p, toProto(&event))
+	}
+}
+
+func TestValidateRejectsValuesOutsideInt32(t *testing.T) {
+	e := NewDrawEvent("draw", "#000000", "", 1, 0, 0, 1<<31, 0)
+	if err := e.Validate(); err == nil {
+		t.Error("Validate() should reject a coordinate that doesn't fit in an int32")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+	"github.com/gorilla/websocket"
+)
+
+// drainGracePeriod is how long a drain lets the notified clients leave by themselves
+var drainGracePeriod = 2 * time.Second
+
+// shutdownReason is sent to the clients when the server shuts down
+const shutdownReason = "server shutting down"
+
+// beginClient registers a connecting client with the drain, it returns false once Drain
+// has started. endClient must be called when the client is gone.
+func (h *CanvasServiceHandler) beginClient() bool {
+	h.SessionsMu.Lock()
+	defer h.SessionsMu.Unlock()
+	if h.draining {
+		return false
+	}
+	h.active.Add(1)
+	return true
+}
+
+// endClient marks a client registered with beginClient as gone
+func (h *CanvasServiceHandler) endClient() {
+	h.active.Done()
+}
+
+// rejectDraining answers a connection attempt made during a drain, it reports whether it did
+func (h *CanvasServiceHandler) rejectDraining(w http.ResponseWriter) bool {
+	if h.beginClient() {
+		return false
+	}
+	http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+	return true
+}
+
+// connectedClients returns the clients of every session
+func (h *CanvasServiceHandler) connectedClients() []*SessionClient {
+	h.SessionsMu.RLock()
+	defer h.SessionsMu.RUnlock()
+	var clients []*SessionClient
+	for _, session := range h.Sessions {
+		session.ClientsMu.RLock()
+		for _, client := range session.Clients {
+			clients = append(clients, client)
+		}
+		session.ClientsMu.RUnlock()
+	}
+	return clients
+}
+
+// Drain rejects new clients and tells the connected ones the server is shutting down.
+// They get drainGracePeriod to leave by themselves, then the remaining ones are closed with
+// a going away code and Drain waits for them until ctx is done.
+func (h *CanvasServiceHandler) Drain(ctx context.Context) error {
+	h.SessionsMu.Lock()
+	h.draining = true
+	h.SessionsMu.Unlock()
+
+	for _, client := range h.connectedClients() {
+		select {
+		case client.Send <- &ClientDrawResponse{Shutdown: &ServerShutdown{Reason: shutdownReason}}:
+		default:
+			// Too slow to get the message, it's closed right away
+			client.closeWithReason(websocket.CloseGoingAway, shutdownReason)
+		}
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(finished)
+	}()
+
+	grace := time.NewTimer(drainGracePeriod)
+	defer grace.Stop()
+	select {
+	case <-finished:
+		return nil
+	case <-grace.C:
+	case <-ctx.Done():
+	}
+
+	// Clients that were joining when the drain started are in their session by now
+	clients := h.connectedClients()
+	fwlog.Infof("Closing %d canvas clients", len(clients))
+	for _, client := range clients {
+		client.closeWithReason(websocket.CloseGoingAway, shutdownReason)
+	}
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		fwlog.Warnf("Canvas clients did not leave before the shutdown deadline")
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDrainNotifiesAndClosesClients(t *testing.T) {
+	grace := drainGracePeriod
+	drainGracePeriod = 50 * time.Millisecond
+	t.Cleanup(func() { drainGracePeriod = grace })
+
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession)}
+	session := newCanvasSession("DRAIN1")
+	url := newTestWebSocketServer(t, h, session)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	waitForClients(t, session, 1)
+
+	// The client doesn't leave by itself, the drain has to close it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.Drain(ctx); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	var notified bool
+	for {
+		var resp ClientDrawResponse
+		if err = conn.ReadJSON(&resp); err != nil {
+			break
+		}
+		notified = notified || (resp.Shutdown != nil && resp.Shutdown.Reason == shutdownReason)
+	}
+	if !notified {
+		t.Error("client got no shutdown message")
+	}
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Errorf("read error = %v, want a going away close frame", err)
+	}
+	waitForClients(t, session, 0)
+
+	// New clients are turned away
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Dial() after the drain = %v, %v, want status %d", resp, err, http.StatusServiceUnavailable)
+	}
+}
+
+func TestDrainWithoutClients(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: map[string]*CanvasSession{"EMPTY1": newCanvasSession("EMPTY1")}}
+	start := time.Now()
+	if err := h.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= drainGracePeriod {
+		t.Errorf("Drain() took %v, want no wait without clients", elapsed)
+	}
+}
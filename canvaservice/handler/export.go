@@ -0,0 +1,148 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+const (
+	maxImportSize   = 10 << 20 // 10MB
+	maxImportEvents = 10000
+)
+
+// ExportCanvas returns the history of a session as a downloadable .fawa file,
+// or as a rendered SVG image when format=svg is requested
+func (h *CanvasServiceHandler) ExportCanvas(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "Missing canvas code", http.StatusBadRequest)
+		return
+	}
+	h.SessionsMu.RLock()
+	session, ok := h.Sessions[code]
+	h.SessionsMu.RUnlock()
+	if !ok {
+		http.Error(w, "Canvas not found", http.StatusNotFound)
+		return
+	}
+
+	file := &CanvasFile{
+		Version:    CanvasFileVersion,
+		Code:       code,
+		ExportedAt: time.Now().UnixMilli(),
+		Events:     session.historySnapshot(),
+	}
+	file.Legend = clientLegend(file.Events)
+
+	if r.URL.Query().Get("format") == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="canvas-%s.svg"`, code))
+		if err := RenderSVG(w, file.Events); err != nil {
+			fwlog.Warnf("write export failed: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="canvas-%s.fawa"`, code))
+	if err := json.NewEncoder(w).Encode(file); err != nil {
+		fwlog.Warnf("write export failed: %v", err)
+	}
+}
+
+// ImportCanvas creates a new session pre-populated from an uploaded .fawa file.
+// The file can be sent as the raw request body or as the "file" field of a multipart form.
+func (h *CanvasServiceHandler) ImportCanvas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var body io.Reader = r.Body
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "multipart/form-data" {
+		part, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing canvas file", http.StatusBadRequest)
+			return
+		}
+		defer func() { _ = part.Close() }()
+		body = part
+	}
+
+	file, err := decodeCanvasFile(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Canvas file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Invalid canvas file: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	code := newCanvasCode()
+	session := newCanvasSession(code)
+	session.History = make([]*canvav1.DrawEvent, len(file.Events))
+	for i := range file.Events {
+		// The imported board starts a new sequence, whatever the exported one was
+		file.Events[i].Seq = int64(i + 1)
+		session.History[i] = toProto(&file.Events[i])
+	}
+	// Nothing stored the imported events yet, they're saved right away to outlive a restart
+	session.unflushed = len(session.History)
+	if err := h.addSession(session); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	h.flushSession(session)
+	fwlog.Infof("Canvas session %s imported with %d events", code, len(file.Events))
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := fmt.Fprintf(w, `{"code":"%s"}`, code); err != nil {
+		fwlog.Warnf("write response failed: %v", err)
+	}
+}
+
+// decodeCanvasFile decodes and validates a .fawa file
+func decodeCanvasFile(r io.Reader) (*CanvasFile, error) {
+	var file CanvasFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if file.Version != CanvasFileVersion {
+		return nil, fmt.Errorf("unsupported version %d", file.Version)
+	}
+	if len(file.Events) > maxImportEvents {
+		return nil, fmt.Errorf("too many events: %d (max %d)", len(file.Events), maxImportEvents)
+	}
+	for i := range file.Events {
+		if err := file.Events[i].Validate(); err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+	}
+	return &file, nil
+}
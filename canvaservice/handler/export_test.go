@@ -0,0 +1,120 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	h := NewCanvasServiceHandler()
+	store := &fakeHistoryStore{}
+	h.HistoryStore = store
+
+	session := newCanvasSession("SRC001")
+	session.History = []*canvav1.DrawEvent{
+		toProto(NewDrawEvent("draw", "#000000", "client-a", 3, 0, 0, 10, 10)),
+		toProto(NewDrawEvent("draw", "#ff0000", "client-b", 5, 10, 10, 20, 20)),
+		toProto(NewDrawEvent("clear", "", "client-a", 0, 0, 0, 0, 0)),
+	}
+	for i, event := range session.History {
+		event.Seq = int64(i + 1)
+	}
+	h.Sessions[session.Code] = session
+
+	rec := httptest.NewRecorder()
+	h.ExportCanvas(rec, httptest.NewRequest(http.MethodGet, "/export?code=SRC001", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ExportCanvas() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "canvas-SRC001.fawa") {
+		t.Errorf("ExportCanvas() Content-Disposition = %q", got)
+	}
+
+	rec2 := httptest.NewRecorder()
+	h.ImportCanvas(rec2, httptest.NewRequest(http.MethodPost, "/import", bytes.NewReader(rec.Body.Bytes())))
+	if rec2.Code != http.StatusOK {
+		t.Fatalf("ImportCanvas() status = %d, body = %s", rec2.Code, rec2.Body.String())
+	}
+	var resp struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec2.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode import response: %v", err)
+	}
+	if resp.Code == "" || resp.Code == session.Code {
+		t.Fatalf("ImportCanvas() returned code %q, want a new code", resp.Code)
+	}
+
+	imported, ok := h.Sessions[resp.Code]
+	if !ok {
+		t.Fatalf("imported session %s not found", resp.Code)
+	}
+	if len(imported.History) != len(session.History) {
+		t.Fatalf("imported history has %d events, want %d", len(imported.History), len(session.History))
+	}
+	for i := range session.History {
+		if !proto.Equal(imported.History[i], session.History[i]) {
+			t.Errorf("event %d = %+v, want %+v", i, imported.History[i], session.History[i])
+		}
+	}
+	store.mu.Lock()
+	stored := store.stored[resp.Code]
+	store.mu.Unlock()
+	if len(stored) != len(session.History) {
+		t.Errorf("stored history of the imported session has %d events, want %d", len(stored), len(session.History))
+	}
+}
+
+func TestImportCanvasRejectsInvalidFiles(t *testing.T) {
+	h := NewCanvasServiceHandler()
+
+	tooMany := CanvasFile{Version: CanvasFileVersion, Events: make([]DrawEvent, maxImportEvents+1)}
+	for i := range tooMany.Events {
+		tooMany.Events[i].Type = "draw"
+	}
+	tooManyJSON, _ := json.Marshal(tooMany)
+
+	testCases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "malformed json", body: "not json", want: http.StatusBadRequest},
+		{name: "unsupported version", body: `{"version":99,"events":[]}`, want: http.StatusBadRequest},
+		{name: "invalid event", body: `{"version":1,"events":[{"type":""}]}`, want: http.StatusBadRequest},
+		{name: "too many events", body: string(tooManyJSON), want: http.StatusBadRequest},
+		{name: "too large", body: `{"version":1,"code":"` + strings.Repeat("A", maxImportSize) + `"}`, want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ImportCanvas(rec, httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(tc.body)))
+			if rec.Code != tc.want {
+				t.Errorf("ImportCanvas() status = %d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+}
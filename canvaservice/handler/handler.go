@@ -52,6 +52,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -59,25 +60,55 @@ import (
 	"github.com/fawa-io/fwpkg/util"
 	"github.com/gorilla/websocket"
 	"github.com/quic-go/webtransport-go"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
 )
 
 const (
 	sessionCleanerInterval = 1 * time.Minute
 	sessionExpiryDuration  = 10 * time.Minute
+	// clientSendQueueSize is the number of events buffered per client before it's considered too slow
+	clientSendQueueSize = 256
+	// defaultPingInterval is how often WebSocket clients are pinged when no interval is configured
+	defaultPingInterval = 30 * time.Second
+	// pingWriteWait is the time allowed to write a ping frame
+	pingWriteWait = 10 * time.Second
+	// closeIdleTimeout is the close code sent to clients disconnected for being idle,
+	// in the range WebSocket leaves to applications
+	closeIdleTimeout = 4000
+	// sessionErrMessageTooBig is the WebTransport session error code of clients disconnected for
+	// sending a message over MaxMessageSize. Session error codes are left to applications, unlike
+	// the WebSocket close codes.
+	sessionErrMessageTooBig = 1
+	// defaultMaxMessageSize is the largest message a client may send when no limit is configured
+	defaultMaxMessageSize = 64 << 10
 )
 
+// errMessageTooBig is returned when a client message exceeds the handler's MaxMessageSize
+var errMessageTooBig = errors.New("message too big")
+
 // CanvasSession represents a collaborative drawing session
 // All clients (WebSocket or WebTransport) join a session by code
-// Each session maintains its own clients, history, and broadcast channel
+// Each session maintains its own clients and history, and every client has its own send queue
 
 type CanvasSession struct {
 	Code       string
 	Clients    map[string]*SessionClient
 	ClientsMu  sync.RWMutex
-	History    []*DrawEvent
+	History    []*canvav1.DrawEvent
 	HistoryMu  sync.RWMutex
-	Broadcast  chan *DrawEvent
+	// LastActive is the time of the last draw event or departure, guarded by activeMu
 	LastActive time.Time
+
+	activeMu sync.Mutex
+	// unflushed counts the events not saved to the HistoryStore yet, guarded by HistoryMu
+	unflushed int
+	flushMu   sync.Mutex
+	// deleted is set once an admin deleted the session, its history is never saved again.
+	// Guarded by HistoryMu.
+	deleted bool
+	// closed is set once the session is removed, guarded by ClientsMu
+	closed bool
 }
 
 type SessionClient struct {
@@ -86,6 +117,119 @@ type SessionClient struct {
 	WSConn       *websocket.Conn
 	WTSession    *webtransport.Session
 	OutputStream io.Writer // For WT: *webtransport.Stream, for WS: *websocket.Conn
+	Color        string    // Assigned from clientPalette when the client joins
+	Send         chan *ClientDrawResponse
+	// NoEcho skips the client's own draw events when broadcasting, set with ?echo=false
+	NoEcho bool
+	// Binary exchanges draw events as protobuf frames, for WebSocket clients using ProtoSubprotocol
+	Binary bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+	// lastActive is the time the client joined or last drew, guarded by activeMu
+	lastActive time.Time
+	activeMu   sync.Mutex
+}
+
+// newSessionClient creates a client with an empty send queue
+func newSessionClient(id, connType string) *SessionClient {
+	return &SessionClient{
+		ID:         id,
+		ConnType:   connType,
+		Send:       make(chan *ClientDrawResponse, clientSendQueueSize),
+		done:       make(chan struct{}),
+		lastActive: time.Now(),
+	}
+}
+
+// Close closes the client connection and stops its writer, it's safe to call multiple times
+func (c *SessionClient) Close() {
+	c.closeWithReason(0, "server closed")
+}
+
+// closeWithReason closes the client connection telling the client why. The code is a
+// WebSocket close code or a WebTransport session error code, zero closes a WebSocket
+// without a close frame.
+func (c *SessionClient) closeWithReason(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		switch c.ConnType {
+		case "websocket":
+			if c.WSConn != nil {
+				if code != 0 {
+					msg := websocket.FormatCloseMessage(code, reason)
+					if err := c.WSConn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(pingWriteWait)); err != nil {
+						fwlog.Warnf("Failed to send close frame: %v", err)
+					}
+				}
+				if err := c.WSConn.Close(); err != nil {
+					fwlog.Warnf("wsConn close failed: %v", err)
+				}
+			}
+		case "webtransport":
+			if c.WTSession != nil {
+				if err := c.WTSession.CloseWithError(webtransport.SessionErrorCode(code), reason); err != nil {
+					fwlog.Warnf("webSession.CloseWithError failed: %v", err)
+				}
+			}
+		}
+	})
+}
+
+// touch marks the client as active now
+func (c *SessionClient) touch() {
+	c.activeMu.Lock()
+	c.lastActive = time.Now()
+	c.activeMu.Unlock()
+}
+
+// lastActivity returns the time the client joined or last drew
+func (c *SessionClient) lastActivity() time.Time {
+	c.activeMu.Lock()
+	defer c.activeMu.Unlock()
+	return c.lastActive
+}
+
+// echoDisabled reports whether the client asked not to receive its own draw events.
+// Echo stays on unless the echo query parameter is false, clients may rely on it.
+func echoDisabled(r *http.Request) bool {
+	echo, err := strconv.ParseBool(r.URL.Query().Get("echo"))
+	return err == nil && !echo
+}
+
+// removeClient unregisters the client from the session and closes its connection.
+// Both the reader and the writer of a client may call it, so it must be idempotent.
+func (s *CanvasSession) removeClient(client *SessionClient) {
+	s.ClientsMu.Lock()
+	removed := s.Clients[client.ID] == client
+	if removed {
+		delete(s.Clients, client.ID)
+	}
+	s.ClientsMu.Unlock()
+	client.Close()
+
+	if removed {
+		s.touch()
+		s.broadcastPresence()
+	}
+}
+
+// broadcast queues the response for every client in the session.
+// A client whose send queue is full is dropped instead of blocking the whole session.
+func (s *CanvasSession) broadcast(resp *ClientDrawResponse) {
+	s.ClientsMu.RLock()
+	defer s.ClientsMu.RUnlock()
+	for _, client := range s.Clients {
+		if client.NoEcho && resp.DrawEvent != nil && resp.DrawEvent.ClientID == client.ID {
+			continue
+		}
+		select {
+		case client.Send <- resp:
+		default:
+			fwlog.Warnf("Client %s in session %s is too slow, dropping connection", client.ID, s.Code)
+			client.Close()
+		}
+	}
 }
 
 // CanvasServiceHandler manages all canvas sessions
@@ -96,15 +240,46 @@ type CanvasServiceHandler struct {
 	SessionsMu sync.RWMutex
 	Upgrader   websocket.Upgrader
 	WTServer   *webtransport.Server
+	// PingInterval is how often WebSocket clients are pinged,
+	// a client that doesn't answer within two intervals is disconnected
+	PingInterval time.Duration
+	// IdleTimeout disconnects clients that haven't drawn for that long, even while the session is busy.
+	// Zero disables it.
+	IdleTimeout time.Duration
+	// MaxMessageSize is the largest message in bytes a client may send, zero means no limit.
+	// A client sending a larger one is disconnected with a policy violation.
+	MaxMessageSize int64
+	// MaxSessions caps the number of sessions, zero means no limit.
+	// At the cap the least recently active empty session makes room for a new one.
+	MaxSessions int
+	// HistoryStore persists the session histories according to FlushPolicy, nil keeps them in memory only
+	HistoryStore HistoryStore
+	FlushPolicy  FlushPolicy
+	// AdminToken is the bearer token required by ListRooms and DeleteCanvas, empty disables them
+	AdminToken string
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	// draining rejects new clients once Drain has started, guarded by SessionsMu
+	draining bool
+	// active counts the connected clients, see beginClient
+	active sync.WaitGroup
+
+	// upgradeWebTransport replaces WTServer.Upgrade in tests
+	upgradeWebTransport func(http.ResponseWriter, *http.Request) (*webtransport.Session, error)
 }
 
 func NewCanvasServiceHandler() *CanvasServiceHandler {
 	h := &CanvasServiceHandler{
 		Sessions: make(map[string]*CanvasSession),
 		Upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true },
+			CheckOrigin:  func(r *http.Request) bool { return true },
+			Subprotocols: []string{ProtoSubprotocol},
 		},
-		WTServer: &webtransport.Server{},
+		WTServer:       &webtransport.Server{},
+		PingInterval:   defaultPingInterval,
+		MaxMessageSize: defaultMaxMessageSize,
+		closed:         make(chan struct{}),
 	}
 	go h.sessionCleaner()
 	return h
@@ -112,22 +287,26 @@ func NewCanvasServiceHandler() *CanvasServiceHandler {
 
 // CreateCanvas creates a new canvas session and returns its code
 func (h *CanvasServiceHandler) CreateCanvas(w http.ResponseWriter, r *http.Request) {
-	code := util.Generaterandomstring(6)
-	session := &CanvasSession{
-		Code:       code,
-		Clients:    make(map[string]*SessionClient),
-		Broadcast:  make(chan *DrawEvent, 100),
-		LastActive: time.Now(),
+	code := newCanvasCode()
+	if err := h.addSession(newCanvasSession(code)); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
 	}
-	h.SessionsMu.Lock()
-	h.Sessions[code] = session
-	h.SessionsMu.Unlock()
 	w.Header().Set("Content-Type", "application/json")
 	if _, err := fmt.Fprintf(w, `{"code":"%s"}`, code); err != nil {
 		fwlog.Warnf("write response failed: %v", err)
 	}
 }
 
+// newCanvasSession creates an empty canvas session with the given code
+func newCanvasSession(code string) *CanvasSession {
+	return &CanvasSession{
+		Code:       code,
+		Clients:    make(map[string]*SessionClient),
+		LastActive: time.Now(),
+	}
+}
+
 // JoinCanvas checks if a session exists for the given code
 func (h *CanvasServiceHandler) JoinCanvas(w http.ResponseWriter, r *http.Request) {
 	code := r.URL.Query().Get("code")
@@ -155,42 +334,32 @@ func (h *CanvasServiceHandler) HandleWebSocket(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Canvas not found", http.StatusNotFound)
 		return
 	}
+	if h.rejectDraining(w) {
+		return
+	}
+	defer h.endClient()
 	conn, err := h.Upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		fwlog.Errorf("WebSocket upgrade failed: %v", err)
 		return
 	}
-	defer func() { _ = conn.Close() }()
 	clientID := util.Generaterandomstring(8)
-	client := &SessionClient{
-		ID:       clientID,
-		ConnType: "websocket",
-		WSConn:   conn,
-	}
-	session.ClientsMu.Lock()
-	session.Clients[clientID] = client
-	session.ClientsMu.Unlock()
-	defer func() {
-		session.ClientsMu.Lock()
-		delete(session.Clients, clientID)
-		session.ClientsMu.Unlock()
-		if err := conn.Close(); err != nil {
-			fwlog.Warnf("wsConn close failed: %v", err)
-		}
-	}()
+	client := newSessionClient(clientID, "websocket")
+	client.WSConn = conn
+	client.NoEcho = echoDisabled(r)
+	client.Binary = conn.Subprotocol() == ProtoSubprotocol
+	session.addClient(client)
+	defer session.removeClient(client)
+
+	// Let the client know its own id so it can recognize its echoed events
+	if err := conn.WriteJSON(&ClientDrawResponse{ClientID: clientID, ClientColor: client.Color}); err != nil {
+		fwlog.Warnf("Failed to send client id: %v", err)
+		return
+	}
 
 	// Send initial history
-	session.HistoryMu.RLock()
-	historyCopy := make([]*DrawEvent, len(session.History))
-	copy(historyCopy, session.History)
-	session.HistoryMu.RUnlock()
-	if len(historyCopy) > 0 {
-		resp := &ClientDrawResponse{
-			InitialHistory: &History{Events: make([]DrawEvent, len(historyCopy))},
-		}
-		for i, e := range historyCopy {
-			resp.InitialHistory.Events[i] = *e
-		}
+	if history := session.historySnapshot(); len(history) > 0 {
+		resp := &ClientDrawResponse{InitialHistory: &History{Events: history}}
 		if err := conn.WriteJSON(resp); err != nil {
 			fwlog.Warnf("Failed to send initial history: %v", err)
 		}
@@ -214,18 +383,34 @@ func (h *CanvasServiceHandler) HandleWebTransport(w http.ResponseWriter, r *http
 		http.Error(w, "Canvas not found", http.StatusNotFound)
 		return
 	}
-	wtSession, err := h.WTServer.Upgrade(w, r)
+	if h.WTServer.CheckOrigin != nil && !h.WTServer.CheckOrigin(r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	if h.rejectDraining(w) {
+		return
+	}
+	defer h.endClient()
+
+	upgrade := h.WTServer.Upgrade
+	if h.upgradeWebTransport != nil {
+		upgrade = h.upgradeWebTransport
+	}
+	tw := &trackingResponseWriter{ResponseWriter: w}
+	wtSession, err := upgrade(tw, r)
 	if err != nil {
 		fwlog.Errorf("WebTransport upgrade failed: %v", err)
-		http.Error(w, "WebTransport upgrade failed", http.StatusInternalServerError)
+		// The upgrade may fail after it has sent its headers, don't write a second response then.
+		if !tw.started {
+			http.Error(w, "WebTransport upgrade failed", http.StatusInternalServerError)
+		}
 		return
 	}
-	defer func() {
-		if err := wtSession.CloseWithError(0, "server closed"); err != nil {
-			fwlog.Warnf("webSession.CloseWithError failed: %v", err)
-		}
-	}()
 	clientID := util.Generaterandomstring(8)
+	client := newSessionClient(clientID, "webtransport")
+	client.NoEcho = echoDisabled(r)
+	client.WTSession = wtSession
+	defer client.Close()
 	// Open a single output stream for this client
 	outputStream, err := wtSession.OpenStream()
 	if err != nil {
@@ -237,34 +422,20 @@ func (h *CanvasServiceHandler) HandleWebTransport(w http.ResponseWriter, r *http
 			fwlog.Warnf("outputStream close failed: %v", err)
 		}
 	}()
-	client := &SessionClient{
-		ID:           clientID,
-		ConnType:     "webtransport",
-		WTSession:    wtSession,
-		OutputStream: outputStream,
-	}
-	session.ClientsMu.Lock()
-	session.Clients[clientID] = client
-	session.ClientsMu.Unlock()
-	defer func() {
-		session.ClientsMu.Lock()
-		delete(session.Clients, clientID)
-		session.ClientsMu.Unlock()
-	}()
+	client.OutputStream = outputStream
+	session.addClient(client)
+	defer session.removeClient(client)
+
+	// Let the client know its own id so it can recognize its echoed events
+	if err := writeToClient(client, &ClientDrawResponse{ClientID: clientID, ClientColor: client.Color}); err != nil {
+		fwlog.Warnf("Failed to send client id: %v", err)
+		return
+	}
 
 	// Send initial history
-	session.HistoryMu.RLock()
-	historyCopy := make([]*DrawEvent, len(session.History))
-	copy(historyCopy, session.History)
-	session.HistoryMu.RUnlock()
-	if len(historyCopy) > 0 {
-		resp := &ClientDrawResponse{
-			InitialHistory: &History{Events: make([]DrawEvent, len(historyCopy))},
-		}
-		for i, e := range historyCopy {
-			resp.InitialHistory.Events[i] = *e
-		}
-		data, err := json.Marshal(resp)
+	if history := session.historySnapshot(); len(history) > 0 {
+		resp := &ClientDrawResponse{InitialHistory: &History{Events: history}}
+		data, err := wireCodec.Marshal(resp)
 		if err == nil {
 			if _, err := outputStream.Write(data); err != nil {
 				fwlog.Warnf("Failed to send initial history: %v", err)
@@ -276,47 +447,178 @@ func (h *CanvasServiceHandler) HandleWebTransport(w http.ResponseWriter, r *http
 	h.sessionWebTransportReader(session, client, r.Context())
 }
 
-// sessionBroadcastWriter writes all broadcast events to the client's output stream
+// sessionBroadcastWriter writes the events queued for the client to its output stream
 func (h *CanvasServiceHandler) sessionBroadcastWriter(session *CanvasSession, client *SessionClient) {
-	for event := range session.Broadcast {
-		resp := &ClientDrawResponse{DrawEvent: event}
-		switch client.ConnType {
-		case "websocket":
-			if err := client.WSConn.WriteJSON(resp); err != nil {
-				fwlog.Warnf("WebSocket WriteJSON failed: %v", err)
-				return
-			}
-		case "webtransport":
-			data, err := json.Marshal(resp)
-			if err != nil {
-				fwlog.Warnf("Marshal failed: %v", err)
+	// WebTransport relies on QUIC keepalives, only WebSocket clients need to be pinged.
+	var ping <-chan time.Time
+	if client.ConnType == "websocket" && h.PingInterval > 0 {
+		ticker := time.NewTicker(h.PingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	var idleTimer *time.Timer
+	var idle <-chan time.Time
+	if h.IdleTimeout > 0 {
+		idleTimer = time.NewTimer(h.IdleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
+	for {
+		var resp *ClientDrawResponse
+		select {
+		case resp = <-client.Send:
+		case <-ping:
+			if err := client.WSConn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingWriteWait)); err != nil {
+				fwlog.Warnf("Failed to ping client %s, removing it from session %s: %v", client.ID, session.Code, err)
+				session.removeClient(client)
 				return
 			}
-			if _, err := client.OutputStream.Write(data); err != nil {
-				fwlog.Warnf("WebTransport Write failed: %v", err)
-				return
+			continue
+		case <-idle:
+			// Draws don't reset the timer, it's rearmed for the time left since the last one
+			if left := h.IdleTimeout - time.Since(client.lastActivity()); left > 0 {
+				idleTimer.Reset(left)
+				continue
 			}
+			fwlog.Infof("Client %s idle for %v, removing it from session %s", client.ID, h.IdleTimeout, session.Code)
+			client.closeWithReason(closeIdleTimeout, "idle timeout")
+			session.removeClient(client)
+			return
+		case <-client.done:
+			return
+		}
+		if err := writeToClient(client, resp); err != nil {
+			fwlog.Warnf("Failed to write to client %s, removing it from session %s: %v", client.ID, session.Code, err)
+			session.removeClient(client)
+			return
+		}
+	}
+}
+
+// writeToClient writes a response to the client using its connection type
+func writeToClient(client *SessionClient, resp *ClientDrawResponse) error {
+	if client.ConnType == "websocket" {
+		messageType, data, err := encodeWebSocketResponse(client, resp)
+		if err != nil {
+			return err
 		}
+		return client.WSConn.WriteMessage(messageType, data)
+	}
+	data, err := wireCodec.Marshal(resp)
+	if err != nil {
+		return err
 	}
+	switch client.ConnType {
+	case "webtransport":
+		_, err = client.OutputStream.Write(data)
+		return err
+	}
+	return fmt.Errorf("unknown connection type %q", client.ConnType)
 }
 
 // sessionWebSocketReader reads messages from a WebSocket client and broadcasts draw events
 func (h *CanvasServiceHandler) sessionWebSocketReader(session *CanvasSession, client *SessionClient) {
+	conn := client.WSConn
+	if h.PingInterval > 0 {
+		// Any message or pong extends the deadline, a client that stays silent for two ping intervals is gone.
+		pongWait := 2 * h.PingInterval
+		if err := conn.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
+			fwlog.Warnf("Failed to set read deadline: %v", err)
+			return
+		}
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+	}
+
 	for {
-		var request ClientDrawRequest
-		if err := client.WSConn.ReadJSON(&request); err != nil {
+		messageType, data, err := readWebSocketMessage(conn, h.MaxMessageSize)
+		if err != nil {
 			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
 				return
 			}
+			if errors.Is(err, errMessageTooBig) {
+				fwlog.Warnf("Client %s sent a message over %d bytes, removing it from session %s", client.ID, h.MaxMessageSize, session.Code)
+				client.closeWithReason(websocket.ClosePolicyViolation, errMessageTooBig.Error())
+				return
+			}
+			fwlog.Warnf("WebSocket read error: %v", err)
+			return
+		}
+		request, err := decodeWebSocketRequest(client, messageType, data)
+		if err != nil {
 			fwlog.Warnf("WebSocket decode error: %v", err)
 			return
 		}
+		if h.PingInterval > 0 {
+			if err := conn.SetReadDeadline(time.Now().Add(2 * h.PingInterval)); err != nil {
+				fwlog.Warnf("Failed to set read deadline: %v", err)
+				return
+			}
+		}
 		if request.DrawEvent != nil {
-			h.processSessionDrawEvent(session, client.ID, request.DrawEvent)
+			h.processSessionDrawEvent(session, client, request.DrawEvent)
+		}
+		if request.Resync != nil {
+			resyncClient(session, client, request.Resync.FromSeq)
 		}
 	}
 }
 
+// readWebSocketMessage reads the next message from conn, failing with errMessageTooBig
+// without buffering the rest of a message longer than limit. Zero means no limit.
+// conn.SetReadLimit isn't used as it closes with 1009 before the caller can send its own code.
+func readWebSocketMessage(conn *websocket.Conn, limit int64) (int, []byte, error) {
+	messageType, r, err := conn.NextReader()
+	if err != nil {
+		return messageType, nil, err
+	}
+	if limit <= 0 {
+		data, err := io.ReadAll(r)
+		return messageType, data, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return messageType, nil, err
+	}
+	if int64(len(data)) > limit {
+		return messageType, nil, errMessageTooBig
+	}
+	return messageType, data, nil
+}
+
+// messageLimitReader bounds the bytes a streaming decoder may read for a single message.
+// The decoder never receives more than max bytes past the offset given to next,
+// so a message that isn't complete by then fails with errMessageTooBig.
+type messageLimitReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+	end  int64
+}
+
+// next starts a new message at the decoder input offset
+func (l *messageLimitReader) next(offset int64) {
+	l.end = offset + l.max
+}
+
+func (l *messageLimitReader) Read(p []byte) (int, error) {
+	if l.max <= 0 {
+		return l.r.Read(p)
+	}
+	left := l.end - l.read
+	if left <= 0 {
+		return 0, errMessageTooBig
+	}
+	if int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
+
 // sessionWebTransportReader reads messages from a WebTransport client and broadcasts draw events
 func (h *CanvasServiceHandler) sessionWebTransportReader(session *CanvasSession, client *SessionClient, ctx context.Context) {
 	for {
@@ -324,50 +626,97 @@ func (h *CanvasServiceHandler) sessionWebTransportReader(session *CanvasSession,
 		if err != nil {
 			return
 		}
-		dec := json.NewDecoder(stream)
+		// Requests are concatenated JSON values on the stream, which only a streaming decoder can split
+		limited := &messageLimitReader{r: stream, max: h.MaxMessageSize}
+		dec := json.NewDecoder(limited)
 		for {
 			var request ClientDrawRequest
+			limited.next(dec.InputOffset())
 			if err := dec.Decode(&request); err != nil {
 				if errors.Is(err, io.EOF) {
 					break
 				}
+				if errors.Is(err, errMessageTooBig) {
+					fwlog.Warnf("Client %s sent a message over %d bytes, removing it from session %s", client.ID, h.MaxMessageSize, session.Code)
+					client.closeWithReason(sessionErrMessageTooBig, errMessageTooBig.Error())
+					return
+				}
 				fwlog.Warnf("WebTransport decode error: %v", err)
 				return
 			}
 			if request.DrawEvent != nil {
-				h.processSessionDrawEvent(session, client.ID, request.DrawEvent)
+				h.processSessionDrawEvent(session, client, request.DrawEvent)
+			}
+			if request.Resync != nil {
+				resyncClient(session, client, request.Resync.FromSeq)
 			}
 		}
 	}
 }
 
+// resyncClient queues the events the client missed since fromSeq.
+// Like a broadcast, a client whose send queue is full is dropped instead of blocking its reader.
+func resyncClient(session *CanvasSession, client *SessionClient, fromSeq int64) {
+	events, truncated := session.historySince(fromSeq)
+	select {
+	case client.Send <- &ClientDrawResponse{Resync: &ResyncResponse{Events: events, Truncated: truncated}}:
+	default:
+		fwlog.Warnf("Client %s in session %s is too slow, dropping connection", client.ID, session.Code)
+		client.Close()
+	}
+}
+
 // processSessionDrawEvent processes a draw event and broadcasts it to all clients in the session
-func (h *CanvasServiceHandler) processSessionDrawEvent(session *CanvasSession, clientID string, event *DrawEvent) {
-	event.ClientID = clientID
-	session.HistoryMu.Lock()
-	session.History = append(session.History, event)
-	session.HistoryMu.Unlock()
-	session.Broadcast <- event
-	session.LastActive = time.Now()
+func (h *CanvasServiceHandler) processSessionDrawEvent(session *CanvasSession, client *SessionClient, event *DrawEvent) {
+	if err := event.Validate(); err != nil {
+		fwlog.Warnf("Dropping invalid draw event from client %s: %v", client.ID, err)
+		return
+	}
+	event.ClientID = client.ID
+	event.ClientColor = client.Color
+	client.touch()
+	unflushed := session.appendHistory(event)
+	session.broadcast(&ClientDrawResponse{DrawEvent: event})
+	session.touch()
+	if h.FlushPolicy.shouldFlush(event, unflushed) {
+		h.flushSession(session)
+	}
 }
 
-// sessionCleaner removes expired sessions
+// sessionCleaner removes expired sessions until Close is called
 func (h *CanvasServiceHandler) sessionCleaner() {
 	ticker := time.NewTicker(sessionCleanerInterval)
 	defer ticker.Stop()
 	for {
-		<-ticker.C
-		now := time.Now()
-		h.SessionsMu.Lock()
-		for code, session := range h.Sessions {
-			session.ClientsMu.RLock()
-			clientCount := len(session.Clients)
-			session.ClientsMu.RUnlock()
-			if clientCount == 0 && now.Sub(session.LastActive) > sessionExpiryDuration {
-				delete(h.Sessions, code)
-				fwlog.Infof("Canvas session %s expired and removed", code)
-			}
+		select {
+		case <-ticker.C:
+			h.removeExpiredSessions(time.Now())
+		case <-h.closed:
+			return
 		}
-		h.SessionsMu.Unlock()
+	}
+}
+
+// removeExpiredSessions removes the sessions left without clients for sessionExpiryDuration.
+// Their histories are flushed once SessionsMu is released, so a slow HistoryStore doesn't hold
+// up the lookups of the other sessions.
+func (h *CanvasServiceHandler) removeExpiredSessions(now time.Time) {
+	var expired []*CanvasSession
+	h.SessionsMu.Lock()
+	for code, session := range h.Sessions {
+		session.ClientsMu.RLock()
+		clientCount := len(session.Clients)
+		session.ClientsMu.RUnlock()
+		if clientCount == 0 && now.Sub(session.lastActive()) > sessionExpiryDuration {
+			delete(h.Sessions, code)
+			expired = append(expired, session)
+		}
+	}
+	h.SessionsMu.Unlock()
+	for _, session := range expired {
+		// Closes a client that joined while the session was being removed
+		session.closeClients()
+		h.flushSession(session)
+		fwlog.Infof("Canvas session %s expired and removed", session.Code)
 	}
 }
@@ -0,0 +1,536 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/quic-go/webtransport-go"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+// newTestWebSocketServer serves HandleWebSocket for a single session
+func newTestWebSocketServer(t *testing.T, h *CanvasServiceHandler, session *CanvasSession) string {
+	t.Helper()
+	h.Sessions[session.Code] = session
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleWebSocket))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "?code=" + session.Code
+}
+
+// clientCount returns the number of clients registered in the session
+func clientCount(session *CanvasSession) int {
+	session.ClientsMu.RLock()
+	defer session.ClientsMu.RUnlock()
+	return len(session.Clients)
+}
+
+// waitForClients waits until the session has exactly n clients
+func waitForClients(t *testing.T, session *CanvasSession, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for clientCount(session) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("session %s has %d clients, want %d", session.Code, clientCount(session), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProcessSessionDrawEventDropsSlowClient(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession)}
+	session := newCanvasSession("ROOM01")
+
+	slow := newSessionClient("slow", "")
+	fast := newSessionClient("fast", "")
+	session.Clients[slow.ID] = slow
+	session.Clients[fast.ID] = fast
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < clientSendQueueSize+1; i++ {
+			h.processSessionDrawEvent(session, fast, NewDrawEvent("draw", "#000000", "", 1, 0, 0, i, i))
+			<-fast.Send
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("processSessionDrawEvent blocked on a slow client")
+	}
+
+	select {
+	case <-slow.done:
+	default:
+		t.Error("slow client was not dropped")
+	}
+	select {
+	case <-fast.done:
+		t.Error("fast client should not be dropped")
+	default:
+	}
+}
+
+func TestEchoSuppression(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession)}
+	session := newCanvasSession("ECHO01")
+	url := newTestWebSocketServer(t, h, session)
+
+	dial := func(url string) (*websocket.Conn, string) {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		var hello ClientDrawResponse
+		if err := conn.ReadJSON(&hello); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		return conn, hello.ClientID
+	}
+	// readDrawEvent returns the next draw event, skipping presence updates
+	readDrawEvent := func(conn *websocket.Conn) (*DrawEvent, error) {
+		for {
+			var resp ClientDrawResponse
+			if err := conn.ReadJSON(&resp); err != nil {
+				return nil, err
+			}
+			if resp.DrawEvent != nil {
+				return resp.DrawEvent, nil
+			}
+		}
+	}
+
+	quiet, quietID := dial(url + "&echo=false")
+	peer, _ := dial(url)
+	waitForClients(t, session, 2)
+
+	if err := quiet.WriteJSON(&ClientDrawRequest{DrawEvent: NewDrawEvent("draw", "#000000", "", 1, 0, 0, 1, 1)}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	got, err := readDrawEvent(peer)
+	if err != nil {
+		t.Fatalf("peer ReadJSON() error = %v", err)
+	}
+	if got.ClientID != quietID {
+		t.Errorf("peer received an event of %s, want %s", got.ClientID, quietID)
+	}
+
+	// The peer's own event is echoed, the quiet client sees it but not its own one before it
+	if err := peer.WriteJSON(&ClientDrawRequest{DrawEvent: NewDrawEvent("draw", "#000000", "", 1, 2, 2, 3, 3)}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if got, err := readDrawEvent(peer); err != nil || got.ClientID == quietID {
+		t.Fatalf("peer echo = %+v, %v, want its own event", got, err)
+	}
+	got, err = readDrawEvent(quiet)
+	if err != nil {
+		t.Fatalf("quiet ReadJSON() error = %v", err)
+	}
+	if got.ClientID == quietID {
+		t.Errorf("client with echo off received its own event")
+	}
+}
+
+func TestIdleClientDisconnected(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession), IdleTimeout: 300 * time.Millisecond}
+	session := newCanvasSession("IDLE01")
+	url := newTestWebSocketServer(t, h, session)
+
+	dial := func() *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+	lurker := dial()
+	drawer := dial()
+	waitForClients(t, session, 2)
+
+	lurkerErr := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := lurker.ReadMessage(); err != nil {
+				lurkerErr <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			if _, _, err := drawer.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The drawer keeps the session active, which must not keep the lurker connected
+	deadline := time.Now().Add(2 * h.IdleTimeout)
+	for i := 0; time.Now().Before(deadline); i++ {
+		if err := drawer.WriteJSON(&ClientDrawRequest{DrawEvent: NewDrawEvent("draw", "#000000", "", 1, 0, 0, i, i)}); err != nil {
+			t.Fatalf("WriteJSON() error = %v", err)
+		}
+		time.Sleep(h.IdleTimeout / 6)
+	}
+
+	select {
+	case err := <-lurkerErr:
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != closeIdleTimeout || closeErr.Text != "idle timeout" {
+			t.Errorf("lurker read error = %v, want an idle timeout close frame", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle client was not disconnected")
+	}
+	waitForClients(t, session, 1)
+}
+
+func TestEchoDisabled(t *testing.T) {
+	testCases := []struct {
+		query string
+		want  bool
+	}{
+		{query: "", want: false},
+		{query: "echo=true", want: false},
+		{query: "echo=false", want: true},
+		{query: "echo=0", want: true},
+		{query: "echo=maybe", want: false},
+	}
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, "/ws/canva?code=A&"+tc.query, nil)
+		if got := echoDisabled(r); got != tc.want {
+			t.Errorf("echoDisabled(%q) = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestSessionBroadcastWriterRemovesClientOnWriteFailure(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession)}
+	session := newCanvasSession("ROOM02")
+
+	// A client without a connection fails every write.
+	dead := newSessionClient("dead", "")
+	session.Clients[dead.ID] = dead
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		h.sessionBroadcastWriter(session, dead)
+	}()
+	dead.Send <- &ClientDrawResponse{DrawEvent: NewDrawEvent("draw", "#000000", "other", 1, 0, 0, 1, 1)}
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sessionBroadcastWriter did not return after a write failure")
+	}
+
+	session.ClientsMu.RLock()
+	_, ok := session.Clients[dead.ID]
+	session.ClientsMu.RUnlock()
+	if ok {
+		t.Error("dead client is still registered in the session")
+	}
+	select {
+	case <-dead.done:
+	default:
+		t.Error("dead client was not closed")
+	}
+
+	// The reader side removing the client again must be a no-op.
+	session.removeClient(dead)
+}
+
+func TestWebSocketHeartbeat(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession), PingInterval: 50 * time.Millisecond}
+
+	t.Run("unresponsive client is disconnected", func(t *testing.T) {
+		session := newCanvasSession("PING01")
+		url := newTestWebSocketServer(t, h, session)
+
+		// The client never reads, so it never answers pings.
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		defer func() { _ = conn.Close() }()
+
+		waitForClients(t, session, 1)
+		waitForClients(t, session, 0)
+	})
+
+	t.Run("responsive client stays connected", func(t *testing.T) {
+		session := newCanvasSession("PING02")
+		url := newTestWebSocketServer(t, h, session)
+
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		defer func() { _ = conn.Close() }()
+		waitForClients(t, session, 1)
+		// Reading lets the default ping handler answer with pongs.
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		time.Sleep(10 * h.PingInterval)
+		if clientCount(session) != 1 {
+			t.Error("responsive client was disconnected")
+		}
+	})
+}
+
+func TestWebSocketSendsClientIDFirst(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession)}
+	session := newCanvasSession("HELLO1")
+	session.History = []*canvav1.DrawEvent{toProto(NewDrawEvent("draw", "#000000", "other", 1, 0, 0, 1, 1))}
+	url := newTestWebSocketServer(t, h, session)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var first ClientDrawResponse
+	if err := conn.ReadJSON(&first); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if first.ClientID == "" {
+		t.Fatalf("first message = %+v, want the assigned client id", first)
+	}
+	session.ClientsMu.RLock()
+	_, ok := session.Clients[first.ClientID]
+	session.ClientsMu.RUnlock()
+	if !ok {
+		t.Errorf("client id %s is not registered in the session", first.ClientID)
+	}
+
+	var second ClientDrawResponse
+	if err := conn.ReadJSON(&second); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if second.InitialHistory == nil || len(second.InitialHistory.Events) != 1 {
+		t.Errorf("second message = %+v, want the initial history", second)
+	}
+}
+
+func TestWebSocketResync(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession)}
+	session := newCanvasSession("RESYNC")
+	url := newTestWebSocketServer(t, h, session)
+	client := newSessionClient("other", "websocket")
+	for range 3 {
+		h.processSessionDrawEvent(session, client, NewDrawEvent("draw", "#000000", "", 1, 0, 0, 1, 1))
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if err := conn.WriteJSON(&ClientDrawRequest{Resync: &ResyncRequest{FromSeq: 1}}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	// The client id, initial history and presence come first
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	for {
+		var resp ClientDrawResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		if resp.Resync == nil {
+			continue
+		}
+		if resp.Resync.Truncated || len(resp.Resync.Events) != 2 || resp.Resync.Events[0].Seq != 2 || resp.Resync.Events[1].Seq != 3 {
+			t.Errorf("resync = %+v, want events 2 and 3", resp.Resync)
+		}
+		return
+	}
+}
+
+func TestWebSocketMessageTooBig(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession), MaxMessageSize: 256}
+	session := newCanvasSession("LIMIT1")
+	url := newTestWebSocketServer(t, h, session)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	waitForClients(t, session, 1)
+
+	// A draw event within the limit is accepted
+	if err := conn.WriteJSON(&ClientDrawRequest{DrawEvent: NewDrawEvent("draw", "#000000", "", 1, 0, 0, 1, 1)}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(session.historySnapshot()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("draw event within the limit was not recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	big := `{"draw_event":{"type":"draw","color":"` + strings.Repeat("f", 1024) + `"}}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(big)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	for {
+		if _, _, err = conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation {
+		t.Errorf("read error = %v, want a policy violation close frame", err)
+	}
+	waitForClients(t, session, 0)
+}
+
+func TestMessageLimitReader(t *testing.T) {
+	small := `{"draw_event":{"type":"draw"}}`
+	tests := []struct {
+		name  string
+		input string
+		max   int64
+		want  int
+		err   error
+	}{
+		{"within limit", small + small + small, int64(len(small)), 3, io.EOF},
+		{"no limit", small + strings.Repeat(" ", 4096) + small, 0, 2, io.EOF},
+		{"too big", small + `{"draw_event":{"color":"` + strings.Repeat("f", 64) + `"}}`, int64(len(small)), 1, errMessageTooBig},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limited := &messageLimitReader{r: strings.NewReader(tc.input), max: tc.max}
+			dec := json.NewDecoder(limited)
+			var decoded int
+			var err error
+			for {
+				limited.next(dec.InputOffset())
+				var request ClientDrawRequest
+				if err = dec.Decode(&request); err != nil {
+					break
+				}
+				decoded++
+			}
+			if decoded != tc.want || !errors.Is(err, tc.err) {
+				t.Errorf("decoded %d messages ending with %v, want %d ending with %v", decoded, err, tc.want, tc.err)
+			}
+		})
+	}
+}
+
+// headerCountingWriter counts the WriteHeader calls made on the response
+type headerCountingWriter struct {
+	*httptest.ResponseRecorder
+	writeHeaders int
+}
+
+func (w *headerCountingWriter) WriteHeader(statusCode int) {
+	w.writeHeaders++
+	w.ResponseRecorder.WriteHeader(statusCode)
+}
+
+func TestHandleWebTransportUpgradeFailure(t *testing.T) {
+	testCases := []struct {
+		name             string
+		upgrade          func(w http.ResponseWriter, r *http.Request) (*webtransport.Session, error)
+		checkOrigin      func(r *http.Request) bool
+		wantStatus       int
+		wantWriteHeaders int
+		wantUpgrade      bool
+	}{
+		{
+			name: "failure after headers were sent",
+			upgrade: func(w http.ResponseWriter, r *http.Request) (*webtransport.Session, error) {
+				w.WriteHeader(http.StatusOK)
+				return nil, errors.New("stream reset")
+			},
+			wantStatus:       http.StatusOK,
+			wantWriteHeaders: 1,
+			wantUpgrade:      true,
+		},
+		{
+			name: "failure before headers were sent",
+			upgrade: func(w http.ResponseWriter, r *http.Request) (*webtransport.Session, error) {
+				return nil, errors.New("missing datagram support")
+			},
+			wantStatus:       http.StatusInternalServerError,
+			wantWriteHeaders: 1,
+			wantUpgrade:      true,
+		},
+		{
+			name: "origin rejected before upgrading",
+			upgrade: func(w http.ResponseWriter, r *http.Request) (*webtransport.Session, error) {
+				return nil, errors.New("should not be called")
+			},
+			checkOrigin:      func(r *http.Request) bool { return false },
+			wantStatus:       http.StatusForbidden,
+			wantWriteHeaders: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			upgraded := false
+			h := &CanvasServiceHandler{
+				Sessions: make(map[string]*CanvasSession),
+				WTServer: &webtransport.Server{CheckOrigin: tc.checkOrigin},
+				upgradeWebTransport: func(w http.ResponseWriter, r *http.Request) (*webtransport.Session, error) {
+					upgraded = true
+					return tc.upgrade(w, r)
+				},
+			}
+			h.Sessions["WT0001"] = newCanvasSession("WT0001")
+
+			w := &headerCountingWriter{ResponseRecorder: httptest.NewRecorder()}
+			h.HandleWebTransport(w, httptest.NewRequest(http.MethodConnect, "/webtransport/canva?code=WT0001", nil))
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tc.wantStatus)
+			}
+			if w.writeHeaders != tc.wantWriteHeaders {
+				t.Errorf("WriteHeader called %d times, want %d", w.writeHeaders, tc.wantWriteHeaders)
+			}
+			if upgraded != tc.wantUpgrade {
+				t.Errorf("upgrade attempted = %v, want %v", upgraded, tc.wantUpgrade)
+			}
+		})
+	}
+}
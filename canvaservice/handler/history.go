@@ -0,0 +1,171 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+// HistoryStore persists the history of canvas sessions
+type HistoryStore interface {
+	// SaveHistory replaces the stored history of the session
+	SaveHistory(code string, events []*canvav1.DrawEvent) error
+	// DeleteHistory removes the stored history of the session, a missing one is not an error
+	DeleteHistory(code string) error
+}
+
+// FlushPolicy decides when session histories are written to the HistoryStore.
+// Frequent flushes lose fewer events on a crash at the cost of more store writes,
+// a graceful shutdown always flushes.
+type FlushPolicy struct {
+	// Events flushes a session once it has this many unflushed events, zero disables it
+	Events int
+	// Interval flushes every session with unflushed events periodically, zero disables it
+	Interval time.Duration
+	// OnClear flushes a session right after a clear event
+	OnClear bool
+}
+
+// shouldFlush reports whether the policy flushes a session after event
+func (p FlushPolicy) shouldFlush(event *DrawEvent, unflushed int) bool {
+	return (p.Events > 0 && unflushed >= p.Events) || (p.OnClear && event.Type == "clear")
+}
+
+// appendHistory stamps the event with the server time and the next sequence number of the
+// session and adds it to the history, it returns the number of unflushed events. Client clocks
+// can't be trusted, and the time is kept past the last event's so the history is totally ordered
+// even when the clock goes back.
+func (s *CanvasSession) appendHistory(event *DrawEvent) int {
+	s.HistoryMu.Lock()
+	defer s.HistoryMu.Unlock()
+	event.Time = time.Now().UnixMilli()
+	event.Seq = 1
+	if n := len(s.History); n > 0 {
+		last := s.History[n-1]
+		event.Time = max(event.Time, last.GetTime()+1)
+		event.Seq = last.GetSeq() + 1
+	}
+	s.History = append(s.History, toProto(event))
+	s.unflushed++
+	return s.unflushed
+}
+
+// flushHistory saves the session history to the store. The history is copied under HistoryMu
+// so draws can go on during the write, their events stay unflushed for the next flush.
+// flushMu keeps concurrent flushes of a session from saving an older snapshot last.
+func (s *CanvasSession) flushHistory(store HistoryStore) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.HistoryMu.Lock()
+	if s.unflushed == 0 || s.deleted {
+		s.HistoryMu.Unlock()
+		return nil
+	}
+	events := slices.Clone(s.History)
+	flushed := s.unflushed
+	s.unflushed = 0
+	s.HistoryMu.Unlock()
+
+	if err := store.SaveHistory(s.Code, events); err != nil {
+		s.HistoryMu.Lock()
+		s.unflushed += flushed
+		s.HistoryMu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// deleteHistory removes the stored history of the session for good, later flushes save nothing.
+// flushMu waits for a flush in progress, which would otherwise store the history again.
+func (s *CanvasSession) deleteHistory(store HistoryStore) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.HistoryMu.Lock()
+	s.deleted = true
+	s.unflushed = 0
+	s.HistoryMu.Unlock()
+	return store.DeleteHistory(s.Code)
+}
+
+// flushSession flushes the session when a HistoryStore is configured
+func (h *CanvasServiceHandler) flushSession(session *CanvasSession) {
+	if h.HistoryStore == nil {
+		return
+	}
+	if err := session.flushHistory(h.HistoryStore); err != nil {
+		fwlog.Warnf("Failed to save history of canvas session %s: %v", session.Code, err)
+	}
+}
+
+// FlushHistories saves the unflushed history of every session
+func (h *CanvasServiceHandler) FlushHistories() error {
+	if h.HistoryStore == nil {
+		return nil
+	}
+	h.SessionsMu.RLock()
+	sessions := make([]*CanvasSession, 0, len(h.Sessions))
+	for _, session := range h.Sessions {
+		sessions = append(sessions, session)
+	}
+	h.SessionsMu.RUnlock()
+
+	var errs []error
+	for _, session := range sessions {
+		if err := session.flushHistory(h.HistoryStore); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StartHistoryFlusher flushes the sessions every FlushPolicy.Interval until Close is called.
+// It does nothing without a HistoryStore or an interval.
+func (h *CanvasServiceHandler) StartHistoryFlusher() {
+	if h.HistoryStore == nil || h.FlushPolicy.Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(h.FlushPolicy.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := h.FlushHistories(); err != nil {
+					fwlog.Warnf("Failed to save canvas histories: %v", err)
+				}
+			case <-h.closed:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the history flusher and saves the unflushed histories, call it on shutdown
+func (h *CanvasServiceHandler) Close() error {
+	h.closeOnce.Do(func() {
+		if h.closed != nil {
+			close(h.closed)
+		}
+	})
+	return h.FlushHistories()
+}
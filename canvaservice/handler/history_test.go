@@ -0,0 +1,260 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+// fakeHistoryStore records the saved histories, block makes SaveHistory wait until it's closed
+type fakeHistoryStore struct {
+	mu    sync.Mutex
+	saves [][]*canvav1.DrawEvent
+	// stored is the last history saved of each session
+	stored  map[string][]*canvav1.DrawEvent
+	err     error
+	started chan struct{}
+	block   chan struct{}
+}
+
+func (f *fakeHistoryStore) SaveHistory(code string, events []*canvav1.DrawEvent) error {
+	if f.block != nil {
+		f.started <- struct{}{}
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.saves = append(f.saves, events)
+	if f.stored == nil {
+		f.stored = make(map[string][]*canvav1.DrawEvent)
+	}
+	f.stored[code] = events
+	return nil
+}
+
+func (f *fakeHistoryStore) DeleteHistory(code string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.stored, code)
+	return nil
+}
+
+// has reports whether a history of the session is stored
+func (f *fakeHistoryStore) has(code string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.stored[code]
+	return ok
+}
+
+// savedLens returns the number of events of each save
+func (f *fakeHistoryStore) savedLens() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lens := make([]int, len(f.saves))
+	for i, events := range f.saves {
+		lens[i] = len(events)
+	}
+	return lens
+}
+
+func newFlushTestHandler(store HistoryStore, policy FlushPolicy) (*CanvasServiceHandler, *CanvasSession, *SessionClient) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession), HistoryStore: store, FlushPolicy: policy}
+	session := newCanvasSession("flush")
+	h.Sessions[session.Code] = session
+	return h, session, newSessionClient("c1", "websocket")
+}
+
+func draw(h *CanvasServiceHandler, session *CanvasSession, client *SessionClient, eventType string) {
+	h.processSessionDrawEvent(session, client, &DrawEvent{Type: eventType, Color: "#000000", Size: 1})
+}
+
+func TestFlushPolicyEventsAndShutdown(t *testing.T) {
+	store := &fakeHistoryStore{}
+	h, session, client := newFlushTestHandler(store, FlushPolicy{Events: 3})
+
+	draw(h, session, client, "draw")
+	draw(h, session, client, "draw")
+	if got := store.savedLens(); len(got) != 0 {
+		t.Fatalf("saves before the event count = %v, want none", got)
+	}
+	draw(h, session, client, "draw")
+	if got := store.savedLens(); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("saves at the event count = %v, want [3]", got)
+	}
+
+	// Shutdown saves the events below the count
+	draw(h, session, client, "draw")
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := store.savedLens(); len(got) != 2 || got[1] != 4 {
+		t.Fatalf("saves after shutdown = %v, want [3 4]", got)
+	}
+
+	// Nothing new, nothing to save
+	if err := h.FlushHistories(); err != nil {
+		t.Fatalf("FlushHistories() error = %v", err)
+	}
+	if got := store.savedLens(); len(got) != 2 {
+		t.Errorf("saves without new events = %v, want [3 4]", got)
+	}
+}
+
+func TestFlushPolicyOnClear(t *testing.T) {
+	store := &fakeHistoryStore{}
+	h, session, client := newFlushTestHandler(store, FlushPolicy{OnClear: true})
+
+	draw(h, session, client, "draw")
+	if got := store.savedLens(); len(got) != 0 {
+		t.Fatalf("saves after a draw = %v, want none", got)
+	}
+	draw(h, session, client, "clear")
+	if got := store.savedLens(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("saves after a clear = %v, want [2]", got)
+	}
+}
+
+func TestFlushDrawDuringFlush(t *testing.T) {
+	store := &fakeHistoryStore{started: make(chan struct{}), block: make(chan struct{})}
+	h, session, client := newFlushTestHandler(store, FlushPolicy{})
+	draw(h, session, client, "draw")
+
+	flushed := make(chan error)
+	go func() { flushed <- h.FlushHistories() }()
+	<-store.started
+
+	// The history lock isn't held during the write, so the draw goes through
+	draw(h, session, client, "draw")
+	close(store.block)
+	if err := <-flushed; err != nil {
+		t.Fatalf("FlushHistories() error = %v", err)
+	}
+	if got := store.savedLens(); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("first save = %v, want the snapshot of [1] event", got)
+	}
+
+	store.block = nil
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := store.savedLens(); len(got) != 2 || got[1] != 2 {
+		t.Fatalf("saves = %v, want the draw made during the flush saved on shutdown", got)
+	}
+}
+
+func TestFlushFailureKeepsEventsUnflushed(t *testing.T) {
+	store := &fakeHistoryStore{err: errors.New("store down")}
+	h, session, client := newFlushTestHandler(store, FlushPolicy{})
+	draw(h, session, client, "draw")
+
+	if err := h.FlushHistories(); err == nil {
+		t.Fatal("FlushHistories() should report the store error")
+	}
+
+	store.mu.Lock()
+	store.err = nil
+	store.mu.Unlock()
+	if err := h.FlushHistories(); err != nil {
+		t.Fatalf("FlushHistories() error = %v", err)
+	}
+	if got := store.savedLens(); len(got) != 1 || got[0] != 1 {
+		t.Errorf("saves after recovery = %v, want [1]", got)
+	}
+}
+
+func TestAppendHistoryStampsServerTime(t *testing.T) {
+	session := newCanvasSession("stamp")
+	// A restored history may end after the server clock, new events still sort after it
+	future := time.Now().Add(time.Hour).UnixMilli()
+	session.History = []*canvav1.DrawEvent{{Type: "draw", Time: future, Seq: 1}}
+
+	before := time.Now().UnixMilli()
+	events := []*DrawEvent{
+		{Type: "draw", Time: 1},
+		{Type: "draw", Time: future * 2},
+		{Type: "draw"},
+	}
+	for _, event := range events {
+		session.appendHistory(event)
+	}
+
+	for i, event := range events {
+		if want := future + int64(i) + 1; event.Time != want {
+			t.Errorf("event %d time = %d, want %d", i, event.Time, want)
+		}
+		if got := session.History[i+1].GetTime(); got != event.Time {
+			t.Errorf("history event %d time = %d, want %d", i+1, got, event.Time)
+		}
+		if want := int64(i + 2); event.Seq != want || session.History[i+1].GetSeq() != want {
+			t.Errorf("event %d seq = %d, history seq = %d, want %d", i, event.Seq, session.History[i+1].GetSeq(), want)
+		}
+	}
+
+	// Without an earlier event the server clock is used
+	fresh := newCanvasSession("fresh")
+	event := &DrawEvent{Type: "draw", Time: 1}
+	fresh.appendHistory(event)
+	if after := time.Now().UnixMilli(); event.Time < before || event.Time > after {
+		t.Errorf("event time = %d, want the server time between %d and %d", event.Time, before, after)
+	}
+	if event.Seq != 1 {
+		t.Errorf("first event seq = %d, want 1", event.Seq)
+	}
+}
+
+func TestHistorySince(t *testing.T) {
+	// An imported history may start past seq 1
+	history := []*canvav1.DrawEvent{{Type: "draw", Seq: 5}, {Type: "draw", Seq: 6}, {Type: "draw", Seq: 7}}
+	testCases := []struct {
+		name          string
+		history       []*canvav1.DrawEvent
+		fromSeq       int64
+		wantSeqs      []int64
+		wantTruncated bool
+	}{
+		{name: "up to date", history: history, fromSeq: 7},
+		{name: "missed events", history: history, fromSeq: 5, wantSeqs: []int64{6, 7}},
+		{name: "missed all events", history: history, fromSeq: 4, wantSeqs: []int64{5, 6, 7}},
+		{name: "evicted", history: history, fromSeq: 2, wantSeqs: []int64{5, 6, 7}, wantTruncated: true},
+		{name: "past the end", history: history, fromSeq: 9, wantSeqs: []int64{5, 6, 7}, wantTruncated: true},
+		{name: "empty history", fromSeq: 0},
+		{name: "empty history after a restart", fromSeq: 3, wantTruncated: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			session := newCanvasSession("resync")
+			session.History = tc.history
+			events, truncated := session.historySince(tc.fromSeq)
+			var seqs []int64
+			for _, e := range events {
+				seqs = append(seqs, e.Seq)
+			}
+			if !slices.Equal(seqs, tc.wantSeqs) || truncated != tc.wantTruncated {
+				t.Errorf("historySince(%d) = seqs %v truncated %v, want seqs %v truncated %v", tc.fromSeq, seqs, truncated, tc.wantSeqs, tc.wantTruncated)
+			}
+		})
+	}
+}
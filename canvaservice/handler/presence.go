@@ -0,0 +1,89 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"slices"
+	"sort"
+)
+
+// clientPalette is cycled to give every client of a session a distinct color.
+// It identifies who drew what, the stroke itself keeps the color the user picked.
+var clientPalette = []string{
+	"#e6194b", "#3cb44b", "#4363d8", "#f58231",
+	"#911eb4", "#42d4f4", "#f032e6", "#9a6324",
+}
+
+// addClient registers the client in the session, assigns it the first free palette color
+// and tells every client about the new presence. A client joining a removed session is closed.
+func (s *CanvasSession) addClient(client *SessionClient) {
+	s.ClientsMu.Lock()
+	if s.closed {
+		s.ClientsMu.Unlock()
+		client.Close()
+		return
+	}
+	used := make([]string, 0, len(s.Clients))
+	for _, c := range s.Clients {
+		used = append(used, c.Color)
+	}
+	client.Color = pickClientColor(used)
+	s.Clients[client.ID] = client
+	s.ClientsMu.Unlock()
+
+	s.broadcastPresence()
+}
+
+// pickClientColor returns the first palette color not in used.
+// Once every color is taken colors are shared, spread evenly over the palette.
+func pickClientColor(used []string) string {
+	for _, color := range clientPalette {
+		if !slices.Contains(used, color) {
+			return color
+		}
+	}
+	return clientPalette[len(used)%len(clientPalette)]
+}
+
+// presence lists the clients currently in the session, ordered by id
+func (s *CanvasSession) presence() *Presence {
+	s.ClientsMu.RLock()
+	defer s.ClientsMu.RUnlock()
+	p := &Presence{Clients: make([]ClientInfo, 0, len(s.Clients))}
+	for _, c := range s.Clients {
+		p.Clients = append(p.Clients, ClientInfo{ID: c.ID, Color: c.Color})
+	}
+	sort.Slice(p.Clients, func(i, j int) bool { return p.Clients[i].ID < p.Clients[j].ID })
+	return p
+}
+
+// broadcastPresence sends the current client list to every client
+func (s *CanvasSession) broadcastPresence() {
+	s.broadcast(&ClientDrawResponse{Presence: s.presence()})
+}
+
+// clientLegend maps every client that drew one of the events to its assigned color
+func clientLegend(events []DrawEvent) map[string]string {
+	legend := make(map[string]string)
+	for _, e := range events {
+		if e.ClientID != "" && e.ClientColor != "" {
+			legend[e.ClientID] = e.ClientColor
+		}
+	}
+	if len(legend) == 0 {
+		return nil
+	}
+	return legend
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddClientAssignsColors(t *testing.T) {
+	session := newCanvasSession("COLOR1")
+
+	first := newSessionClient("first", "")
+	second := newSessionClient("second", "")
+	session.addClient(first)
+	session.addClient(second)
+
+	if first.Color == "" || second.Color == "" {
+		t.Fatalf("clients were not assigned colors: %q, %q", first.Color, second.Color)
+	}
+	if first.Color == second.Color {
+		t.Errorf("both clients got color %s", first.Color)
+	}
+
+	freed := first.Color
+	session.removeClient(first)
+	third := newSessionClient("third", "")
+	session.addClient(third)
+	if third.Color != freed {
+		t.Errorf("third client got color %s, want the freed color %s", third.Color, freed)
+	}
+
+	// Everyone still connected is told about the join and the leave
+	var last *Presence
+	for len(second.Send) > 0 {
+		if resp := <-second.Send; resp.Presence != nil {
+			last = resp.Presence
+		}
+	}
+	if last == nil || len(last.Clients) != 2 {
+		t.Fatalf("last presence = %+v, want 2 clients", last)
+	}
+}
+
+func TestPickClientColorWhenPaletteIsExhausted(t *testing.T) {
+	used := append([]string{}, clientPalette...)
+	if got := pickClientColor(used); got != clientPalette[0] {
+		t.Errorf("pickClientColor() = %s, want %s", got, clientPalette[0])
+	}
+}
+
+func TestProcessSessionDrawEventSetsClientColor(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession)}
+	session := newCanvasSession("COLOR2")
+	client := newSessionClient("drawer", "")
+	session.addClient(client)
+
+	h.processSessionDrawEvent(session, client, NewDrawEvent("draw", "#000000", "", 1, 0, 0, 1, 1))
+
+	if got := session.History[0].GetClientColor(); got != client.Color {
+		t.Errorf("ClientColor = %s, want %s", got, client.Color)
+	}
+}
+
+func TestRenderSVGLegend(t *testing.T) {
+	events := []DrawEvent{
+		{Type: "draw", Color: "#000000", Size: 1, CurrX: 1, ClientID: "alice", ClientColor: "#e6194b"},
+		{Type: "draw", Color: "#000000", Size: 1, CurrX: 2, ClientID: "bob", ClientColor: "#3cb44b"},
+	}
+	var b strings.Builder
+	if err := RenderSVG(&b, events); err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+	for _, want := range []string{`fill="#e6194b"`, ">alice</text>", `fill="#3cb44b"`, ">bob</text>"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("RenderSVG() = %s, want it to contain %s", b.String(), want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// svgMargin is the space kept around the drawing when rendering to SVG
+	svgMargin = 10
+	// svgBackground is the canvas background, which is also what the eraser paints
+	svgBackground = "#ffffff"
+	// svgLegendRow is the height of a client entry in the legend under the drawing
+	svgLegendRow = 16
+	// svgLegendWidth is the room kept for a client entry in the legend
+	svgLegendWidth = 120
+)
+
+// RenderSVG renders the drawing events to an SVG image.
+// Events before the last "clear" are skipped since they are no longer visible,
+// the clients that drew the rest are listed with their assigned colors under the drawing.
+func RenderSVG(w io.Writer, events []DrawEvent) error {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Type == "clear" {
+			events = events[i+1:]
+			break
+		}
+	}
+
+	width, height := svgMargin, svgMargin
+	for _, e := range events {
+		width = max(width, e.PrevX+e.Size+svgMargin, e.CurrX+e.Size+svgMargin)
+		height = max(height, e.PrevY+e.Size+svgMargin, e.CurrY+e.Size+svgMargin)
+	}
+
+	legend := clientLegend(events)
+	clients := make([]string, 0, len(legend))
+	for id := range legend {
+		clients = append(clients, id)
+	}
+	sort.Strings(clients)
+	legendTop := height
+	if len(clients) > 0 {
+		width = max(width, svgLegendWidth+svgMargin)
+		height += len(clients)*svgLegendRow + svgMargin
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height, width, height)
+	b.WriteString("\n")
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, svgBackground)
+	b.WriteString("\n")
+	for _, e := range events {
+		if e.Type == "ping" {
+			continue
+		}
+		renderSVGElement(&b, &e)
+	}
+	for i, id := range clients {
+		y := legendTop + i*svgLegendRow + svgLegendRow/2
+		fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="5" fill="%s"/><text x="%d" y="%d" font-size="12" dominant-baseline="middle">%s</text>`,
+			svgMargin+5, y, html.EscapeString(legend[id]), svgMargin+16, y, html.EscapeString(id))
+		b.WriteString("\n")
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderSVGElement writes the SVG element for a single event according to its tool
+func renderSVGElement(b *strings.Builder, e *DrawEvent) {
+	color := html.EscapeString(e.Color)
+	if color == "" {
+		color = "#000000"
+	}
+	size := max(e.Size, 1)
+
+	switch e.Tool {
+	case ToolEraser:
+		fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d" stroke-linecap="round"/>`,
+			e.PrevX, e.PrevY, e.CurrX, e.CurrY, svgBackground, size)
+	case ToolRect:
+		fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="%s" stroke-width="%d"/>`,
+			min(e.PrevX, e.CurrX), min(e.PrevY, e.CurrY), abs(e.CurrX-e.PrevX), abs(e.CurrY-e.PrevY), color, size)
+	case ToolEllipse:
+		fmt.Fprintf(b, `<ellipse cx="%d" cy="%d" rx="%d" ry="%d" fill="none" stroke="%s" stroke-width="%d"/>`,
+			(e.PrevX+e.CurrX)/2, (e.PrevY+e.CurrY)/2, abs(e.CurrX-e.PrevX)/2, abs(e.CurrY-e.PrevY)/2, color, size)
+	case ToolLine:
+		fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d"/>`,
+			e.PrevX, e.PrevY, e.CurrX, e.CurrY, color, size)
+	default:
+		// Pen strokes are sent as short segments, round caps join them smoothly.
+		fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d" stroke-linecap="round"/>`,
+			e.PrevX, e.PrevY, e.CurrX, e.CurrY, color, size)
+	}
+	b.WriteString("\n")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
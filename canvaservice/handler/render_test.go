@@ -0,0 +1,89 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSVGTools(t *testing.T) {
+	testCases := []struct {
+		name  string
+		event DrawEvent
+		want  string
+	}{
+		{
+			name:  "pen",
+			event: DrawEvent{Type: "draw", Tool: ToolPen, Color: "#ff0000", Size: 2, PrevX: 1, PrevY: 2, CurrX: 3, CurrY: 4},
+			want:  `<line x1="1" y1="2" x2="3" y2="4" stroke="#ff0000" stroke-width="2" stroke-linecap="round"/>`,
+		},
+		{
+			name:  "eraser",
+			event: DrawEvent{Type: "draw", Tool: ToolEraser, Size: 8, PrevX: 1, PrevY: 2, CurrX: 3, CurrY: 4},
+			want:  `<line x1="1" y1="2" x2="3" y2="4" stroke="#ffffff" stroke-width="8" stroke-linecap="round"/>`,
+		},
+		{
+			name:  "line",
+			event: DrawEvent{Type: "draw", Tool: ToolLine, Color: "#0000ff", Size: 1, PrevX: 0, PrevY: 0, CurrX: 50, CurrY: 20},
+			want:  `<line x1="0" y1="0" x2="50" y2="20" stroke="#0000ff" stroke-width="1"/>`,
+		},
+		{
+			name:  "rect",
+			event: DrawEvent{Type: "draw", Tool: ToolRect, Color: "#000000", Size: 1, PrevX: 30, PrevY: 40, CurrX: 10, CurrY: 20},
+			want:  `<rect x="10" y="20" width="20" height="20" fill="none" stroke="#000000" stroke-width="1"/>`,
+		},
+		{
+			name:  "ellipse",
+			event: DrawEvent{Type: "draw", Tool: ToolEllipse, Color: "#000000", Size: 1, PrevX: 10, PrevY: 10, CurrX: 30, CurrY: 50},
+			want:  `<ellipse cx="20" cy="30" rx="10" ry="20" fill="none" stroke="#000000" stroke-width="1"/>`,
+		},
+		{
+			name:  "color is escaped",
+			event: DrawEvent{Type: "draw", Color: `"/><script>`, Size: 1, CurrX: 1},
+			want:  `stroke="&#34;/&gt;&lt;script&gt;"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := RenderSVG(&b, []DrawEvent{tc.event}); err != nil {
+				t.Fatalf("RenderSVG() error = %v", err)
+			}
+			if !strings.Contains(b.String(), tc.want) {
+				t.Errorf("RenderSVG() = %s, want it to contain %s", b.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderSVGSkipsClearedEvents(t *testing.T) {
+	events := []DrawEvent{
+		{Type: "draw", Color: "#111111", Size: 1, CurrX: 1},
+		{Type: "clear"},
+		{Type: "draw", Color: "#222222", Size: 1, CurrX: 1},
+	}
+	var b strings.Builder
+	if err := RenderSVG(&b, events); err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+	if strings.Contains(b.String(), "#111111") {
+		t.Error("RenderSVG() rendered an event from before the clear")
+	}
+	if !strings.Contains(b.String(), "#222222") {
+		t.Error("RenderSVG() did not render the event after the clear")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+)
+
+const (
+	// maxReplayEvents bounds the number of events a replay streams
+	maxReplayEvents = maxImportEvents
+	// maxReplayDuration bounds the total time a replay waits between events,
+	// longer histories are played back faster
+	maxReplayDuration = 2 * time.Minute
+	// maxReplayGap caps a single pause so idle stretches of a session are skipped
+	maxReplayGap = 3 * time.Second
+	// maxReplaySpeed is the highest accepted speed factor
+	maxReplaySpeed = 1000
+)
+
+// ReplayCanvas streams the history of a session as newline-delimited JSON events,
+// pausing between events as long as the original drawing did divided by the speed query parameter.
+// speed defaults to 1, and 0 streams the events without pauses.
+func (h *CanvasServiceHandler) ReplayCanvas(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "Missing canvas code", http.StatusBadRequest)
+		return
+	}
+	speed := 1.0
+	if s := r.URL.Query().Get("speed"); s != "" {
+		var err error
+		speed, err = strconv.ParseFloat(s, 64)
+		if err != nil || speed < 0 || speed > maxReplaySpeed {
+			http.Error(w, fmt.Sprintf("Invalid speed, want a number between 0 and %d", maxReplaySpeed), http.StatusBadRequest)
+			return
+		}
+	}
+	h.SessionsMu.RLock()
+	session, ok := h.Sessions[code]
+	h.SessionsMu.RUnlock()
+	if !ok {
+		http.Error(w, "Canvas not found", http.StatusNotFound)
+		return
+	}
+
+	events := session.historySnapshot()
+	if len(events) > maxReplayEvents {
+		events = events[:maxReplayEvents]
+	}
+	delays := replayDelays(events, speed)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	for i := range events {
+		if !sleepContext(r.Context(), delays[i]) {
+			return
+		}
+		if err := enc.Encode(&events[i]); err != nil {
+			fwlog.Debugf("replay of %s stopped: %v", code, err)
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			fwlog.Debugf("replay of %s stopped: %v", code, err)
+			return
+		}
+	}
+}
+
+// replayDelays returns the pause before each event, the time since the previous event divided by speed.
+// Gaps are capped at maxReplayGap and the total at maxReplayDuration, a zero speed means no pauses.
+func replayDelays(events []DrawEvent, speed float64) []time.Duration {
+	delays := make([]time.Duration, len(events))
+	if speed <= 0 {
+		return delays
+	}
+	var total time.Duration
+	for i := 1; i < len(events); i++ {
+		gap := time.Duration(events[i].Time-events[i-1].Time) * time.Millisecond
+		if gap <= 0 {
+			continue
+		}
+		delays[i] = min(time.Duration(float64(gap)/speed), maxReplayGap)
+		total += delays[i]
+	}
+	if total > maxReplayDuration {
+		scale := float64(maxReplayDuration) / float64(total)
+		for i := range delays {
+			delays[i] = time.Duration(float64(delays[i]) * scale)
+		}
+	}
+	return delays
+}
+
+// sleepContext waits for d and reports false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
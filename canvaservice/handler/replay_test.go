@@ -0,0 +1,154 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+func TestReplayCanvasTiming(t *testing.T) {
+	h := NewCanvasServiceHandler()
+	session := newCanvasSession("REPLAY")
+	// Gaps of 1s and 2s, played back at 20x
+	for i, at := range []int64{1000, 2000, 4000} {
+		e := NewDrawEvent("draw", "#000000", "client-a", 2, i, i, i+1, i+1)
+		e.Time = at
+		session.History = append(session.History, toProto(e))
+	}
+	h.Sessions[session.Code] = session
+
+	srv := httptest.NewServer(http.HandlerFunc(h.ReplayCanvas))
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL + "/replay?code=REPLAY&speed=20")
+	if err != nil {
+		t.Fatalf("GET /replay failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var arrivals []time.Duration
+	scanner := bufio.NewScanner(resp.Body)
+	for i := 0; scanner.Scan(); i++ {
+		arrivals = append(arrivals, time.Since(start))
+		var e DrawEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("event %d is not JSON: %v", i, err)
+		}
+		if e.PrevX != i {
+			t.Errorf("event %d out of order, got the one drawn at %d", i, e.PrevX)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("reading replay failed: %v", err)
+	}
+	if len(arrivals) != 3 {
+		t.Fatalf("got %d events, want 3", len(arrivals))
+	}
+
+	// Expected gaps are 50ms and 100ms, leave room for a slow CI machine
+	wantGaps := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond}
+	for i, want := range wantGaps {
+		got := arrivals[i+1] - arrivals[i]
+		if got < want-10*time.Millisecond || got > want+250*time.Millisecond {
+			t.Errorf("gap before event %d = %v, want about %v", i+1, got, want)
+		}
+	}
+}
+
+func TestReplayDelays(t *testing.T) {
+	at := func(times ...int64) []DrawEvent {
+		events := make([]DrawEvent, len(times))
+		for i, ms := range times {
+			events[i].Time = ms
+		}
+		return events
+	}
+
+	testCases := []struct {
+		name   string
+		events []DrawEvent
+		speed  float64
+		want   []time.Duration
+	}{
+		{name: "real time", events: at(0, 100, 300), speed: 1, want: []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond}},
+		{name: "double speed", events: at(0, 100, 300), speed: 2, want: []time.Duration{0, 50 * time.Millisecond, 100 * time.Millisecond}},
+		{name: "no pauses", events: at(0, 100, 300), speed: 0, want: []time.Duration{0, 0, 0}},
+		{name: "out of order times", events: at(500, 100, 200), speed: 1, want: []time.Duration{0, 0, 100 * time.Millisecond}},
+		{name: "idle gap capped", events: at(0, int64(time.Hour/time.Millisecond)), speed: 1, want: []time.Duration{0, maxReplayGap}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := replayDelays(tc.events, tc.speed)
+			if len(got) != len(tc.want) {
+				t.Fatalf("replayDelays() returned %d delays, want %d", len(got), len(tc.want))
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("delay %d = %v, want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+
+	// Long histories are compressed into maxReplayDuration
+	times := make([]int64, 1000)
+	for i := range times {
+		times[i] = int64(i) * 1000
+	}
+	var total time.Duration
+	for _, d := range replayDelays(at(times...), 1) {
+		total += d
+	}
+	if total > maxReplayDuration {
+		t.Errorf("total delay = %v, want at most %v", total, maxReplayDuration)
+	}
+}
+
+func TestReplayCanvasRejectsInvalidRequests(t *testing.T) {
+	h := NewCanvasServiceHandler()
+	h.Sessions["EXISTS"] = newCanvasSession("EXISTS")
+	h.Sessions["EXISTS"].History = []*canvav1.DrawEvent{toProto(NewDrawEvent("clear", "", "a", 0, 0, 0, 0, 0))}
+
+	testCases := []struct {
+		url  string
+		want int
+	}{
+		{url: "/replay", want: http.StatusBadRequest},
+		{url: "/replay?code=MISSING", want: http.StatusNotFound},
+		{url: "/replay?code=EXISTS&speed=fast", want: http.StatusBadRequest},
+		{url: "/replay?code=EXISTS&speed=-1", want: http.StatusBadRequest},
+		{url: "/replay?code=EXISTS&speed=0", want: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		h.ReplayCanvas(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		if rec.Code != tc.want {
+			t.Errorf("GET %s status = %d, want %d", tc.url, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+)
+
+// RoomStats describes an active canvas session
+type RoomStats struct {
+	Code          string    `json:"code"`
+	Clients       int       `json:"clients"`
+	HistoryLength int       `json:"historyLength"`
+	LastActive    time.Time `json:"lastActive"`
+}
+
+// authorizeAdmin checks the request carries the AdminToken as a bearer token and writes the
+// error response otherwise. Without an AdminToken the admin endpoints don't exist.
+func (h *CanvasServiceHandler) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if h.AdminToken == "" {
+		http.NotFound(w, r)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// ListRooms returns the stats of every session sorted by code, for operators.
+// It requires the AdminToken as a bearer token and answers 404 when none is configured.
+func (h *CanvasServiceHandler) ListRooms(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.SessionsMu.RLock()
+	rooms := make([]RoomStats, 0, len(h.Sessions))
+	for code, session := range h.Sessions {
+		session.ClientsMu.RLock()
+		clients := len(session.Clients)
+		session.ClientsMu.RUnlock()
+		session.HistoryMu.RLock()
+		historyLength := len(session.History)
+		session.HistoryMu.RUnlock()
+		rooms = append(rooms, RoomStats{
+			Code:          code,
+			Clients:       clients,
+			HistoryLength: historyLength,
+			LastActive:    session.lastActive(),
+		})
+	}
+	h.SessionsMu.RUnlock()
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Code < rooms[j].Code })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(map[string][]RoomStats{"rooms": rooms}); err != nil {
+		fwlog.Warnf("write room list failed: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+func TestListRooms(t *testing.T) {
+	testCases := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "disabled", authorization: "Bearer secret", wantStatus: http.StatusNotFound},
+		{name: "missing token", adminToken: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", adminToken: "secret", authorization: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", adminToken: "secret", authorization: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "valid token", adminToken: "secret", authorization: "Bearer secret", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewCanvasServiceHandler()
+			h.AdminToken = tc.adminToken
+			busy := addTestSession(h, "BUSY01", time.Minute, 2)
+			busy.History = []*canvav1.DrawEvent{{}, {}, {}}
+			addTestSession(h, "IDLE01", time.Hour, 0)
+
+			req := httptest.NewRequest(http.MethodGet, "/canvas/rooms", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.ListRooms(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("ListRooms() status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Rooms []RoomStats `json:"rooms"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode room list: %v", err)
+			}
+			if len(resp.Rooms) != 2 {
+				t.Fatalf("got %d rooms, want 2", len(resp.Rooms))
+			}
+			got := resp.Rooms[0]
+			if got.Code != "BUSY01" || got.Clients != 2 || got.HistoryLength != 3 {
+				t.Errorf("rooms[0] = %+v, want BUSY01 with 2 clients and 3 events", got)
+			}
+			if !got.LastActive.Equal(busy.lastActive()) {
+				t.Errorf("rooms[0].LastActive = %v, want %v", got.LastActive, busy.lastActive())
+			}
+			if resp.Rooms[1].Code != "IDLE01" || resp.Rooms[1].Clients != 0 {
+				t.Errorf("rooms[1] = %+v, want empty IDLE01", resp.Rooms[1])
+			}
+		})
+	}
+}
+
+func TestDeleteCanvas(t *testing.T) {
+	h := NewCanvasServiceHandler()
+	h.AdminToken = "secret"
+	session := newCanvasSession("ROOM01")
+	url := newTestWebSocketServer(t, h, session)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	waitForClients(t, session, 1)
+
+	deleteCanvas := func(code, authorization string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/canvas/delete?code="+code, nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		h.DeleteCanvas(rec, req)
+		return rec.Code
+	}
+	if got := deleteCanvas("ROOM01", "Bearer nope"); got != http.StatusUnauthorized {
+		t.Fatalf("DeleteCanvas() with a wrong token status = %d, want %d", got, http.StatusUnauthorized)
+	}
+	if got := deleteCanvas("NOPE01", "Bearer secret"); got != http.StatusNotFound {
+		t.Fatalf("DeleteCanvas() of an unknown code status = %d, want %d", got, http.StatusNotFound)
+	}
+	if got := deleteCanvas("ROOM01", "Bearer secret"); got != http.StatusNoContent {
+		t.Fatalf("DeleteCanvas() status = %d, want %d", got, http.StatusNoContent)
+	}
+
+	if _, ok := h.Sessions["ROOM01"]; ok {
+		t.Error("deleted session is still registered")
+	}
+	waitForClients(t, session, 0)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Fatal("client connection was not closed")
+			}
+			break
+		}
+	}
+
+	// A client that looked the session up before the deletion can't join it
+	late := newSessionClient("late", "")
+	session.addClient(late)
+	select {
+	case <-late.done:
+	default:
+		t.Error("client joining a deleted session was not closed")
+	}
+	if n := clientCount(session); n != 0 {
+		t.Errorf("deleted session has %d clients, want 0", n)
+	}
+}
+
+// An abusive room's content must be gone from the store too
+func TestDeleteCanvasDeletesStoredHistory(t *testing.T) {
+	store := &fakeHistoryStore{}
+	h, session, client := newFlushTestHandler(store, FlushPolicy{})
+	h.AdminToken = "secret"
+	draw(h, session, client, "draw")
+	if err := h.FlushHistories(); err != nil {
+		t.Fatalf("FlushHistories() error = %v", err)
+	}
+	draw(h, session, client, "draw")
+
+	req := httptest.NewRequest(http.MethodDelete, "/canvas/delete?code="+session.Code, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	h.DeleteCanvas(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("DeleteCanvas() status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if store.has(session.Code) {
+		t.Error("the history of the deleted session is still stored")
+	}
+
+	// Neither a late flush nor the shutdown store it again
+	draw(h, session, client, "draw")
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := session.flushHistory(store); err != nil {
+		t.Fatalf("flushHistory() error = %v", err)
+	}
+	if store.has(session.Code) {
+		t.Error("the history of the deleted session was stored again")
+	}
+}
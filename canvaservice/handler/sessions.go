@@ -0,0 +1,132 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+)
+
+// errTooManySessions is returned when the session cap is reached and every session has clients
+var errTooManySessions = errors.New("too many active canvas sessions, try again later")
+
+// touch marks the session as active now
+func (s *CanvasSession) touch() {
+	s.activeMu.Lock()
+	s.LastActive = time.Now()
+	s.activeMu.Unlock()
+}
+
+// lastActive returns the time the session was last active
+func (s *CanvasSession) lastActive() time.Time {
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+	return s.LastActive
+}
+
+// addSession registers a new session. At MaxSessions it evicts the least recently active
+// empty session first, and fails with errTooManySessions when there is none.
+func (h *CanvasServiceHandler) addSession(session *CanvasSession) error {
+	h.SessionsMu.Lock()
+	defer h.SessionsMu.Unlock()
+
+	if h.MaxSessions > 0 && len(h.Sessions) >= h.MaxSessions {
+		if !h.evictIdleSessionLocked() {
+			return errTooManySessions
+		}
+	}
+	h.Sessions[session.Code] = session
+	return nil
+}
+
+// evictIdleSessionLocked removes the least recently active session without clients.
+// The caller must hold SessionsMu.
+func (h *CanvasServiceHandler) evictIdleSessionLocked() bool {
+	var oldest *CanvasSession
+	var oldestActive time.Time
+	for _, session := range h.Sessions {
+		session.ClientsMu.RLock()
+		clientCount := len(session.Clients)
+		session.ClientsMu.RUnlock()
+		if clientCount > 0 {
+			continue
+		}
+		if active := session.lastActive(); oldest == nil || active.Before(oldestActive) {
+			oldest, oldestActive = session, active
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(h.Sessions, oldest.Code)
+	oldest.closeClients()
+	fwlog.Infof("Canvas session %s evicted to make room, last active %v", oldest.Code, oldestActive)
+	return true
+}
+
+// closeClients disconnects every client of a removed session, clients joining afterwards
+// are closed by addClient. Their readers and writers unregister them as they exit.
+func (s *CanvasSession) closeClients() {
+	s.ClientsMu.Lock()
+	s.closed = true
+	clients := make([]*SessionClient, 0, len(s.Clients))
+	for _, client := range s.Clients {
+		clients = append(clients, client)
+	}
+	s.ClientsMu.Unlock()
+	for _, client := range clients {
+		client.Close()
+	}
+}
+
+// DeleteCanvas removes the session given by the code parameter and disconnects its clients,
+// to close an abusive room right away. It requires the AdminToken like ListRooms.
+func (h *CanvasServiceHandler) DeleteCanvas(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "Missing canvas code", http.StatusBadRequest)
+		return
+	}
+
+	h.SessionsMu.Lock()
+	session, ok := h.Sessions[code]
+	if ok {
+		delete(h.Sessions, code)
+	}
+	h.SessionsMu.Unlock()
+	if !ok {
+		http.Error(w, "Canvas not found", http.StatusNotFound)
+		return
+	}
+	session.closeClients()
+	// The room is deleted for its content, which must not outlive it in the store
+	if h.HistoryStore != nil {
+		if err := session.deleteHistory(h.HistoryStore); err != nil {
+			fwlog.Warnf("Failed to delete the stored history of canvas session %s: %v", code, err)
+		}
+	}
+	fwlog.Infof("Canvas session %s deleted by an admin", code)
+	w.WriteHeader(http.StatusNoContent)
+}
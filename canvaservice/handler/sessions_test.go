@@ -0,0 +1,153 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// addTestSession registers a session last active at the given offset from now
+func addTestSession(h *CanvasServiceHandler, code string, age time.Duration, clients int) *CanvasSession {
+	session := newCanvasSession(code)
+	session.LastActive = time.Now().Add(-age)
+	for i := 0; i < clients; i++ {
+		id := fmt.Sprintf("%s-client-%d", code, i)
+		session.Clients[id] = newSessionClient(id, "websocket")
+	}
+	h.Sessions[code] = session
+	return session
+}
+
+func TestCreateCanvasEvictsLeastRecentlyActiveEmptySession(t *testing.T) {
+	h := NewCanvasServiceHandler()
+	h.MaxSessions = 4
+	addTestSession(h, "BUSY01", 3*time.Hour, 1) // oldest, but has a client
+	addTestSession(h, "IDLE01", 2*time.Hour, 0) // oldest empty session
+	addTestSession(h, "IDLE02", time.Hour, 0)
+	addTestSession(h, "FRESH1", time.Minute, 0)
+
+	rec := httptest.NewRecorder()
+	h.CreateCanvas(rec, httptest.NewRequest(http.MethodPost, "/create", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("CreateCanvas() status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+
+	if len(h.Sessions) != h.MaxSessions {
+		t.Errorf("%d sessions, want %d", len(h.Sessions), h.MaxSessions)
+	}
+	if _, ok := h.Sessions["IDLE01"]; ok {
+		t.Error("least recently active empty session IDLE01 was not evicted")
+	}
+	for _, code := range []string{"BUSY01", "IDLE02", "FRESH1", resp.Code} {
+		if _, ok := h.Sessions[code]; !ok {
+			t.Errorf("session %s should have been kept", code)
+		}
+	}
+}
+
+func TestCreateCanvasRejectsWhenNoSessionIsEmpty(t *testing.T) {
+	h := NewCanvasServiceHandler()
+	h.MaxSessions = 2
+	addTestSession(h, "BUSY01", time.Hour, 1)
+	addTestSession(h, "BUSY02", time.Hour, 2)
+
+	rec := httptest.NewRecorder()
+	h.CreateCanvas(rec, httptest.NewRequest(http.MethodPost, "/create", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("CreateCanvas() status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if len(h.Sessions) != 2 {
+		t.Errorf("%d sessions, want the 2 busy ones", len(h.Sessions))
+	}
+}
+
+func TestRemoveClientMarksSessionActive(t *testing.T) {
+	session := newCanvasSession("TOUCH1")
+	session.LastActive = time.Now().Add(-time.Hour)
+	client := newSessionClient("c1", "websocket")
+	session.Clients[client.ID] = client
+
+	session.removeClient(client)
+	if time.Since(session.lastActive()) > time.Minute {
+		t.Errorf("LastActive = %v, want about now after the last client left", session.lastActive())
+	}
+}
+
+func TestRemoveExpiredSessionsFlushesOutsideTheLock(t *testing.T) {
+	store := &fakeHistoryStore{started: make(chan struct{}), block: make(chan struct{})}
+	h, session, client := newFlushTestHandler(store, FlushPolicy{})
+	draw(h, session, client, "draw")
+	session.LastActive = time.Now().Add(-2 * sessionExpiryDuration)
+	addTestSession(h, "FRESH1", time.Minute, 0)
+
+	removed := make(chan struct{})
+	go func() {
+		h.removeExpiredSessions(time.Now())
+		close(removed)
+	}()
+	<-store.started
+
+	// The sessions can be looked up while the expired one is saved
+	locked := make(chan struct{})
+	go func() {
+		h.SessionsMu.RLock()
+		_, expiredKept := h.Sessions[session.Code]
+		_, freshKept := h.Sessions["FRESH1"]
+		h.SessionsMu.RUnlock()
+		if expiredKept || !freshKept {
+			t.Errorf("sessions kept: expired %v, fresh %v, want only the fresh one", expiredKept, freshKept)
+		}
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SessionsMu is held while the history is saved")
+	}
+
+	close(store.block)
+	<-removed
+	if got := store.savedLens(); len(got) != 1 || got[0] != 1 {
+		t.Errorf("saves = %v, want the expired session's [1] event", got)
+	}
+}
+
+func TestSessionCleanerStopsOnClose(t *testing.T) {
+	h := &CanvasServiceHandler{Sessions: make(map[string]*CanvasSession), closed: make(chan struct{})}
+	stopped := make(chan struct{})
+	go func() {
+		h.sessionCleaner()
+		close(stopped)
+	}()
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sessionCleaner still running after Close")
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+
+	"github.com/gorilla/websocket"
+)
+
+// ProtoSubprotocol is the WebSocket subprotocol sending draw events both ways as binary frames
+// holding a canvav1.DrawEvent, about half the size of their JSON. The other messages stay
+// JSON text frames, clients that don't request it get JSON only.
+const ProtoSubprotocol = "canva.v1.proto"
+
+// errUnexpectedBinary is returned for a binary frame from a client that didn't negotiate ProtoSubprotocol
+var errUnexpectedBinary = errors.New("binary message without the " + ProtoSubprotocol + " subprotocol")
+
+// encodeWebSocketResponse returns the frame type and payload of resp for the client
+func encodeWebSocketResponse(client *SessionClient, resp *ClientDrawResponse) (int, []byte, error) {
+	if client.Binary && resp.DrawEvent != nil {
+		data, err := ProtoCodec.Marshal(resp.DrawEvent)
+		return websocket.BinaryMessage, data, err
+	}
+	data, err := wireCodec.Marshal(resp)
+	return websocket.TextMessage, data, err
+}
+
+// decodeWebSocketRequest decodes a frame read from the client
+func decodeWebSocketRequest(client *SessionClient, messageType int, data []byte) (*ClientDrawRequest, error) {
+	var request ClientDrawRequest
+	if messageType != websocket.BinaryMessage {
+		err := wireCodec.Unmarshal(data, &request)
+		return &request, err
+	}
+	if !client.Binary {
+		return nil, errUnexpectedBinary
+	}
+	var event DrawEvent
+	if err := ProtoCodec.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+	request.DrawEvent = &event
+	return &request, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+
+	canvav1 "github.com/fawa-io/fawa/canvaservice/gen/canva/v1"
+)
+
+func TestWebSocketProtoSubprotocol(t *testing.T) {
+	h := NewCanvasServiceHandler()
+	session := newCanvasSession("PROTO1")
+	url := newTestWebSocketServer(t, h, session)
+
+	dial := func(subprotocols ...string) (*websocket.Conn, string) {
+		dialer := websocket.Dialer{Subprotocols: subprotocols}
+		conn, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		var hello ClientDrawResponse
+		if err := conn.ReadJSON(&hello); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		return conn, hello.ClientID
+	}
+	binary, binaryID := dial("unknown", ProtoSubprotocol)
+	if got := binary.Subprotocol(); got != ProtoSubprotocol {
+		t.Fatalf("negotiated subprotocol = %q, want %q", got, ProtoSubprotocol)
+	}
+	text, textID := dial()
+	if got := text.Subprotocol(); got != "" {
+		t.Fatalf("negotiated subprotocol = %q without asking for one", got)
+	}
+	waitForClients(t, session, 2)
+
+	// nextDrawEvent returns the client id of the next draw event, skipping presence updates
+	nextDrawEvent := func(conn *websocket.Conn, wantType int) string {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("ReadMessage() error = %v", err)
+			}
+			if messageType == websocket.BinaryMessage {
+				if wantType != websocket.BinaryMessage {
+					t.Fatal("JSON client received a binary frame")
+				}
+				var event canvav1.DrawEvent
+				if err := proto.Unmarshal(data, &event); err != nil {
+					t.Fatalf("proto.Unmarshal() error = %v", err)
+				}
+				return event.GetClientId()
+			}
+			var resp ClientDrawResponse
+			if err := json.Unmarshal(data, &resp); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if resp.DrawEvent != nil {
+				if wantType != websocket.TextMessage {
+					t.Fatal("binary client received a JSON draw event")
+				}
+				return resp.DrawEvent.ClientID
+			}
+		}
+	}
+
+	data, err := proto.Marshal(toProto(NewDrawEvent("draw", "#000000", "", 1, 0, 0, 1, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := binary.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	if got := nextDrawEvent(text, websocket.TextMessage); got != binaryID {
+		t.Errorf("JSON client received an event of %q, want %q", got, binaryID)
+	}
+	if got := nextDrawEvent(binary, websocket.BinaryMessage); got != binaryID {
+		t.Errorf("binary client echo is from %q, want %q", got, binaryID)
+	}
+
+	if err := text.WriteJSON(&ClientDrawRequest{DrawEvent: NewDrawEvent("draw", "#000000", "", 1, 1, 1, 2, 2)}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if got := nextDrawEvent(binary, websocket.BinaryMessage); got != textID {
+		t.Errorf("binary client received an event of %q, want %q", got, textID)
+	}
+	if got := nextDrawEvent(text, websocket.TextMessage); got != textID {
+		t.Errorf("JSON client echo is from %q, want %q", got, textID)
+	}
+	if n := len(session.historySnapshot()); n != 2 {
+		t.Errorf("history has %d events, want 2", n)
+	}
+
+	// Binary frames are only understood once the subprotocol is negotiated
+	if err := text.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	waitForClients(t, session, 1)
+}
@@ -47,12 +47,31 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 )
 
-// DrawEvent represents a drawing event
+// Drawing tools supported by DrawEvent.Tool
+const (
+	ToolPen     = "pen"
+	ToolEraser  = "eraser"
+	ToolRect    = "rect"
+	ToolEllipse = "ellipse"
+	ToolLine    = "line"
+)
+
+// Bounds of a valid DrawEvent, well past any real canvas so only garbage is rejected
+const (
+	maxDrawSize       = 512
+	maxDrawCoordinate = 1 << 16
+)
+
+// DrawEvent is the JSON form of canvav1.DrawEvent, used on the WebSocket and WebTransport
+// wire and in .fawa files. Sessions keep their history as canvav1.DrawEvent.
 type DrawEvent struct {
 	Type     string `json:"type"`
+	Tool     string `json:"tool,omitempty"`
 	Color    string `json:"color"`
 	Size     int    `json:"size"`
 	PrevX    int    `json:"prev_x"`
@@ -60,7 +79,59 @@ type DrawEvent struct {
 	CurrX    int    `json:"curr_x"`
 	CurrY    int    `json:"curr_y"`
 	ClientID string `json:"client_id"`
-	Time     int64  `json:"time"`
+	// ClientColor is the color assigned to the client that drew the event
+	ClientColor string `json:"client_color,omitempty"`
+	Time        int64  `json:"time"`
+	// Seq is the position of the event in its session history, assigned by the server from 1
+	Seq int64 `json:"seq,omitempty"`
+}
+
+// Validate checks that the draw event is well formed and normalizes tool-specific fields
+func (e *DrawEvent) Validate() error {
+	switch e.Type {
+	case "":
+		return errors.New("draw event type cannot be empty")
+	case "draw", "clear", "ping":
+	default:
+		return fmt.Errorf("unknown draw event type %q", e.Type)
+	}
+	if e.Size < 0 || e.Size > maxDrawSize {
+		return fmt.Errorf("draw event size %d is out of range [0, %d]", e.Size, maxDrawSize)
+	}
+	for _, n := range []int{e.PrevX, e.PrevY, e.CurrX, e.CurrY} {
+		if n < -maxDrawCoordinate || n > maxDrawCoordinate {
+			return fmt.Errorf("draw event coordinate %d is out of range [%d, %d]", n, -maxDrawCoordinate, maxDrawCoordinate)
+		}
+	}
+	if e.Color != "" && !isHexColor(e.Color) {
+		return fmt.Errorf("draw event color %q is not a #rgb or #rrggbb hex color", e.Color)
+	}
+	switch e.Tool {
+	case "", ToolPen, ToolLine:
+	case ToolEraser:
+		// The eraser always paints the background, the color is meaningless.
+		e.Color = ""
+	case ToolRect, ToolEllipse:
+		if e.PrevX == e.CurrX || e.PrevY == e.CurrY {
+			return fmt.Errorf("%s needs two distinct corners", e.Tool)
+		}
+	default:
+		return fmt.Errorf("unknown tool %q", e.Tool)
+	}
+	return nil
+}
+
+// isHexColor reports whether s is a CSS hex color in the #rgb or #rrggbb form
+func isHexColor(s string) bool {
+	if (len(s) != 4 && len(s) != 7) || s[0] != '#' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if !(('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')) {
+			return false
+		}
+	}
+	return true
 }
 
 // History represents the drawing history
@@ -68,15 +139,67 @@ type History struct {
 	Events []DrawEvent `json:"events"`
 }
 
+// CanvasFileVersion is the current version of the .fawa file format
+const CanvasFileVersion = 1
+
+// CanvasFile represents an exported canvas session (.fawa file)
+type CanvasFile struct {
+	Version    int         `json:"version"`
+	Code       string      `json:"code"`
+	ExportedAt int64       `json:"exported_at"`
+	Events     []DrawEvent `json:"events"`
+	// Legend maps the clients that drew the events to their assigned colors
+	Legend map[string]string `json:"legend,omitempty"`
+}
+
+// ClientInfo identifies a client connected to a session
+type ClientInfo struct {
+	ID    string `json:"id"`
+	Color string `json:"color"`
+}
+
+// Presence lists the clients connected to a session, sent whenever a client joins or leaves
+type Presence struct {
+	Clients []ClientInfo `json:"clients"`
+}
+
+// ResyncRequest asks for the events a client missed, e.g. after a reconnect
+type ResyncRequest struct {
+	// FromSeq is the seq of the last event the client has, it gets the events after it
+	FromSeq int64 `json:"from_seq"`
+}
+
+// ResyncResponse answers a ResyncRequest with the events after its FromSeq.
+// When some of them are no longer in the history or FromSeq is past its end, Events is
+// the whole history and Truncated is set, the client has to replace its canvas with it.
+type ResyncResponse struct {
+	Events    []DrawEvent `json:"events"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
 // ClientDrawRequest represents a client request
 type ClientDrawRequest struct {
-	DrawEvent *DrawEvent `json:"draw_event,omitempty"`
+	DrawEvent *DrawEvent     `json:"draw_event,omitempty"`
+	Resync    *ResyncRequest `json:"resync,omitempty"`
 }
 
 // ClientDrawResponse represents a server response
 type ClientDrawResponse struct {
 	DrawEvent      *DrawEvent `json:"draw_event,omitempty"`
 	InitialHistory *History   `json:"initial_history,omitempty"`
+	// ClientID is the id assigned to the client, sent once right after it connects
+	ClientID string `json:"client_id,omitempty"`
+	// ClientColor is the color assigned to the client, sent along with ClientID
+	ClientColor string          `json:"client_color,omitempty"`
+	Presence    *Presence       `json:"presence,omitempty"`
+	Resync      *ResyncResponse `json:"resync,omitempty"`
+	// Shutdown is sent before the server closes the connection on shutdown
+	Shutdown *ServerShutdown `json:"shutdown,omitempty"`
+}
+
+// ServerShutdown tells a client the server is going away, it may reconnect later
+type ServerShutdown struct {
+	Reason string `json:"reason"`
 }
 
 // WebTransportSession represents a WebTransport session
@@ -0,0 +1,65 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"testing"
+)
+
+func TestDrawEventValidateTools(t *testing.T) {
+	testCases := []struct {
+		name      string
+		event     DrawEvent
+		wantErr   bool
+		wantColor string
+	}{
+		{name: "no tool", event: DrawEvent{Type: "draw", Color: "#000000", CurrX: 1}, wantColor: "#000000"},
+		{name: "pen", event: DrawEvent{Type: "draw", Tool: ToolPen, Color: "#ff0000", CurrX: 1}, wantColor: "#ff0000"},
+		{name: "line", event: DrawEvent{Type: "draw", Tool: ToolLine, Color: "#00ff00", CurrX: 5}, wantColor: "#00ff00"},
+		{name: "eraser ignores color", event: DrawEvent{Type: "draw", Tool: ToolEraser, Color: "#ff0000"}, wantColor: ""},
+		{name: "rect", event: DrawEvent{Type: "draw", Tool: ToolRect, CurrX: 10, CurrY: 10}},
+		{name: "rect without width", event: DrawEvent{Type: "draw", Tool: ToolRect, CurrY: 10}, wantErr: true},
+		{name: "ellipse", event: DrawEvent{Type: "draw", Tool: ToolEllipse, PrevX: 5, PrevY: 5, CurrX: 1, CurrY: 1}},
+		{name: "ellipse with same corners", event: DrawEvent{Type: "draw", Tool: ToolEllipse, PrevX: 5, PrevY: 5, CurrX: 5, CurrY: 5}, wantErr: true},
+		{name: "unknown tool", event: DrawEvent{Type: "draw", Tool: "spray"}, wantErr: true},
+		{name: "missing type", event: DrawEvent{Tool: ToolPen}, wantErr: true},
+		{name: "unknown type", event: DrawEvent{Type: "explode", Color: "#000000"}, wantErr: true},
+		{name: "clear", event: DrawEvent{Type: "clear"}},
+		{name: "ping", event: DrawEvent{Type: "ping"}},
+		{name: "short hex color", event: DrawEvent{Type: "draw", Color: "#F0a", CurrX: 1}, wantColor: "#F0a"},
+		{name: "named color", event: DrawEvent{Type: "draw", Color: "red", CurrX: 1}, wantErr: true},
+		{name: "markup in color", event: DrawEvent{Type: "draw", Color: `"/><script>`, CurrX: 1}, wantErr: true},
+		{name: "hex color without hash", event: DrawEvent{Type: "draw", Color: "ff0000", CurrX: 1}, wantErr: true},
+		{name: "bad hex digit", event: DrawEvent{Type: "draw", Color: "#00ff0g", CurrX: 1}, wantErr: true},
+		{name: "largest size", event: DrawEvent{Type: "draw", Size: maxDrawSize, CurrX: 1}},
+		{name: "size too large", event: DrawEvent{Type: "draw", Size: maxDrawSize + 1, CurrX: 1}, wantErr: true},
+		{name: "negative size", event: DrawEvent{Type: "draw", Size: -1, CurrX: 1}, wantErr: true},
+		{name: "negative coordinates", event: DrawEvent{Type: "draw", PrevX: -10, PrevY: -10, CurrX: 1}},
+		{name: "coordinate too large", event: DrawEvent{Type: "draw", CurrX: maxDrawCoordinate + 1}, wantErr: true},
+		{name: "coordinate too small", event: DrawEvent{Type: "draw", PrevY: -maxDrawCoordinate - 1}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.event.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && tc.event.Color != tc.wantColor {
+				t.Errorf("Validate() color = %q, want %q", tc.event.Color, tc.wantColor)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// trackingResponseWriter records whether the response was started, so an error
+// isn't written on top of the headers a failed upgrade already sent.
+// It forwards the HTTP/3 interfaces the WebTransport upgrade relies on.
+type trackingResponseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *trackingResponseWriter) WriteHeader(statusCode int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *trackingResponseWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *trackingResponseWriter) Flush() {
+	w.started = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Connection implements http3.Hijacker
+func (w *trackingResponseWriter) Connection() *http3.Conn {
+	return w.ResponseWriter.(http3.Hijacker).Connection()
+}
+
+// HTTPStream implements http3.HTTPStreamer
+func (w *trackingResponseWriter) HTTPStream() *http3.Stream {
+	return w.ResponseWriter.(http3.HTTPStreamer).HTTPStream()
+}
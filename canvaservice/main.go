@@ -46,24 +46,19 @@
 package main
 
 import (
-	"context"
-	"crypto/tls"
 	"errors"
 	"log"
 	"net/http"
 	"net/http/pprof"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	"github.com/fawa-io/fwpkg/cors"
 	"github.com/fawa-io/fwpkg/fwlog"
 	"github.com/quic-go/quic-go/http3"
 	"github.com/quic-go/webtransport-go"
 
 	"github.com/fawa-io/fawa/canvaservice/config"
 	"github.com/fawa-io/fawa/canvaservice/handler"
+	"github.com/fawa-io/fawa/canvaservice/server"
 )
 
 func main() {
@@ -81,33 +76,29 @@ func main() {
 	fwlog.SetLevel(logLevel)
 	fwlog.Infof("Logger initialized with level: %s", cfg.LogLevel)
 
-	// Check if certificate files exist
-	var tlsConfig *tls.Config
-	var useTLS bool
-
-	if cfg.CertFile != "" && cfg.KeyFile != "" {
-		if _, err := os.Stat(cfg.CertFile); err == nil {
-			if _, err := os.Stat(cfg.KeyFile); err == nil {
-				// Load TLS certificate
-				tlsConfig = &tls.Config{}
-				cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
-				if err != nil {
-					fwlog.Fatalf("Failed to load TLS certificate: %v", err)
-				}
-				tlsConfig.Certificates = []tls.Certificate{cert}
-				useTLS = true
-				fwlog.Infof("TLS certificates loaded successfully")
-			}
-		}
+	tlsConfig, err := cfg.LoadTLSConfig()
+	if err != nil {
+		fwlog.Fatalf("Invalid TLS configuration: %v", err)
 	}
-
-	if !useTLS {
-		fwlog.Warnf("Certificate files not found, falling back to HTTP mode")
-		tlsConfig = nil
+	useTLS := tlsConfig != nil
+	if useTLS {
+		fwlog.Infof("TLS certificates loaded successfully")
+	} else {
+		fwlog.Infof("No TLS certificates configured, serving plain HTTP")
 	}
 
 	// Create canvas service handler
 	canvaHandler := handler.NewCanvasServiceHandler()
+	canvaHandler.PingInterval = cfg.PingInterval
+	canvaHandler.IdleTimeout = cfg.ClientIdleTimeout
+	canvaHandler.MaxMessageSize = cfg.MaxMessageSize
+	canvaHandler.MaxSessions = cfg.MaxSessions
+	canvaHandler.AdminToken = cfg.AdminToken
+	// Histories stay in memory: there's no HistoryStore implementation yet, so neither the
+	// historyFlush settings nor StartHistoryFlusher are wired in
+	// Browsers connect from the origins allowed to call the HTTP endpoints
+	checkOrigin := server.CheckOrigin(cfg.CORS, cfg.DevMode)
+	canvaHandler.Upgrader.CheckOrigin = checkOrigin
 
 	// Create HTTP server with CORS support (for WebSocket fallback)
 	mux := http.NewServeMux()
@@ -128,6 +119,11 @@ func main() {
 
 	mux.HandleFunc("/create", canvaHandler.CreateCanvas)
 	mux.HandleFunc("/join", canvaHandler.JoinCanvas)
+	mux.HandleFunc("/export", canvaHandler.ExportCanvas)
+	mux.HandleFunc("/import", canvaHandler.ImportCanvas)
+	mux.HandleFunc("/replay", canvaHandler.ReplayCanvas)
+	mux.HandleFunc("/canvas/rooms", canvaHandler.ListRooms)
+	mux.HandleFunc("/canvas/delete", canvaHandler.DeleteCanvas)
 
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -136,38 +132,24 @@ func main() {
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	// Create HTTP server with CORS middleware (for WebSocket fallback)
 	httpServer := &http.Server{
-		Addr:    cfg.Addr,
-		Handler: cors.NewCORS().Handler(mux),
+		Addr:              cfg.Addr,
+		Handler:           server.NewCORS(cfg.CORS, cfg.DevMode).Handler(mux),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
 	}
 
 	// Declare h3Server variable
 	var h3Server *http3.Server
 
-	// Setup graceful shutdown
+	// Setup graceful shutdown, the HTTP/3 server is registered once it's created
+	shutdowner := server.NewShutdowner(cfg.ShutdownTimeout)
+	shutdowner.Register(server.StageDrain, "canvas clients", canvaHandler.Drain)
+	shutdowner.RegisterCloser(server.StageServices, "canvas history", canvaHandler)
 	go func() {
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-		<-sigCh
-
-		fwlog.Info("Shutting down server...")
-
-		// Set timeout for server shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		// Shutdown HTTP/3 server if it exists
-		if useTLS && h3Server != nil {
-			if err := h3Server.Close(); err != nil {
-				fwlog.Errorf("HTTP/3 server shutdown error: %v", err)
-			}
-		}
-
-		// Shutdown HTTP server
-		if err := httpServer.Shutdown(ctx); err != nil {
-			fwlog.Errorf("HTTP server shutdown error: %v", err)
-		}
-
-		fwlog.Info("Server shutdown complete")
+		shutdowner.WaitForSignal()
+		os.Exit(0)
 	}()
 
 	fwlog.Infof("NewCanva WebTransport server starting on %v", cfg.Addr)
@@ -181,14 +163,14 @@ func main() {
 			Addr:      cfg.Addr,
 			TLSConfig: tlsConfig,
 		}
+		shutdowner.RegisterCloser(server.StageServers, "HTTP/3 server", h3Server)
+		shutdowner.RegisterServer("HTTP server", httpServer)
 
 		// Create WebTransport server
 		wtServer := &webtransport.Server{
 			//nolint:govet
-			H3: *h3Server, // H3: *h3Server,
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for development
-			},
+			H3:          *h3Server, // H3: *h3Server,
+			CheckOrigin: checkOrigin,
 		}
 
 		// Set the WebTransport server in the handler
@@ -208,6 +190,7 @@ func main() {
 	} else {
 		fwlog.Infof("WebSocket fallback endpoint: ws://%s/ws/canva", cfg.Addr)
 		fwlog.Infof("Starting HTTP server")
+		shutdowner.RegisterServer("HTTP server", httpServer)
 
 		// Start the HTTP server
 		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
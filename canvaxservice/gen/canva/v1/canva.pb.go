@@ -47,6 +47,15 @@ type DrawEvent struct {
 	CurrX    int32  `protobuf:"varint,6,opt,name=curr_x,json=currX,proto3" json:"curr_x,omitempty"`
 	CurrY    int32  `protobuf:"varint,7,opt,name=curr_y,json=currY,proto3" json:"curr_y,omitempty"`
 	ClientId string `protobuf:"bytes,8,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// The drawing tool, empty means pen.
+	Tool string `protobuf:"bytes,9,opt,name=tool,proto3" json:"tool,omitempty"`
+	// The color assigned to the client that drew the event.
+	ClientColor string `protobuf:"bytes,10,opt,name=client_color,json=clientColor,proto3" json:"client_color,omitempty"`
+	// When the event was drawn, in Unix milliseconds.
+	Time int64 `protobuf:"varint,11,opt,name=time,proto3" json:"time,omitempty"`
+	// The position of the event in its room's history, assigned by the server from 1.
+	// A gap in the sequence tells a client it missed events.
+	Seq int64 `protobuf:"varint,12,opt,name=seq,proto3" json:"seq,omitempty"`
 }
 
 func (x *DrawEvent) Reset() {
@@ -137,6 +146,34 @@ func (x *DrawEvent) GetClientId() string {
 	return ""
 }
 
+func (x *DrawEvent) GetTool() string {
+	if x != nil {
+		return x.Tool
+	}
+	return ""
+}
+
+func (x *DrawEvent) GetClientColor() string {
+	if x != nil {
+		return x.ClientColor
+	}
+	return ""
+}
+
+func (x *DrawEvent) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *DrawEvent) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
 type History struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -192,6 +229,7 @@ type ClientDrawRequest struct {
 	// Types that are assignable to Message:
 	//
 	//	*ClientDrawRequest_DrawEvent
+	//	*ClientDrawRequest_Resync
 	Message isClientDrawRequest_Message `protobuf_oneof:"message"`
 }
 
@@ -241,6 +279,13 @@ func (x *ClientDrawRequest) GetDrawEvent() *DrawEvent {
 	return nil
 }
 
+func (x *ClientDrawRequest) GetResync() *ResyncRequest {
+	if x, ok := x.GetMessage().(*ClientDrawRequest_Resync); ok {
+		return x.Resync
+	}
+	return nil
+}
+
 type isClientDrawRequest_Message interface {
 	isClientDrawRequest_Message()
 }
@@ -249,8 +294,15 @@ type ClientDrawRequest_DrawEvent struct {
 	DrawEvent *DrawEvent `protobuf:"bytes,1,opt,name=draw_event,json=drawEvent,proto3,oneof"`
 }
 
+type ClientDrawRequest_Resync struct {
+	// Asks for the events missed since a sequence number, e.g. after a reconnect.
+	Resync *ResyncRequest `protobuf:"bytes,2,opt,name=resync,proto3,oneof"`
+}
+
 func (*ClientDrawRequest_DrawEvent) isClientDrawRequest_Message() {}
 
+func (*ClientDrawRequest_Resync) isClientDrawRequest_Message() {}
+
 type ClientDrawResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -260,6 +312,9 @@ type ClientDrawResponse struct {
 	//
 	//	*ClientDrawResponse_DrawEvent
 	//	*ClientDrawResponse_InitialHistory
+	//	*ClientDrawResponse_ClientId
+	//	*ClientDrawResponse_Resync
+	//	*ClientDrawResponse_Shutdown
 	Message isClientDrawResponse_Message `protobuf_oneof:"message"`
 }
 
@@ -316,6 +371,27 @@ func (x *ClientDrawResponse) GetInitialHistory() *History {
 	return nil
 }
 
+func (x *ClientDrawResponse) GetClientId() string {
+	if x, ok := x.GetMessage().(*ClientDrawResponse_ClientId); ok {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *ClientDrawResponse) GetResync() *ResyncResponse {
+	if x, ok := x.GetMessage().(*ClientDrawResponse_Resync); ok {
+		return x.Resync
+	}
+	return nil
+}
+
+func (x *ClientDrawResponse) GetShutdown() *ServerShutdown {
+	if x, ok := x.GetMessage().(*ClientDrawResponse_Shutdown); ok {
+		return x.Shutdown
+	}
+	return nil
+}
+
 type isClientDrawResponse_Message interface {
 	isClientDrawResponse_Message()
 }
@@ -328,16 +404,295 @@ type ClientDrawResponse_InitialHistory struct {
 	InitialHistory *History `protobuf:"bytes,2,opt,name=initial_history,json=initialHistory,proto3,oneof"`
 }
 
+type ClientDrawResponse_ClientId struct {
+	// The id assigned to the client, sent once right after it connects.
+	ClientId string `protobuf:"bytes,3,opt,name=client_id,json=clientId,proto3,oneof"`
+}
+
+type ClientDrawResponse_Resync struct {
+	// The answer to a ResyncRequest.
+	Resync *ResyncResponse `protobuf:"bytes,4,opt,name=resync,proto3,oneof"`
+}
+
+type ClientDrawResponse_Shutdown struct {
+	// Sent before the server ends the stream on shutdown, the client may reconnect later.
+	Shutdown *ServerShutdown `protobuf:"bytes,5,opt,name=shutdown,proto3,oneof"`
+}
+
 func (*ClientDrawResponse_DrawEvent) isClientDrawResponse_Message() {}
 
 func (*ClientDrawResponse_InitialHistory) isClientDrawResponse_Message() {}
 
+func (*ClientDrawResponse_ClientId) isClientDrawResponse_Message() {}
+
+func (*ClientDrawResponse_Resync) isClientDrawResponse_Message() {}
+
+func (*ClientDrawResponse_Shutdown) isClientDrawResponse_Message() {}
+
+type ResyncRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The seq of the last event the client has, it gets the events after it.
+	FromSeq int64 `protobuf:"varint,1,opt,name=from_seq,json=fromSeq,proto3" json:"from_seq,omitempty"`
+}
+
+func (x *ResyncRequest) Reset() {
+	*x = ResyncRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_canva_v1_canva_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResyncRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResyncRequest) ProtoMessage() {}
+
+func (x *ResyncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_canva_v1_canva_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResyncRequest.ProtoReflect.Descriptor instead.
+func (*ResyncRequest) Descriptor() ([]byte, []int) {
+	return file_canva_v1_canva_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ResyncRequest) GetFromSeq() int64 {
+	if x != nil {
+		return x.FromSeq
+	}
+	return 0
+}
+
+type ResyncResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The events after from_seq, or the whole history when truncated.
+	Events []*DrawEvent `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	// Set when events after from_seq are no longer in the history, or from_seq is past its end.
+	// The client has to replace its canvas with the events instead of applying them.
+	Truncated bool `protobuf:"varint,2,opt,name=truncated,proto3" json:"truncated,omitempty"`
+}
+
+func (x *ResyncResponse) Reset() {
+	*x = ResyncResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_canva_v1_canva_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResyncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResyncResponse) ProtoMessage() {}
+
+func (x *ResyncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_canva_v1_canva_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResyncResponse.ProtoReflect.Descriptor instead.
+func (*ResyncResponse) Descriptor() ([]byte, []int) {
+	return file_canva_v1_canva_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ResyncResponse) GetEvents() []*DrawEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *ResyncResponse) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+type ServerShutdown struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Why the server is going away, meant for logs rather than users.
+	Reason string `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *ServerShutdown) Reset() {
+	*x = ServerShutdown{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_canva_v1_canva_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ServerShutdown) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerShutdown) ProtoMessage() {}
+
+func (x *ServerShutdown) ProtoReflect() protoreflect.Message {
+	mi := &file_canva_v1_canva_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerShutdown.ProtoReflect.Descriptor instead.
+func (*ServerShutdown) Descriptor() ([]byte, []int) {
+	return file_canva_v1_canva_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ServerShutdown) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type GetServerInfoRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetServerInfoRequest) Reset() {
+	*x = GetServerInfoRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_canva_v1_canva_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetServerInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServerInfoRequest) ProtoMessage() {}
+
+func (x *GetServerInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_canva_v1_canva_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServerInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetServerInfoRequest) Descriptor() ([]byte, []int) {
+	return file_canva_v1_canva_proto_rawDescGZIP(), []int{7}
+}
+
+type GetServerInfoResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The server's current time, in Unix milliseconds like DrawEvent.time.
+	ServerTime int64 `protobuf:"varint,1,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"`
+	// The build version, "dev" for builds without one.
+	Version string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	// The git commit the server was built from, empty when unknown.
+	Commit string `protobuf:"bytes,3,opt,name=commit,proto3" json:"commit,omitempty"`
+}
+
+func (x *GetServerInfoResponse) Reset() {
+	*x = GetServerInfoResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_canva_v1_canva_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetServerInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServerInfoResponse) ProtoMessage() {}
+
+func (x *GetServerInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_canva_v1_canva_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServerInfoResponse.ProtoReflect.Descriptor instead.
+func (*GetServerInfoResponse) Descriptor() ([]byte, []int) {
+	return file_canva_v1_canva_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetServerInfoResponse) GetServerTime() int64 {
+	if x != nil {
+		return x.ServerTime
+	}
+	return 0
+}
+
+func (x *GetServerInfoResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *GetServerInfoResponse) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
 var File_canva_v1_canva_proto protoreflect.FileDescriptor
 
 var file_canva_v1_canva_proto_rawDesc = []byte{
 	0x0a, 0x14, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2f, 0x76, 0x31, 0x2f, 0x63, 0x61, 0x6e, 0x76, 0x61,
 	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31,
-	0x22, 0xc2, 0x01, 0x0a, 0x09, 0x44, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x12,
+	0x22, 0x9f, 0x02, 0x0a, 0x09, 0x44, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x12,
 	0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79,
 	0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65,
@@ -349,35 +704,78 @@ var file_canva_v1_canva_proto_rawDesc = []byte{
 	0x58, 0x12, 0x15, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x72, 0x5f, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28,
 	0x05, 0x52, 0x05, 0x63, 0x75, 0x72, 0x72, 0x59, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x6c, 0x69, 0x65,
 	0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6c, 0x69,
-	0x65, 0x6e, 0x74, 0x49, 0x64, 0x22, 0x36, 0x0a, 0x07, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79,
-	0x12, 0x2b, 0x0a, 0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
-	0x32, 0x13, 0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x72, 0x61, 0x77,
-	0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x54, 0x0a,
-	0x11, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x44, 0x72, 0x61, 0x77, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x12, 0x34, 0x0a, 0x0a, 0x64, 0x72, 0x61, 0x77, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76,
-	0x31, 0x2e, 0x44, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x09, 0x64,
-	0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x42, 0x09, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73,
-	0x61, 0x67, 0x65, 0x22, 0x93, 0x01, 0x0a, 0x12, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x44, 0x72,
-	0x61, 0x77, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x34, 0x0a, 0x0a, 0x64, 0x72,
-	0x61, 0x77, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13,
-	0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x72, 0x61, 0x77, 0x45, 0x76,
-	0x65, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x09, 0x64, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74,
-	0x12, 0x3c, 0x0a, 0x0f, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x5f, 0x68, 0x69, 0x73, 0x74,
-	0x6f, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x63, 0x61, 0x6e, 0x76,
-	0x61, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x48, 0x00, 0x52, 0x0e,
-	0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x42, 0x09,
-	0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x32, 0x5c, 0x0a, 0x0c, 0x43, 0x61, 0x6e,
-	0x76, 0x61, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x4c, 0x0a, 0x0b, 0x43, 0x6f, 0x6c,
-	0x6c, 0x61, 0x62, 0x6f, 0x72, 0x61, 0x74, 0x65, 0x12, 0x1b, 0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61,
-	0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x44, 0x72, 0x61, 0x77, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31,
-	0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x44, 0x72, 0x61, 0x77, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x28, 0x01, 0x30, 0x01, 0x42, 0x3c, 0x5a, 0x3a, 0x67, 0x69, 0x74, 0x68, 0x75,
-	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x66, 0x61, 0x77, 0x61, 0x2d, 0x69, 0x6f, 0x2f, 0x66, 0x61,
-	0x77, 0x61, 0x2f, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x78, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
-	0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2f, 0x76, 0x31, 0x3b, 0x63, 0x61,
-	0x6e, 0x76, 0x61, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x6f, 0x6f, 0x6c, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x6f, 0x6f, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6c, 0x69,
+	0x65, 0x6e, 0x74, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04,
+	0x74, 0x69, 0x6d, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65,
+	0x12, 0x10, 0x0a, 0x03, 0x73, 0x65, 0x71, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x73,
+	0x65, 0x71, 0x22, 0x36, 0x0a, 0x07, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x2b, 0x0a,
+	0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e,
+	0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65,
+	0x6e, 0x74, 0x52, 0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x87, 0x01, 0x0a, 0x11, 0x43,
+	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x44, 0x72, 0x61, 0x77, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x34, 0x0a, 0x0a, 0x64, 0x72, 0x61, 0x77, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e,
+	0x44, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x09, 0x64, 0x72, 0x61,
+	0x77, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x31, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x79, 0x6e, 0x63,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76,
+	0x31, 0x2e, 0x52, 0x65, 0x73, 0x79, 0x6e, 0x63, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48,
+	0x00, 0x52, 0x06, 0x72, 0x65, 0x73, 0x79, 0x6e, 0x63, 0x42, 0x09, 0x0a, 0x07, 0x6d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x22, 0x9e, 0x02, 0x0a, 0x12, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x44,
+	0x72, 0x61, 0x77, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x34, 0x0a, 0x0a, 0x64,
+	0x72, 0x61, 0x77, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x13, 0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x72, 0x61, 0x77, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x09, 0x64, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x12, 0x3c, 0x0a, 0x0f, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x5f, 0x68, 0x69, 0x73,
+	0x74, 0x6f, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x63, 0x61, 0x6e,
+	0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x48, 0x00, 0x52,
+	0x0e, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12,
+	0x1d, 0x0a, 0x09, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x48, 0x00, 0x52, 0x08, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x32,
+	0x0a, 0x06, 0x72, 0x65, 0x73, 0x79, 0x6e, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18,
+	0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x79, 0x6e, 0x63,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x48, 0x00, 0x52, 0x06, 0x72, 0x65, 0x73, 0x79,
+	0x6e, 0x63, 0x12, 0x36, 0x0a, 0x08, 0x73, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e,
+	0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x48, 0x00,
+	0x52, 0x08, 0x73, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x42, 0x09, 0x0a, 0x07, 0x6d, 0x65,
+	0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x2a, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x79, 0x6e, 0x63, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x73,
+	0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x66, 0x72, 0x6f, 0x6d, 0x53, 0x65,
+	0x71, 0x22, 0x5b, 0x0a, 0x0e, 0x52, 0x65, 0x73, 0x79, 0x6e, 0x63, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x2b, 0x0a, 0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x44,
+	0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73,
+	0x12, 0x1c, 0x0a, 0x09, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x09, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x22, 0x28,
+	0x0a, 0x0e, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x53, 0x68, 0x75, 0x74, 0x64, 0x6f, 0x77, 0x6e,
+	0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x16, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x53,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x22, 0x6a, 0x0a, 0x15, 0x47, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x32, 0xae, 0x01, 0x0a,
+	0x0c, 0x43, 0x61, 0x6e, 0x76, 0x61, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x4c, 0x0a,
+	0x0b, 0x43, 0x6f, 0x6c, 0x6c, 0x61, 0x62, 0x6f, 0x72, 0x61, 0x74, 0x65, 0x12, 0x1b, 0x2e, 0x63,
+	0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x44, 0x72,
+	0x61, 0x77, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x63, 0x61, 0x6e, 0x76,
+	0x61, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x44, 0x72, 0x61, 0x77, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x30, 0x01, 0x12, 0x50, 0x0a, 0x0d, 0x47,
+	0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x1e, 0x2e, 0x63,
+	0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x63,
+	0x61, 0x6e, 0x76, 0x61, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x3c, 0x5a,
+	0x3a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x66, 0x61, 0x77, 0x61,
+	0x2d, 0x69, 0x6f, 0x2f, 0x66, 0x61, 0x77, 0x61, 0x2f, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x78, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x63, 0x61, 0x6e, 0x76, 0x61,
+	0x2f, 0x76, 0x31, 0x3b, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -392,25 +790,36 @@ func file_canva_v1_canva_proto_rawDescGZIP() []byte {
 	return file_canva_v1_canva_proto_rawDescData
 }
 
-var file_canva_v1_canva_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_canva_v1_canva_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_canva_v1_canva_proto_goTypes = []interface{}{
-	(*DrawEvent)(nil),          // 0: canva.v1.DrawEvent
-	(*History)(nil),            // 1: canva.v1.History
-	(*ClientDrawRequest)(nil),  // 2: canva.v1.ClientDrawRequest
-	(*ClientDrawResponse)(nil), // 3: canva.v1.ClientDrawResponse
+	(*DrawEvent)(nil),             // 0: canva.v1.DrawEvent
+	(*History)(nil),               // 1: canva.v1.History
+	(*ClientDrawRequest)(nil),     // 2: canva.v1.ClientDrawRequest
+	(*ClientDrawResponse)(nil),    // 3: canva.v1.ClientDrawResponse
+	(*ResyncRequest)(nil),         // 4: canva.v1.ResyncRequest
+	(*ResyncResponse)(nil),        // 5: canva.v1.ResyncResponse
+	(*ServerShutdown)(nil),        // 6: canva.v1.ServerShutdown
+	(*GetServerInfoRequest)(nil),  // 7: canva.v1.GetServerInfoRequest
+	(*GetServerInfoResponse)(nil), // 8: canva.v1.GetServerInfoResponse
 }
 var file_canva_v1_canva_proto_depIdxs = []int32{
-	0, // 0: canva.v1.History.events:type_name -> canva.v1.DrawEvent
-	0, // 1: canva.v1.ClientDrawRequest.draw_event:type_name -> canva.v1.DrawEvent
-	0, // 2: canva.v1.ClientDrawResponse.draw_event:type_name -> canva.v1.DrawEvent
-	1, // 3: canva.v1.ClientDrawResponse.initial_history:type_name -> canva.v1.History
-	2, // 4: canva.v1.CanvaService.Collaborate:input_type -> canva.v1.ClientDrawRequest
-	3, // 5: canva.v1.CanvaService.Collaborate:output_type -> canva.v1.ClientDrawResponse
-	5, // [5:6] is the sub-list for method output_type
-	4, // [4:5] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	0,  // 0: canva.v1.History.events:type_name -> canva.v1.DrawEvent
+	0,  // 1: canva.v1.ClientDrawRequest.draw_event:type_name -> canva.v1.DrawEvent
+	4,  // 2: canva.v1.ClientDrawRequest.resync:type_name -> canva.v1.ResyncRequest
+	0,  // 3: canva.v1.ClientDrawResponse.draw_event:type_name -> canva.v1.DrawEvent
+	1,  // 4: canva.v1.ClientDrawResponse.initial_history:type_name -> canva.v1.History
+	5,  // 5: canva.v1.ClientDrawResponse.resync:type_name -> canva.v1.ResyncResponse
+	6,  // 6: canva.v1.ClientDrawResponse.shutdown:type_name -> canva.v1.ServerShutdown
+	0,  // 7: canva.v1.ResyncResponse.events:type_name -> canva.v1.DrawEvent
+	2,  // 8: canva.v1.CanvaService.Collaborate:input_type -> canva.v1.ClientDrawRequest
+	7,  // 9: canva.v1.CanvaService.GetServerInfo:input_type -> canva.v1.GetServerInfoRequest
+	3,  // 10: canva.v1.CanvaService.Collaborate:output_type -> canva.v1.ClientDrawResponse
+	8,  // 11: canva.v1.CanvaService.GetServerInfo:output_type -> canva.v1.GetServerInfoResponse
+	10, // [10:12] is the sub-list for method output_type
+	8,  // [8:10] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_canva_v1_canva_proto_init() }
@@ -467,13 +876,77 @@ func file_canva_v1_canva_proto_init() {
 				return nil
 			}
 		}
+		file_canva_v1_canva_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResyncRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_canva_v1_canva_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResyncResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_canva_v1_canva_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ServerShutdown); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_canva_v1_canva_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetServerInfoRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_canva_v1_canva_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetServerInfoResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_canva_v1_canva_proto_msgTypes[2].OneofWrappers = []interface{}{
 		(*ClientDrawRequest_DrawEvent)(nil),
+		(*ClientDrawRequest_Resync)(nil),
 	}
 	file_canva_v1_canva_proto_msgTypes[3].OneofWrappers = []interface{}{
 		(*ClientDrawResponse_DrawEvent)(nil),
 		(*ClientDrawResponse_InitialHistory)(nil),
+		(*ClientDrawResponse_ClientId)(nil),
+		(*ClientDrawResponse_Resync)(nil),
+		(*ClientDrawResponse_Shutdown)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -481,7 +954,7 @@ func file_canva_v1_canva_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_canva_v1_canva_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -50,17 +50,23 @@ const (
 	// CanvaServiceCollaborateProcedure is the fully-qualified name of the CanvaService's Collaborate
 	// RPC.
 	CanvaServiceCollaborateProcedure = "/canva.v1.CanvaService/Collaborate"
+	// CanvaServiceGetServerInfoProcedure is the fully-qualified name of the CanvaService's
+	// GetServerInfo RPC.
+	CanvaServiceGetServerInfoProcedure = "/canva.v1.CanvaService/GetServerInfo"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
 var (
-	canvaServiceServiceDescriptor           = v1.File_canva_v1_canva_proto.Services().ByName("CanvaService")
-	canvaServiceCollaborateMethodDescriptor = canvaServiceServiceDescriptor.Methods().ByName("Collaborate")
+	canvaServiceServiceDescriptor             = v1.File_canva_v1_canva_proto.Services().ByName("CanvaService")
+	canvaServiceCollaborateMethodDescriptor   = canvaServiceServiceDescriptor.Methods().ByName("Collaborate")
+	canvaServiceGetServerInfoMethodDescriptor = canvaServiceServiceDescriptor.Methods().ByName("GetServerInfo")
 )
 
 // CanvaServiceClient is a client for the canva.v1.CanvaService service.
 type CanvaServiceClient interface {
 	Collaborate(context.Context) *connect.BidiStreamForClient[v1.ClientDrawRequest, v1.ClientDrawResponse]
+	// GetServerInfo returns the server clock and build, clients use it to compute their clock offset.
+	GetServerInfo(context.Context, *connect.Request[v1.GetServerInfoRequest]) (*connect.Response[v1.GetServerInfoResponse], error)
 }
 
 // NewCanvaServiceClient constructs a client for the canva.v1.CanvaService service. By default, it
@@ -79,12 +85,19 @@ func NewCanvaServiceClient(httpClient connect.HTTPClient, baseURL string, opts .
 			connect.WithSchema(canvaServiceCollaborateMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		getServerInfo: connect.NewClient[v1.GetServerInfoRequest, v1.GetServerInfoResponse](
+			httpClient,
+			baseURL+CanvaServiceGetServerInfoProcedure,
+			connect.WithSchema(canvaServiceGetServerInfoMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // canvaServiceClient implements CanvaServiceClient.
 type canvaServiceClient struct {
-	collaborate *connect.Client[v1.ClientDrawRequest, v1.ClientDrawResponse]
+	collaborate   *connect.Client[v1.ClientDrawRequest, v1.ClientDrawResponse]
+	getServerInfo *connect.Client[v1.GetServerInfoRequest, v1.GetServerInfoResponse]
 }
 
 // Collaborate calls canva.v1.CanvaService.Collaborate.
@@ -92,9 +105,16 @@ func (c *canvaServiceClient) Collaborate(ctx context.Context) *connect.BidiStrea
 	return c.collaborate.CallBidiStream(ctx)
 }
 
+// GetServerInfo calls canva.v1.CanvaService.GetServerInfo.
+func (c *canvaServiceClient) GetServerInfo(ctx context.Context, req *connect.Request[v1.GetServerInfoRequest]) (*connect.Response[v1.GetServerInfoResponse], error) {
+	return c.getServerInfo.CallUnary(ctx, req)
+}
+
 // CanvaServiceHandler is an implementation of the canva.v1.CanvaService service.
 type CanvaServiceHandler interface {
 	Collaborate(context.Context, *connect.BidiStream[v1.ClientDrawRequest, v1.ClientDrawResponse]) error
+	// GetServerInfo returns the server clock and build, clients use it to compute their clock offset.
+	GetServerInfo(context.Context, *connect.Request[v1.GetServerInfoRequest]) (*connect.Response[v1.GetServerInfoResponse], error)
 }
 
 // NewCanvaServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -109,10 +129,18 @@ func NewCanvaServiceHandler(svc CanvaServiceHandler, opts ...connect.HandlerOpti
 		connect.WithSchema(canvaServiceCollaborateMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	canvaServiceGetServerInfoHandler := connect.NewUnaryHandler(
+		CanvaServiceGetServerInfoProcedure,
+		svc.GetServerInfo,
+		connect.WithSchema(canvaServiceGetServerInfoMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/canva.v1.CanvaService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case CanvaServiceCollaborateProcedure:
 			canvaServiceCollaborateHandler.ServeHTTP(w, r)
+		case CanvaServiceGetServerInfoProcedure:
+			canvaServiceGetServerInfoHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -125,3 +153,7 @@ type UnimplementedCanvaServiceHandler struct{}
 func (UnimplementedCanvaServiceHandler) Collaborate(context.Context, *connect.BidiStream[v1.ClientDrawRequest, v1.ClientDrawResponse]) error {
 	return connect.NewError(connect.CodeUnimplemented, errors.New("canva.v1.CanvaService.Collaborate is not implemented"))
 }
+
+func (UnimplementedCanvaServiceHandler) GetServerInfo(context.Context, *connect.Request[v1.GetServerInfoRequest]) (*connect.Response[v1.GetServerInfoResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("canva.v1.CanvaService.GetServerInfo is not implemented"))
+}
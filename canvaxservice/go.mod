@@ -7,15 +7,17 @@ require (
 	github.com/fawa-io/fawa v0.2.0
 	github.com/fawa-io/fwpkg v0.0.0-20250729040635-e49839d3bf75
 	github.com/fsnotify/fsnotify v1.9.0
+	github.com/gorilla/websocket v1.5.3
+	github.com/rs/cors v1.11.1
 	github.com/spf13/pflag v1.0.7
 	github.com/spf13/viper v1.20.1
+	golang.org/x/net v0.42.0
 	google.golang.org/protobuf v1.36.6
 )
 
 require (
 	github.com/go-viper/mapstructure/v2 v2.2.1 // indirect
 	github.com/pelletier/go-toml/v2 v2.2.3 // indirect
-	github.com/rs/cors v1.11.1 // indirect
 	github.com/sagikazarmark/locafero v0.7.0 // indirect
 	github.com/sourcegraph/conc v0.3.0 // indirect
 	github.com/spf13/afero v1.12.0 // indirect
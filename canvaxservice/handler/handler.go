@@ -18,15 +18,25 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sort"
 	"sync"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/fawa-io/fwpkg/fwlog"
 	"github.com/fawa-io/fwpkg/util"
+	"github.com/gorilla/websocket"
 
 	canvav1 "github.com/fawa-io/fawa/canvaxservice/gen/canva/v1"
+	"github.com/fawa-io/fawa/canvaxservice/pkg/requestid"
 )
 
+// defaultDrainTimeout is how long Close waits for the clients to leave
+const defaultDrainTimeout = 10 * time.Second
+
+// drainGracePeriod is how long a drain lets the notified clients leave by themselves
+var drainGracePeriod = 2 * time.Second
+
 // CanvaServiceHandler handles canvas service requests
 // It manages multiple client connections and drawing history
 type CanvaServiceHandler struct {
@@ -42,11 +52,46 @@ type CanvaServiceHandler struct {
 	broadcast chan *canvav1.DrawEvent
 	// Channel for service shutdown
 	done chan struct{}
+
+	// draining rejects new clients once Drain has started, guarded by clientsMu
+	draining bool
+	// active counts the clients whose collaborate call hasn't returned yet
+	active sync.WaitGroup
+
+	// Upgrader upgrades the WebSocket fallback connections
+	Upgrader websocket.Upgrader
+	// DrainTimeout bounds the drain of the clients when Close is called
+	DrainTimeout time.Duration
 }
 
 type client struct {
-	id     string
-	stream *connect.BidiStream[canvav1.ClientDrawRequest, canvav1.ClientDrawResponse]
+	id   string
+	conn clientConn
+	// sendMu serializes the sends of the client's own goroutine and the broadcaster
+	sendMu sync.Mutex
+	// quit is closed to end the client's stream on shutdown
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+// send sends msg to the client
+func (c *client) send(msg *canvav1.ClientDrawResponse) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.conn.Send(msg)
+}
+
+// stop ends the client's stream, its collaborate call returns once it notices
+func (c *client) stop() {
+	c.quitOnce.Do(func() { close(c.quit) })
+}
+
+// clientConn is the connection of a collaborating client, a Connect bidi stream or a WebSocket.
+// Send isn't safe for concurrent use, client.send serializes it.
+type clientConn interface {
+	Send(*canvav1.ClientDrawResponse) error
+	// Receive returns io.EOF once the client has disconnected
+	Receive() (*canvav1.ClientDrawRequest, error)
 }
 
 // NewCanvaServiceHandler creates a new canvas service handler
@@ -70,56 +115,101 @@ func (h *CanvaServiceHandler) Collaborate(
 	ctx context.Context,
 	stream *connect.BidiStream[canvav1.ClientDrawRequest, canvav1.ClientDrawResponse],
 ) error {
+	return h.collaborate(ctx, stream)
+}
+
+// collaborate joins a client to the canvas until it disconnects, whatever its transport
+func (h *CanvaServiceHandler) collaborate(ctx context.Context, stream clientConn) error {
+	log := requestid.Logger(ctx)
 	// Generate unique client identifier
 	clientID := util.Generaterandomstring(8)
-	fwlog.Infof("New canvas connection: client %s", clientID)
+	log.Infof("New canvas connection: client %s", clientID)
 
 	// Register client
-	h.registerClient(clientID, stream)
+	cl, err := h.registerClient(clientID, stream)
+	if err != nil {
+		return err
+	}
 	defer h.unregisterClient(clientID)
 
-	fwlog.Debugf("Client %s: Sending initial history", clientID)
+	// Let the client know its own id so it can recognize its echoed events
+	if err := cl.send(&canvav1.ClientDrawResponse{
+		Message: &canvav1.ClientDrawResponse_ClientId{
+			ClientId: clientID,
+		},
+	}); err != nil {
+		log.Errorf("Failed to send client id to client %s: %v", clientID, err)
+		return err
+	}
+
+	log.Debugf("Client %s: Sending initial history", clientID)
 	// Send initial history
-	if err := h.sendInitialHistory(stream); err != nil {
-		fwlog.Errorf("Failed to send history to client %s: %v", clientID, err)
+	if err := h.sendInitialHistory(cl); err != nil {
+		log.Errorf("Failed to send history to client %s: %v", clientID, err)
 		return err
 	}
 
-	fwlog.Debugf("Client %s: Entering message processing loop", clientID)
-	// Process client messages
-	for {
-		// Check if context is canceled
-		if err := ctx.Err(); err != nil {
-			fwlog.Infof("Client %s context canceled: %v", clientID, err)
-			return err
+	// Receive in the background so a drain can end the stream of a silent client
+	requests := make(chan *canvav1.ClientDrawRequest)
+	receiveErr := make(chan error, 1)
+	stopReceiving := make(chan struct{})
+	defer close(stopReceiving)
+	go func() {
+		for {
+			msg, err := stream.Receive()
+			if err != nil {
+				receiveErr <- err
+				return
+			}
+			select {
+			case requests <- msg:
+			case <-stopReceiving:
+				return
+			}
 		}
+	}()
 
-		fwlog.Debugf("Client %s: Waiting to receive message", clientID)
+	log.Debugf("Client %s: Entering message processing loop", clientID)
+	// Process client messages
+	for {
+		log.Debugf("Client %s: Waiting to receive message", clientID)
 		// Receive client message
-		msg, err := stream.Receive()
-		if err != nil {
+		var msg *canvav1.ClientDrawRequest
+		select {
+		case msg = <-requests:
+		case err := <-receiveErr:
 			if errors.Is(err, io.EOF) || connect.CodeOf(err) == connect.CodeCanceled {
-				fwlog.Infof("Client %s disconnected", clientID)
+				log.Infof("Client %s disconnected", clientID)
 				return nil
 			}
-			fwlog.Errorf("Failed to receive message from client %s: %v", clientID, err)
+			log.Errorf("Failed to receive message from client %s: %v", clientID, err)
 			return err
+		case <-cl.quit:
+			log.Infof("Client %s: Ending stream for shutdown", clientID)
+			return nil
+		case <-ctx.Done():
+			log.Infof("Client %s context canceled: %v", clientID, ctx.Err())
+			return ctx.Err()
 		}
 
-		fwlog.Debugf("Client %s: Received message: %+v", clientID, msg)
+		log.Debugf("Client %s: Received message: %+v", clientID, msg)
 
 		// Process drawing events
 		if drawEvent := msg.GetDrawEvent(); drawEvent != nil {
-			fwlog.Debugf("Client %s: Processing draw event: %+v", clientID, drawEvent)
+			log.Debugf("Client %s: Processing draw event: %+v", clientID, drawEvent)
 
+			if err := validateDrawEvent(drawEvent); err != nil {
+				log.Warnf("Client %s: Dropping invalid draw event: %v", clientID, err)
+				continue
+			}
 			// Ensure client ID is set
 			drawEvent.ClientId = clientID
 
 			switch drawEvent.Type {
 			case "ping":
-				fwlog.Debugf("Client %s: Received ping, keeping connection alive", clientID)
+				log.Debugf("Client %s: Received ping, keeping connection alive", clientID)
 			case "clear":
-				fwlog.Infof("Client %s: Received clear canvas command", clientID)
+				log.Infof("Client %s: Received clear canvas command", clientID)
 				h.addToHistory(drawEvent)
 				h.broadcast <- drawEvent
 				h.clearHistory(drawEvent)
@@ -127,24 +217,37 @@ func (h *CanvaServiceHandler) Collaborate(
 				h.addToHistory(drawEvent)
 				h.broadcast <- drawEvent
 			}
+		} else if resync := msg.GetResync(); resync != nil {
+			log.Debugf("Client %s: Resyncing from seq %d", clientID, resync.GetFromSeq())
+			if err := h.sendResync(cl, resync.GetFromSeq()); err != nil {
+				log.Errorf("Failed to resync client %s: %v", clientID, err)
+				return err
+			}
 		} else {
-			fwlog.Debugf("Client %s: Received non-draw event or empty message", clientID)
+			log.Debugf("Client %s: Received non-draw event or empty message", clientID)
 		}
 	}
 }
 
 // Internal helper methods
 
-// Register new client
-func (h *CanvaServiceHandler) registerClient(id string, stream *connect.BidiStream[canvav1.ClientDrawRequest, canvav1.ClientDrawResponse]) {
+// Register new client, unless the service is shutting down
+func (h *CanvaServiceHandler) registerClient(id string, conn clientConn) (*client, error) {
 	h.clientsMu.Lock()
 	defer h.clientsMu.Unlock()
 
-	h.clients[id] = &client{
-		id:     id,
-		stream: stream,
+	if h.draining {
+		return nil, connect.NewError(connect.CodeUnavailable, errors.New("canvas service is shutting down"))
 	}
+	cl := &client{
+		id:   id,
+		conn: conn,
+		quit: make(chan struct{}),
+	}
+	h.clients[id] = cl
+	h.active.Add(1)
 	fwlog.Infof("Client %s registered, active connections: %d", id, len(h.clients))
+	return cl, nil
 }
 
 // Unregister client
@@ -153,11 +256,19 @@ func (h *CanvaServiceHandler) unregisterClient(id string) {
 	defer h.clientsMu.Unlock()
 
 	delete(h.clients, id)
+	h.active.Done()
 	fwlog.Infof("Client %s unregistered, active connections: %d", id, len(h.clients))
 }
 
+// isDraining reports whether Drain has started
+func (h *CanvaServiceHandler) isDraining() bool {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+	return h.draining
+}
+
 // Send initial history
-func (h *CanvaServiceHandler) sendInitialHistory(stream *connect.BidiStream[canvav1.ClientDrawRequest, canvav1.ClientDrawResponse]) error {
+func (h *CanvaServiceHandler) sendInitialHistory(cl *client) error {
 	h.historyMu.RLock()
 	events := make([]*canvav1.DrawEvent, len(h.history))
 	copy(events, h.history) // Create copy to avoid holding lock for too long
@@ -167,13 +278,38 @@ func (h *CanvaServiceHandler) sendInitialHistory(stream *connect.BidiStream[canv
 		Events: events,
 	}
 
-	return stream.Send(&canvav1.ClientDrawResponse{
+	return cl.send(&canvav1.ClientDrawResponse{
 		Message: &canvav1.ClientDrawResponse_InitialHistory{
 			InitialHistory: history,
 		},
 	})
 }
 
+// Send the events after fromSeq, or the whole history flagged as truncated when
+// some of them are gone or fromSeq is past the end of it
+func (h *CanvaServiceHandler) sendResync(cl *client, fromSeq int64) error {
+	h.historyMu.RLock()
+	var truncated bool
+	if n := len(h.history); n == 0 {
+		truncated = fromSeq > 0
+	} else {
+		truncated = fromSeq < h.history[0].GetSeq()-1 || fromSeq > h.history[n-1].GetSeq()
+	}
+	start := 0
+	if !truncated {
+		start = sort.Search(len(h.history), func(i int) bool { return h.history[i].GetSeq() > fromSeq })
+	}
+	events := make([]*canvav1.DrawEvent, len(h.history)-start)
+	copy(events, h.history[start:])
+	h.historyMu.RUnlock()
+
+	return cl.send(&canvav1.ClientDrawResponse{
+		Message: &canvav1.ClientDrawResponse_Resync{
+			Resync: &canvav1.ResyncResponse{Events: events, Truncated: truncated},
+		},
+	})
+}
+
 // Purge history, but retain the specified purge events
 func (h *CanvaServiceHandler) clearHistory(clearEvent *canvav1.DrawEvent) {
 	h.historyMu.Lock()
@@ -184,11 +320,21 @@ func (h *CanvaServiceHandler) clearHistory(clearEvent *canvav1.DrawEvent) {
 	fwlog.Infof("Canvas history cleared by client %s", clearEvent.ClientId)
 }
 
-// Add event to history
+// Stamp the event with the server time and the next sequence number and add it to history.
+// Client clocks can't be trusted, and the time is kept past the last event's so the history
+// is totally ordered.
 func (h *CanvaServiceHandler) addToHistory(event *canvav1.DrawEvent) {
 	h.historyMu.Lock()
 	defer h.historyMu.Unlock()
 
+	event.Time = time.Now().UnixMilli()
+	event.Seq = 1
+	if n := len(h.history); n > 0 {
+		last := h.history[n-1]
+		event.Time = max(event.Time, last.GetTime()+1)
+		event.Seq = last.GetSeq() + 1
+	}
+
 	// Implement history size limit
 	if len(h.history) >= 1000 {
 		// If history gets too large, remove old events or implement persistence
@@ -225,7 +371,7 @@ func (h *CanvaServiceHandler) broadcastToClients(event *canvav1.DrawEvent) {
 	for id, cl := range h.clients {
 		// Use anonymous function to avoid defer in loop
 		func(clientID string, cl *client) {
-			if err := cl.stream.Send(message); err != nil {
+			if err := cl.send(message); err != nil {
 				fwlog.Errorf("Failed to send message to client %s: %v", clientID, err)
 				// Note: we don't remove the client here because we hold a read lock
 				// Client will be automatically unregistered via Collaborate method's defer
@@ -234,9 +380,70 @@ func (h *CanvaServiceHandler) broadcastToClients(event *canvav1.DrawEvent) {
 	}
 }
 
-// Close shuts down the canvas service
+// Drain rejects new clients and tells the connected ones the server is shutting down.
+// They get drainGracePeriod to leave by themselves, then the remaining streams are ended
+// and Drain waits for them until ctx is done.
+func (h *CanvaServiceHandler) Drain(ctx context.Context) error {
+	h.clientsMu.Lock()
+	h.draining = true
+	clients := make([]*client, 0, len(h.clients))
+	for _, cl := range h.clients {
+		clients = append(clients, cl)
+	}
+	h.clientsMu.Unlock()
+
+	shutdown := &canvav1.ClientDrawResponse{
+		Message: &canvav1.ClientDrawResponse_Shutdown{
+			Shutdown: &canvav1.ServerShutdown{Reason: "server shutting down"},
+		},
+	}
+	for _, cl := range clients {
+		if err := cl.send(shutdown); err != nil {
+			fwlog.Debugf("Failed to notify client %s of the shutdown: %v", cl.id, err)
+		}
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(finished)
+	}()
+
+	grace := time.NewTimer(drainGracePeriod)
+	defer grace.Stop()
+	select {
+	case <-finished:
+		return nil
+	case <-grace.C:
+	case <-ctx.Done():
+	}
+
+	fwlog.Infof("Ending the streams of %d canvas clients", len(clients))
+	for _, cl := range clients {
+		cl.stop()
+	}
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		fwlog.Warnf("Canvas clients did not leave before the shutdown deadline")
+		return ctx.Err()
+	}
+}
+
+// Close drains the clients within DrainTimeout and shuts down the canvas service
 // Call this when stopping the service
 func (h *CanvaServiceHandler) Close() {
+	timeout := h.DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := h.Drain(ctx); err != nil {
+		fwlog.Warnf("Failed to drain canvas clients: %v", err)
+	}
+
 	close(h.done)
 
 	h.clientsMu.Lock()
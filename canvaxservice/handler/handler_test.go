@@ -0,0 +1,126 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	canvav1 "github.com/fawa-io/fawa/canvaxservice/gen/canva/v1"
+)
+
+func TestAddToHistoryStampsServerTime(t *testing.T) {
+	h := NewCanvaServiceHandler()
+	t.Cleanup(h.Close)
+
+	before := time.Now().UnixMilli()
+	first := &canvav1.DrawEvent{Type: "draw", Time: 1}
+	h.addToHistory(first)
+	if after := time.Now().UnixMilli(); first.Time < before || first.Time > after {
+		t.Errorf("event time = %d, want the server time between %d and %d", first.Time, before, after)
+	}
+
+	// Events in the same millisecond or from a skewed client clock still sort after the earlier ones
+	last := first.Time
+	for _, clientTime := range []int64{0, first.Time - 1000, first.Time + 3600000} {
+		event := &canvav1.DrawEvent{Type: "draw", Time: clientTime}
+		h.addToHistory(event)
+		if event.Time <= last {
+			t.Errorf("event time = %d, want after %d", event.Time, last)
+		}
+		last = event.Time
+	}
+}
+
+func TestAddToHistoryAssignsSeq(t *testing.T) {
+	h := NewCanvaServiceHandler()
+	t.Cleanup(h.Close)
+
+	for i := range 3 {
+		event := &canvav1.DrawEvent{Type: "draw", Seq: 100}
+		h.addToHistory(event)
+		if want := int64(i + 1); event.Seq != want {
+			t.Errorf("event %d seq = %d, want %d", i, event.Seq, want)
+		}
+	}
+
+	// A clear keeps its own place in the sequence, the next event follows it
+	clearEvent := &canvav1.DrawEvent{Type: "clear"}
+	h.addToHistory(clearEvent)
+	h.clearHistory(clearEvent)
+	next := &canvav1.DrawEvent{Type: "draw"}
+	h.addToHistory(next)
+	if clearEvent.Seq != 4 || next.Seq != 5 {
+		t.Errorf("clear seq = %d, next seq = %d, want 4 and 5", clearEvent.Seq, next.Seq)
+	}
+}
+
+// recordingConn records the responses sent to a client
+type recordingConn struct {
+	sent []*canvav1.ClientDrawResponse
+}
+
+func (c *recordingConn) Send(resp *canvav1.ClientDrawResponse) error {
+	c.sent = append(c.sent, resp)
+	return nil
+}
+
+func (c *recordingConn) Receive() (*canvav1.ClientDrawRequest, error) { return nil, io.EOF }
+
+func TestSendResync(t *testing.T) {
+	// The history starts at a clear, the events before it are gone
+	history := []*canvav1.DrawEvent{{Type: "clear", Seq: 5}, {Type: "draw", Seq: 6}, {Type: "draw", Seq: 7}}
+	testCases := []struct {
+		name          string
+		history       []*canvav1.DrawEvent
+		fromSeq       int64
+		wantSeqs      []int64
+		wantTruncated bool
+	}{
+		{name: "up to date", history: history, fromSeq: 7},
+		{name: "missed events", history: history, fromSeq: 5, wantSeqs: []int64{6, 7}},
+		{name: "missed the clear", history: history, fromSeq: 4, wantSeqs: []int64{5, 6, 7}},
+		{name: "evicted", history: history, fromSeq: 2, wantSeqs: []int64{5, 6, 7}, wantTruncated: true},
+		{name: "past the end", history: history, fromSeq: 9, wantSeqs: []int64{5, 6, 7}, wantTruncated: true},
+		{name: "empty history", fromSeq: 0},
+		{name: "empty history after a restart", fromSeq: 3, wantTruncated: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewCanvaServiceHandler()
+			t.Cleanup(h.Close)
+			h.history = tc.history
+			conn := &recordingConn{}
+
+			if err := h.sendResync(&client{id: "c1", conn: conn}, tc.fromSeq); err != nil {
+				t.Fatalf("sendResync() error = %v", err)
+			}
+			if len(conn.sent) != 1 {
+				t.Fatalf("sent %d responses, want 1", len(conn.sent))
+			}
+			resync := conn.sent[0].GetResync()
+			var seqs []int64
+			for _, e := range resync.GetEvents() {
+				seqs = append(seqs, e.GetSeq())
+			}
+			if !slices.Equal(seqs, tc.wantSeqs) || resync.GetTruncated() != tc.wantTruncated {
+				t.Errorf("resync = seqs %v truncated %v, want seqs %v truncated %v", seqs, resync.GetTruncated(), tc.wantSeqs, tc.wantTruncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"time"
+
+	"connectrpc.com/connect"
+
+	canvav1 "github.com/fawa-io/fawa/canvaxservice/gen/canva/v1"
+	"github.com/fawa-io/fawa/canvaxservice/pkg/version"
+)
+
+// GetServerInfo returns the server's current time and build version.
+// Clients compare the time with their own clock to order events despite clock skew.
+func (h *CanvaServiceHandler) GetServerInfo(
+	ctx context.Context,
+	req *connect.Request[canvav1.GetServerInfoRequest],
+) (*connect.Response[canvav1.GetServerInfoResponse], error) {
+	return connect.NewResponse(&canvav1.GetServerInfoResponse{
+		ServerTime: time.Now().UnixMilli(),
+		Version:    version.Version,
+		Commit:     version.Commit,
+	}), nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+
+	canvav1 "github.com/fawa-io/fawa/canvaxservice/gen/canva/v1"
+	"github.com/fawa-io/fawa/canvaxservice/gen/canva/v1/canvav1connect"
+	"github.com/fawa-io/fawa/canvaxservice/pkg/version"
+)
+
+func TestGetServerInfo(t *testing.T) {
+	h := NewCanvaServiceHandler()
+	t.Cleanup(h.Close)
+	srv := newTestServer(t, h)
+	client := canvav1connect.NewCanvaServiceClient(srv.Client(), srv.URL)
+
+	before := time.Now().UnixMilli()
+	resp, err := client.GetServerInfo(context.Background(), connect.NewRequest(&canvav1.GetServerInfoRequest{}))
+	if err != nil {
+		t.Fatalf("GetServerInfo() error = %v", err)
+	}
+	after := time.Now().UnixMilli()
+
+	if got := resp.Msg.GetServerTime(); got < before || got > after {
+		t.Errorf("server time = %d, want between %d and %d", got, before, after)
+	}
+	if resp.Msg.GetVersion() != version.Version || resp.Msg.GetCommit() != version.Commit {
+		t.Errorf("build = %s (%s), want %s (%s)", resp.Msg.GetVersion(), resp.Msg.GetCommit(), version.Version, version.Commit)
+	}
+}
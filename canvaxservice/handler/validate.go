@@ -0,0 +1,70 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	canvav1 "github.com/fawa-io/fawa/canvaxservice/gen/canva/v1"
+)
+
+// Bounds of a valid draw event, well past any real canvas so only garbage is rejected
+const (
+	maxDrawSize       = 512
+	maxDrawCoordinate = 1 << 16
+)
+
+// validateDrawEvent checks that a draw event received from a client is well formed,
+// so a buggy client can't send events that break the renderers of the others.
+func validateDrawEvent(e *canvav1.DrawEvent) error {
+	switch e.GetType() {
+	case "":
+		return errors.New("draw event type cannot be empty")
+	case "draw", "clear", "ping":
+	default:
+		return fmt.Errorf("unknown draw event type %q", e.GetType())
+	}
+	switch e.GetTool() {
+	case "", "pen", "eraser", "rect", "ellipse", "line":
+	default:
+		return fmt.Errorf("unknown tool %q", e.GetTool())
+	}
+	if size := e.GetSize(); size < 0 || size > maxDrawSize {
+		return fmt.Errorf("draw event size %d is out of range [0, %d]", size, maxDrawSize)
+	}
+	for _, n := range []int32{e.GetPrevX(), e.GetPrevY(), e.GetCurrX(), e.GetCurrY()} {
+		if n < -maxDrawCoordinate || n > maxDrawCoordinate {
+			return fmt.Errorf("draw event coordinate %d is out of range [%d, %d]", n, -maxDrawCoordinate, maxDrawCoordinate)
+		}
+	}
+	if color := e.GetColor(); color != "" && !isHexColor(color) {
+		return fmt.Errorf("draw event color %q is not a #rgb or #rrggbb hex color", color)
+	}
+	return nil
+}
+
+// isHexColor reports whether s is a CSS hex color in the #rgb or #rrggbb form
+func isHexColor(s string) bool {
+	if (len(s) != 4 && len(s) != 7) || s[0] != '#' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if !(('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
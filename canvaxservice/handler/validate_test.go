@@ -0,0 +1,54 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"testing"
+
+	canvav1 "github.com/fawa-io/fawa/canvaxservice/gen/canva/v1"
+)
+
+func TestValidateDrawEvent(t *testing.T) {
+	testCases := []struct {
+		name    string
+		event   *canvav1.DrawEvent
+		wantErr bool
+	}{
+		{name: "draw", event: &canvav1.DrawEvent{Type: "draw", Color: "#0000ff", Size: 5, CurrX: 10, CurrY: 10}},
+		{name: "short hex color", event: &canvav1.DrawEvent{Type: "draw", Color: "#F0a"}},
+		{name: "clear", event: &canvav1.DrawEvent{Type: "clear"}},
+		{name: "ping", event: &canvav1.DrawEvent{Type: "ping"}},
+		{name: "eraser", event: &canvav1.DrawEvent{Type: "draw", Tool: "eraser", PrevX: -10, PrevY: -10}},
+		{name: "missing type", event: &canvav1.DrawEvent{Color: "#000000"}, wantErr: true},
+		{name: "unknown type", event: &canvav1.DrawEvent{Type: "explode"}, wantErr: true},
+		{name: "unknown tool", event: &canvav1.DrawEvent{Type: "draw", Tool: "spray"}, wantErr: true},
+		{name: "named color", event: &canvav1.DrawEvent{Type: "draw", Color: "red"}, wantErr: true},
+		{name: "markup in color", event: &canvav1.DrawEvent{Type: "draw", Color: `"/><script>`}, wantErr: true},
+		{name: "bad hex digit", event: &canvav1.DrawEvent{Type: "draw", Color: "#00ff0g"}, wantErr: true},
+		{name: "largest size", event: &canvav1.DrawEvent{Type: "draw", Size: maxDrawSize}},
+		{name: "size too large", event: &canvav1.DrawEvent{Type: "draw", Size: maxDrawSize + 1}, wantErr: true},
+		{name: "negative size", event: &canvav1.DrawEvent{Type: "draw", Size: -1}, wantErr: true},
+		{name: "coordinate too large", event: &canvav1.DrawEvent{Type: "draw", CurrY: maxDrawCoordinate + 1}, wantErr: true},
+		{name: "coordinate too small", event: &canvav1.DrawEvent{Type: "draw", PrevX: -maxDrawCoordinate - 1}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateDrawEvent(tc.event); (err != nil) != tc.wantErr {
+				t.Errorf("validateDrawEvent() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	canvav1 "github.com/fawa-io/fawa/canvaxservice/gen/canva/v1"
+	"github.com/fawa-io/fawa/canvaxservice/pkg/requestid"
+)
+
+// HandleWebSocket serves the canvas to browsers without a Connect client.
+// Each text message carries a ClientDrawRequest or ClientDrawResponse in the JSON encoding Connect uses,
+// and the client shares the clients and history of the Collaborate stream.
+func (h *CanvaServiceHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// The Connect interceptors don't see this endpoint, tag the request like they would
+	id := requestid.ID(r.Header)
+	ctx := requestid.NewContext(r.Context(), id)
+	log := requestid.Logger(ctx)
+	header := http.Header{}
+	header.Set(requestid.Header, id)
+
+	conn, err := h.Upgrader.Upgrade(w, r, header)
+	if err != nil {
+		log.Errorf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Debugf("Failed to close WebSocket: %v", err)
+		}
+	}()
+
+	if err := h.collaborate(ctx, &wsConn{conn: conn}); err != nil {
+		log.Warnf("WebSocket client ended with error: %v", err)
+	}
+	if h.isDraining() {
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+		if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeWriteWait)); err != nil {
+			log.Debugf("Failed to send close frame: %v", err)
+		}
+	}
+}
+
+// closeWriteWait is the time allowed to write a close frame
+const closeWriteWait = time.Second
+
+// wsConn adapts a WebSocket to clientConn
+type wsConn struct {
+	conn *websocket.Conn
+}
+
+func (c *wsConn) Send(msg *canvav1.ClientDrawResponse) error {
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (c *wsConn) Receive() (*canvav1.ClientDrawRequest, error) {
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		var req canvav1.ClientDrawRequest
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, &req); err != nil {
+			fwlog.Warnf("Dropping malformed WebSocket message: %v", err)
+			continue
+		}
+		return &req, nil
+	}
+}
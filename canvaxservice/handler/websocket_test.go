@@ -0,0 +1,202 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	canvav1 "github.com/fawa-io/fawa/canvaxservice/gen/canva/v1"
+	"github.com/fawa-io/fawa/canvaxservice/gen/canva/v1/canvav1connect"
+)
+
+// newTestServer serves h over HTTP/2 so Connect bidi streams work next to the WebSocket endpoint
+func newTestServer(t *testing.T, h *CanvaServiceHandler) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(canvav1connect.NewCanvaServiceHandler(h))
+	mux.HandleFunc("/ws/canva", h.HandleWebSocket)
+	srv := httptest.NewUnstartedServer(mux)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dialWebSocket(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	t.Helper()
+	// The WebSocket handshake needs HTTP/1.1, the test client would negotiate HTTP/2
+	tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	tlsConfig.NextProtos = []string{"http/1.1"}
+	dialer := websocket.Dialer{TLSClientConfig: tlsConfig}
+	conn, _, err := dialer.Dial("wss"+strings.TrimPrefix(srv.URL, "https")+"/ws/canva", nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readWebSocket(t *testing.T, conn *websocket.Conn) *canvav1.ClientDrawResponse {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	var resp canvav1.ClientDrawResponse
+	if err := protojson.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("message %s is not a ClientDrawResponse: %v", data, err)
+	}
+	return &resp
+}
+
+func TestWebSocketSharesCanvasWithCollaborate(t *testing.T) {
+	h := NewCanvaServiceHandler()
+	t.Cleanup(h.Close)
+	srv := newTestServer(t, h)
+
+	ws := dialWebSocket(t, srv)
+	wsID := readWebSocket(t, ws).GetClientId()
+	if wsID == "" {
+		t.Fatal("WebSocket client got no client id")
+	}
+	if events := readWebSocket(t, ws).GetInitialHistory().GetEvents(); len(events) != 0 {
+		t.Fatalf("initial history has %d events, want none", len(events))
+	}
+
+	stream := canvav1connect.NewCanvaServiceClient(srv.Client(), srv.URL).Collaborate(context.Background())
+	defer func() {
+		_ = stream.CloseRequest()
+		_ = stream.CloseResponse()
+	}()
+	ping := &canvav1.ClientDrawRequest{Message: &canvav1.ClientDrawRequest_DrawEvent{DrawEvent: &canvav1.DrawEvent{Type: "ping"}}}
+	if err := stream.Send(ping); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	receive := func() *canvav1.ClientDrawResponse {
+		t.Helper()
+		resp, err := stream.Receive()
+		if err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+		return resp
+	}
+	grpcID := receive().GetClientId()
+	receive() // initial history
+
+	// A WebSocket event reaches the Connect client
+	err := ws.WriteMessage(websocket.TextMessage, []byte(`{"drawEvent":{"type":"draw","color":"#ff0000","currX":3,"currY":4}}`))
+	if err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	got := receive().GetDrawEvent()
+	if got.GetColor() != "#ff0000" || got.GetCurrX() != 3 || got.GetClientId() != wsID {
+		t.Errorf("Connect client got %v, want the WebSocket event of client %s", got, wsID)
+	}
+	if echo := readWebSocket(t, ws).GetDrawEvent(); echo.GetClientId() != wsID {
+		t.Errorf("WebSocket client got %v, want its own event echoed", echo)
+	}
+
+	// A Connect event reaches the WebSocket client
+	draw := &canvav1.ClientDrawRequest{Message: &canvav1.ClientDrawRequest_DrawEvent{DrawEvent: &canvav1.DrawEvent{Type: "draw", Color: "#0000ff"}}}
+	if err := stream.Send(draw); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := readWebSocket(t, ws).GetDrawEvent(); got.GetColor() != "#0000ff" || got.GetClientId() != grpcID {
+		t.Errorf("WebSocket client got %v, want the Connect event of client %s", got, grpcID)
+	}
+	receive() // echo
+
+	// Both events are in the history a new WebSocket client receives
+	late := dialWebSocket(t, srv)
+	readWebSocket(t, late) // client id
+	events := readWebSocket(t, late).GetInitialHistory().GetEvents()
+	if len(events) != 2 || events[0].GetClientId() != wsID || events[1].GetClientId() != grpcID {
+		t.Errorf("initial history = %v, want the WebSocket event then the Connect event", events)
+	}
+}
+
+func TestDrainNotifiesAndDisconnectsClients(t *testing.T) {
+	grace := drainGracePeriod
+	drainGracePeriod = 50 * time.Millisecond
+	t.Cleanup(func() { drainGracePeriod = grace })
+
+	h := NewCanvaServiceHandler()
+	t.Cleanup(h.Close)
+	srv := newTestServer(t, h)
+	client := canvav1connect.NewCanvaServiceClient(srv.Client(), srv.URL)
+
+	ws := dialWebSocket(t, srv)
+	readWebSocket(t, ws) // client id
+	readWebSocket(t, ws) // initial history
+
+	stream := client.Collaborate(context.Background())
+	defer func() {
+		_ = stream.CloseRequest()
+		_ = stream.CloseResponse()
+	}()
+	if err := stream.Send(&canvav1.ClientDrawRequest{}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	for range 2 { // client id and initial history
+		if _, err := stream.Receive(); err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+	}
+
+	// Neither client leaves by itself, the drain has to end their streams
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.Drain(ctx); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+
+	if got := readWebSocket(t, ws).GetShutdown(); got == nil {
+		t.Error("WebSocket client got no shutdown message")
+	}
+	_, _, err := ws.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("WebSocket read error = %v, want a going away close frame", err)
+	}
+
+	resp, err := stream.Receive()
+	if err != nil || resp.GetShutdown() == nil {
+		t.Fatalf("Receive() = %v, %v, want the shutdown message", resp, err)
+	}
+	if _, err := stream.Receive(); !errors.Is(err, io.EOF) {
+		t.Errorf("Receive() error = %v, want the stream to end cleanly", err)
+	}
+
+	// New clients are turned away
+	late := client.Collaborate(context.Background())
+	defer func() { _ = late.CloseResponse() }()
+	_ = late.Send(&canvav1.ClientDrawRequest{})
+	if _, err := late.Receive(); connect.CodeOf(err) != connect.CodeUnavailable {
+		t.Errorf("Receive() after the drain error = %v, want %v", err, connect.CodeUnavailable)
+	}
+}
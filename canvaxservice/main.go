@@ -19,16 +19,19 @@ import (
 	"errors"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	"github.com/fawa-io/fwpkg/cors"
+	"connectrpc.com/connect"
 	"github.com/fawa-io/fwpkg/fwlog"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/fawa-io/fawa/canvaxservice/config"
 	"github.com/fawa-io/fawa/canvaxservice/gen/canva/v1/canvav1connect"
 	"github.com/fawa-io/fawa/canvaxservice/handler"
+	"github.com/fawa-io/fawa/canvaxservice/pkg/logsample"
+	"github.com/fawa-io/fawa/canvaxservice/pkg/requestid"
+	"github.com/fawa-io/fawa/canvaxservice/pkg/version"
+	"github.com/fawa-io/fawa/canvaxservice/server"
 )
 
 func main() {
@@ -42,48 +45,67 @@ func main() {
 	if err != nil {
 		fwlog.Warnf("Invalid initial log level '%s': %v. Using default.", cfg.LogLevel, err)
 	}
+	// Sample before anything keeps a reference to the default logger, then set the level on the
+	// sampler so it drops the lines below it before counting them
+	fwlog.SetLogger(logsample.New(fwlog.DefaultLogger(), logsample.Config(cfg.LogSampling)))
 	fwlog.SetLevel(logLevel)
 	fwlog.Infof("Logger initialized with level: %s", cfg.LogLevel)
+	fwlog.Infof("canvaxservice version %s, commit %s", version.Version, version.Commit)
+
+	tlsConfig, err := cfg.LoadTLSConfig()
+	if err != nil {
+		fwlog.Fatalf("Invalid TLS configuration: %v", err)
+	}
 
 	canvaSvcHdr := handler.NewCanvaServiceHandler()
-	canvaProcedure, canvaHandler := canvav1connect.NewCanvaServiceHandler(canvaSvcHdr)
+	// Leave the HTTP server the other half of the shutdown
+	canvaSvcHdr.DrainTimeout = cfg.ShutdownTimeout / 2
+	// Browsers connect from the origins allowed to call the Connect endpoints
+	canvaSvcHdr.Upgrader.CheckOrigin = server.CheckOrigin(cfg.CORS, cfg.DevMode)
+	canvaProcedure, canvaHandler := canvav1connect.NewCanvaServiceHandler(canvaSvcHdr, connect.WithInterceptors(requestid.NewInterceptor()))
 
 	// Register all handlers
 	mux := http.NewServeMux()
 	mux.Handle(canvaProcedure, canvaHandler)
+	// WebSocket fallback for browsers without a Connect client
+	mux.HandleFunc("/ws/canva", canvaSvcHdr.HandleWebSocket)
 
 	canvaSrv := &http.Server{
-		Addr:    cfg.Addr,
-		Handler: cors.NewCORS().Handler(mux),
+		Addr:              cfg.Addr,
+		Handler:           server.NewCORS(cfg.CORS, cfg.DevMode).Handler(mux),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
 	}
 
 	// Setup graceful shutdown
-	go func() {
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-		<-sigCh
-
-		fwlog.Info("Shutting down server...")
-
-		// Close canvas service
+	shutdowner := server.NewShutdowner(cfg.ShutdownTimeout)
+	shutdowner.Register(server.StageServices, "canvas service", func(context.Context) error {
 		canvaSvcHdr.Close()
-
-		// Set timeout for HTTP server shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		if err := canvaSrv.Shutdown(ctx); err != nil {
-			fwlog.Errorf("Server shutdown error: %v", err)
-		}
-
-		fwlog.Info("Server shutdown complete")
+		return nil
+	})
+	shutdowner.RegisterServer("canvas server", canvaSrv)
+	go func() {
+		shutdowner.WaitForSignal()
 		os.Exit(0)
 	}()
 
 	fwlog.Infof("Server starting on %v", cfg.Addr)
 
-	// Start the HTTPS server.
-	if err := canvaSrv.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	if tlsConfig != nil {
+		// Start the HTTPS server.
+		canvaSrv.TLSConfig = tlsConfig
+		if err := canvaSrv.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fwlog.Fatalf("Failed to start server: %v", err)
+		}
+		return
+	}
+
+	// Start the HTTP server. h2c serves cleartext HTTP/2 so Connect streaming works without certificates.
+	canvaSrv.Handler = h2c.NewHandler(canvaSrv.Handler, &http2.Server{})
+	fwlog.Infof("Starting HTTP server (h2c enabled)")
+	if err := canvaSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		fwlog.Fatalf("Failed to start server: %v", err)
 	}
 }
@@ -0,0 +1,112 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package logsample thins out repeated debug and info lines, so that chatty paths such as the
+// per-message logs of a drawing session can stay enabled without flooding the logs.
+package logsample
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+)
+
+// Config logs the First lines of each message per Interval, then every Thereafter-th one.
+// Messages are told apart by level and format string, or by text for the unformatted calls.
+type Config struct {
+	First      int
+	Thereafter int
+	// Interval restarts the counts, zero disables sampling
+	Interval time.Duration
+}
+
+// Logger samples the debug and info lines passed to the wrapped logger. Warnings and errors
+// are always logged. Lines below the level set with SetLevel are dropped before they're
+// counted, set the level on the Logger rather than on the wrapped logger.
+type Logger struct {
+	fwlog.Logger
+	cfg Config
+	now func() time.Time
+	// level is the lowest fwlog.Level logged, every level until SetLevel is called
+	level atomic.Int32
+
+	mu sync.Mutex
+	// counts are the lines of each message seen since windowEnd - Interval
+	counts    map[string]int
+	windowEnd time.Time
+}
+
+var _ fwlog.Logger = (*Logger)(nil)
+
+// New returns a logger sampling the lines passed to next
+func New(next fwlog.Logger, cfg Config) *Logger {
+	return &Logger{Logger: next, cfg: cfg, now: time.Now, counts: make(map[string]int)}
+}
+
+// allow counts a line of the message key and reports whether to log it
+func (l *Logger) allow(key string) bool {
+	if l.cfg.Interval <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Restarting every count at once keeps the map down to the messages of one interval
+	if now := l.now(); !now.Before(l.windowEnd) {
+		clear(l.counts)
+		l.windowEnd = now.Add(l.cfg.Interval)
+	}
+	l.counts[key]++
+	n := l.counts[key]
+	if n <= l.cfg.First {
+		return true
+	}
+	return l.cfg.Thereafter > 0 && (n-l.cfg.First)%l.cfg.Thereafter == 0
+}
+
+// SetLevel sets the level of the wrapped logger and drops the lines below it
+func (l *Logger) SetLevel(lv fwlog.Level) {
+	l.level.Store(int32(lv))
+	l.Logger.SetLevel(lv)
+}
+
+// enabled reports whether lines of lv are logged, so that the others skip the counts and their lock
+func (l *Logger) enabled(lv fwlog.Level) bool {
+	return fwlog.Level(l.level.Load()) <= lv
+}
+
+func (l *Logger) Debugf(format string, v ...any) {
+	if l.enabled(fwlog.LevelDebug) && l.allow("D"+format) {
+		l.Logger.Debugf(format, v...)
+	}
+}
+
+func (l *Logger) Infof(format string, v ...any) {
+	if l.enabled(fwlog.LevelInfo) && l.allow("I"+format) {
+		l.Logger.Infof(format, v...)
+	}
+}
+
+func (l *Logger) Debug(v ...any) {
+	if l.enabled(fwlog.LevelDebug) && l.allow("D"+fmt.Sprint(v...)) {
+		l.Logger.Debug(v...)
+	}
+}
+
+func (l *Logger) Info(v ...any) {
+	if l.enabled(fwlog.LevelInfo) && l.allow("I"+fmt.Sprint(v...)) {
+		l.Logger.Info(v...)
+	}
+}
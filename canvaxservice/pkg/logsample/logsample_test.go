@@ -0,0 +1,141 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package logsample
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	"github.com/fawa-io/fawa/canvaxservice/pkg/requestid"
+)
+
+// recorder counts the lines it's passed
+type recorder struct {
+	fwlog.Logger
+	lines int
+}
+
+func (r *recorder) Debugf(string, ...any) { r.lines++ }
+func (r *recorder) Infof(string, ...any)  { r.lines++ }
+func (r *recorder) Warnf(string, ...any)  { r.lines++ }
+func (r *recorder) Debug(...any)          { r.lines++ }
+func (r *recorder) SetLevel(fwlog.Level)  {}
+
+func TestLogger(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cfg   Config
+		log   func(l *Logger)
+		calls int
+		want  int
+	}{
+		{
+			name:  "first then every thereafter",
+			cfg:   Config{First: 3, Thereafter: 10, Interval: time.Second},
+			log:   func(l *Logger) { l.Debugf("received %d", 1) },
+			calls: 25,
+			want:  5, // 1, 2, 3, 13, 23
+		},
+		{
+			name:  "first only",
+			cfg:   Config{First: 2, Interval: time.Second},
+			log:   func(l *Logger) { l.Infof("received") },
+			calls: 10,
+			want:  2,
+		},
+		{
+			name:  "messages counted apart",
+			cfg:   Config{First: 1, Interval: time.Second},
+			log:   func(l *Logger) { l.Debugf("a"); l.Debugf("b"); l.Debug("c") },
+			calls: 5,
+			want:  3,
+		},
+		{
+			name:  "warnings not sampled",
+			cfg:   Config{First: 1, Interval: time.Second},
+			log:   func(l *Logger) { l.Warnf("slow") },
+			calls: 5,
+			want:  5,
+		},
+		{
+			name:  "disabled",
+			cfg:   Config{First: 1},
+			log:   func(l *Logger) { l.Debugf("received") },
+			calls: 5,
+			want:  5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recorder{}
+			l := New(rec, tc.cfg)
+			now := time.Unix(0, 0)
+			l.now = func() time.Time { return now }
+			for range tc.calls {
+				tc.log(l)
+			}
+			if rec.lines != tc.want {
+				t.Errorf("logged %d lines, want %d", rec.lines, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoggerIntervalRestartsCounts(t *testing.T) {
+	rec := &recorder{}
+	l := New(rec, Config{First: 1, Interval: time.Second})
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	l.Debugf("received")
+	l.Debugf("received")
+	now = now.Add(time.Second)
+	l.Debugf("received")
+	if rec.lines != 2 {
+		t.Errorf("logged %d lines, want the first of each interval", rec.lines)
+	}
+}
+
+func TestLoggerLevel(t *testing.T) {
+	rec := &recorder{}
+	l := New(rec, Config{First: 1, Interval: time.Second})
+	l.SetLevel(fwlog.LevelInfo)
+
+	// Dropped lines don't take the first line of the message either
+	l.Debugf("received")
+	l.SetLevel(fwlog.LevelDebug)
+	l.Debugf("received")
+	if rec.lines != 1 {
+		t.Errorf("logged %d lines, want only the line at the enabled level", rec.lines)
+	}
+}
+
+// The lines of different requests are the same message
+func TestLoggerSamplesRequestLines(t *testing.T) {
+	rec := &recorder{}
+	prev := fwlog.DefaultLogger()
+	fwlog.SetLogger(New(rec, Config{First: 1, Interval: time.Second}))
+	t.Cleanup(func() { fwlog.SetLogger(prev) })
+
+	for _, id := range []string{"a", "b", "c"} {
+		requestid.Logger(requestid.NewContext(context.Background(), id)).Debugf("received %d", 1)
+	}
+	if rec.lines != 1 {
+		t.Errorf("logged %d lines, want the first line of all requests", rec.lines)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package version holds the build version of the service, stamped at link time with
+//
+//	go build -ldflags "-X github.com/fawa-io/fawa/canvaxservice/pkg/version.Version=v1.2.3 -X github.com/fawa-io/fawa/canvaxservice/pkg/version.Commit=abc123"
+package version
+
+import "runtime/debug"
+
+var (
+	// Version is the release the service was built from, "dev" when not stamped
+	Version = "dev"
+	// Commit is the git commit the service was built from, read from the
+	// build info when not stamped and empty when neither is available
+	Commit = ""
+)
+
+func init() {
+	if Commit != "" {
+		return
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			Commit = setting.Value
+			return
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command client is a reference client of the file service. It uploads a local file with
+// SendFile, an info message followed by the content in chunks, then downloads it back with
+// ReceiveFile using the returned key:
+//
+//	go run ./cmd/client -addr http://localhost:8080 -file photo.jpg
+//
+// It speaks the raw RPCs to show the protocol, pkg/client wraps the same calls for real use.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"connectrpc.com/connect"
+	"golang.org/x/net/http2"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+)
+
+// chunkSize is the size of the uploaded chunks
+const chunkSize = 64 << 10
+
+func main() {
+	addr := flag.String("addr", envOr("FAWA_ADDR", "http://localhost:8080"), "URL of the file service, also read from FAWA_ADDR.")
+	path := flag.String("file", "", "Path of the file to upload.")
+	out := flag.String("out", "", "Where to write the downloaded copy, defaults to <file>.download.")
+	token := flag.String("token", os.Getenv("FAWA_TOKEN"), "API key or JWT sent as a bearer token when the service requires one, also read from FAWA_TOKEN.")
+	flag.Parse()
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *out == "" {
+		*out = *path + ".download"
+	}
+
+	var opts []connect.ClientOption
+	if *token != "" {
+		opts = append(opts, connect.WithInterceptors(bearerToken(*token)))
+	}
+	client := filev1connect.NewFileServiceClient(newHTTPClient(*addr), *addr, opts...)
+	ctx := context.Background()
+
+	key, err := upload(ctx, client, *path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "upload failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Uploaded %s, download key: %s\n", *path, key)
+
+	if err := download(ctx, client, key, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "download failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Downloaded it back to %s\n", *out)
+}
+
+// upload sends the file info then its content in chunks, and returns the download key
+func upload(ctx context.Context, client filev1connect.FileServiceClient, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	stat, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	stream := client.SendFile(ctx)
+	// The first message describes the file, the server checks it before any content is sent
+	err = stream.Send(&filev1.SendFileRequest{
+		Payload: &filev1.SendFileRequest_Info{Info: &filev1.FileInfo{Name: filepath.Base(path), Size: stat.Size()}},
+	})
+	buf := make([]byte, chunkSize)
+	for err == nil {
+		var n int
+		n, err = f.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&filev1.SendFileRequest{
+				Payload: &filev1.SendFileRequest_ChunkData{ChunkData: buf[:n]},
+			}); sendErr != nil {
+				err = sendErr
+			}
+		}
+	}
+	// A send fails with io.EOF when the server rejected the upload, CloseAndReceive has its error
+	resp, closeErr := stream.CloseAndReceive()
+	if closeErr != nil {
+		return "", closeErr
+	}
+	if !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return resp.Msg.GetRandomkey(), nil
+}
+
+// download writes the file shared under key to path. The stream starts with the file size,
+// then the content in chunks, and may end with its SHA-256.
+func download(ctx context.Context, client filev1connect.FileServiceClient, key, path string) error {
+	stream, err := client.ReceiveFile(ctx, connect.NewRequest(&filev1.ReceiveFileRequest{Randomkey: key}))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stream.Close() }()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	var size, written int64
+	for stream.Receive() {
+		switch payload := stream.Msg().GetPayload().(type) {
+		case *filev1.ReceiveFileResponse_FileSize:
+			size = payload.FileSize
+		case *filev1.ReceiveFileResponse_ChunkData:
+			n, err := f.Write(payload.ChunkData)
+			written += int64(n)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("received %d bytes, want %d", written, size)
+	}
+	return f.Close()
+}
+
+// newHTTPClient returns a client speaking HTTP/2, which the streaming RPCs need.
+// Plain http:// addresses use cleartext HTTP/2, like the service serves without certificates.
+func newHTTPClient(addr string) *http.Client {
+	if strings.HasPrefix(addr, "https://") {
+		return http.DefaultClient
+	}
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+// bearerToken adds the token to the Authorization header of every request
+func bearerToken(token string) connect.Interceptor {
+	return bearerInterceptor("Bearer " + token)
+}
+
+type bearerInterceptor string
+
+func (b bearerInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		req.Header().Set("Authorization", string(b))
+		return next(ctx, req)
+	}
+}
+
+func (b bearerInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		conn.RequestHeader().Set("Authorization", string(b))
+		return conn
+	}
+}
+
+func (b bearerInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// envOr returns the environment variable key, or def when it is unset
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,222 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command fawactl uploads files to and downloads files from the file service:
+//
+//	fawactl upload [flags] <path>
+//	fawactl download [flags] <key> <dest>
+//
+// upload prints the download key on stdout, followed by the plain HTTP share URL.
+// Files are streamed in chunks, so their size is not limited by memory.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"connectrpc.com/connect"
+	"golang.org/x/net/http2"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/client"
+)
+
+const usage = `Usage:
+  fawactl upload [flags] <path>
+  fawactl download [flags] <key> <dest>
+
+Flags go before the arguments, run "fawactl <command> -h" to list them.
+`
+
+// options are the flags shared by all commands
+type options struct {
+	server   string
+	insecure bool
+	token    string
+	quiet    bool
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "upload":
+		err = runUpload(ctx, args)
+	case "download":
+		err = runDownload(ctx, args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fawactl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// newFlagSet returns the flag set of a command with the shared options registered
+func newFlagSet(name, args string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: fawactl %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opts.server, "server", envOr("FAWA_SERVER", "http://localhost:8080"), "URL of the file service, also read from FAWA_SERVER.")
+	fs.BoolVar(&opts.insecure, "insecure", false, "Skip verifying the server's TLS certificate.")
+	fs.StringVar(&opts.token, "token", os.Getenv("FAWA_TOKEN"), "API key or JWT sent as a bearer token, also read from FAWA_TOKEN.")
+	fs.BoolVar(&opts.quiet, "quiet", false, "Don't show the progress bar.")
+	return fs
+}
+
+func runUpload(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("upload", "<path>", &opts)
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+	bar := newProgressBar(os.Stderr, "Uploading "+filepath.Base(path), opts.quiet)
+	c.Progress = bar.Update
+	key, err := c.UploadFile(ctx, path)
+	bar.Done()
+	if err != nil {
+		return err
+	}
+	fmt.Println(key)
+	fmt.Println(shareURL(opts.server, key))
+	return nil
+}
+
+func runDownload(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("download", "<key> <dest>", &opts)
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	key, dest := fs.Arg(0), fs.Arg(1)
+
+	c, err := opts.client()
+	if err != nil {
+		return err
+	}
+	// A directory destination keeps the shared file's name
+	if stat, err := os.Stat(dest); err == nil && stat.IsDir() {
+		info, err := c.RPC().GetFileInfo(ctx, connect.NewRequest(&filev1.GetFileInfoRequest{Randomkey: key}))
+		if err != nil {
+			return err
+		}
+		dest = filepath.Join(dest, filepath.Base(info.Msg.GetFilename()))
+	}
+	bar := newProgressBar(os.Stderr, "Downloading "+filepath.Base(dest), opts.quiet)
+	c.Progress = bar.Update
+	err = c.DownloadFile(ctx, key, dest)
+	bar.Done()
+	if err != nil {
+		return err
+	}
+	fmt.Println(dest)
+	return nil
+}
+
+// client returns an SDK client of the configured server
+func (o *options) client() (*client.Client, error) {
+	u, err := url.Parse(o.server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q, want http(s)://host[:port]", o.server)
+	}
+	var clientOpts []connect.ClientOption
+	if o.token != "" {
+		clientOpts = append(clientOpts, connect.WithInterceptors(bearerInterceptor("Bearer "+o.token)))
+	}
+	return client.New(o.httpClient(u.Scheme == "https"), strings.TrimSuffix(o.server, "/"), clientOpts...), nil
+}
+
+// httpClient returns an HTTP/2 client, which the streaming RPCs need.
+// Without TLS it speaks cleartext HTTP/2, like the service serves without certificates.
+func (o *options) httpClient(useTLS bool) *http.Client {
+	if useTLS {
+		return &http.Client{Transport: &http2.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: o.insecure}, //nolint:gosec // requested with --insecure
+		}}
+	}
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+// shareURL is the plain HTTP download URL of key, for browsers and curl
+func shareURL(server, key string) string {
+	return strings.TrimSuffix(server, "/") + "/download/" + url.PathEscape(key)
+}
+
+// bearerInterceptor sets the Authorization header of every request to its value
+type bearerInterceptor string
+
+func (b bearerInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		req.Header().Set("Authorization", string(b))
+		return next(ctx, req)
+	}
+}
+
+func (b bearerInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		conn.RequestHeader().Set("Authorization", string(b))
+		return conn
+	}
+}
+
+func (b bearerInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// envOr returns the environment variable key, or def when it is unset
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
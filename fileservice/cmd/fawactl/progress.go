@@ -0,0 +1,99 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// barWidth is the number of cells of the progress bar
+	barWidth = 30
+	// redrawInterval limits how often the bar is redrawn
+	redrawInterval = 100 * time.Millisecond
+)
+
+// progressBar draws the progress of a transfer on a single terminal line
+type progressBar struct {
+	w     io.Writer
+	label string
+	// disabled is set with --quiet or when w is not a terminal, so scripts only get the results
+	disabled bool
+	drawn    time.Time
+	last     string
+}
+
+func newProgressBar(w io.Writer, label string, quiet bool) *progressBar {
+	return &progressBar{w: w, label: label, disabled: quiet || !isTerminal(w)}
+}
+
+// Update redraws the bar, it is the progress callback of the client SDK
+func (p *progressBar) Update(done, total int64) {
+	if p.disabled || (done < total && time.Since(p.drawn) < redrawInterval) {
+		return
+	}
+	p.drawn = time.Now()
+	p.last = renderProgress(p.label, done, total)
+	_, _ = fmt.Fprint(p.w, "\r"+p.last)
+}
+
+// Done ends the line of the bar
+func (p *progressBar) Done() {
+	if p.last != "" {
+		_, _ = fmt.Fprintln(p.w)
+	}
+}
+
+// renderProgress formats a progress line such as
+// "photo.jpg [=======>            ]  36%  1.2 MiB / 3.4 MiB"
+func renderProgress(label string, done, total int64) string {
+	ratio := 1.0
+	if total > 0 {
+		ratio = min(float64(done)/float64(total), 1)
+	}
+	filled := int(ratio * barWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < barWidth {
+		bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+	return fmt.Sprintf("%s [%s] %3d%%  %s / %s", label, bar, int(ratio*100), formatBytes(done), formatBytes(total))
+}
+
+// formatBytes formats n with a binary unit, e.g. 1.5 KiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderProgress(t *testing.T) {
+	tests := []struct {
+		name        string
+		done, total int64
+		want        string
+	}{
+		{"start", 0, 2048, "f [>                             ]   0%  0 B / 2.0 KiB"},
+		{"half", 1024, 2048, "f [===============>              ]  50%  1.0 KiB / 2.0 KiB"},
+		{"done", 3 << 20, 3 << 20, "f [==============================] 100%  3.0 MiB / 3.0 MiB"},
+		{"empty file", 0, 0, "f [==============================] 100%  0 B / 0 B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderProgress("f", tt.done, tt.total); got != tt.want {
+				t.Errorf("renderProgress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestProgressBarDisabledOutsideTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	bar := newProgressBar(&buf, "f", false)
+	bar.Update(1, 1)
+	bar.Done()
+	if buf.Len() != 0 {
+		t.Errorf("progress bar wrote %q to a non-terminal", buf.String())
+	}
+}
+
+func TestShareURL(t *testing.T) {
+	if got, want := shareURL("https://files.example.com/", "a b"), "https://files.example.com/download/a%20b"; got != want {
+		t.Errorf("shareURL() = %q, want %q", got, want)
+	}
+	if !strings.HasSuffix(shareURL("http://localhost:8080", "k1"), ":8080/download/k1") {
+		t.Errorf("shareURL() dropped the port")
+	}
+}
@@ -15,9 +15,19 @@
 package config
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/fawa-io/fwpkg/fwlog"
 	"github.com/fsnotify/fsnotify"
@@ -25,11 +35,200 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Config is the service configuration. Fields tagged reload:"live" take effect when the
+// config file changes, the others only on restart.
 type Config struct {
 	Addr     string `mapstructure:"addr"`
 	CertFile string `mapstructure:"certFile"`
 	KeyFile  string `mapstructure:"keyFile"`
-	LogLevel string `mapstructure:"logLevel"`
+	LogLevel string `mapstructure:"logLevel" reload:"live"`
+	// ShutdownTimeout bounds the whole graceful shutdown sequence
+	ShutdownTimeout time.Duration `mapstructure:"shutdownTimeout"`
+	// DevMode relaxes production safeguards, every CORS origin is allowed
+	DevMode bool `mapstructure:"devMode"`
+	// CORS restricts which browser origins may call the service
+	CORS CORSConfig `mapstructure:"cors"`
+	// HTTP bounds how long the server waits on a client connection
+	HTTP HTTPConfig `mapstructure:"http"`
+	// PublicEndpoint is the externally reachable MinIO address download URLs are signed for
+	PublicEndpoint string `mapstructure:"publicEndpoint"`
+	// ChunkSize is the default ReceiveFile chunk size in bytes
+	ChunkSize int `mapstructure:"chunkSize"`
+	// MaxConcurrentDownloads bounds the downloads in progress per file, 0 is unlimited
+	MaxConcurrentDownloads int64 `mapstructure:"maxConcurrentDownloads"`
+	// ThumbnailSize is the largest side in pixels of the thumbnails of uploaded images, zero disables them
+	ThumbnailSize int `mapstructure:"thumbnailSize"`
+	// MaxDownloadURLExpiry caps the lifetime GetDownloadURL callers may ask for, zero is one hour
+	MaxDownloadURLExpiry time.Duration `mapstructure:"maxDownloadURLExpiry"`
+	// MaxLinkTTL caps the lifetime ExtendLink callers may ask for, zero is one day
+	MaxLinkTTL time.Duration `mapstructure:"maxLinkTTL"`
+	// OrphanGCInterval is how often the objects no metadata references anymore are removed, zero disables it
+	OrphanGCInterval time.Duration `mapstructure:"orphanGCInterval"`
+	// EncryptionKey is the base64 AES-256 master key of encrypted uploads, empty disables them
+	EncryptionKey string `mapstructure:"encryptionKey"`
+	// TrustedProxies lists the CIDRs of the reverse proxies whose forwarding headers are believed
+	TrustedProxies []string `mapstructure:"trustedProxies"`
+	// AdminToken is the bearer token of the admin endpoints listing the stored objects, empty
+	// disables them. Set it with FAWA_ADMINTOKEN rather than in the config file.
+	AdminToken string `mapstructure:"adminToken"`
+	// FileTypes restricts the types of the uploaded files
+	FileTypes FileTypesConfig `mapstructure:"fileTypes"`
+	// Quota limits the bytes each user or API key stores
+	Quota QuotaConfig `mapstructure:"quota"`
+	// Scan sends the uploads to a ClamAV daemon before they're shared
+	Scan ScanConfig `mapstructure:"scan"`
+	// Auth requires an API key on the file service RPCs, no key disables it
+	Auth AuthConfig `mapstructure:"auth"`
+	// RateLimit throttles requests per client IP, its budgets are applied on reload
+	RateLimit RateLimitConfig `mapstructure:"rateLimit" reload:"live"`
+	// MinIO is the object store holding the file contents
+	MinIO MinIOConfig `mapstructure:"minio"`
+	// Dragonfly is the Redis compatible store holding the file metadata
+	Dragonfly DragonflyConfig `mapstructure:"dragonfly"`
+}
+
+// MinIOConfig is the connection of the object store. Each setting can also come
+// from the MINIO_* variable used before it moved into the config.
+type MinIOConfig struct {
+	Endpoint        string `mapstructure:"endpoint"`
+	AccessKeyID     string `mapstructure:"accessKeyID"`
+	SecretAccessKey string `mapstructure:"secretAccessKey"`
+	// BucketName is the primary bucket, used by uploads that don't select one
+	BucketName string `mapstructure:"bucketName"`
+	// Buckets are the buckets uploads may select besides the primary one
+	Buckets []string `mapstructure:"buckets"`
+	UseSSL  bool     `mapstructure:"useSSL"`
+	// OpTimeout bounds each MinIO call that doesn't stream a file, and the wait for the response
+	// headers of those that do. Zero keeps the client defaults.
+	OpTimeout time.Duration `mapstructure:"opTimeout"`
+}
+
+// DragonflyConfig is the connection of the metadata store
+type DragonflyConfig struct {
+	Addr     string `mapstructure:"addr"`
+	DB       int    `mapstructure:"db"`
+	Password string `mapstructure:"password"`
+	// TLS connects over TLS, as managed Redis services usually require
+	TLS bool `mapstructure:"tls"`
+	// ClusterAddrs are the seed nodes of a Redis cluster, setting them enables cluster mode and replaces Addr
+	ClusterAddrs []string `mapstructure:"clusterAddrs"`
+	// MaxRetries retries commands failing on a connection error or a failover, 0 disables it
+	MaxRetries int `mapstructure:"maxRetries"`
+	// MinRetryBackoff and MaxRetryBackoff bound the exponential backoff between retries
+	MinRetryBackoff time.Duration `mapstructure:"minRetryBackoff"`
+	MaxRetryBackoff time.Duration `mapstructure:"maxRetryBackoff"`
+}
+
+// HTTPConfig are the http.Server timeouts, zero disables one. Streaming RPCs and long lived
+// connections outlast any sensible request deadline, so ReadTimeout and WriteTimeout are off by default.
+type HTTPConfig struct {
+	// ReadHeaderTimeout bounds reading the request headers, which is what slowloris clients stall
+	ReadHeaderTimeout time.Duration `mapstructure:"readHeaderTimeout"`
+	// ReadTimeout bounds reading the whole request, body included
+	ReadTimeout time.Duration `mapstructure:"readTimeout"`
+	// WriteTimeout bounds writing the response, streams included
+	WriteTimeout time.Duration `mapstructure:"writeTimeout"`
+	// IdleTimeout closes keep-alive connections without a request for that long
+	IdleTimeout time.Duration `mapstructure:"idleTimeout"`
+}
+
+// CORSConfig is the allowlist of cross-origin requests, empty lists fall back to the defaults
+// except for AllowedOrigins, which allows no cross-origin request unless DevMode is set.
+type CORSConfig struct {
+	AllowedOrigins []string `mapstructure:"allowedOrigins"`
+	AllowedMethods []string `mapstructure:"allowedMethods"`
+	AllowedHeaders []string `mapstructure:"allowedHeaders"`
+}
+
+// AuthConfig lists the API keys accepted as bearer tokens. Keep the plain keys out of the
+// config file, set them with FAWA_AUTH_APIKEYS or list their hashes instead.
+type AuthConfig struct {
+	APIKeys []string `mapstructure:"apiKeys"`
+	// APIKeyHashes are the hex SHA-256 hashes of more accepted keys
+	APIKeyHashes []string `mapstructure:"apiKeyHashes"`
+	// PublicProcedures don't require a key, e.g. /file.v1.FileService/ReceiveFile to share download links
+	PublicProcedures []string `mapstructure:"publicProcedures"`
+	// JWTPublicKeyFile is the PEM public key verifying user JWTs, which are accepted besides the
+	// API keys. Users only reach the files they uploaded. Empty accepts API keys only.
+	JWTPublicKeyFile string `mapstructure:"jwtPublicKeyFile"`
+}
+
+// FileTypesConfig lists the upload extensions (".exe") and MIME types ("image/*") allowed and
+// blocked. The blocklists take precedence, an empty allowlist allows every type that isn't blocked.
+// The MIME types are checked against both the extension and the sniffed content.
+type FileTypesConfig struct {
+	AllowedExtensions []string `mapstructure:"allowedExtensions"`
+	BlockedExtensions []string `mapstructure:"blockedExtensions"`
+	AllowedMIMETypes  []string `mapstructure:"allowedMIMETypes"`
+	BlockedMIMETypes  []string `mapstructure:"blockedMIMETypes"`
+}
+
+// QuotaConfig limits the bytes each user or API key stores at once, a file counts until its
+// link expires. Anonymous uploads, taken when SendFile is public, have no quota.
+type QuotaConfig struct {
+	// Default is the quota in bytes of the users and keys in no tier, 0 is unlimited
+	Default int64 `mapstructure:"default"`
+	// Tiers give their users and keys another quota
+	Tiers []QuotaTier `mapstructure:"tiers"`
+}
+
+// QuotaTier is the quota shared by a group of users and API keys, each of them gets the full Limit
+type QuotaTier struct {
+	// Limit is the quota in bytes, 0 is unlimited
+	Limit int64 `mapstructure:"limit"`
+	// Users are the subjects of their JWTs
+	Users []string `mapstructure:"users"`
+	// APIKeyHashes are the hex SHA-256 hashes of the keys, as listed in auth.apiKeyHashes
+	APIKeyHashes []string `mapstructure:"apiKeyHashes"`
+}
+
+// ScanConfig enables the virus scan of the uploads. In sync mode an upload fails when its file is
+// infected, in async mode it's shared right away but can't be downloaded until the scan cleared it.
+type ScanConfig struct {
+	// ClamdAddr is the TCP address of clamd, e.g. clamav:3310, empty disables scanning
+	ClamdAddr string `mapstructure:"clamdAddr"`
+	// Mode is "sync" or "async"
+	Mode string `mapstructure:"mode"`
+	// Timeout bounds the scan of a file
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// RateLimitConfig sets separate per-IP budgets for uploads and for the cheaper download calls
+type RateLimitConfig struct {
+	Upload   RateLimit `mapstructure:"upload"`
+	Download RateLimit `mapstructure:"download"`
+	// MaxClients bounds the number of client IPs tracked per budget
+	MaxClients int `mapstructure:"maxClients"`
+}
+
+// RateLimit allows RPS requests per second on average with bursts of up to Burst requests.
+// A non-positive RPS disables the limit.
+type RateLimit struct {
+	RPS   float64 `mapstructure:"rps"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// storageEnv maps the store connection keys to their environment variables
+var storageEnv = map[string]string{
+	"minio.endpoint":        "MINIO_ENDPOINT",
+	"minio.accessKeyID":     "MINIO_ACCESS_KEY_ID",
+	"minio.secretAccessKey": "MINIO_SECRET_ACCESS_KEY",
+	"minio.bucketName":      "MINIO_BUCKET_NAME",
+	"minio.buckets":         "MINIO_BUCKETS",
+	"minio.useSSL":          "MINIO_USE_SSL",
+	"dragonfly.addr":        "DRAGONFLY_ADDR",
+	"dragonfly.password":    "DRAGONFLY_PASSWORD",
+}
+
+// bindStorageEnv reads the store connections from the environment variables of the deployments
+// predating the config keys. The credentials have no flag so they stay off the command line.
+func bindStorageEnv(v *viper.Viper) error {
+	for key, env := range storageEnv {
+		if err := v.BindEnv(key, env); err != nil {
+			return fmt.Errorf("failed to bind env: %w", err)
+		}
+	}
+	return nil
 }
 
 var (
@@ -38,6 +237,8 @@ var (
 	mu sync.RWMutex
 
 	config Config
+	// onChange are the callbacks registered with OnChange, guarded by mu
+	onChange []func(old, new Config)
 )
 
 func InitConfig() error {
@@ -54,20 +255,85 @@ func Get() Config {
 	return config
 }
 
+// OnChange registers fn to be called with the previous and the new config whenever a reload
+// changes it. Since only fields tagged reload:"live" are reloaded, those are the only ones that
+// can differ. fn runs on the file watcher goroutine once Get returns the new config, so it may
+// call Get but must not block.
+func OnChange(fn func(old, new Config)) {
+	mu.Lock()
+	defer mu.Unlock()
+	onChange = append(onChange, fn)
+}
+
+// LoadAndWatch loads the config and reloads it when the config file changes.
+// A setting is taken from the first of: a command line flag, its FAWA_ environment variable,
+// the config file, the default.
 func LoadAndWatch() error {
-	pflag.String("addr", "", "List of HTTP service address (e.g., '127.0.0.1:9090')")
+	pflag.String("addr", "127.0.0.1:8080", "List of HTTP service address (e.g., '127.0.0.1:9090')")
+	pflag.String("logLevel", "info", "Minimum level logged: debug, info, warn, error or fatal.")
 	pflag.String("certFile", "", "Path to the TLS certificate file.")
 	pflag.String("keyFile", "", "Path to the TLS private key file.")
+	pflag.Duration("shutdownTimeout", 10*time.Second, "Maximum time allowed for the graceful shutdown.")
+	pflag.Duration("http.readHeaderTimeout", 10*time.Second, "Maximum time to read the request headers, 0 disables it.")
+	pflag.Duration("http.readTimeout", 0, "Maximum time to read a whole request, 0 disables it so client streams may run long.")
+	pflag.Duration("http.writeTimeout", 0, "Maximum time to write a response, 0 disables it so server streams may run long.")
+	pflag.Duration("http.idleTimeout", 2*time.Minute, "Close keep-alive connections idle for this long, 0 disables it.")
+	pflag.String("publicEndpoint", "", "Externally reachable MinIO address for download URLs (e.g., 'https://files.example.com/minio').")
+	pflag.Int("chunkSize", 64<<10, "Default ReceiveFile chunk size in bytes, clients may request another within 4KiB to 4MiB.")
+	pflag.Int64("maxConcurrentDownloads", 0, "Maximum downloads in progress per file across all replicas, 0 is unlimited.")
+	pflag.Int("thumbnailSize", 256, "Largest side in pixels of the thumbnails generated for uploaded images, 0 disables them.")
+	pflag.Duration("maxDownloadURLExpiry", time.Hour, "Longest lifetime a client may request for a presigned download URL, at most 7 days.")
+	pflag.Duration("maxLinkTTL", 24*time.Hour, "Longest a client may keep a file shared with ExtendLink, counted from the call.")
+	pflag.Duration("orphanGCInterval", time.Hour, "How often to remove the stored objects whose metadata expired, 0 disables it.")
+	pflag.Float64("rateLimit.upload.rps", 1, "Uploads allowed per second and client IP, 0 disables the limit.")
+	pflag.Int("rateLimit.upload.burst", 5, "Uploads a client IP may burst above the rate.")
+	pflag.Float64("rateLimit.download.rps", 10, "Download and file info requests allowed per second and client IP, 0 disables the limit.")
+	pflag.Int("rateLimit.download.burst", 30, "Download and file info requests a client IP may burst above the rate.")
+	pflag.Int("rateLimit.maxClients", 10000, "Maximum number of client IPs tracked by each rate limiter.")
+	pflag.StringSlice("trustedProxies", nil, "CIDRs of reverse proxies trusted to set X-Forwarded-For and Forwarded (e.g., '10.0.0.0/8').")
+	pflag.StringSlice("fileTypes.allowedExtensions", nil, "Only accept uploads with these extensions (e.g., '.png,.pdf'), empty accepts all.")
+	pflag.StringSlice("fileTypes.blockedExtensions", nil, "Reject uploads with these extensions (e.g., '.exe,.js').")
+	pflag.StringSlice("fileTypes.allowedMIMETypes", nil, "Only accept uploads of these MIME types (e.g., 'image/*'), empty accepts all.")
+	pflag.StringSlice("fileTypes.blockedMIMETypes", nil, "Reject uploads of these MIME types (e.g., 'text/html,application/octet-stream').")
+	pflag.Int64("quota.default", 0, "Bytes each user or API key may store at once, 0 is unlimited. Set tiers in the config file.")
+	pflag.String("scan.clamdAddr", "", "ClamAV daemon address scanning the uploads (e.g., 'clamav:3310'), scanning is disabled when unset.")
+	pflag.String("scan.mode", "sync", "sync rejects infected uploads, async shares them quarantined until scanned.")
+	pflag.Duration("scan.timeout", time.Minute, "Maximum time to scan a file.")
+	pflag.String("minio.endpoint", "", "MinIO address (e.g., 'minio:9000'), object storage is disabled when unset.")
+	pflag.String("minio.bucketName", "", "Primary MinIO bucket.")
+	pflag.StringSlice("minio.buckets", nil, "Extra MinIO buckets uploads may select.")
+	pflag.Bool("minio.useSSL", false, "Connect to MinIO over TLS.")
+	pflag.Duration("minio.opTimeout", 30*time.Second, "Maximum time of a MinIO call, uploads and downloads only wait this long for a response to start.")
+	pflag.String("dragonfly.addr", "localhost:6379", "Dragonfly/Redis address of the metadata store.")
+	pflag.Int("dragonfly.db", 0, "Dragonfly/Redis database number.")
+	pflag.Bool("dragonfly.tls", false, "Connect to Dragonfly/Redis over TLS.")
+	pflag.Int("dragonfly.maxRetries", 3, "Retries of a Dragonfly/Redis command failing on a connection error or a failover, 0 disables them.")
+	pflag.Duration("dragonfly.minRetryBackoff", 8*time.Millisecond, "Backoff before the first Dragonfly/Redis retry, doubled on each retry.")
+	pflag.Duration("dragonfly.maxRetryBackoff", 512*time.Millisecond, "Maximum backoff between Dragonfly/Redis retries.")
+	pflag.StringSlice("dragonfly.clusterAddrs", nil, "Seed nodes of a Redis cluster holding the metadata, replaces dragonfly.addr.")
+	pflag.Bool("devMode", false, "Allow every CORS origin, for local development only.")
+	pflag.String("config", "", "Path to the config file, the format is detected from its extension (yaml, json or toml).")
 	pflag.Parse()
 
 	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
 		return fmt.Errorf("failed to bind pflags: %w", err)
 	}
+	if err := bindEnv(viper.GetViper()); err != nil {
+		return err
+	}
+	// Deployments predating the config key set the public endpoint through the environment
+	if err := viper.BindEnv("publicEndpoint", "MINIO_PUBLIC_ENDPOINT"); err != nil {
+		return fmt.Errorf("failed to bind env: %w", err)
+	}
+	// Keep the master key off the command line
+	if err := viper.BindEnv("encryptionKey", "FAWA_ENCRYPTION_KEY"); err != nil {
+		return fmt.Errorf("failed to bind env: %w", err)
+	}
+	if err := bindStorageEnv(viper.GetViper()); err != nil {
+		return err
+	}
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("/etc/fawa/")
+	setConfigFile(viper.GetViper(), viper.GetString("config"))
 
 	if err := viper.ReadInConfig(); err != nil {
 		var configFileNotFoundError viper.ConfigFileNotFoundError
@@ -84,32 +350,258 @@ func LoadAndWatch() error {
 		return fmt.Errorf("the initial configuration cannot be decoded into the struct: %w", err)
 	}
 	mu.Unlock()
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
 
 	viper.SetDefault("addr", "127.0.0.1:8080")
 	viper.SetDefault("uploadDir", "./upload")
 	viper.SetDefault("certFile", "")
 	viper.SetDefault("keyFile", "")
 	viper.SetDefault("logLevel", "info")
+	viper.SetDefault("shutdownTimeout", "10s")
+	viper.SetDefault("devMode", false)
+	viper.SetDefault("chunkSize", 64<<10)
 
 	viper.OnConfigChange(func(e fsnotify.Event) {
 		fwlog.Infof("the Profile HasChanged: %s。reloading...", e.Name)
 
-		mu.Lock()
-		defer mu.Unlock()
-
-		if err := viper.Unmarshal(&config); err != nil {
+		var next Config
+		if err := viper.Unmarshal(&next); err != nil {
 			fwlog.Errorf("Error while reloading config: %v", err)
-		} else {
-			newLogLevel, err := fwlog.ParseLevel(config.LogLevel)
-			if err != nil {
-				fwlog.Warnf("New log level in config is invalid: %v. Keeping previous level.", err)
-			} else {
-				fwlog.SetLevel(newLogLevel)
-				fwlog.Infof("Log level reloaded successfully to: %s", config.LogLevel)
-			}
+			return
+		}
+		if err := next.Validate(); err != nil {
+			fwlog.Errorf("Ignoring invalid config, keeping the running one:\n%v", err)
+			return
 		}
+		reload(next)
 	})
 	viper.WatchConfig()
 
 	return nil
 }
+
+// envPrefix prefixes the environment variables overriding config keys, e.g. FAWA_ADDR
+const envPrefix = "FAWA"
+
+// bindEnv lets FAWA_ prefixed environment variables override the config file. The key is
+// upper-cased with dots replaced by underscores, rateLimit.upload.rps is FAWA_RATELIMIT_UPLOAD_RPS.
+func bindEnv(v *viper.Viper) error {
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// Unmarshal only decodes the keys viper knows about, bind those without a flag or default too
+	return bindEnvKeys(v, "", reflect.TypeOf(Config{}))
+}
+
+// bindEnvKeys binds the key of every leaf field of the struct type t to its environment variable
+func bindEnvKeys(v *viper.Viper, prefix string, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		if field.Type.Kind() == reflect.Struct {
+			if err := bindEnvKeys(v, key+".", field.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := v.BindEnv(key); err != nil {
+			return fmt.Errorf("failed to bind env: %w", err)
+		}
+	}
+	return nil
+}
+
+// reload makes next the current config and notifies the OnChange callbacks.
+// Only fields tagged reload:"live" are applied, the running servers were built from the others,
+// which keep their previous value until a restart.
+func reload(next Config) {
+	mu.Lock()
+	prev := config
+	var pending []string
+	config, pending = applyReload(config, next)
+	current, callbacks := config, onChange
+	mu.Unlock()
+
+	for _, name := range pending {
+		fwlog.Warnf("Config %s changed, restart required to apply it", name)
+	}
+
+	newLogLevel, err := fwlog.ParseLevel(current.LogLevel)
+	if err != nil {
+		fwlog.Warnf("New log level in config is invalid: %v. Keeping previous level.", err)
+	} else {
+		fwlog.SetLevel(newLogLevel)
+		fwlog.Infof("Log level reloaded successfully to: %s", current.LogLevel)
+	}
+
+	// The callbacks run without mu held so they can read the config back with Get
+	if reflect.DeepEqual(prev, current) {
+		return
+	}
+	for _, fn := range callbacks {
+		fn(prev, current)
+	}
+}
+
+// applyReload returns next with the fields not tagged reload:"live" reset to their value in current,
+// along with the names of those that differ.
+func applyReload(current, next Config) (Config, []string) {
+	cur := reflect.ValueOf(current)
+	nxt := reflect.ValueOf(&next).Elem()
+	var pending []string
+	for i := 0; i < nxt.NumField(); i++ {
+		field := nxt.Type().Field(i)
+		if field.Tag.Get("reload") == "live" {
+			continue
+		}
+		if !reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			pending = append(pending, field.Tag.Get("mapstructure"))
+			nxt.Field(i).Set(cur.Field(i))
+		}
+	}
+	return next, pending
+}
+
+// LoadTLSConfig loads the key pair named by CertFile and KeyFile.
+// It returns a nil config when both are empty so the caller serves plain HTTP,
+// and an error naming the offending file when only one is set or the pair can't be loaded.
+func (c Config) LoadTLSConfig() (*tls.Config, error) {
+	if c.CertFile == "" && c.KeyFile == "" {
+		return nil, nil
+	}
+	if c.CertFile == "" {
+		return nil, fmt.Errorf("keyFile %q is set but certFile is empty", c.KeyFile)
+	}
+	if c.KeyFile == "" {
+		return nil, fmt.Errorf("certFile %q is set but keyFile is empty", c.CertFile)
+	}
+	for _, file := range []string{c.CertFile, c.KeyFile} {
+		if _, err := os.Stat(file); err != nil {
+			return nil, fmt.Errorf("cannot read TLS file %q: %w", file, err)
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS key pair (certFile %q, keyFile %q): %w", c.CertFile, c.KeyFile, err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// Validate reports every invalid setting of c at once, so a misconfigured service refuses
+// to start with the full list instead of failing on the first request that needs one.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Addr == "" {
+		errs = append(errs, errors.New("addr is required"))
+	} else if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("addr %q must be host:port: %w", c.Addr, err))
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("certFile %q and keyFile %q must be set together or both left empty", c.CertFile, c.KeyFile))
+	}
+	if _, err := fwlog.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel %q is invalid, use debug, info, warn, error or fatal", c.LogLevel))
+	}
+	if len(c.Dragonfly.ClusterAddrs) > 0 && c.Dragonfly.DB != 0 {
+		errs = append(errs, fmt.Errorf("dragonfly.db %d must be 0 with dragonfly.clusterAddrs, a cluster only has database 0", c.Dragonfly.DB))
+	}
+	if c.Dragonfly.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("dragonfly.maxRetries %d must not be negative", c.Dragonfly.MaxRetries))
+	}
+	if c.Dragonfly.MinRetryBackoff < 0 || c.Dragonfly.MaxRetryBackoff < c.Dragonfly.MinRetryBackoff {
+		errs = append(errs, fmt.Errorf("dragonfly.minRetryBackoff %v and dragonfly.maxRetryBackoff %v must be ordered and not negative",
+			c.Dragonfly.MinRetryBackoff, c.Dragonfly.MaxRetryBackoff))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdownTimeout %v must be positive", c.ShutdownTimeout))
+	}
+	if c.ThumbnailSize < 0 || c.ThumbnailSize > 2048 {
+		errs = append(errs, fmt.Errorf("thumbnailSize %d must be between 0 and 2048", c.ThumbnailSize))
+	}
+	for _, mediaType := range slices.Concat(c.FileTypes.AllowedMIMETypes, c.FileTypes.BlockedMIMETypes) {
+		if !strings.Contains(mediaType, "/") {
+			errs = append(errs, fmt.Errorf("fileTypes MIME type %q must be a type/subtype", mediaType))
+		}
+	}
+	errs = append(errs, c.Quota.validate()...)
+	if c.Scan.ClamdAddr != "" {
+		if c.Scan.Mode != "sync" && c.Scan.Mode != "async" {
+			errs = append(errs, fmt.Errorf("scan.mode %q must be sync or async", c.Scan.Mode))
+		}
+		if c.Scan.Timeout <= 0 {
+			errs = append(errs, fmt.Errorf("scan.timeout %v must be positive", c.Scan.Timeout))
+		}
+	}
+	// MinIO refuses to presign URLs valid for longer than a week
+	if c.MaxDownloadURLExpiry < 0 || c.MaxDownloadURLExpiry > 7*24*time.Hour {
+		errs = append(errs, fmt.Errorf("maxDownloadURLExpiry %v must be between 0 and 168h", c.MaxDownloadURLExpiry))
+	}
+	if c.MaxLinkTTL < 0 {
+		errs = append(errs, fmt.Errorf("maxLinkTTL %v must not be negative", c.MaxLinkTTL))
+	}
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"http.readHeaderTimeout", c.HTTP.ReadHeaderTimeout},
+		{"http.readTimeout", c.HTTP.ReadTimeout},
+		{"http.writeTimeout", c.HTTP.WriteTimeout},
+		{"http.idleTimeout", c.HTTP.IdleTimeout},
+		{"minio.opTimeout", c.MinIO.OpTimeout},
+	} {
+		if timeout.value < 0 {
+			errs = append(errs, fmt.Errorf("%s %v must not be negative", timeout.key, timeout.value))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate reports the negative quotas and the users and keys of several tiers
+func (q QuotaConfig) validate() []error {
+	var errs []error
+	if q.Default < 0 {
+		errs = append(errs, fmt.Errorf("quota.default %d must not be negative", q.Default))
+	}
+	tierOf := make(map[string]int)
+	for i, tier := range q.Tiers {
+		if tier.Limit < 0 {
+			errs = append(errs, fmt.Errorf("quota.tiers[%d].limit %d must not be negative", i, tier.Limit))
+		}
+		owners := make([]string, 0, len(tier.Users)+len(tier.APIKeyHashes))
+		for _, user := range tier.Users {
+			owners = append(owners, "user "+user)
+		}
+		for _, hash := range tier.APIKeyHashes {
+			if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+				errs = append(errs, fmt.Errorf("quota.tiers[%d] API key hash %q is not a hex SHA-256 hash", i, hash))
+			}
+			owners = append(owners, "API key "+strings.ToLower(hash))
+		}
+		for _, owner := range owners {
+			if j, ok := tierOf[owner]; ok {
+				errs = append(errs, fmt.Errorf("quota.tiers[%d] repeats %s of quota.tiers[%d]", i, owner, j))
+				continue
+			}
+			tierOf[owner] = i
+		}
+	}
+	return errs
+}
+
+// setConfigFile tells v where to find the config file.
+// The format is detected from the file extension, YAML is assumed when it can't be detected.
+// Without an explicit path, config.{yaml,json,toml} is searched in the working directory and /etc/fawa/.
+func setConfigFile(v *viper.Viper, path string) {
+	if path == "" {
+		v.SetConfigName("config")
+		v.AddConfigPath(".")
+		v.AddConfigPath("/etc/fawa/")
+		return
+	}
+	v.SetConfigFile(path)
+	if ext := filepath.Ext(path); ext == "" || !slices.Contains(viper.SupportedExts, ext[1:]) {
+		v.SetConfigType("yaml")
+	}
+}
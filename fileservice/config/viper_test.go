@@ -0,0 +1,618 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestSetConfigFileFormats(t *testing.T) {
+	want := Config{
+		Addr:     "0.0.0.0:9090",
+		CertFile: "/etc/fawa/cert.pem",
+		KeyFile:  "/etc/fawa/key.pem",
+		LogLevel: "debug",
+	}
+
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `addr: "0.0.0.0:9090"
+certFile: "/etc/fawa/cert.pem"
+keyFile: "/etc/fawa/key.pem"
+logLevel: "debug"
+`,
+		},
+		{
+			name: "json",
+			file: "config.json",
+			content: `{
+  "addr": "0.0.0.0:9090",
+  "certFile": "/etc/fawa/cert.pem",
+  "keyFile": "/etc/fawa/key.pem",
+  "logLevel": "debug"
+}`,
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			content: `addr = "0.0.0.0:9090"
+certFile = "/etc/fawa/cert.pem"
+keyFile = "/etc/fawa/key.pem"
+logLevel = "debug"
+`,
+		},
+		{
+			name: "no extension defaults to yaml",
+			file: "config",
+			content: `addr: "0.0.0.0:9090"
+certFile: "/etc/fawa/cert.pem"
+keyFile: "/etc/fawa/key.pem"
+logLevel: "debug"
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			v := viper.New()
+			setConfigFile(v, path)
+			if err := v.ReadInConfig(); err != nil {
+				t.Fatalf("ReadInConfig() error = %v", err)
+			}
+			var got Config
+			if err := v.Unmarshal(&got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// writeKeyPair writes a self-signed certificate and its key into dir
+func writeKeyPair(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir)
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a pem file"), 0o600); err != nil {
+		t.Fatalf("failed to write garbage file: %v", err)
+	}
+	missing := filepath.Join(dir, "missing.pem")
+
+	testCases := []struct {
+		name    string
+		cfg     Config
+		wantTLS bool
+		wantErr string
+	}{
+		{name: "both empty falls back to HTTP"},
+		{name: "valid pair", cfg: Config{CertFile: certFile, KeyFile: keyFile}, wantTLS: true},
+		{name: "missing key file setting", cfg: Config{CertFile: certFile}, wantErr: certFile},
+		{name: "missing cert file setting", cfg: Config{KeyFile: keyFile}, wantErr: keyFile},
+		{name: "cert file does not exist", cfg: Config{CertFile: missing, KeyFile: keyFile}, wantErr: missing},
+		{name: "key file does not exist", cfg: Config{CertFile: certFile, KeyFile: missing}, wantErr: missing},
+		{name: "malformed cert", cfg: Config{CertFile: garbage, KeyFile: keyFile}, wantErr: garbage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tlsConfig, err := tc.cfg.LoadTLSConfig()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("LoadTLSConfig() error = %v, want it to mention %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadTLSConfig() error = %v", err)
+			}
+			if got := tlsConfig != nil; got != tc.wantTLS {
+				t.Errorf("LoadTLSConfig() returned TLS config = %v, want %v", got, tc.wantTLS)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Config{Addr: "127.0.0.1:8080", LogLevel: "info", ShutdownTimeout: 10 * time.Second}
+
+	testCases := []struct {
+		name     string
+		mutate   func(c *Config)
+		wantErrs []string
+	}{
+		{name: "valid", mutate: func(c *Config) {}},
+		{name: "valid with TLS", mutate: func(c *Config) { c.CertFile, c.KeyFile = "cert.pem", "key.pem" }},
+		{name: "port only", mutate: func(c *Config) { c.Addr = ":8080" }},
+		{name: "missing addr", mutate: func(c *Config) { c.Addr = "" }, wantErrs: []string{"addr is required"}},
+		{name: "addr without port", mutate: func(c *Config) { c.Addr = "localhost" }, wantErrs: []string{`addr "localhost"`}},
+		{name: "cert without key", mutate: func(c *Config) { c.CertFile = "cert.pem" }, wantErrs: []string{"must be set together"}},
+		{name: "key without cert", mutate: func(c *Config) { c.KeyFile = "key.pem" }, wantErrs: []string{"must be set together"}},
+		{name: "unknown log level", mutate: func(c *Config) { c.LogLevel = "verbose" }, wantErrs: []string{`logLevel "verbose"`}},
+		{name: "zero shutdown timeout", mutate: func(c *Config) { c.ShutdownTimeout = 0 }, wantErrs: []string{"shutdownTimeout"}},
+		{name: "file types", mutate: func(c *Config) { c.FileTypes.BlockedMIMETypes = []string{"text/html", "application/*"} }},
+		{
+			name:     "MIME type without a subtype",
+			mutate:   func(c *Config) { c.FileTypes.AllowedMIMETypes = []string{"image"} },
+			wantErrs: []string{`"image"`},
+		},
+		{
+			name: "quota tiers",
+			mutate: func(c *Config) {
+				c.Quota = QuotaConfig{Default: 1 << 30, Tiers: []QuotaTier{
+					{Limit: 10 << 30, Users: []string{"alice"}},
+					{Limit: 0, APIKeyHashes: []string{strings.Repeat("ab", 32)}},
+				}}
+			},
+		},
+		{
+			name: "invalid quotas",
+			mutate: func(c *Config) {
+				c.Quota = QuotaConfig{Default: -1, Tiers: []QuotaTier{
+					{Limit: -1, Users: []string{"alice"}, APIKeyHashes: []string{"abc"}},
+					{Limit: 1, Users: []string{"alice"}},
+				}}
+			},
+			wantErrs: []string{"quota.default -1", "quota.tiers[0].limit -1", `"abc"`, "quota.tiers[1] repeats user alice"},
+		},
+		{name: "scan", mutate: func(c *Config) { c.Scan = ScanConfig{ClamdAddr: "clamav:3310", Mode: "async", Timeout: time.Minute} }},
+		{
+			name:     "unknown scan mode",
+			mutate:   func(c *Config) { c.Scan = ScanConfig{ClamdAddr: "clamav:3310", Mode: "later", Timeout: time.Minute} },
+			wantErrs: []string{`scan.mode "later"`},
+		},
+		{
+			name:     "zero scan timeout",
+			mutate:   func(c *Config) { c.Scan = ScanConfig{ClamdAddr: "clamav:3310", Mode: "sync"} },
+			wantErrs: []string{"scan.timeout"},
+		},
+		{
+			name:     "thumbnail size too large",
+			mutate:   func(c *Config) { c.ThumbnailSize = 4096 },
+			wantErrs: []string{"thumbnailSize 4096"},
+		},
+		{
+			name:     "negative download URL expiry",
+			mutate:   func(c *Config) { c.MaxDownloadURLExpiry = -time.Second },
+			wantErrs: []string{"maxDownloadURLExpiry"},
+		},
+		{
+			name:     "download URL expiry over a week",
+			mutate:   func(c *Config) { c.MaxDownloadURLExpiry = 8 * 24 * time.Hour },
+			wantErrs: []string{"maxDownloadURLExpiry"},
+		},
+		{
+			name:     "negative link ttl",
+			mutate:   func(c *Config) { c.MaxLinkTTL = -time.Minute },
+			wantErrs: []string{"maxLinkTTL"},
+		},
+		{name: "cluster", mutate: func(c *Config) { c.Dragonfly.ClusterAddrs = []string{"node1:6379"} }},
+		{
+			name:     "cluster with a database",
+			mutate:   func(c *Config) { c.Dragonfly.ClusterAddrs, c.Dragonfly.DB = []string{"node1:6379"}, 1 },
+			wantErrs: []string{"dragonfly.db 1"},
+		},
+		{
+			name: "retry backoffs out of order",
+			mutate: func(c *Config) {
+				c.Dragonfly.MinRetryBackoff, c.Dragonfly.MaxRetryBackoff = time.Second, time.Millisecond
+			},
+			wantErrs: []string{"dragonfly.minRetryBackoff 1s"},
+		},
+		{name: "disabled HTTP timeouts", mutate: func(c *Config) { c.HTTP = HTTPConfig{} }},
+		{name: "negative write timeout", mutate: func(c *Config) { c.HTTP.WriteTimeout = -time.Second }, wantErrs: []string{"http.writeTimeout -1s"}},
+		{name: "negative MinIO timeout", mutate: func(c *Config) { c.MinIO.OpTimeout = -time.Second }, wantErrs: []string{"minio.opTimeout -1s"}},
+		{
+			name: "every problem is reported",
+			mutate: func(c *Config) {
+				c.Addr = "localhost"
+				c.KeyFile = "key.pem"
+				c.LogLevel = ""
+			},
+			wantErrs: []string{`addr "localhost"`, "must be set together", `logLevel ""`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := valid
+			tc.mutate(&cfg)
+			err := cfg.Validate()
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %q", tc.wantErrs)
+			}
+			for _, want := range tc.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReloadKeepsRestartRequiredFields(t *testing.T) {
+	var logs bytes.Buffer
+	fwlog.SetOutput(&logs)
+	t.Cleanup(func() {
+		fwlog.SetOutput(os.Stdout)
+		fwlog.SetLevel(fwlog.LevelInfo)
+	})
+
+	running := Config{
+		Addr:     "127.0.0.1:8080",
+		LogLevel: "info",
+		CORS:     CORSConfig{AllowedOrigins: []string{"https://a.example.com"}},
+	}
+	mu.Lock()
+	config = running
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		config = Config{}
+		mu.Unlock()
+	})
+
+	next := running
+	next.Addr = "0.0.0.0:9090"
+	next.LogLevel = "debug"
+	next.CORS = CORSConfig{AllowedOrigins: []string{"https://b.example.com"}}
+	reload(next)
+
+	got := Get()
+	if got.Addr != running.Addr {
+		t.Errorf("Addr = %q, want the running %q", got.Addr, running.Addr)
+	}
+	if !reflect.DeepEqual(got.CORS, running.CORS) {
+		t.Errorf("CORS = %+v, want the running %+v", got.CORS, running.CORS)
+	}
+	if got.LogLevel != "debug" {
+		t.Errorf("LogLevel = %q, want the live reloaded debug", got.LogLevel)
+	}
+
+	for _, name := range []string{"addr", "cors"} {
+		if !strings.Contains(logs.String(), "Config "+name+" changed, restart required to apply it") {
+			t.Errorf("no restart warning for %s in logs:\n%s", name, logs.String())
+		}
+	}
+	if strings.Contains(logs.String(), "Config logLevel changed") {
+		t.Errorf("live field logLevel reported as restart required:\n%s", logs.String())
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	fwlog.SetOutput(&bytes.Buffer{})
+	running := Config{Addr: "127.0.0.1:8080", LogLevel: "info"}
+	mu.Lock()
+	config = running
+	mu.Unlock()
+	t.Cleanup(func() {
+		fwlog.SetOutput(os.Stdout)
+		fwlog.SetLevel(fwlog.LevelInfo)
+		mu.Lock()
+		config = Config{}
+		onChange = nil
+		mu.Unlock()
+	})
+
+	type call struct{ prev, next, got Config }
+	var calls []call
+	OnChange(func(prev, next Config) {
+		// Get already returns the new config and must not deadlock
+		calls = append(calls, call{prev: prev, next: next, got: Get()})
+	})
+
+	// Only restart required fields change, the running config stays the same
+	restartOnly := running
+	restartOnly.Addr = "0.0.0.0:9090"
+	reload(restartOnly)
+	if len(calls) != 0 {
+		t.Fatalf("callback called %d times for a reload without live changes", len(calls))
+	}
+
+	next := running
+	next.Addr = "0.0.0.0:9090"
+	next.LogLevel = "debug"
+	next.RateLimit.Upload = RateLimit{RPS: 5, Burst: 10}
+	reload(next)
+	if len(calls) != 1 {
+		t.Fatalf("callback called %d times, want 1", len(calls))
+	}
+
+	want := running
+	want.LogLevel = "debug"
+	want.RateLimit.Upload = RateLimit{RPS: 5, Burst: 10}
+	c := calls[0]
+	if !reflect.DeepEqual(c.prev, running) {
+		t.Errorf("old config = %+v, want %+v", c.prev, running)
+	}
+	if !reflect.DeepEqual(c.next, want) {
+		t.Errorf("new config = %+v, want %+v", c.next, want)
+	}
+	if !reflect.DeepEqual(c.got, want) {
+		t.Errorf("Get() in callback = %+v, want the new config %+v", c.got, want)
+	}
+}
+
+func TestBindEnvPrecedence(t *testing.T) {
+	t.Setenv("FAWA_ADDR", "0.0.0.0:9000")
+	t.Setenv("FAWA_LOGLEVEL", "debug")
+	t.Setenv("FAWA_RATELIMIT_UPLOAD_RPS", "2.5")
+	t.Setenv("FAWA_CORS_ALLOWEDORIGINS", "https://a.example.com,https://b.example.com")
+	t.Setenv("FAWA_CHUNKSIZE", "8192")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("addr", "127.0.0.1:8080", "")
+	flags.Int("chunkSize", 64<<10, "")
+	flags.Int("maxConcurrentDownloads", 0, "")
+	flags.Duration("shutdownTimeout", 10*time.Second, "")
+	if err := flags.Parse([]string{"--chunkSize=16384"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	v := viper.New()
+	if err := v.BindPFlags(flags); err != nil {
+		t.Fatalf("BindPFlags() error = %v", err)
+	}
+	if err := bindEnv(v); err != nil {
+		t.Fatalf("bindEnv() error = %v", err)
+	}
+	v.SetConfigType("yaml")
+	file := "addr: 127.0.0.1:7000\nlogLevel: warn\nmaxConcurrentDownloads: 3\n"
+	if err := v.ReadConfig(strings.NewReader(file)); err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+
+	var got Config
+	if err := v.Unmarshal(&got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{name: "flag over env", got: got.ChunkSize, want: 16384},
+		{name: "env over file", got: got.Addr, want: "0.0.0.0:9000"},
+		{name: "env over file without flag", got: got.LogLevel, want: "debug"},
+		{name: "file over default", got: got.MaxConcurrentDownloads, want: int64(3)},
+		{name: "default", got: got.ShutdownTimeout, want: 10 * time.Second},
+		{name: "nested key", got: got.RateLimit.Upload.RPS, want: 2.5},
+		{name: "list", got: got.CORS.AllowedOrigins, want: []string{"https://a.example.com", "https://b.example.com"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.got, tc.want) {
+				t.Errorf("got %#v, want %#v", tc.got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStorageEnv(t *testing.T) {
+	t.Setenv("MINIO_ENDPOINT", "minio:9000")
+	t.Setenv("MINIO_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("MINIO_BUCKETS", "public,private")
+	t.Setenv("MINIO_USE_SSL", "true")
+	t.Setenv("DRAGONFLY_ADDR", "dragonfly:6379")
+	t.Setenv("DRAGONFLY_PASSWORD", "hunter2")
+
+	v := viper.New()
+	if err := bindStorageEnv(v); err != nil {
+		t.Fatalf("bindStorageEnv() error = %v", err)
+	}
+	var got Config
+	if err := v.Unmarshal(&got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	wantMinIO := MinIOConfig{
+		Endpoint:        "minio:9000",
+		SecretAccessKey: "secret",
+		Buckets:         []string{"public", "private"},
+		UseSSL:          true,
+	}
+	if !reflect.DeepEqual(got.MinIO, wantMinIO) {
+		t.Errorf("MinIO = %+v, want %+v", got.MinIO, wantMinIO)
+	}
+	wantDragonfly := DragonflyConfig{Addr: "dragonfly:6379", Password: "hunter2"}
+	if !reflect.DeepEqual(got.Dragonfly, wantDragonfly) {
+		t.Errorf("Dragonfly = %+v, want %+v", got.Dragonfly, wantDragonfly)
+	}
+}
+
+// sampleConfig sets every config key, a key whose name doesn't match its mapstructure tag leaves the field empty
+const sampleConfig = `
+addr: "0.0.0.0:8080"
+certFile: "/etc/fawa/cert.pem"
+keyFile: "/etc/fawa/key.pem"
+logLevel: "debug"
+shutdownTimeout: 20s
+devMode: true
+cors:
+  allowedOrigins: ["https://fawa.example.com"]
+  allowedMethods: ["GET", "POST"]
+  allowedHeaders: ["Content-Type"]
+http:
+  readHeaderTimeout: 5s
+  readTimeout: 1m
+  writeTimeout: 2m
+  idleTimeout: 90s
+publicEndpoint: "https://files.example.com/minio"
+chunkSize: 131072
+maxConcurrentDownloads: 50
+encryptionKey: "c2VjcmV0"
+thumbnailSize: 128
+maxDownloadURLExpiry: 24h
+maxLinkTTL: 48h
+orphanGCInterval: 30m
+trustedProxies: ["10.0.0.0/8"]
+adminToken: "admin"
+fileTypes:
+  allowedExtensions: [".png"]
+  blockedExtensions: [".exe"]
+  allowedMIMETypes: ["image/*"]
+  blockedMIMETypes: ["image/svg+xml"]
+quota:
+  default: 1073741824
+  tiers:
+    - limit: 10737418240
+      users: ["alice"]
+      apiKeyHashes: ["2c70e12b7a0646f92279f427c7b38e7334d8e5389cff167a1dc30e73f826b683"]
+scan:
+  clamdAddr: "clamav:3310"
+  mode: async
+  timeout: 30s
+auth:
+  apiKeys: ["key"]
+  apiKeyHashes: ["2c70e12b7a0646f92279f427c7b38e7334d8e5389cff167a1dc30e73f826b683"]
+  publicProcedures: ["/file.v1.FileService/ReceiveFile"]
+  jwtPublicKeyFile: "/etc/fawa/jwt.pem"
+rateLimit:
+  upload:
+    rps: 2
+    burst: 4
+  download:
+    rps: 20
+    burst: 40
+  maxClients: 5000
+minio:
+  endpoint: "minio:9000"
+  accessKeyID: "access"
+  secretAccessKey: "secret"
+  bucketName: "fawa"
+  buckets: ["fawa-public"]
+  useSSL: true
+  opTimeout: 15s
+dragonfly:
+  addr: "dragonfly:6379"
+  db: 1
+  password: "hunter2"
+  tls: true
+  clusterAddrs: ["node1:6379", "node2:6379"]
+  maxRetries: 5
+  minRetryBackoff: 10ms
+  maxRetryBackoff: 1s
+`
+
+func TestSampleConfigSetsEveryField(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(sampleConfig)); err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	var got Config
+	if err := v.Unmarshal(&got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if got.KeyFile != "/etc/fawa/key.pem" {
+		t.Errorf("KeyFile = %q, want the keyFile of the sample", got.KeyFile)
+	}
+	// A new field must be added to the sample, and a mistyped tag leaves its field empty
+	for _, name := range zeroFields(reflect.ValueOf(got), "Config") {
+		t.Errorf("%s is not set by the sample config, check its mapstructure tag", name)
+	}
+}
+
+// zeroFields returns the paths of the zero leaf fields of a struct, and of the structs it lists
+func zeroFields(v reflect.Value, path string) []string {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct && v.Len() > 0 {
+		var names []string
+		for i := 0; i < v.Len(); i++ {
+			names = append(names, zeroFields(v.Index(i), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return names
+	}
+	if v.Kind() != reflect.Struct {
+		if v.IsZero() {
+			return []string{path}
+		}
+		return nil
+	}
+	var names []string
+	for i := 0; i < v.NumField(); i++ {
+		names = append(names, zeroFields(v.Field(i), path+"."+v.Type().Field(i).Name)...)
+	}
+	return names
+}
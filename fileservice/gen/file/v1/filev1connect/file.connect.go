@@ -49,26 +49,49 @@ const (
 const (
 	// FileServiceSendFileProcedure is the fully-qualified name of the FileService's SendFile RPC.
 	FileServiceSendFileProcedure = "/file.v1.FileService/SendFile"
+	// FileServiceSendFilesProcedure is the fully-qualified name of the FileService's SendFiles RPC.
+	FileServiceSendFilesProcedure = "/file.v1.FileService/SendFiles"
 	// FileServiceReceiveFileProcedure is the fully-qualified name of the FileService's ReceiveFile RPC.
 	FileServiceReceiveFileProcedure = "/file.v1.FileService/ReceiveFile"
 	// FileServiceGetDownloadURLProcedure is the fully-qualified name of the FileService's
 	// GetDownloadURL RPC.
 	FileServiceGetDownloadURLProcedure = "/file.v1.FileService/GetDownloadURL"
+	// FileServiceGetFileInfoProcedure is the fully-qualified name of the FileService's GetFileInfo RPC.
+	FileServiceGetFileInfoProcedure = "/file.v1.FileService/GetFileInfo"
+	// FileServiceGetThumbnailURLProcedure is the fully-qualified name of the FileService's
+	// GetThumbnailURL RPC.
+	FileServiceGetThumbnailURLProcedure = "/file.v1.FileService/GetThumbnailURL"
+	// FileServiceExtendLinkProcedure is the fully-qualified name of the FileService's ExtendLink RPC.
+	FileServiceExtendLinkProcedure = "/file.v1.FileService/ExtendLink"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
 var (
-	fileServiceServiceDescriptor              = v1.File_file_v1_file_proto.Services().ByName("FileService")
-	fileServiceSendFileMethodDescriptor       = fileServiceServiceDescriptor.Methods().ByName("SendFile")
-	fileServiceReceiveFileMethodDescriptor    = fileServiceServiceDescriptor.Methods().ByName("ReceiveFile")
-	fileServiceGetDownloadURLMethodDescriptor = fileServiceServiceDescriptor.Methods().ByName("GetDownloadURL")
+	fileServiceServiceDescriptor               = v1.File_file_v1_file_proto.Services().ByName("FileService")
+	fileServiceSendFileMethodDescriptor        = fileServiceServiceDescriptor.Methods().ByName("SendFile")
+	fileServiceSendFilesMethodDescriptor       = fileServiceServiceDescriptor.Methods().ByName("SendFiles")
+	fileServiceReceiveFileMethodDescriptor     = fileServiceServiceDescriptor.Methods().ByName("ReceiveFile")
+	fileServiceGetDownloadURLMethodDescriptor  = fileServiceServiceDescriptor.Methods().ByName("GetDownloadURL")
+	fileServiceGetFileInfoMethodDescriptor     = fileServiceServiceDescriptor.Methods().ByName("GetFileInfo")
+	fileServiceGetThumbnailURLMethodDescriptor = fileServiceServiceDescriptor.Methods().ByName("GetThumbnailURL")
+	fileServiceExtendLinkMethodDescriptor      = fileServiceServiceDescriptor.Methods().ByName("ExtendLink")
 )
 
 // FileServiceClient is a client for the file.v1.FileService service.
 type FileServiceClient interface {
 	SendFile(context.Context) *connect.ClientStreamForClient[v1.SendFileRequest, v1.SendFileResponse]
+	// SendFiles uploads several files in one stream. Each file is framed by an info message,
+	// its chunks and an end message. A failing file doesn't stop the others, the response
+	// reports the outcome of each file.
+	SendFiles(context.Context) *connect.ClientStreamForClient[v1.SendFilesRequest, v1.SendFilesResponse]
 	ReceiveFile(context.Context, *connect.Request[v1.ReceiveFileRequest]) (*connect.ServerStreamForClient[v1.ReceiveFileResponse], error)
 	GetDownloadURL(context.Context, *connect.Request[v1.GetDownloadURLRequest]) (*connect.Response[v1.GetDownloadURLResponse], error)
+	// GetFileInfo returns the metadata of a shared file without downloading it.
+	GetFileInfo(context.Context, *connect.Request[v1.GetFileInfoRequest]) (*connect.Response[v1.GetFileInfoResponse], error)
+	// GetThumbnailURL returns a presigned URL of the thumbnail generated for an uploaded image.
+	GetThumbnailURL(context.Context, *connect.Request[v1.GetThumbnailURLRequest]) (*connect.Response[v1.GetThumbnailURLResponse], error)
+	// ExtendLink resets how long a shared file stays downloadable, counted from now.
+	ExtendLink(context.Context, *connect.Request[v1.ExtendLinkRequest]) (*connect.Response[v1.ExtendLinkResponse], error)
 }
 
 // NewFileServiceClient constructs a client for the file.v1.FileService service. By default, it uses
@@ -87,6 +110,12 @@ func NewFileServiceClient(httpClient connect.HTTPClient, baseURL string, opts ..
 			connect.WithSchema(fileServiceSendFileMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		sendFiles: connect.NewClient[v1.SendFilesRequest, v1.SendFilesResponse](
+			httpClient,
+			baseURL+FileServiceSendFilesProcedure,
+			connect.WithSchema(fileServiceSendFilesMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 		receiveFile: connect.NewClient[v1.ReceiveFileRequest, v1.ReceiveFileResponse](
 			httpClient,
 			baseURL+FileServiceReceiveFileProcedure,
@@ -99,14 +128,36 @@ func NewFileServiceClient(httpClient connect.HTTPClient, baseURL string, opts ..
 			connect.WithSchema(fileServiceGetDownloadURLMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		getFileInfo: connect.NewClient[v1.GetFileInfoRequest, v1.GetFileInfoResponse](
+			httpClient,
+			baseURL+FileServiceGetFileInfoProcedure,
+			connect.WithSchema(fileServiceGetFileInfoMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		getThumbnailURL: connect.NewClient[v1.GetThumbnailURLRequest, v1.GetThumbnailURLResponse](
+			httpClient,
+			baseURL+FileServiceGetThumbnailURLProcedure,
+			connect.WithSchema(fileServiceGetThumbnailURLMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		extendLink: connect.NewClient[v1.ExtendLinkRequest, v1.ExtendLinkResponse](
+			httpClient,
+			baseURL+FileServiceExtendLinkProcedure,
+			connect.WithSchema(fileServiceExtendLinkMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // fileServiceClient implements FileServiceClient.
 type fileServiceClient struct {
-	sendFile       *connect.Client[v1.SendFileRequest, v1.SendFileResponse]
-	receiveFile    *connect.Client[v1.ReceiveFileRequest, v1.ReceiveFileResponse]
-	getDownloadURL *connect.Client[v1.GetDownloadURLRequest, v1.GetDownloadURLResponse]
+	sendFile        *connect.Client[v1.SendFileRequest, v1.SendFileResponse]
+	sendFiles       *connect.Client[v1.SendFilesRequest, v1.SendFilesResponse]
+	receiveFile     *connect.Client[v1.ReceiveFileRequest, v1.ReceiveFileResponse]
+	getDownloadURL  *connect.Client[v1.GetDownloadURLRequest, v1.GetDownloadURLResponse]
+	getFileInfo     *connect.Client[v1.GetFileInfoRequest, v1.GetFileInfoResponse]
+	getThumbnailURL *connect.Client[v1.GetThumbnailURLRequest, v1.GetThumbnailURLResponse]
+	extendLink      *connect.Client[v1.ExtendLinkRequest, v1.ExtendLinkResponse]
 }
 
 // SendFile calls file.v1.FileService.SendFile.
@@ -114,6 +165,11 @@ func (c *fileServiceClient) SendFile(ctx context.Context) *connect.ClientStreamF
 	return c.sendFile.CallClientStream(ctx)
 }
 
+// SendFiles calls file.v1.FileService.SendFiles.
+func (c *fileServiceClient) SendFiles(ctx context.Context) *connect.ClientStreamForClient[v1.SendFilesRequest, v1.SendFilesResponse] {
+	return c.sendFiles.CallClientStream(ctx)
+}
+
 // ReceiveFile calls file.v1.FileService.ReceiveFile.
 func (c *fileServiceClient) ReceiveFile(ctx context.Context, req *connect.Request[v1.ReceiveFileRequest]) (*connect.ServerStreamForClient[v1.ReceiveFileResponse], error) {
 	return c.receiveFile.CallServerStream(ctx, req)
@@ -124,11 +180,36 @@ func (c *fileServiceClient) GetDownloadURL(ctx context.Context, req *connect.Req
 	return c.getDownloadURL.CallUnary(ctx, req)
 }
 
+// GetFileInfo calls file.v1.FileService.GetFileInfo.
+func (c *fileServiceClient) GetFileInfo(ctx context.Context, req *connect.Request[v1.GetFileInfoRequest]) (*connect.Response[v1.GetFileInfoResponse], error) {
+	return c.getFileInfo.CallUnary(ctx, req)
+}
+
+// GetThumbnailURL calls file.v1.FileService.GetThumbnailURL.
+func (c *fileServiceClient) GetThumbnailURL(ctx context.Context, req *connect.Request[v1.GetThumbnailURLRequest]) (*connect.Response[v1.GetThumbnailURLResponse], error) {
+	return c.getThumbnailURL.CallUnary(ctx, req)
+}
+
+// ExtendLink calls file.v1.FileService.ExtendLink.
+func (c *fileServiceClient) ExtendLink(ctx context.Context, req *connect.Request[v1.ExtendLinkRequest]) (*connect.Response[v1.ExtendLinkResponse], error) {
+	return c.extendLink.CallUnary(ctx, req)
+}
+
 // FileServiceHandler is an implementation of the file.v1.FileService service.
 type FileServiceHandler interface {
 	SendFile(context.Context, *connect.ClientStream[v1.SendFileRequest]) (*connect.Response[v1.SendFileResponse], error)
+	// SendFiles uploads several files in one stream. Each file is framed by an info message,
+	// its chunks and an end message. A failing file doesn't stop the others, the response
+	// reports the outcome of each file.
+	SendFiles(context.Context, *connect.ClientStream[v1.SendFilesRequest]) (*connect.Response[v1.SendFilesResponse], error)
 	ReceiveFile(context.Context, *connect.Request[v1.ReceiveFileRequest], *connect.ServerStream[v1.ReceiveFileResponse]) error
 	GetDownloadURL(context.Context, *connect.Request[v1.GetDownloadURLRequest]) (*connect.Response[v1.GetDownloadURLResponse], error)
+	// GetFileInfo returns the metadata of a shared file without downloading it.
+	GetFileInfo(context.Context, *connect.Request[v1.GetFileInfoRequest]) (*connect.Response[v1.GetFileInfoResponse], error)
+	// GetThumbnailURL returns a presigned URL of the thumbnail generated for an uploaded image.
+	GetThumbnailURL(context.Context, *connect.Request[v1.GetThumbnailURLRequest]) (*connect.Response[v1.GetThumbnailURLResponse], error)
+	// ExtendLink resets how long a shared file stays downloadable, counted from now.
+	ExtendLink(context.Context, *connect.Request[v1.ExtendLinkRequest]) (*connect.Response[v1.ExtendLinkResponse], error)
 }
 
 // NewFileServiceHandler builds an HTTP handler from the service implementation. It returns the path
@@ -143,6 +224,12 @@ func NewFileServiceHandler(svc FileServiceHandler, opts ...connect.HandlerOption
 		connect.WithSchema(fileServiceSendFileMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	fileServiceSendFilesHandler := connect.NewClientStreamHandler(
+		FileServiceSendFilesProcedure,
+		svc.SendFiles,
+		connect.WithSchema(fileServiceSendFilesMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	fileServiceReceiveFileHandler := connect.NewServerStreamHandler(
 		FileServiceReceiveFileProcedure,
 		svc.ReceiveFile,
@@ -155,14 +242,40 @@ func NewFileServiceHandler(svc FileServiceHandler, opts ...connect.HandlerOption
 		connect.WithSchema(fileServiceGetDownloadURLMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	fileServiceGetFileInfoHandler := connect.NewUnaryHandler(
+		FileServiceGetFileInfoProcedure,
+		svc.GetFileInfo,
+		connect.WithSchema(fileServiceGetFileInfoMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	fileServiceGetThumbnailURLHandler := connect.NewUnaryHandler(
+		FileServiceGetThumbnailURLProcedure,
+		svc.GetThumbnailURL,
+		connect.WithSchema(fileServiceGetThumbnailURLMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	fileServiceExtendLinkHandler := connect.NewUnaryHandler(
+		FileServiceExtendLinkProcedure,
+		svc.ExtendLink,
+		connect.WithSchema(fileServiceExtendLinkMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/file.v1.FileService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case FileServiceSendFileProcedure:
 			fileServiceSendFileHandler.ServeHTTP(w, r)
+		case FileServiceSendFilesProcedure:
+			fileServiceSendFilesHandler.ServeHTTP(w, r)
 		case FileServiceReceiveFileProcedure:
 			fileServiceReceiveFileHandler.ServeHTTP(w, r)
 		case FileServiceGetDownloadURLProcedure:
 			fileServiceGetDownloadURLHandler.ServeHTTP(w, r)
+		case FileServiceGetFileInfoProcedure:
+			fileServiceGetFileInfoHandler.ServeHTTP(w, r)
+		case FileServiceGetThumbnailURLProcedure:
+			fileServiceGetThumbnailURLHandler.ServeHTTP(w, r)
+		case FileServiceExtendLinkProcedure:
+			fileServiceExtendLinkHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -176,6 +289,10 @@ func (UnimplementedFileServiceHandler) SendFile(context.Context, *connect.Client
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("file.v1.FileService.SendFile is not implemented"))
 }
 
+func (UnimplementedFileServiceHandler) SendFiles(context.Context, *connect.ClientStream[v1.SendFilesRequest]) (*connect.Response[v1.SendFilesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("file.v1.FileService.SendFiles is not implemented"))
+}
+
 func (UnimplementedFileServiceHandler) ReceiveFile(context.Context, *connect.Request[v1.ReceiveFileRequest], *connect.ServerStream[v1.ReceiveFileResponse]) error {
 	return connect.NewError(connect.CodeUnimplemented, errors.New("file.v1.FileService.ReceiveFile is not implemented"))
 }
@@ -183,3 +300,15 @@ func (UnimplementedFileServiceHandler) ReceiveFile(context.Context, *connect.Req
 func (UnimplementedFileServiceHandler) GetDownloadURL(context.Context, *connect.Request[v1.GetDownloadURLRequest]) (*connect.Response[v1.GetDownloadURLResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("file.v1.FileService.GetDownloadURL is not implemented"))
 }
+
+func (UnimplementedFileServiceHandler) GetFileInfo(context.Context, *connect.Request[v1.GetFileInfoRequest]) (*connect.Response[v1.GetFileInfoResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("file.v1.FileService.GetFileInfo is not implemented"))
+}
+
+func (UnimplementedFileServiceHandler) GetThumbnailURL(context.Context, *connect.Request[v1.GetThumbnailURLRequest]) (*connect.Response[v1.GetThumbnailURLResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("file.v1.FileService.GetThumbnailURL is not implemented"))
+}
+
+func (UnimplementedFileServiceHandler) ExtendLink(context.Context, *connect.Request[v1.ExtendLinkRequest]) (*connect.Response[v1.ExtendLinkResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("file.v1.FileService.ExtendLink is not implemented"))
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	"github.com/fawa-io/fawa/fileservice/pkg/auth"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+const (
+	// defaultListObjectsLimit is the ListObjects page size when the request doesn't set one
+	defaultListObjectsLimit = 100
+	// maxListObjectsLimit bounds the ListObjects page size
+	maxListObjectsLimit = 1000
+)
+
+// listObjects is storage.ListObjects, replaced by the tests
+var listObjects = storage.ListObjects
+
+// AdminHandler serves the operator endpoints. They require Token as a bearer token and
+// answer 404 when none is configured.
+type AdminHandler struct {
+	Token string
+}
+
+// authorize checks the request carries the Token and writes the error response otherwise
+func (a *AdminHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if a.Token == "" {
+		http.NotFound(w, r)
+		return false
+	}
+	token, ok := auth.BearerToken(r.Header)
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// listObjectsResponse is a page of ListObjects, Next is the after parameter of the next page
+// and is empty on the last one
+type listObjectsResponse struct {
+	Objects []storage.ObjectInfo `json:"objects"`
+	Next    string               `json:"next,omitempty"`
+}
+
+// ListObjects returns a page of the objects stored in MinIO, so they can be reconciled with the
+// metadata in Dragonfly. The query selects the bucket (the primary one by default), a name
+// prefix, the after name the page starts after and the limit of objects returned.
+func (a *AdminHandler) ListObjects(w http.ResponseWriter, r *http.Request) {
+	if !a.authorize(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	bucket := query.Get("bucket")
+	if err := storage.ValidateBucket(bucket); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := defaultListObjectsLimit
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxListObjectsLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxListObjectsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	objects, more, err := listObjects(r.Context(), bucket, query.Get("prefix"), query.Get("after"), limit)
+	if err != nil {
+		fwlog.Errorf("Failed to list the objects of bucket %q: %v", bucket, err)
+		http.Error(w, "Failed to list objects", http.StatusBadGateway)
+		return
+	}
+	resp := listObjectsResponse{Objects: objects}
+	if resp.Objects == nil {
+		resp.Objects = []storage.ObjectInfo{}
+	}
+	if more {
+		resp.Next = objects[len(objects)-1].Name
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		fwlog.Warnf("write response failed: %v", err)
+	}
+}
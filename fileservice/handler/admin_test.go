@@ -0,0 +1,106 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+func TestAdminListObjects(t *testing.T) {
+	stored := []storage.ObjectInfo{{Name: "a.txt", Size: 1}, {Name: "b.txt", Size: 2}, {Name: "c.txt", Size: 3}}
+	prev := listObjects
+	listObjects = func(_ context.Context, bucket, prefix, startAfter string, limit int) ([]storage.ObjectInfo, bool, error) {
+		if prefix == "broken" {
+			return nil, false, errors.New("connection refused")
+		}
+		var page []storage.ObjectInfo
+		for _, object := range stored {
+			if object.Name > startAfter {
+				page = append(page, object)
+			}
+		}
+		if len(page) > limit {
+			return page[:limit], true, nil
+		}
+		return page, false, nil
+	}
+	t.Cleanup(func() { listObjects = prev })
+
+	testCases := []struct {
+		name       string
+		token      string
+		auth       string
+		query      string
+		wantStatus int
+		wantNames  []string
+		wantNext   string
+	}{
+		{name: "disabled", auth: "Bearer secret", wantStatus: http.StatusNotFound},
+		{name: "missing token", token: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", auth: "Bearer guess", wantStatus: http.StatusUnauthorized},
+		{
+			name: "first page", token: "secret", auth: "Bearer secret", query: "?limit=2",
+			wantStatus: http.StatusOK, wantNames: []string{"a.txt", "b.txt"}, wantNext: "b.txt",
+		},
+		{
+			name: "last page", token: "secret", auth: "Bearer secret", query: "?limit=2&after=b.txt",
+			wantStatus: http.StatusOK, wantNames: []string{"c.txt"},
+		},
+		{name: "invalid limit", token: "secret", auth: "Bearer secret", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "unknown bucket", token: "secret", auth: "Bearer secret", query: "?bucket=other", wantStatus: http.StatusBadRequest},
+		{name: "storage down", token: "secret", auth: "Bearer secret", query: "?prefix=broken", wantStatus: http.StatusBadGateway},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			admin := &AdminHandler{Token: tc.token}
+			req := httptest.NewRequest(http.MethodGet, "/admin/objects"+tc.query, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			rec := httptest.NewRecorder()
+			admin.ListObjects(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var resp listObjectsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			var names []string
+			for _, object := range resp.Objects {
+				names = append(names, object.Name)
+			}
+			if !slices.Equal(names, tc.wantNames) {
+				t.Errorf("objects = %v, want %v", names, tc.wantNames)
+			}
+			if resp.Next != tc.wantNext {
+				t.Errorf("next = %q, want %q", resp.Next, tc.wantNext)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"io"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/metrics"
+	"github.com/fawa-io/fawa/fileservice/pkg/requestid"
+)
+
+// SendFiles handles the client-streaming RPC uploading several files, each sent as an info
+// message, its chunks and an end message. Every file is stored and shared like a SendFile upload.
+// A file that fails is reported in the response and the batch goes on with the next one, only an
+// out of place message stops it since the following chunks can't be told apart anymore. Every
+// file after the first takes another request from the upload rate limit, the batch stops at the
+// first file over it.
+func (s *FileServiceHandler) SendFiles(
+	ctx context.Context,
+	stream *connect.ClientStream[filev1.SendFilesRequest],
+) (*connect.Response[filev1.SendFilesResponse], error) {
+	metrics.FileUploadsInFlight.Add(1)
+	defer metrics.FileUploadsInFlight.Add(-1)
+
+	log := requestid.Logger(ctx)
+	log.Infof("SendFiles request started from %s", s.clientIP(stream.Peer(), stream.RequestHeader()))
+
+	res := &filev1.SendFilesResponse{}
+	for stream.Receive() {
+		info := stream.Msg().GetInfo()
+		if info == nil {
+			res.Results = append(res.Results, failedResult("", apierr.InvalidArgument("each file must start with a file info message")))
+			return connect.NewResponse(res), nil
+		}
+		// A batch costs as much as uploading its files one by one
+		if len(res.Results) > 0 && s.RateLimiter != nil {
+			if err := s.RateLimiter.Allow(filev1connect.FileServiceSendFilesProcedure, stream.Peer(), stream.RequestHeader()); err != nil {
+				log.Warnf("Batch stopped at %s: %v", info.GetName(), err)
+				res.Results = append(res.Results, failedResult(info.GetName(), err))
+				return connect.NewResponse(res), nil
+			}
+		}
+
+		file := &batchFile{stream: stream}
+		downloadKey, err := s.upload(ctx, info, file.copyTo)
+		code := "ok"
+		if err != nil {
+			code = connect.CodeOf(err).String()
+		}
+		metrics.FileUploads.Inc(code)
+		if err == nil {
+			res.Results = append(res.Results, &filev1.SendFilesResult{Name: info.GetName(), Success: true, Randomkey: downloadKey})
+			continue
+		}
+
+		// Nothing can be reported to a client that's gone
+		if err := stream.Err(); err != nil {
+			return nil, apierr.From(err)
+		}
+		log.Warnf("Upload of %s in a batch failed: %v", info.GetName(), err)
+		res.Results = append(res.Results, failedResult(info.GetName(), err))
+		if !file.skip() {
+			break
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, apierr.From(err)
+	}
+	log.Infof("SendFiles request finished with %d files", len(res.Results))
+	return connect.NewResponse(res), nil
+}
+
+func failedResult(name string, err error) *filev1.SendFilesResult {
+	return &filev1.SendFilesResult{Name: name, Error: err.Error(), Reason: apierr.ReasonOf(err)}
+}
+
+// batchFile reads the chunks of one file of a SendFiles stream
+type batchFile struct {
+	stream *connect.ClientStream[filev1.SendFilesRequest]
+	// ended is set once the end message of the file was received
+	ended bool
+	// desynced is set when a message that can't be part of the file was received
+	desynced bool
+}
+
+// copyTo writes the chunks of the file to w, up to its end message
+func (f *batchFile) copyTo(w io.Writer) error {
+	for f.stream.Receive() {
+		switch payload := f.stream.Msg().GetPayload().(type) {
+		case *filev1.SendFilesRequest_ChunkData:
+			metrics.FileUploadBytes.Add(float64(len(payload.ChunkData)))
+			if _, err := w.Write(payload.ChunkData); err != nil {
+				return err
+			}
+		case *filev1.SendFilesRequest_End:
+			f.ended = true
+			return nil
+		default:
+			f.desynced = true
+			return apierr.InvalidArgument("a file must be closed by an end message before the next one starts")
+		}
+	}
+	if err := f.stream.Err(); err != nil {
+		return err
+	}
+	return apierr.InvalidArgument("the stream ended before the end message of the file")
+}
+
+// skip discards the rest of a file that failed, it reports whether the stream may have more files
+func (f *batchFile) skip() bool {
+	if f.desynced {
+		return false
+	}
+	for !f.ended {
+		if !f.stream.Receive() {
+			return false
+		}
+		switch f.stream.Msg().GetPayload().(type) {
+		case *filev1.SendFilesRequest_End:
+			f.ended = true
+		case *filev1.SendFilesRequest_Info:
+			return false
+		}
+	}
+	return true
+}
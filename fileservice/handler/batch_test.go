@@ -0,0 +1,250 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+	"github.com/fawa-io/fawa/fileservice/pkg/ratelimit"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+func batchInfo(name string, encrypt bool) *filev1.SendFilesRequest {
+	return &filev1.SendFilesRequest{Payload: &filev1.SendFilesRequest_Info{Info: &filev1.FileInfo{Name: name, Size: 4, Encrypt: encrypt}}}
+}
+
+func batchChunk(data string) *filev1.SendFilesRequest {
+	return &filev1.SendFilesRequest{Payload: &filev1.SendFilesRequest_ChunkData{ChunkData: []byte(data)}}
+}
+
+var batchEnd = &filev1.SendFilesRequest{Payload: &filev1.SendFilesRequest_End{End: &filev1.FileEnd{}}}
+
+// The files of these batches all fail before reaching the object store
+func TestSendFilesReportsEachFile(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(&FileServiceHandler{}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+
+	tests := []struct {
+		name     string
+		messages []*filev1.SendFilesRequest
+		want     []*filev1.SendFilesResult
+	}{
+		{
+			name: "failures don't stop the batch",
+			messages: []*filev1.SendFilesRequest{
+				batchInfo("../escape.txt", false), batchChunk("da"), batchChunk("ta"), batchEnd,
+				batchInfo("secret.txt", true), batchChunk("data"), batchEnd,
+			},
+			want: []*filev1.SendFilesResult{
+				{Name: "../escape.txt"},
+				{Name: "secret.txt", Reason: filev1.ErrorReason_ERROR_REASON_ENCRYPTION_DISABLED},
+			},
+		},
+		{
+			name:     "chunk before any info",
+			messages: []*filev1.SendFilesRequest{batchChunk("data"), batchInfo("a.txt", true), batchEnd},
+			want:     []*filev1.SendFilesResult{{}},
+		},
+		{
+			name: "missing end message",
+			messages: []*filev1.SendFilesRequest{
+				batchInfo("secret.txt", true), batchChunk("data"),
+				batchInfo("other.txt", true), batchChunk("data"), batchEnd,
+			},
+			want: []*filev1.SendFilesResult{{Name: "secret.txt", Reason: filev1.ErrorReason_ERROR_REASON_ENCRYPTION_DISABLED}},
+		},
+		{name: "empty batch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := client.SendFiles(context.Background())
+			for _, msg := range tt.messages {
+				// The server stops reading a batch it gave up on, CloseAndReceive has its response
+				if err := stream.Send(msg); err != nil {
+					break
+				}
+			}
+			resp, err := stream.CloseAndReceive()
+			if err != nil {
+				t.Fatalf("SendFiles() error = %v", err)
+			}
+			results := resp.Msg.GetResults()
+			if len(results) != len(tt.want) {
+				t.Fatalf("SendFiles() returned %d results, want %d: %v", len(results), len(tt.want), results)
+			}
+			for i, got := range results {
+				want := tt.want[i]
+				if got.GetName() != want.GetName() || got.GetSuccess() || got.GetRandomkey() != "" || got.GetError() == "" {
+					t.Errorf("result %d = %v, want a failure of %q", i, got, want.GetName())
+				}
+				if got.GetReason() != want.GetReason() {
+					t.Errorf("result %d reason = %v, want %v", i, got.GetReason(), want.GetReason())
+				}
+			}
+		})
+	}
+}
+
+// The stream takes the first request of the budget, each further file another one
+func TestSendFilesRateLimitsEachFile(t *testing.T) {
+	limiter := ratelimit.NewInterceptor(map[string]*ratelimit.Limiter{
+		filev1connect.FileServiceSendFilesProcedure: ratelimit.NewLimiter(ratelimit.Limit{RPS: 0.001, Burst: 2}, 0),
+	})
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(&FileServiceHandler{RateLimiter: limiter}, connect.WithInterceptors(limiter)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+
+	stream := client.SendFiles(context.Background())
+	for _, msg := range []*filev1.SendFilesRequest{
+		batchInfo("a.txt", true), batchChunk("data"), batchEnd,
+		batchInfo("b.txt", true), batchChunk("data"), batchEnd,
+		batchInfo("c.txt", true), batchChunk("data"), batchEnd,
+		batchInfo("d.txt", true), batchChunk("data"), batchEnd,
+	} {
+		if err := stream.Send(msg); err != nil {
+			break
+		}
+	}
+	resp, err := stream.CloseAndReceive()
+	if err != nil {
+		t.Fatalf("SendFiles() error = %v", err)
+	}
+	results := resp.Msg.GetResults()
+	if len(results) != 3 {
+		t.Fatalf("SendFiles() returned %d results, want 3: %v", len(results), results)
+	}
+	if got := results[2]; got.GetName() != "c.txt" || got.GetReason() != filev1.ErrorReason_ERROR_REASON_RATE_LIMITED {
+		t.Errorf("third result = %v, want c.txt rate limited", got)
+	}
+}
+
+func TestSendFilesStoresEachFile(t *testing.T) {
+	t.Setenv("FAWA_STORAGE_TEST_MODE", "")
+	bucket := &fakeBucket{objects: make(map[string][]byte)}
+	s3 := httptest.NewServer(bucket)
+	defer s3.Close()
+	err := storage.InitMinIO(storage.MinIOConfig{
+		Endpoint:        strings.TrimPrefix(s3.URL, "http://"),
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		BucketName:      "uploads",
+	})
+	if err != nil {
+		t.Fatalf("InitMinIO() error = %v", err)
+	}
+
+	// The download keys are random, so the commands are recorded instead of matched
+	client, mock := redismock.NewClientMock()
+	defer storage.SetDragonflyClient(client)()
+	saved := make(map[string]storage.FileMetadata)
+	record := mock.CustomMatch(func(_, actual []interface{}) error {
+		if actual[0] != "set" {
+			return nil
+		}
+		var metadata storage.FileMetadata
+		if err := json.Unmarshal(actual[2].([]byte), &metadata); err != nil {
+			return err
+		}
+		saved[actual[1].(string)] = metadata
+		return nil
+	})
+	for range 2 {
+		record.ExpectTxPipeline()
+		record.ExpectSetArgs("key", "metadata", redis.SetArgs{ExpireAt: time.Unix(1, 0)}).SetVal("OK")
+		record.ExpectSetNX("ref", "key", 0).SetVal(true)
+		record.ExpectDo("expireat", "ref", int64(1), "nx").SetVal(int64(1))
+		record.ExpectDo("expireat", "ref", int64(1), "gt").SetVal(int64(0))
+		record.ExpectTxPipelineExec()
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(&FileServiceHandler{}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	stream := filev1connect.NewFileServiceClient(srv.Client(), srv.URL).SendFiles(context.Background())
+	for _, msg := range []*filev1.SendFilesRequest{
+		batchInfo("a.txt", false), batchChunk("aaaa"), batchEnd,
+		batchInfo("../escape.txt", false), batchChunk("data"), batchEnd,
+		batchInfo("b.txt", false), batchChunk("bb"), batchChunk("bb"), batchEnd,
+	} {
+		if err := stream.Send(msg); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	resp, err := stream.CloseAndReceive()
+	if err != nil {
+		t.Fatalf("SendFiles() error = %v", err)
+	}
+
+	results := resp.Msg.GetResults()
+	want := []struct {
+		name    string
+		success bool
+	}{
+		{name: "a.txt", success: true},
+		{name: "../escape.txt"},
+		{name: "b.txt", success: true},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("SendFiles() returned %d results, want %d: %v", len(results), len(want), results)
+	}
+	for i, got := range results {
+		if got.GetName() != want[i].name || got.GetSuccess() != want[i].success {
+			t.Errorf("result %d = %v, want %q with success %v", i, got, want[i].name, want[i].success)
+			continue
+		}
+		if !got.GetSuccess() {
+			if got.GetRandomkey() != "" || got.GetError() == "" {
+				t.Errorf("result %d = %v, want an error and no key", i, got)
+			}
+			continue
+		}
+		key := got.GetRandomkey()
+		metadata, ok := saved[key]
+		if !ok {
+			t.Errorf("no metadata saved for %s under its key %q", got.GetName(), key)
+			continue
+		}
+		if metadata.Filename != want[i].name || metadata.StoragePath != key+"/"+want[i].name {
+			t.Errorf("metadata of %s = %+v", got.GetName(), metadata)
+		}
+		if _, ok := bucket.objects[metadata.StoragePath]; !ok {
+			t.Errorf("object %s of %s was not stored", metadata.StoragePath, got.GetName())
+		}
+	}
+	if results[0].GetRandomkey() == results[2].GetRandomkey() {
+		t.Errorf("both files got the key %q", results[0].GetRandomkey())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+	"github.com/klauspost/compress/zstd"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+)
+
+// compressionName names c for FileMetadata, unknown algorithms fall back to none
+func compressionName(c filev1.Compression) string {
+	switch c {
+	case filev1.Compression_COMPRESSION_GZIP:
+		return "gzip"
+	case filev1.Compression_COMPRESSION_ZSTD:
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+// knownCompression maps algorithms this server doesn't know to none
+func knownCompression(c filev1.Compression) filev1.Compression {
+	if _, ok := filev1.Compression_name[int32(c)]; !ok {
+		fwlog.Warnf("Unknown compression %d, treating the stream as uncompressed", c)
+		return filev1.Compression_COMPRESSION_NONE
+	}
+	return c
+}
+
+// newDecompressor returns a reader of the uncompressed content of r.
+// A malformed stream reads as an error wrapping apierr.ErrInvalidArgument.
+func newDecompressor(c filev1.Compression, r io.Reader) (io.ReadCloser, error) {
+	switch knownCompression(c) {
+	case filev1.Compression_COMPRESSION_GZIP:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, invalidStream(c, err)
+		}
+		return &decompressor{r: zr, close: zr.Close, c: c}, nil
+	case filev1.Compression_COMPRESSION_ZSTD:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, invalidStream(c, err)
+		}
+		return &decompressor{r: zr, close: func() error { zr.Close(); return nil }, c: c}, nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+// newCompressor returns a writer compressing into w, Close flushes the compressed stream
+func newCompressor(c filev1.Compression, w io.Writer) (io.WriteCloser, error) {
+	switch knownCompression(c) {
+	case filev1.Compression_COMPRESSION_GZIP:
+		return gzip.NewWriter(w), nil
+	case filev1.Compression_COMPRESSION_ZSTD:
+		return zstd.NewWriter(w)
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+// sendChunks compresses r with c and passes the result to send in chunks of at most chunkSize bytes.
+// The chunk passed to send is only valid until send returns.
+func sendChunks(r io.Reader, c filev1.Compression, chunkSize int, send func(chunk []byte) error) error {
+	bw := bufio.NewWriterSize(chunkSender{send: send, size: chunkSize}, chunkSize)
+	cw, err := newCompressor(c, bw)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(cw, r); err != nil {
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// chunkSender splits the writes bufio passes through unbuffered into chunks
+type chunkSender struct {
+	send func([]byte) error
+	size int
+}
+
+func (s chunkSender) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), s.size)
+		if err := s.send(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+type decompressor struct {
+	r     io.Reader
+	close func() error
+	c     filev1.Compression
+}
+
+func (d *decompressor) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = invalidStream(d.c, err)
+	}
+	return n, err
+}
+
+func (d *decompressor) Close() error {
+	return d.close()
+}
+
+func invalidStream(c filev1.Compression, err error) error {
+	return fmt.Errorf("invalid %s stream: %w: %w", compressionName(c), apierr.ErrInvalidArgument, err)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
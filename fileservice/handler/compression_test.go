@@ -0,0 +1,118 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	content := []byte(strings.Repeat("fawa moves files around. ", 4096))
+	const chunkSize = MinChunkSize
+
+	testCases := []struct {
+		name        string
+		compression filev1.Compression
+	}{
+		{name: "none", compression: filev1.Compression_COMPRESSION_NONE},
+		{name: "gzip", compression: filev1.Compression_COMPRESSION_GZIP},
+		{name: "zstd", compression: filev1.Compression_COMPRESSION_ZSTD},
+		{name: "unknown falls back to none", compression: filev1.Compression(42)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var wire bytes.Buffer
+			err := sendChunks(bytes.NewReader(content), tc.compression, chunkSize, func(chunk []byte) error {
+				if len(chunk) == 0 || len(chunk) > chunkSize {
+					t.Errorf("chunk of %d bytes, want 1..%d", len(chunk), chunkSize)
+				}
+				wire.Write(chunk)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("sendChunks() failed: %v", err)
+			}
+			if compressionName(tc.compression) != "" && wire.Len() >= len(content) {
+				t.Errorf("compressed %d bytes into %d", len(content), wire.Len())
+			}
+
+			r, err := newDecompressor(tc.compression, &wire)
+			if err != nil {
+				t.Fatalf("newDecompressor() failed: %v", err)
+			}
+			defer func() { _ = r.Close() }()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("reading decompressed stream failed: %v", err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("round trip returned %d bytes, want the original %d", len(got), len(content))
+			}
+		})
+	}
+}
+
+func TestDecompressorInvalidStream(t *testing.T) {
+	testCases := []struct {
+		name        string
+		compression filev1.Compression
+	}{
+		{name: "gzip", compression: filev1.Compression_COMPRESSION_GZIP},
+		{name: "zstd", compression: filev1.Compression_COMPRESSION_ZSTD},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := func() error {
+				r, err := newDecompressor(tc.compression, strings.NewReader("definitely not compressed"))
+				if err != nil {
+					return err
+				}
+				defer func() { _ = r.Close() }()
+				_, err = io.ReadAll(r)
+				return err
+			}()
+			if !errors.Is(err, apierr.ErrInvalidArgument) {
+				t.Errorf("got %v, want an error wrapping ErrInvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestCompressionName(t *testing.T) {
+	testCases := []struct {
+		compression filev1.Compression
+		want        string
+	}{
+		{compression: filev1.Compression_COMPRESSION_NONE, want: ""},
+		{compression: filev1.Compression_COMPRESSION_GZIP, want: "gzip"},
+		{compression: filev1.Compression_COMPRESSION_ZSTD, want: "zstd"},
+		{compression: filev1.Compression(42), want: ""},
+	}
+
+	for _, tc := range testCases {
+		if got := compressionName(tc.compression); got != tc.want {
+			t.Errorf("compressionName(%v) = %q, want %q", tc.compression, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+)
+
+// sniffLen is how much of an upload http.DetectContentType looks at
+const sniffLen = 512
+
+// FileTypePolicy restricts the types of the uploaded files by extension and by MIME type.
+// Extensions match case-insensitively with or without their leading dot, MIME types may end
+// with a "/*" wildcard such as "image/*". The blocklists take precedence, an empty allowlist
+// allows every type that isn't blocked.
+type FileTypePolicy struct {
+	AllowedExtensions []string
+	BlockedExtensions []string
+	AllowedMIMETypes  []string
+	BlockedMIMETypes  []string
+}
+
+// checkName rejects a file name whose extension, or the content type it implies, isn't allowed
+func (p *FileTypePolicy) checkName(name, contentType string) error {
+	ext := strings.ToLower(filepath.Ext(name))
+	if matchesExtension(p.BlockedExtensions, ext) ||
+		len(p.AllowedExtensions) > 0 && !matchesExtension(p.AllowedExtensions, ext) {
+		return apierr.WithReason(apierr.InvalidArgument(fmt.Sprintf("files of type %q are not allowed", ext)),
+			filev1.ErrorReason_ERROR_REASON_FILE_TYPE_NOT_ALLOWED, map[string]string{"type": ext})
+	}
+	return p.checkMIMEType(contentType)
+}
+
+// checkMIMEType rejects a content type that isn't allowed
+func (p *FileTypePolicy) checkMIMEType(contentType string) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(contentType)
+	}
+	if matchesMIMEType(p.BlockedMIMETypes, mediaType) ||
+		len(p.AllowedMIMETypes) > 0 && !matchesMIMEType(p.AllowedMIMETypes, mediaType) {
+		return apierr.WithReason(apierr.InvalidArgument(fmt.Sprintf("files of type %s are not allowed", mediaType)),
+			filev1.ErrorReason_ERROR_REASON_FILE_TYPE_NOT_ALLOWED, map[string]string{"type": mediaType})
+	}
+	return nil
+}
+
+// sniffs reports whether the content of an upload has to be looked at
+func (p *FileTypePolicy) sniffs() bool {
+	return len(p.AllowedMIMETypes) > 0 || len(p.BlockedMIMETypes) > 0
+}
+
+// sniff checks the content type detected from the beginning of r, so a renamed executable
+// can't pass for an allowed type. The returned reader still yields all of r.
+func (p *FileTypePolicy) sniff(r io.Reader) (io.Reader, error) {
+	if !p.sniffs() {
+		return r, nil
+	}
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := p.checkMIMEType(http.DetectContentType(head)); err != nil {
+		return nil, err
+	}
+	return br, nil
+}
+
+func matchesExtension(exts []string, ext string) bool {
+	return slices.ContainsFunc(exts, func(e string) bool {
+		return strings.EqualFold("."+strings.TrimPrefix(e, "."), ext)
+	})
+}
+
+func matchesMIMEType(types []string, mediaType string) bool {
+	return slices.ContainsFunc(types, func(t string) bool {
+		t = strings.ToLower(t)
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			return strings.HasPrefix(mediaType, prefix+"/")
+		}
+		return t == mediaType
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+)
+
+func TestFileTypePolicyCheckName(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      FileTypePolicy
+		filename    string
+		contentType string
+		wantErr     bool
+	}{
+		{name: "no policy", filename: "setup.exe", contentType: "application/octet-stream"},
+		{name: "blocked", policy: FileTypePolicy{BlockedExtensions: []string{".exe", "js"}}, filename: "setup.exe", wantErr: true},
+		{name: "blocked without a dot", policy: FileTypePolicy{BlockedExtensions: []string{".exe", "js"}}, filename: "app.JS", wantErr: true},
+		{name: "not blocked", policy: FileTypePolicy{BlockedExtensions: []string{".exe"}}, filename: "photo.png", contentType: "image/png"},
+		{name: "allowed", policy: FileTypePolicy{AllowedExtensions: []string{".png"}}, filename: "photo.PNG", contentType: "image/png"},
+		{name: "not allowed", policy: FileTypePolicy{AllowedExtensions: []string{".png"}}, filename: "notes.txt", wantErr: true},
+		{name: "no extension", policy: FileTypePolicy{AllowedExtensions: []string{".png"}}, filename: "README", wantErr: true},
+		{
+			name:     "blocklist takes precedence",
+			policy:   FileTypePolicy{AllowedExtensions: []string{".js"}, BlockedExtensions: []string{".js"}},
+			filename: "app.js",
+			wantErr:  true,
+		},
+		{
+			name:        "MIME type wildcard",
+			policy:      FileTypePolicy{AllowedMIMETypes: []string{"image/*"}},
+			filename:    "photo.png",
+			contentType: "image/png",
+		},
+		{
+			name:        "MIME type blocked",
+			policy:      FileTypePolicy{AllowedMIMETypes: []string{"image/*"}, BlockedMIMETypes: []string{"image/svg+xml"}},
+			filename:    "logo.svg",
+			contentType: "image/svg+xml",
+			wantErr:     true,
+		},
+		{
+			name:        "MIME type parameters",
+			policy:      FileTypePolicy{BlockedMIMETypes: []string{"text/javascript"}},
+			filename:    "app.mjs",
+			contentType: "text/javascript; charset=utf-8",
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.checkName(tc.filename, tc.contentType)
+			if tc.wantErr {
+				if connect.CodeOf(err) != connect.CodeInvalidArgument {
+					t.Errorf("checkName(%q) error = %v, want %v", tc.filename, err, connect.CodeInvalidArgument)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("checkName(%q) error = %v", tc.filename, err)
+			}
+		})
+	}
+}
+
+func TestFileTypePolicySniff(t *testing.T) {
+	exe := "MZ\x90\x00" + strings.Repeat("\x00", 1024)
+	testCases := []struct {
+		name    string
+		policy  FileTypePolicy
+		content string
+		wantErr bool
+	}{
+		{name: "no MIME types", policy: FileTypePolicy{BlockedExtensions: []string{".exe"}}, content: exe},
+		{name: "allowed", policy: FileTypePolicy{AllowedMIMETypes: []string{"image/*"}}, content: "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 1024)},
+		{name: "renamed executable", policy: FileTypePolicy{AllowedMIMETypes: []string{"image/*"}}, content: exe, wantErr: true},
+		{name: "blocked", policy: FileTypePolicy{BlockedMIMETypes: []string{"text/html"}}, content: "<!DOCTYPE html><script>", wantErr: true},
+		{name: "shorter than the sniffed length", policy: FileTypePolicy{AllowedMIMETypes: []string{"text/plain"}}, content: "hello"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := tc.policy.sniff(strings.NewReader(tc.content))
+			if tc.wantErr {
+				if connect.CodeOf(err) != connect.CodeInvalidArgument {
+					t.Errorf("sniff() error = %v, want %v", err, connect.CodeInvalidArgument)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sniff() error = %v", err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil || string(got) != tc.content {
+				t.Errorf("sniff() reader yields %d bytes, %v, want all %d", len(got), err, len(tc.content))
+			}
+		})
+	}
+}
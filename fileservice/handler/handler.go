@@ -16,12 +16,16 @@ package handler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 
 	"io"
-	"net/url"
-	"os"
+	"mime"
+	"net"
+	"net/http"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -30,19 +34,109 @@ import (
 	"connectrpc.com/connect"
 	"github.com/fawa-io/fwpkg/fwlog"
 	"github.com/fawa-io/fwpkg/util"
+	"github.com/redis/go-redis/v9"
 
 	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/auth"
+	"github.com/fawa-io/fawa/fileservice/pkg/filecrypt"
+	"github.com/fawa-io/fawa/fileservice/pkg/metrics"
+	"github.com/fawa-io/fawa/fileservice/pkg/ratelimit"
+	"github.com/fawa-io/fawa/fileservice/pkg/requestid"
 	"github.com/fawa-io/fawa/fileservice/storage"
 )
 
+// Lifetimes of the presigned download URLs
+const (
+	// downloadURLExpiry is used when the client asks for none
+	downloadURLExpiry = 5 * time.Minute
+	// defaultMaxDownloadURLExpiry is the longest a client may ask for when MaxDownloadURLExpiry is unset
+	defaultMaxDownloadURLExpiry = time.Hour
+)
+
+// defaultMaxLinkTTL is the longest ExtendLink may keep a file shared when MaxLinkTTL is unset
+const defaultMaxLinkTTL = 24 * time.Hour
+
+const (
+	msgFileNotFound   = "file not found or link expired"
+	msgEmptyRandomkey = "randomkey cannot be empty"
+	msgNotOwner       = "the file belongs to another user"
+)
+
+// Bounds of the ReceiveFile chunk size a client may request
+const (
+	DefaultChunkSize = 64 << 10
+	MinChunkSize     = 4 << 10
+	MaxChunkSize     = 4 << 20
+)
+
 // FileServiceHandler implements the gRPC file service.
 // It depends on a Storage interface for data persistence.
-type FileServiceHandler struct{}
+type FileServiceHandler struct {
+	// DrainTimeout bounds how long Close waits for in-flight uploads, defaults to defaultDrainTimeout
+	DrainTimeout time.Duration
+	// ChunkSize is the ReceiveFile chunk size used when the client requests none, defaults to DefaultChunkSize
+	ChunkSize int
+	// EncryptionKey is the master key of encrypted uploads, nil rejects them
+	EncryptionKey []byte
+	// MaxDownloadURLExpiry caps the expires_seconds of GetDownloadURL, defaults to defaultMaxDownloadURLExpiry
+	MaxDownloadURLExpiry time.Duration
+	// MaxLinkTTL caps the ttl_seconds of ExtendLink, defaults to defaultMaxLinkTTL
+	MaxLinkTTL time.Duration
+	// MaxConcurrentDownloads bounds the ReceiveFile calls in progress per file, 0 is unlimited
+	MaxConcurrentDownloads int64
+	// ClientIP returns the client address logged for a request, the peer address when nil
+	ClientIP func(peerAddr string, header http.Header) string
+	// RateLimiter charges the SendFiles budget again for every file after the first of a batch,
+	// the interceptor charging the stream itself, nil doesn't limit
+	RateLimiter *ratelimit.Interceptor
+	// FileTypes restricts the types of the uploaded files, the zero value allows all of them
+	FileTypes FileTypePolicy
+	// Quota limits the bytes each user or API key stores, the zero value is unlimited
+	Quota QuotaPolicy
+	// Scanner scans the uploads for malware before they're shared, nil disables scanning
+	Scanner Scanner
+	// ScanAsync shares uploads right away but quarantined until the scan releases them,
+	// instead of making the upload wait for its scan
+	ScanAsync bool
+	// ThumbnailSize is the largest side in pixels of the thumbnails generated for images, 0 disables them
+	ThumbnailSize int
+	// RedirectDownloads makes DownloadHTTP redirect to presigned URLs instead of streaming the
+	// file, only set it when clients can reach MinIO
+	RedirectDownloads bool
+
+	uploads uploadTracker
+}
 
 // Close shuts down the file service and its resources
 func (s *FileServiceHandler) Close() error {
 	fwlog.Info("Shutting down file service...")
-	return storage.Close()
+
+	timeout := s.DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return errors.Join(s.Drain(ctx), storage.Close())
+}
+
+// Drain stops accepting uploads and waits for the in-flight ones to finish.
+// Uploads still running when ctx is done are cancelled and their partial objects removed.
+func (s *FileServiceHandler) Drain(ctx context.Context) error {
+	return s.uploads.drain(ctx)
+}
+
+func (s *FileServiceHandler) clientIP(peer connect.Peer, header http.Header) string {
+	if s.ClientIP != nil {
+		return s.ClientIP(peer.Addr, header)
+	}
+	host, _, err := net.SplitHostPort(peer.Addr)
+	if err != nil {
+		return peer.Addr
+	}
+	return host
 }
 
 // SendFile handles the client-streaming RPC to upload a file.
@@ -50,231 +144,678 @@ func (s *FileServiceHandler) SendFile(
 	ctx context.Context,
 	stream *connect.ClientStream[filev1.SendFileRequest],
 ) (*connect.Response[filev1.SendFileResponse], error) {
-	fwlog.Info("SendFile request started")
+	metrics.FileUploadsInFlight.Add(1)
+	defer metrics.FileUploadsInFlight.Add(-1)
+
+	res, err := s.sendFile(ctx, stream)
+	code := "ok"
+	if err != nil {
+		code = connect.CodeOf(err).String()
+	}
+	metrics.FileUploads.Inc(code)
+	return res, err
+}
+
+func (s *FileServiceHandler) sendFile(
+	ctx context.Context,
+	stream *connect.ClientStream[filev1.SendFileRequest],
+) (*connect.Response[filev1.SendFileResponse], error) {
+	log := requestid.Logger(ctx)
+	log.Infof("SendFile request started from %s", s.clientIP(stream.Peer(), stream.RequestHeader()))
 
 	if !stream.Receive() {
 		if err := stream.Err(); err != nil {
-			return nil, connect.NewError(connect.CodeAborted, err)
+			return nil, apierr.From(err)
 		}
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("missing file info message"))
+		return nil, apierr.InvalidArgument("missing file info message")
 	}
 
 	// The first message must contain file info.
 	payload := stream.Msg().GetPayload()
 	info, ok := payload.(*filev1.SendFileRequest_Info)
 	if !ok {
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("first message must be file info"))
+		return nil, apierr.InvalidArgument("first message must be file info")
 	}
 
-	fileInfo := info.Info
+	downloadKey, err := s.upload(ctx, info.Info, func(w io.Writer) error {
+		for stream.Receive() {
+			payload := stream.Msg().GetPayload()
+			chunk, ok := payload.(*filev1.SendFileRequest_ChunkData)
+			if !ok {
+				return apierr.InvalidArgument("subsequent messages must be chunk data")
+			}
+			metrics.FileUploadBytes.Add(float64(len(chunk.ChunkData)))
+			if _, err := w.Write(chunk.ChunkData); err != nil {
+				return err
+			}
+		}
+		return stream.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := info.Info.GetName()
+	res := connect.NewResponse(&filev1.SendFileResponse{
+		Success:   true,
+		Message:   "File " + fileName + " uploaded successfully.",
+		Randomkey: downloadKey,
+	})
+	return res, nil
+}
+
+// upload stores the file described by fileInfo, whose content copyBody writes, and saves its
+// metadata under a new download key. It's shared by SendFile and the HTTP upload endpoint.
+// A negative size is unknown, the metadata then records the size received.
+func (s *FileServiceHandler) upload(ctx context.Context, fileInfo *filev1.FileInfo, copyBody func(w io.Writer) error) (string, error) {
+	log := requestid.Logger(ctx)
 	fileName := fileInfo.GetName()
 	fileSize := fileInfo.GetSize()
 
 	if fileName == "" {
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("file name cannot be empty"))
+		return "", apierr.InvalidArgument("file name cannot be empty")
+	}
+	if !validObjectName(fileName) {
+		return "", apierr.InvalidArgument("invalid file name")
+	}
+
+	downloadKey := util.Generaterandomstring(6)
+	metadata, err := newFileMetadata(downloadKey, fileInfo)
+	if err != nil {
+		return "", apierr.From(err)
+	}
+	if err := s.FileTypes.checkName(fileName, metadata.ContentType); err != nil {
+		return "", err
+	}
+	metadata.OwnerID, _ = auth.User(ctx)
+	quotaOwner, quota := s.Quota.owner(ctx)
+	if quota > 0 && fileSize >= 0 {
+		if err := checkQuota(ctx, quotaOwner, fileSize, quota); err != nil {
+			return "", err
+		}
+	}
+	if fileInfo.GetEncrypt() {
+		if s.EncryptionKey == nil {
+			return "", apierr.WithReason(apierr.FailedPrecondition("encryption is not enabled on this server"), filev1.ErrorReason_ERROR_REASON_ENCRYPTION_DISABLED, nil)
+		}
+		if metadata.EncryptionNonce, err = filecrypt.NewNonce(); err != nil {
+			return "", apierr.Internal(err)
+		}
+	}
+
+	ctx, done, err := s.uploads.begin(ctx)
+	if err != nil {
+		return "", err
 	}
-	if filepath.IsAbs(fileName) || strings.Contains(fileName, "..") {
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid file name"))
+	defer done()
+
+	// Synchronous scans read the plain content as it's uploaded
+	var scanWriter *io.PipeWriter
+	var waitScan func() (string, error)
+	if s.Scanner != nil && !s.ScanAsync {
+		scanWriter, waitScan = s.startScan(ctx)
 	}
 
 	pr, pw := io.Pipe()
 	var wg sync.WaitGroup
 	wg.Add(1)
 	errChan := make(chan error, 1)
+	// received counts the bytes of the file and checksum hashes them, read once wg is done
+	var received int64
+	checksum := sha256.New()
 
 	go func() {
 		defer wg.Done()
 		defer func() {
 			if err := pr.Close(); err != nil {
-				fwlog.Errorf("Failed to close pipe reader: %v", err)
+				log.Errorf("Failed to close pipe reader: %v", err)
 			}
 		}()
-		uploadInfo, err := storage.UploadFile(ctx, fileName, pr, fileSize)
+		if scanWriter != nil {
+			// Ends the scan of an upload failing before it's stored, a no-op once closed
+			defer scanWriter.CloseWithError(errUploadAborted)
+		}
+		body, err := newDecompressor(fileInfo.GetCompression(), pr)
 		if err != nil {
-			errChan <- fmt.Errorf("minio upload failed: %w", err)
-			fwlog.Errorf("Failed to upload file to MinIO: %v", err)
+			// Fail the chunk writes with the cause instead of a closed pipe
+			_ = pr.CloseWithError(err)
+			errChan <- err
 			return
 		}
-		fwlog.Infof("File uploaded to MinIO: %+v", uploadInfo)
-	}()
-
-	processErr := func() error {
-		for stream.Receive() {
-			payload := stream.Msg().GetPayload()
-			chunk, ok := payload.(*filev1.SendFileRequest_ChunkData)
-			if !ok {
-				return connect.NewError(connect.CodeInvalidArgument, errors.New("subsequent messages must be chunk data"))
+		defer func() {
+			if err := body.Close(); err != nil {
+				log.Errorf("Failed to close decompressor: %v", err)
 			}
-			if _, err := pw.Write(chunk.ChunkData); err != nil {
-				return err
+		}()
+		sniffed, err := s.FileTypes.sniff(body)
+		if err != nil {
+			_ = pr.CloseWithError(err)
+			errChan <- err
+			return
+		}
+		var object io.Reader = io.TeeReader(&countingReader{r: sniffed, n: &received}, checksum)
+		if scanWriter != nil {
+			object = io.TeeReader(object, scanWriter)
+		}
+		objectSize := fileSize
+		if metadata.EncryptionNonce != nil {
+			if object, err = filecrypt.NewEncryptingReader(s.EncryptionKey, metadata.EncryptionNonce, object); err != nil {
+				_ = pr.CloseWithError(err)
+				errChan <- err
+				return
+			}
+			if fileSize >= 0 {
+				objectSize = filecrypt.EncryptedSize(fileSize)
 			}
 		}
-		return stream.Err()
+		uploadInfo, err := storage.UploadFile(ctx, metadata.StoragePath, object, objectSize, storage.UploadOptions{
+			Bucket:       metadata.Bucket,
+			ContentType:  metadata.ContentType,
+			StorageClass: metadata.StorageClass,
+			Tags:         metadata.StorageTags,
+		})
+		if scanWriter != nil {
+			_ = scanWriter.CloseWithError(err)
+		}
+		if err != nil {
+			err = fmt.Errorf("minio upload failed: %w", err)
+			_ = pr.CloseWithError(err)
+			errChan <- err
+			log.Errorf("Failed to upload file to MinIO: %v", err)
+			removePartialUpload(metadata.Bucket, metadata.StoragePath)
+			return
+		}
+		log.Infof("File uploaded to MinIO: %+v", uploadInfo)
 	}()
 
+	processErr := copyBody(pw)
+
 	if processErr != nil {
 		if err := pw.CloseWithError(processErr); err != nil {
-			fwlog.Errorf("Failed to close pipe writer with error: %v", err)
+			log.Errorf("Failed to close pipe writer with error: %v", err)
 		}
-		wg.Wait() // Wait for the upload goroutine to finish
-		return nil, connect.NewError(connect.CodeInternal, processErr)
+		// The upload fails on the closed pipe and removes its partial object before returning
+		wg.Wait()
+		if err := ctx.Err(); err != nil {
+			log.Infof("Upload of %s cancelled: %v", fileName, err)
+			return "", apierr.From(err)
+		}
+		return "", apierr.From(processErr)
 	}
 
 	if err := pw.Close(); err != nil {
 		wg.Wait()
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to close pipe writer: %w", err))
+		return "", apierr.Internal(fmt.Errorf("failed to close pipe writer: %w", err))
 	}
 
 	wg.Wait()
 	close(errChan)
 
 	if err := <-errChan; err != nil {
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return "", apierr.From(err)
 	}
 
-	downloadKey := util.Generaterandomstring(6)
-	metadata := &storage.FileMetadata{
-		Filename:    fileName,
-		Size:        fileSize,
-		StoragePath: fileName,
+	// The upload may have completed just as it was cancelled by a drain
+	if err := ctx.Err(); err != nil {
+		removeUploadedFile(metadata.Bucket, metadata.StoragePath)
+		return "", apierr.From(err)
 	}
 
-	if err := storage.SaveFileMeta(downloadKey, metadata); err != nil {
-		return nil, connect.NewError(connect.CodeInternal, err)
+	if waitScan != nil {
+		virus, err := waitScan()
+		if err := checkScan(ctx, metadata, virus, err); err != nil {
+			return "", err
+		}
 	}
 
-	fwlog.Infof("File %s uploaded successfully.", fileName)
-	res := connect.NewResponse(&filev1.SendFileResponse{
-		Success:   true,
-		Message:   "File " + fileName + " uploaded successfully.",
-		Randomkey: downloadKey,
-	})
-	return res, nil
+	if metadata.Size < 0 {
+		metadata.Size = received
+	}
+	metadata.SHA256 = hex.EncodeToString(checksum.Sum(nil))
+	metadata.Quarantined = s.Scanner != nil && s.ScanAsync
+	if quota > 0 {
+		if err := storage.ReserveQuota(ctx, quotaOwner, downloadKey, metadata.Size, quota); err != nil {
+			removeUploadedFile(metadata.Bucket, metadata.StoragePath)
+			return "", quotaError(err)
+		}
+		metadata.QuotaOwner = quotaOwner
+	}
+	metadata.ThumbnailPath = s.storeThumbnail(ctx, downloadKey, metadata)
+	if err := storage.SaveFileMeta(ctx, downloadKey, metadata); err != nil {
+		removeUploadedFile(metadata.Bucket, metadata.StoragePath)
+		removeThumbnail(metadata)
+		releaseQuota(downloadKey, metadata)
+		return "", apierr.Internal(err)
+	}
+	if metadata.Quarantined {
+		go s.scanQuarantined(log, downloadKey, metadata)
+	}
+
+	log.Infof("File %s uploaded successfully.", fileName)
+	return downloadKey, nil
+}
+
+// countingReader counts the bytes read from r into n
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// validObjectName reports whether name can be used as an object name,
+// it must be relative, can't climb out of the bucket with ".." and can't take the thumbnail names.
+func validObjectName(name string) bool {
+	return name != "" && !filepath.IsAbs(name) && !strings.Contains(name, "..") && !strings.HasPrefix(name, thumbnailPrefix)
+}
+
+// checkOwner keeps users from the files of other users. Anonymous uploads are open to all,
+// and so is every file to callers without a user, which are trusted API key holders or
+// callers of public procedures.
+func checkOwner(ctx context.Context, metadata *storage.FileMetadata) error {
+	userID, ok := auth.User(ctx)
+	if !ok || metadata.OwnerID == "" || metadata.OwnerID == userID {
+		return nil
+	}
+	return apierr.WithReason(apierr.PermissionDenied(msgNotOwner), filev1.ErrorReason_ERROR_REASON_NOT_OWNER, nil)
+}
+
+// objectName names the object of the upload shared under key. Naming it after the key rather
+// than the client's file name keeps uploads of the same name, by any user, from replacing each other.
+func objectName(key, filename string) string {
+	return key + "/" + filename
+}
+
+// newFileMetadata returns the metadata recorded for an upload described by info, shared under key
+func newFileMetadata(key string, info *filev1.FileInfo) (*storage.FileMetadata, error) {
+	storageClass, err := storage.ParseStorageClass(info.GetStorageClass())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", apierr.ErrInvalidArgument, err)
+	}
+	if err := storage.ValidateTags(info.GetTags()); err != nil {
+		return nil, fmt.Errorf("%w: %w", apierr.ErrInvalidArgument, err)
+	}
+	if err := storage.ValidateBucket(info.GetBucket()); err != nil {
+		return nil, fmt.Errorf("%w: %w", apierr.ErrInvalidArgument, err)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(info.GetName()))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &storage.FileMetadata{
+		Filename:     info.GetName(),
+		Size:         info.GetSize(),
+		StoragePath:  objectName(key, info.GetName()),
+		Bucket:       info.GetBucket(),
+		ContentType:  contentType,
+		Compression:  compressionName(info.GetCompression()),
+		StorageClass: storageClass,
+		StorageTags:  info.GetTags(),
+	}, nil
+}
+
+// errFileNotFound reports a download key without a file, which never existed or expired
+func errFileNotFound() *connect.Error {
+	return apierr.WithReason(apierr.NotFound(msgFileNotFound), filev1.ErrorReason_ERROR_REASON_FILE_NOT_FOUND, nil)
+}
+
+// storageError converts an object store error into a Connect error reported as msg. Timeouts and
+// an offline store keep their code so that clients know to retry, other errors are internal.
+func storageError(err error, msg string) error {
+	switch code := apierr.From(err).Code(); code {
+	case connect.CodeDeadlineExceeded, connect.CodeUnavailable, connect.CodeCanceled:
+		return connect.NewError(code, errors.New(msg))
+	}
+	return apierr.Internal(errors.New(msg))
 }
 
 // ReceiveFile handles the server-streaming RPC to download a file.
-// The client requests a file by name, and the server streams it back in chunks.
+// The client requests a file by its download key, and the server streams the object back from MinIO in chunks.
 func (s *FileServiceHandler) ReceiveFile(
 	ctx context.Context,
 	req *connect.Request[filev1.ReceiveFileRequest],
 	stream *connect.ServerStream[filev1.ReceiveFileResponse],
-) (err error) {
-	//randomkey := req.Msg.Randomkey
-	//if randomkey == "" {
-	//	return connect.NewError(connect.CodeInvalidArgument, errors.New("randomkey cannot be empty"))
-	//}
-	//
-	//metadata, err := storage.GetFileMeta(randomkey)
-	//if err != nil {
-	//	return connect.NewError(connect.CodeNotFound, errors.New("file not found"))
-	//}
-	//
-	//fileName := metadata.Filename
-	//fwlog.Debugf("Request to download file: %s", fileName)
-	//
-	//filePath := filepath.Join(s.UploadDir, fileName)
-	//file, err := os.Open(filePath)
-	//if err != nil {
-	//	return connect.NewError(connect.CodeNotFound, errors.New("file not found"))
-	//}
-	//defer func() {
-	//	if closeErr := file.Close(); err == nil {
-	//		err = closeErr
-	//	}
-	//}()
-	//
-	//// Get file info to send the size first.
-	//fileInfo, err := file.Stat()
-	//if err != nil {
-	//	return connect.NewError(connect.CodeInternal, err)
-	//}
-	//// Send file size as the first message in the stream.
-	//if err := stream.Send(&filev1.ReceiveFileResponse{
-	//	Payload: &filev1.ReceiveFileResponse_FileSize{
-	//		FileSize: fileInfo.Size(),
-	//	},
-	//}); err != nil {
-	//	return err
-	//}
-	//
-	//// Stream the file content in chunks.
-	//buffer := make([]byte, 1024*64) // 64KB buffer
-	//for {
-	//	n, err := file.Read(buffer)
-	//	if err == io.EOF {
-	//		break // End of file reached.
-	//	}
-	//	if err != nil {
-	//		return connect.NewError(connect.CodeInternal, err)
-	//	}
-	//
-	//	// Send a data chunk.
-	//	if err := stream.Send(&filev1.ReceiveFileResponse{
-	//		Filename: fileName,
-	//		Payload: &filev1.ReceiveFileResponse_ChunkData{
-	//			ChunkData: buffer[:n],
-	//		},
-	//	}); err != nil {
-	//		return err
-	//	}
-	//}
-	//
-	//fwlog.Infof("File %s sent successfully.", fileName)
+) error {
+	log := requestid.Logger(ctx)
+	randomkey := req.Msg.Randomkey
+	if randomkey == "" {
+		return apierr.InvalidArgument(msgEmptyRandomkey)
+	}
+
+	metadata, err := storage.GetFileMeta(ctx, randomkey)
+	if err != nil {
+		log.Debugf("Failed to get file metadata for key %s: %v", randomkey, err)
+		return errFileNotFound()
+	}
+	// The metadata names the object, never let it point outside the bucket
+	if !validObjectName(metadata.StoragePath) {
+		log.Errorf("Refusing to download invalid object name %q of key %s", metadata.StoragePath, randomkey)
+		return errFileNotFound()
+	}
+	if err := checkOwner(ctx, metadata); err != nil {
+		return err
+	}
+	if err := checkQuarantine(metadata); err != nil {
+		return err
+	}
+
+	release, err := s.acquireDownload(randomkey)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	fileName := metadata.Filename
+	log.Infof("Request from %s to download file: %s", s.clientIP(req.Peer(), req.Header()), fileName)
+
+	object, objectSize, err := storage.GetFile(ctx, metadata.Bucket, metadata.StoragePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return errFileNotFound()
+		}
+		log.Errorf("Failed to open object %s: %v", metadata.StoragePath, err)
+		return storageError(err, "could not read file")
+	}
+	defer func() {
+		if closeErr := object.Close(); closeErr != nil {
+			log.Warnf("Failed to close object %s: %v", metadata.StoragePath, closeErr)
+		}
+	}()
+
+	if _, err := storage.IncrDownloadCount(randomkey); err != nil {
+		log.Warnf("Failed to record download of %s: %v", randomkey, err)
+	}
+
+	// Send the file size as the first message in the stream, the stored object of an
+	// encrypted file is larger than the content the client receives.
+	fileSize := objectSize
+	if metadata.EncryptionNonce != nil {
+		fileSize = metadata.Size
+	}
+	compression := knownCompression(req.Msg.GetCompression())
+	if err := stream.Send(&filev1.ReceiveFileResponse{
+		Payload: &filev1.ReceiveFileResponse_FileSize{
+			FileSize: fileSize,
+		},
+		Compression: compression,
+	}); err != nil {
+		return err
+	}
+
+	// Decrypt encrypted files, a modified object fails with filecrypt.ErrAuthentication.
+	var content io.Reader = object
+	if metadata.EncryptionNonce != nil {
+		if content, err = filecrypt.NewDecryptingReader(s.EncryptionKey, metadata.EncryptionNonce, object); err != nil {
+			return apierr.Internal(err)
+		}
+	}
+	// Hash the content as it streams to detect an object corrupted in storage
+	var checksum hash.Hash
+	if req.Msg.GetVerifyChecksum() && metadata.SHA256 != "" {
+		checksum = sha256.New()
+		content = io.TeeReader(content, checksum)
+	}
+
+	// Stream the file content in chunks, compressed when the client asked for it.
+	err = sendChunks(content, compression, s.chunkSize(req.Msg.ChunkSize), func(chunk []byte) error {
+		return stream.Send(&filev1.ReceiveFileResponse{
+			Filename: fileName,
+			Payload: &filev1.ReceiveFileResponse_ChunkData{
+				ChunkData: chunk,
+			},
+			Compression: compression,
+		})
+	})
+	if err != nil {
+		return apierr.From(err)
+	}
+
+	if checksum != nil {
+		if sum := hex.EncodeToString(checksum.Sum(nil)); sum != metadata.SHA256 {
+			log.Errorf("Object %s of key %s is corrupted: SHA-256 %s, want %s", metadata.StoragePath, randomkey, sum, metadata.SHA256)
+			return apierr.WithReason(connect.NewError(connect.CodeDataLoss, errors.New("file content does not match its checksum")),
+				filev1.ErrorReason_ERROR_REASON_CHECKSUM_MISMATCH, nil)
+		}
+	}
+	// End with the checksum recorded at upload for the client to verify
+	if metadata.SHA256 != "" {
+		if err := stream.Send(&filev1.ReceiveFileResponse{
+			Payload: &filev1.ReceiveFileResponse_Sha256{
+				Sha256: metadata.SHA256,
+			},
+			Compression: compression,
+		}); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("File %s sent successfully.", fileName)
 	return nil
 }
 
+// acquireDownload takes one of the download slots of a file, the returned func gives it back.
+// A viral link is throttled with CodeResourceExhausted instead of saturating the object store.
+func (s *FileServiceHandler) acquireDownload(randomkey string) (func(), error) {
+	if s.MaxConcurrentDownloads <= 0 {
+		return func() {}, nil
+	}
+	if err := storage.AcquireDownload(randomkey, s.MaxConcurrentDownloads); err != nil {
+		if errors.Is(err, storage.ErrTooManyDownloads) {
+			return nil, apierr.WithReason(apierr.ResourceExhausted("too many concurrent downloads of this file, retry later"),
+				filev1.ErrorReason_ERROR_REASON_TOO_MANY_DOWNLOADS, nil)
+		}
+		return nil, apierr.Internal(err)
+	}
+	return func() {
+		if err := storage.ReleaseDownload(randomkey); err != nil {
+			fwlog.Warnf("Failed to release download slot of %s: %v", randomkey, err)
+		}
+	}, nil
+}
+
+// chunkSize returns the ReceiveFile chunk size for a client request,
+// clamped to [MinChunkSize, MaxChunkSize] so a client can't make the server allocate huge buffers.
+func (s *FileServiceHandler) chunkSize(requested uint32) int {
+	size := int(requested)
+	if size == 0 {
+		size = s.ChunkSize
+	}
+	if size <= 0 {
+		return DefaultChunkSize
+	}
+	return min(max(size, MinChunkSize), MaxChunkSize)
+}
+
 func (s *FileServiceHandler) GetDownloadURL(
 	ctx context.Context,
 	req *connect.Request[filev1.GetDownloadURLRequest],
 ) (*connect.Response[filev1.GetDownloadURLResponse], error) {
+	log := requestid.Logger(ctx)
+	randomkey := req.Msg.Randomkey
+	if randomkey == "" {
+		return nil, apierr.InvalidArgument(msgEmptyRandomkey)
+	}
+	expires, err := s.downloadURLExpiry(req.Msg.ExpiresSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := storage.GetFileMeta(ctx, randomkey)
+	if err != nil {
+		log.Errorf("Failed to get file metadata for key %s: %v", randomkey, err)
+		return nil, errFileNotFound()
+	}
+	if err := checkOwner(ctx, metadata); err != nil {
+		return nil, err
+	}
+	if err := checkQuarantine(metadata); err != nil {
+		return nil, err
+	}
+
+	// The object store would hand out the ciphertext
+	if metadata.EncryptionNonce != nil {
+		return nil, apierr.WithReason(apierr.FailedPrecondition("encrypted files can only be downloaded with ReceiveFile"),
+			filev1.ErrorReason_ERROR_REASON_ENCRYPTED_FILE, nil)
+	}
+
+	log.Infof("Request from %s to generate download URL for file: %s", s.clientIP(req.Peer(), req.Header()), metadata.StoragePath)
+
+	if _, err := storage.IncrDownloadCount(randomkey); err != nil {
+		log.Warnf("Failed to record download of %s: %v", randomkey, err)
+	}
+
+	presignedURL, err := storage.GetPresignedURL(ctx, metadata.Bucket, metadata.StoragePath, expires, downloadOptions(metadata))
+	if err != nil {
+		log.Errorf("Failed to generate presigned URL for %s: %v", metadata.StoragePath, err)
+		return nil, storageError(err, "could not generate download link")
+	}
+
+	// The URL already points at the public endpoint when one is configured
+	res := connect.NewResponse(&filev1.GetDownloadURLResponse{
+		Url:       presignedURL.String(),
+		Filename:  metadata.Filename,
+		ExpiresAt: linkExpiresAt(randomkey, metadata),
+	})
+
+	return res, nil
+}
+
+// downloadURLExpiry returns the lifetime of a presigned URL from the seconds a client asked for,
+// rejecting lifetimes that aren't positive or exceed MaxDownloadURLExpiry.
+func (s *FileServiceHandler) downloadURLExpiry(seconds int64) (time.Duration, error) {
+	limit := s.MaxDownloadURLExpiry
+	if limit <= 0 {
+		limit = defaultMaxDownloadURLExpiry
+	}
+	if seconds == 0 {
+		return min(downloadURLExpiry, limit), nil
+	}
+	if seconds < 0 || seconds > int64(limit/time.Second) {
+		return 0, apierr.InvalidArgument(fmt.Sprintf("expires_seconds must be between 1 and %d", int64(limit/time.Second)))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// downloadOptions makes a presigned download save the file under its original name and type
+func downloadOptions(metadata *storage.FileMetadata) storage.DownloadOptions {
+	return storage.DownloadOptions{Filename: metadata.Filename, ContentType: metadata.ContentType}
+}
+
+// GetFileInfo returns the metadata of a shared file without touching the object store.
+func (s *FileServiceHandler) GetFileInfo(
+	ctx context.Context,
+	req *connect.Request[filev1.GetFileInfoRequest],
+) (*connect.Response[filev1.GetFileInfoResponse], error) {
+	log := requestid.Logger(ctx)
 	randomkey := req.Msg.Randomkey
 	if randomkey == "" {
-		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("randomkey cannot be empty"))
+		return nil, apierr.InvalidArgument(msgEmptyRandomkey)
 	}
 
-	metadata, err := storage.GetFileMeta(randomkey)
+	metadata, err := storage.GetFileMeta(ctx, randomkey)
 	if err != nil {
-		fwlog.Error("Failed to get file metadata for key %s: %v", randomkey, err)
-		return nil, connect.NewError(connect.CodeNotFound, errors.New("file not found or link expired"))
+		log.Debugf("Failed to get file metadata for key %s: %v", randomkey, err)
+		return nil, errFileNotFound()
+	}
+	if err := checkOwner(ctx, metadata); err != nil {
+		return nil, err
 	}
 
-	fwlog.Infof("Request to generate download URL for file: %s", metadata.StoragePath)
+	ttl, err := storage.GetFileTTL(randomkey)
+	if err != nil {
+		return nil, errFileNotFound()
+	}
 
-	expires := 5 * time.Minute
-	presignedURL, err := storage.GetPresignedURL(ctx, metadata.StoragePath, expires)
+	downloadCount, err := storage.GetDownloadCount(randomkey)
 	if err != nil {
-		fwlog.Error("Failed to generate presigned URL for %s: %v", metadata.StoragePath, err)
-		return nil, connect.NewError(connect.CodeInternal, errors.New("could not generate download link"))
+		log.Warnf("Failed to get download count for key %s: %v", randomkey, err)
 	}
 
-	publicEndpointStr := os.Getenv("MINIO_PUBLIC_ENDPOINT")
-	if publicEndpointStr == "" {
-		return connect.NewResponse(&filev1.GetDownloadURLResponse{
-			Url:      presignedURL.String(),
-			Filename: metadata.Filename,
-		}), nil
+	return connect.NewResponse(&filev1.GetFileInfoResponse{
+		Filename:      metadata.Filename,
+		Size:          metadata.Size,
+		ContentType:   metadata.ContentType,
+		TtlSeconds:    int64(ttl.Seconds()),
+		DownloadCount: downloadCount,
+		Encrypted:     metadata.EncryptionNonce != nil,
+		Tags:          metadata.StorageTags,
+		HasThumbnail:  metadata.ThumbnailPath != "",
+		ExpiresAt:     expiresAt(metadata, ttl),
+	}), nil
+}
+
+// ExtendLink keeps a shared file downloadable for the requested time from now,
+// the lifetime of a new upload when none is requested.
+func (s *FileServiceHandler) ExtendLink(
+	ctx context.Context,
+	req *connect.Request[filev1.ExtendLinkRequest],
+) (*connect.Response[filev1.ExtendLinkResponse], error) {
+	log := requestid.Logger(ctx)
+	randomkey := req.Msg.Randomkey
+	if randomkey == "" {
+		return nil, apierr.InvalidArgument(msgEmptyRandomkey)
+	}
+	ttl, err := s.linkTTL(req.Msg.TtlSeconds)
+	if err != nil {
+		return nil, err
 	}
 
-	publicEndpoint, err := url.Parse(publicEndpointStr)
+	metadata, err := storage.GetFileMeta(ctx, randomkey)
 	if err != nil {
-		fwlog.Errorf("Failed to parse MINIO_PUBLIC_ENDPOINT '%s': %v", publicEndpointStr, err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("invalid public endpoint configuration"))
+		log.Debugf("Failed to get file metadata for key %s: %v", randomkey, err)
+		return nil, errFileNotFound()
+	}
+	if err := checkOwner(ctx, metadata); err != nil {
+		return nil, err
 	}
 
-	finalURL := presignedURL
-	finalURL.Scheme = publicEndpoint.Scheme
-	finalURL.Host = publicEndpoint.Host
+	if err := storage.ExtendFileMeta(ctx, randomkey, metadata, ttl); err != nil {
+		// The link expired since it was read
+		if errors.Is(err, redis.Nil) {
+			return nil, errFileNotFound()
+		}
+		log.Errorf("Failed to extend the link of %s: %v", randomkey, err)
+		return nil, storageError(err, "could not extend link")
+	}
+	log.Infof("Extended the link of %s until %s", metadata.StoragePath, metadata.ExpiresAt.Format(time.RFC3339))
+
+	return connect.NewResponse(&filev1.ExtendLinkResponse{ExpiresAt: metadata.ExpiresAt.Unix()}), nil
+}
 
-	// publicEndpoint.Path = /minio, presignedURL.Path = /fawa/file.docx
-	// finalpath = /minio/fawa/file.docx
-	if publicEndpoint.Path != "" {
-		finalURL.Path = publicEndpoint.Path + finalURL.Path
+// linkTTL returns the lifetime ExtendLink gives a file from the seconds a client asked for,
+// 0 for the storage default. Lifetimes that are negative or exceed MaxLinkTTL are rejected.
+func (s *FileServiceHandler) linkTTL(seconds int64) (time.Duration, error) {
+	limit := s.MaxLinkTTL
+	if limit <= 0 {
+		limit = defaultMaxLinkTTL
 	}
+	if seconds < 0 || seconds > int64(limit/time.Second) {
+		return 0, apierr.InvalidArgument(fmt.Sprintf("ttl_seconds must be between 0 and %d", int64(limit/time.Second)))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
 
-	res := connect.NewResponse(&filev1.GetDownloadURLResponse{
-		Url:      finalURL.String(),
-		Filename: metadata.Filename,
-	})
+// linkExpiresAt returns when the link of key expires in Unix seconds, 0 when it is unknown
+func linkExpiresAt(key string, metadata *storage.FileMetadata) int64 {
+	if !metadata.ExpiresAt.IsZero() {
+		return metadata.ExpiresAt.Unix()
+	}
+	ttl, err := storage.GetFileTTL(key)
+	if err != nil {
+		return 0
+	}
+	return expiresAt(metadata, ttl)
+}
 
-	return res, nil
+// expiresAt returns the recorded expiry of metadata in Unix seconds,
+// or computes it from the TTL left for metadata saved before it was recorded
+func expiresAt(metadata *storage.FileMetadata, ttl time.Duration) int64 {
+	if !metadata.ExpiresAt.IsZero() {
+		return metadata.ExpiresAt.Unix()
+	}
+	return time.Now().Add(ttl).Unix()
 }
@@ -0,0 +1,348 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/auth"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+func TestChunkSize(t *testing.T) {
+	testCases := []struct {
+		name       string
+		configured int
+		requested  uint32
+		want       int
+	}{
+		{name: "defaults", want: DefaultChunkSize},
+		{name: "configured default", configured: 1 << 20, want: 1 << 20},
+		{name: "requested", configured: 1 << 20, requested: 256 << 10, want: 256 << 10},
+		{name: "clamped to min", requested: 1, want: MinChunkSize},
+		{name: "clamped to max", requested: 1 << 30, want: MaxChunkSize},
+		{name: "configured default clamped", configured: 1 << 30, want: MaxChunkSize},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &FileServiceHandler{ChunkSize: tc.configured}
+			if got := s.chunkSize(tc.requested); got != tc.want {
+				t.Errorf("chunkSize(%d) = %d, want %d", tc.requested, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStorageError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want connect.Code
+	}{
+		{name: "timeout", err: fmt.Errorf("stat: %w", context.DeadlineExceeded), want: connect.CodeDeadlineExceeded},
+		{name: "offline", err: storage.ErrOffline, want: connect.CodeUnavailable},
+		{name: "other", err: errors.New("access denied"), want: connect.CodeInternal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := storageError(tc.err, "could not read file")
+			if connect.CodeOf(err) != tc.want {
+				t.Errorf("storageError(%v) = %v, want %v", tc.err, err, tc.want)
+			}
+			var connectErr *connect.Error
+			if errors.As(err, &connectErr) && connectErr.Message() != "could not read file" {
+				t.Errorf("storageError(%v) message = %q, want the storage error hidden", tc.err, connectErr.Message())
+			}
+		})
+	}
+}
+
+func TestDownloadURLExpiry(t *testing.T) {
+	testCases := []struct {
+		name       string
+		configured time.Duration
+		seconds    int64
+		want       time.Duration
+		wantErr    bool
+	}{
+		{name: "default", want: downloadURLExpiry},
+		{name: "requested", seconds: 1800, want: 30 * time.Minute},
+		{name: "at the default cap", seconds: 3600, want: time.Hour},
+		{name: "over the default cap", seconds: 3601, wantErr: true},
+		{name: "configured cap", configured: 24 * time.Hour, seconds: 86400, want: 24 * time.Hour},
+		{name: "default over the configured cap", configured: time.Minute, want: time.Minute},
+		{name: "negative", seconds: -1, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &FileServiceHandler{MaxDownloadURLExpiry: tc.configured}
+			got, err := s.downloadURLExpiry(tc.seconds)
+			if tc.wantErr {
+				if connect.CodeOf(err) != connect.CodeInvalidArgument {
+					t.Errorf("downloadURLExpiry(%d) error = %v, want %v", tc.seconds, err, connect.CodeInvalidArgument)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Errorf("downloadURLExpiry(%d) = %v, %v, want %v", tc.seconds, got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLinkTTL(t *testing.T) {
+	testCases := []struct {
+		name       string
+		configured time.Duration
+		seconds    int64
+		want       time.Duration
+		wantErr    bool
+	}{
+		{name: "default", want: 0},
+		{name: "requested", seconds: 7200, want: 2 * time.Hour},
+		{name: "at the default cap", seconds: 86400, want: 24 * time.Hour},
+		{name: "over the default cap", seconds: 86401, wantErr: true},
+		{name: "configured cap", configured: 7 * 24 * time.Hour, seconds: 3 * 86400, want: 3 * 24 * time.Hour},
+		{name: "over the configured cap", configured: time.Hour, seconds: 3601, wantErr: true},
+		{name: "negative", seconds: -1, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &FileServiceHandler{MaxLinkTTL: tc.configured}
+			got, err := s.linkTTL(tc.seconds)
+			if tc.wantErr {
+				if connect.CodeOf(err) != connect.CodeInvalidArgument {
+					t.Errorf("linkTTL(%d) error = %v, want %v", tc.seconds, err, connect.CodeInvalidArgument)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Errorf("linkTTL(%d) = %v, %v, want %v", tc.seconds, got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewFileMetadata(t *testing.T) {
+	testCases := []struct {
+		name    string
+		info    *filev1.FileInfo
+		want    *storage.FileMetadata
+		wantErr error
+	}{
+		{
+			name: "defaults",
+			info: &filev1.FileInfo{Name: "notes.txt", Size: 42},
+			want: &storage.FileMetadata{
+				Filename:     "notes.txt",
+				Size:         42,
+				StoragePath:  "key/notes.txt",
+				ContentType:  "text/plain; charset=utf-8",
+				StorageClass: storage.StorageClassStandard,
+			},
+		},
+		{
+			name: "storage class and compression",
+			info: &filev1.FileInfo{
+				Name:         "archive.bin",
+				Size:         1 << 20,
+				Compression:  filev1.Compression_COMPRESSION_ZSTD,
+				StorageClass: "reduced_redundancy",
+			},
+			want: &storage.FileMetadata{
+				Filename:     "archive.bin",
+				Size:         1 << 20,
+				StoragePath:  "key/archive.bin",
+				ContentType:  "application/octet-stream",
+				Compression:  "zstd",
+				StorageClass: "REDUCED_REDUNDANCY",
+			},
+		},
+		{
+			name: "tags",
+			info: &filev1.FileInfo{Name: "scratch.bin", Tags: map[string]string{"class": "temp", "team": "canvas"}},
+			want: &storage.FileMetadata{
+				Filename:     "scratch.bin",
+				StoragePath:  "key/scratch.bin",
+				ContentType:  "application/octet-stream",
+				StorageClass: storage.StorageClassStandard,
+				StorageTags:  map[string]string{"class": "temp", "team": "canvas"},
+			},
+		},
+		{
+			name:    "invalid tag",
+			info:    &filev1.FileInfo{Name: "a.txt", Tags: map[string]string{"": "no key"}},
+			wantErr: apierr.ErrInvalidArgument,
+		},
+		{
+			name:    "unknown bucket",
+			info:    &filev1.FileInfo{Name: "a.txt", Bucket: "elsewhere"},
+			wantErr: apierr.ErrInvalidArgument,
+		},
+		{
+			name:    "unknown storage class",
+			info:    &filev1.FileInfo{Name: "a.txt", StorageClass: "DEEP_FREEZER"},
+			wantErr: apierr.ErrInvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := newFileMetadata("key", tc.info)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("newFileMetadata() error = %v, want %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("newFileMetadata() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSendFileEncryptWithoutKey(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(&FileServiceHandler{}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+	stream := client.SendFile(context.Background())
+	err := stream.Send(&filev1.SendFileRequest{
+		Payload: &filev1.SendFileRequest_Info{Info: &filev1.FileInfo{Name: "secret.txt", Size: 6, Encrypt: true}},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	_, err = stream.CloseAndReceive()
+	if connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Errorf("SendFile() error = %v, want %v", err, connect.CodeFailedPrecondition)
+	}
+}
+
+func TestObjectNameIsPerUpload(t *testing.T) {
+	// Two users uploading report.pdf must not share an object
+	first, _ := newFileMetadata("abc123", &filev1.FileInfo{Name: "report.pdf"})
+	second, _ := newFileMetadata("def456", &filev1.FileInfo{Name: "report.pdf"})
+	if first.StoragePath == second.StoragePath {
+		t.Errorf("uploads of the same name share the object %q", first.StoragePath)
+	}
+	if first.Filename != "report.pdf" {
+		t.Errorf("Filename = %q, want the uploaded name", first.Filename)
+	}
+}
+
+func TestAcquireDownloadUnlimited(t *testing.T) {
+	// Without a limit the metadata store isn't consulted, so this works without Dragonfly
+	s := &FileServiceHandler{}
+	release, err := s.acquireDownload("key")
+	if err != nil {
+		t.Fatalf("acquireDownload() error = %v", err)
+	}
+	release()
+}
+
+func TestValidObjectName(t *testing.T) {
+	testCases := []struct {
+		name string
+		want bool
+	}{
+		{name: "report.pdf", want: true},
+		{name: "dir/report.pdf", want: true},
+		{name: ""},
+		{name: "/etc/passwd"},
+		{name: "../secret"},
+		{name: "dir/../../secret"},
+		{name: ".thumbnails/abc123"},
+		{name: "dir/.thumbnails/abc123", want: true},
+	}
+	for _, tc := range testCases {
+		if got := validObjectName(tc.name); got != tc.want {
+			t.Errorf("validObjectName(%q) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCheckOwner(t *testing.T) {
+	testCases := []struct {
+		name    string
+		user    string
+		owner   string
+		wantErr bool
+	}{
+		{name: "owner", user: "alice", owner: "alice"},
+		{name: "other user", user: "bob", owner: "alice", wantErr: true},
+		{name: "anonymous upload", user: "bob"},
+		{name: "caller without user", owner: "alice"},
+	}
+	for _, tc := range testCases {
+		ctx := context.Background()
+		if tc.user != "" {
+			ctx = auth.WithUser(ctx, tc.user)
+		}
+		err := checkOwner(ctx, &storage.FileMetadata{OwnerID: tc.owner})
+		if tc.wantErr && connect.CodeOf(err) != connect.CodePermissionDenied || !tc.wantErr && err != nil {
+			t.Errorf("%s: checkOwner() = %v, want error %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestReceiveFileEmptyKey(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(&FileServiceHandler{}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+	stream, err := client.ReceiveFile(context.Background(), connect.NewRequest(&filev1.ReceiveFileRequest{}))
+	if err != nil {
+		t.Fatalf("ReceiveFile() error = %v", err)
+	}
+	for stream.Receive() {
+		t.Fatal("ReceiveFile() sent a message for an empty key")
+	}
+	if connect.CodeOf(stream.Err()) != connect.CodeInvalidArgument {
+		t.Errorf("ReceiveFile() error = %v, want %v", stream.Err(), connect.CodeInvalidArgument)
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	recorded := time.Date(2025, 6, 1, 12, 25, 0, 0, time.UTC)
+	if got := expiresAt(&storage.FileMetadata{ExpiresAt: recorded}, time.Minute); got != recorded.Unix() {
+		t.Errorf("expiresAt() = %d, want the recorded %d", got, recorded.Unix())
+	}
+
+	// Metadata saved before the expiry was recorded falls back to the TTL left
+	before := time.Now().Add(10 * time.Minute).Unix()
+	got := expiresAt(&storage.FileMetadata{}, 10*time.Minute)
+	if after := time.Now().Add(10 * time.Minute).Unix(); got < before || got > after {
+		t.Errorf("expiresAt() = %d, want between %d and %d", got, before, after)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+// readinessTimeout bounds each dependency check of the readiness probe
+const readinessTimeout = 2 * time.Second
+
+// readinessCheck reports whether a downstream dependency is usable
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// readinessChecks are the dependencies an upload needs
+var readinessChecks = []readinessCheck{
+	{name: "dragonfly", check: storage.PingDragonfly},
+	{name: "minio", check: storage.CheckBucket},
+}
+
+// Healthz is the liveness probe, it only reports that the process is serving
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, map[string]any{"status": "ok", "service": "file"})
+}
+
+// Readyz is the readiness probe, it answers 503 when Dragonfly or MinIO is unreachable
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		ready  = true
+		checks = make(map[string]string, len(readinessChecks))
+	)
+	for _, c := range readinessChecks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fwlog.Warnf("Readiness check %s failed: %v", c.name, err)
+				checks[c.name] = err.Error()
+				ready = false
+				return
+			}
+			checks[c.name] = "ok"
+		}()
+	}
+	wg.Wait()
+
+	if !ready {
+		writeHealth(w, http.StatusServiceUnavailable, map[string]any{"status": "unavailable", "checks": checks})
+		return
+	}
+	writeHealth(w, http.StatusOK, map[string]any{"status": "ok", "checks": checks})
+}
+
+func writeHealth(w http.ResponseWriter, status int, body map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		fwlog.Warnf("write response failed: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyz(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	testCases := []struct {
+		name       string
+		checks     []readinessCheck
+		wantStatus int
+	}{
+		{
+			name:       "all dependencies up",
+			checks:     []readinessCheck{{name: "dragonfly", check: ok}, {name: "minio", check: ok}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "dragonfly down",
+			checks:     []readinessCheck{{name: "dragonfly", check: down}, {name: "minio", check: ok}},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "minio down",
+			checks:     []readinessCheck{{name: "dragonfly", check: ok}, {name: "minio", check: down}},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prev := readinessChecks
+			readinessChecks = tc.checks
+			t.Cleanup(func() { readinessChecks = prev })
+
+			rec := httptest.NewRecorder()
+			Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			var body struct {
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if len(body.Checks) != len(tc.checks) {
+				t.Errorf("checks = %v, want %d entries", body.Checks, len(tc.checks))
+			}
+		})
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Healthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
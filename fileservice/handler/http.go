@@ -0,0 +1,294 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/auth"
+	"github.com/fawa-io/fawa/fileservice/pkg/filecrypt"
+	"github.com/fawa-io/fawa/fileservice/pkg/metrics"
+	"github.com/fawa-io/fawa/fileservice/pkg/ratelimit"
+	"github.com/fawa-io/fawa/fileservice/pkg/requestid"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+// msgObjectGone reports a file whose link is still valid but whose object was removed
+const msgObjectGone = "the file is no longer stored"
+
+// HTTPGuard gives a plain HTTP endpoint standing in for a procedure the checks the Connect
+// interceptors make on it: the request ID, the rate limit and the authentication.
+type HTTPGuard struct {
+	// RateLimiter charges the budget of the procedure, nil doesn't limit
+	RateLimiter *ratelimit.Interceptor
+	// Auth requires the credentials of the procedure, nil lets every request through
+	Auth *auth.Interceptor
+}
+
+// Handler returns next guarded like procedure
+func (g HTTPGuard) Handler(procedure string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestid.ID(r.Header)
+		w.Header().Set(requestid.Header, id)
+		ctx := requestid.NewContext(r.Context(), id)
+
+		if g.RateLimiter != nil {
+			if err := g.RateLimiter.Allow(procedure, connect.Peer{Addr: r.RemoteAddr}, r.Header); err != nil {
+				apierr.WriteHTTP(w, err)
+				return
+			}
+		}
+		if g.Auth != nil {
+			var err error
+			if ctx, err = g.Auth.Authenticate(ctx, procedure, r.Header); err != nil {
+				apierr.WriteHTTP(w, err)
+				return
+			}
+		}
+		next(w, r.WithContext(ctx))
+	})
+}
+
+// UploadHTTP handles POST /upload for clients without a Connect client, e.g.
+// curl -F file=@photo.jpg. The first file part of the multipart/form-data body is uploaded
+// like SendFile and the response is the SendFileResponse as JSON.
+func (s *FileServiceHandler) UploadHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	metrics.FileUploadsInFlight.Add(1)
+	defer metrics.FileUploadsInFlight.Add(-1)
+
+	res, err := s.uploadHTTP(r)
+	code := "ok"
+	if err != nil {
+		code = connect.CodeOf(err).String()
+	}
+	metrics.FileUploads.Inc(code)
+	if err != nil {
+		apierr.WriteHTTP(w, err)
+		return
+	}
+
+	body, err := protojson.Marshal(res)
+	if err != nil {
+		apierr.WriteHTTP(w, apierr.Internal(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		requestid.Logger(r.Context()).Warnf("write response failed: %v", err)
+	}
+}
+
+func (s *FileServiceHandler) uploadHTTP(r *http.Request) (*filev1.SendFileResponse, error) {
+	ctx := r.Context()
+	log := requestid.Logger(ctx)
+	log.Infof("HTTP upload started from %s", s.clientIP(connect.Peer{Addr: r.RemoteAddr}, r.Header))
+
+	form, err := r.MultipartReader()
+	if err != nil {
+		return nil, apierr.InvalidArgument("the body must be multipart/form-data")
+	}
+	part, err := nextFilePart(form)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := part.Close(); err != nil {
+			log.Debugf("Failed to close the file part: %v", err)
+		}
+	}()
+
+	// The size of a part isn't known before it has been read
+	info := &filev1.FileInfo{Name: part.FileName(), Size: -1}
+	downloadKey, err := s.upload(ctx, info, func(w io.Writer) error {
+		_, err := io.Copy(w, &metricsReader{r: part})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &filev1.SendFileResponse{
+		Success:   true,
+		Message:   "File " + info.GetName() + " uploaded successfully.",
+		Randomkey: downloadKey,
+	}, nil
+}
+
+// nextFilePart skips the form fields up to the first file part
+func nextFilePart(form *multipart.Reader) (*multipart.Part, error) {
+	for {
+		part, err := form.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, apierr.InvalidArgument("the form has no file")
+		}
+		if err != nil {
+			return nil, apierr.InvalidArgument("malformed multipart body: " + err.Error())
+		}
+		if part.FileName() != "" {
+			return part, nil
+		}
+		_ = part.Close()
+	}
+}
+
+// metricsReader counts the bytes read from r as uploaded
+type metricsReader struct {
+	r io.Reader
+}
+
+func (m *metricsReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	metrics.FileUploadBytes.Add(float64(n))
+	return n, err
+}
+
+// DownloadHTTP handles GET /download/{key} so that browsers can download a shared file with a
+// plain link. It redirects to a presigned URL when RedirectDownloads is set, except for
+// encrypted files which are always streamed decrypted. An expired key is 404 Not Found,
+// an object missing while its link is still valid 410 Gone.
+func (s *FileServiceHandler) DownloadHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := requestid.Logger(ctx)
+	randomkey := r.PathValue("key")
+	if randomkey == "" {
+		apierr.WriteHTTP(w, apierr.InvalidArgument(msgEmptyRandomkey))
+		return
+	}
+
+	metadata, err := storage.GetFileMeta(ctx, randomkey)
+	if err != nil {
+		log.Debugf("Failed to get file metadata for key %s: %v", randomkey, err)
+		apierr.WriteHTTP(w, errFileNotFound())
+		return
+	}
+	// The metadata names the object, never let it point outside the bucket
+	if !validObjectName(metadata.StoragePath) {
+		log.Errorf("Refusing to download invalid object name %q of key %s", metadata.StoragePath, randomkey)
+		apierr.WriteHTTP(w, errFileNotFound())
+		return
+	}
+	if err := checkOwner(ctx, metadata); err != nil {
+		apierr.WriteHTTP(w, err)
+		return
+	}
+	if err := checkQuarantine(metadata); err != nil {
+		apierr.WriteHTTP(w, err)
+		return
+	}
+	log.Infof("HTTP download of %s from %s", metadata.Filename, s.clientIP(connect.Peer{Addr: r.RemoteAddr}, r.Header))
+
+	if s.RedirectDownloads && metadata.EncryptionNonce == nil {
+		s.redirectDownload(w, r, randomkey, metadata)
+		return
+	}
+	s.streamDownload(w, r, randomkey, metadata)
+}
+
+// redirectDownload sends the client to a presigned URL of the object
+func (s *FileServiceHandler) redirectDownload(w http.ResponseWriter, r *http.Request, randomkey string, metadata *storage.FileMetadata) {
+	ctx := r.Context()
+	log := requestid.Logger(ctx)
+	// MinIO would answer the redirected client with its own error page
+	if _, err := storage.StatFile(ctx, metadata.Bucket, metadata.StoragePath); err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			writeGone(w)
+			return
+		}
+		log.Errorf("Failed to stat object %s: %v", metadata.StoragePath, err)
+		apierr.WriteHTTP(w, storageError(err, "could not read file"))
+		return
+	}
+
+	presignedURL, err := storage.GetPresignedURL(ctx, metadata.Bucket, metadata.StoragePath, downloadURLExpiry, downloadOptions(metadata))
+	if err != nil {
+		log.Errorf("Failed to generate presigned URL for %s: %v", metadata.StoragePath, err)
+		apierr.WriteHTTP(w, storageError(err, "could not generate download link"))
+		return
+	}
+	if _, err := storage.IncrDownloadCount(randomkey); err != nil {
+		log.Warnf("Failed to record download of %s: %v", randomkey, err)
+	}
+	http.Redirect(w, r, presignedURL.String(), http.StatusFound)
+}
+
+// streamDownload sends the file content as the response body
+func (s *FileServiceHandler) streamDownload(w http.ResponseWriter, r *http.Request, randomkey string, metadata *storage.FileMetadata) {
+	ctx := r.Context()
+	log := requestid.Logger(ctx)
+	release, err := s.acquireDownload(randomkey)
+	if err != nil {
+		apierr.WriteHTTP(w, err)
+		return
+	}
+	defer release()
+
+	object, objectSize, err := storage.GetFile(ctx, metadata.Bucket, metadata.StoragePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			writeGone(w)
+			return
+		}
+		log.Errorf("Failed to open object %s: %v", metadata.StoragePath, err)
+		apierr.WriteHTTP(w, storageError(err, "could not read file"))
+		return
+	}
+	defer func() {
+		if closeErr := object.Close(); closeErr != nil {
+			log.Warnf("Failed to close object %s: %v", metadata.StoragePath, closeErr)
+		}
+	}()
+
+	// The stored object of an encrypted file is larger than its content
+	var content io.Reader = object
+	size := objectSize
+	if metadata.EncryptionNonce != nil {
+		if content, err = filecrypt.NewDecryptingReader(s.EncryptionKey, metadata.EncryptionNonce, object); err != nil {
+			apierr.WriteHTTP(w, apierr.Internal(err))
+			return
+		}
+		size = metadata.Size
+	}
+	if _, err := storage.IncrDownloadCount(randomkey); err != nil {
+		log.Warnf("Failed to record download of %s: %v", randomkey, err)
+	}
+
+	w.Header().Set("Content-Type", metadata.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Content-Disposition", storage.ContentDisposition(metadata.Filename))
+	n, err := io.Copy(w, content)
+	if err != nil {
+		// The status is sent, cutting the body short is all that's left
+		log.Warnf("HTTP download of %s stopped after %d bytes: %v", metadata.Filename, n, err)
+	}
+}
+
+// writeGone answers like apierr.WriteHTTP for an object missing while its link is still valid
+func writeGone(w http.ResponseWriter) {
+	err := apierr.WithReason(apierr.NotFound(msgObjectGone), filev1.ErrorReason_ERROR_REASON_OBJECT_GONE, nil)
+	apierr.WriteHTTPStatus(w, err, http.StatusGone)
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+	"github.com/fawa-io/fawa/fileservice/pkg/auth"
+	"github.com/fawa-io/fawa/fileservice/pkg/ratelimit"
+	"github.com/fawa-io/fawa/fileservice/pkg/requestid"
+)
+
+// multipartBody returns a form with a text field and, unless fileName is empty, a file part
+func multipartBody(t *testing.T, fileName, content string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("comment", "holiday photos"); err != nil {
+		t.Fatal(err)
+	}
+	if fileName != "" {
+		part, err := form.CreateFormFile("file", fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &body, form.FormDataContentType()
+}
+
+func TestUploadHTTPRejected(t *testing.T) {
+	noFile, noFileType := multipartBody(t, "", "")
+	badName, badNameType := multipartBody(t, "..", "data")
+
+	testCases := []struct {
+		name        string
+		method      string
+		body        *bytes.Buffer
+		contentType string
+		wantStatus  int
+	}{
+		{name: "GET", method: http.MethodGet, body: &bytes.Buffer{}, wantStatus: http.StatusMethodNotAllowed},
+		{name: "not multipart", method: http.MethodPost, body: bytes.NewBufferString("data"), contentType: "text/plain", wantStatus: http.StatusBadRequest},
+		{name: "no file", method: http.MethodPost, body: noFile, contentType: noFileType, wantStatus: http.StatusBadRequest},
+		{name: "invalid file name", method: http.MethodPost, body: badName, contentType: badNameType, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/upload", tc.body)
+			req.Header.Set("Content-Type", tc.contentType)
+			rec := httptest.NewRecorder()
+			(&FileServiceHandler{}).UploadHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestHTTPGuard(t *testing.T) {
+	keys, err := auth.NewKeys([]string{"secret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard := HTTPGuard{
+		RateLimiter: ratelimit.NewInterceptor(map[string]*ratelimit.Limiter{
+			filev1connect.FileServiceSendFileProcedure: ratelimit.NewLimiter(ratelimit.Limit{RPS: 0.001, Burst: 2}, 0),
+		}),
+		Auth: auth.NewInterceptor(keys),
+	}
+	var reached int
+	handler := guard.Handler(filev1connect.FileServiceSendFileProcedure, func(w http.ResponseWriter, r *http.Request) {
+		if requestid.FromContext(r.Context()) == "" {
+			t.Error("the request ID should be in the context")
+		}
+		reached++
+	})
+
+	send := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(""))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("without a key: status = %d, WWW-Authenticate = %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+	if rec.Header().Get(requestid.Header) == "" {
+		t.Error("the response should carry a request ID")
+	}
+	if rec := send("secret"); rec.Code != http.StatusOK {
+		t.Errorf("with a key: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := send("secret"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("over the rate limit: status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if reached != 1 {
+		t.Errorf("handler reached %d times, want 1", reached)
+	}
+}
+
+func TestDownloadHTTPEmptyKey(t *testing.T) {
+	rec := httptest.NewRecorder()
+	(&FileServiceHandler{}).DownloadHTTP(rec, httptest.NewRequest(http.MethodGet, "/download/", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestWriteGone(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeGone(rec)
+	if rec.Code != http.StatusGone {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGone)
+	}
+	if !strings.Contains(rec.Body.String(), msgObjectGone) {
+		t.Errorf("body = %s, want the message %q", rec.Body, msgObjectGone)
+	}
+}
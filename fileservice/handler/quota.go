@@ -0,0 +1,95 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/auth"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+// QuotaPolicy limits the bytes each user or API key stores at once, the files count until
+// their link expires. Anonymous uploads, taken when SendFile needs no key, have no quota.
+type QuotaPolicy struct {
+	// Default is the quota in bytes of the users and keys without their own, 0 is unlimited
+	Default int64
+	// Users sets the quota of user IDs, 0 is unlimited
+	Users map[string]int64
+	// APIKeys sets the quota of API keys by their hex SHA-256 hash, 0 is unlimited
+	APIKeys map[string]int64
+}
+
+// owner returns who the bytes stored by the caller count against and their quota,
+// a zero quota is unlimited
+func (p *QuotaPolicy) owner(ctx context.Context) (string, int64) {
+	if userID, ok := auth.User(ctx); ok {
+		return "user:" + userID, p.limit(p.Users, userID)
+	}
+	if hash, ok := auth.APIKeyHash(ctx); ok {
+		return "key:" + hash, p.limit(p.APIKeys, hash)
+	}
+	return "", 0
+}
+
+func (p *QuotaPolicy) limit(limits map[string]int64, id string) int64 {
+	if limit, ok := limits[id]; ok {
+		return limit
+	}
+	return p.Default
+}
+
+// checkQuota rejects an upload of size bytes that would take owner over limit before it's
+// stored. Concurrent uploads are only caught by storage.ReserveQuota once stored.
+func checkQuota(ctx context.Context, owner string, size, limit int64) error {
+	usage, err := storage.QuotaUsage(ctx, owner)
+	if err != nil {
+		return apierr.Internal(fmt.Errorf("failed to read quota usage: %w", err))
+	}
+	if usage+size > limit {
+		return quotaError(&storage.QuotaExceededError{Usage: usage, Limit: limit})
+	}
+	return nil
+}
+
+// quotaError converts a storage.QuotaExceededError into an error telling the client their usage and quota
+func quotaError(err error) error {
+	var quotaErr *storage.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		return apierr.Internal(err)
+	}
+	return apierr.WithReason(apierr.ResourceExhausted(quotaErr.Error()), filev1.ErrorReason_ERROR_REASON_QUOTA_EXCEEDED, map[string]string{
+		"usage": strconv.FormatInt(quotaErr.Usage, 10),
+		"limit": strconv.FormatInt(quotaErr.Limit, 10),
+	})
+}
+
+// releaseQuota stops counting a file that was never shared against its owner
+func releaseQuota(key string, metadata *storage.FileMetadata) {
+	if metadata.QuotaOwner == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if err := storage.ReleaseQuota(ctx, metadata.QuotaOwner, key, metadata.Size); err != nil {
+		fwlog.Warnf("Failed to release the quota of unshared upload %s: %v", metadata.StoragePath, err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/auth"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+func TestQuotaPolicyOwner(t *testing.T) {
+	policy := QuotaPolicy{
+		Default: 100,
+		Users:   map[string]int64{"alice": 1000, "root": 0},
+		APIKeys: map[string]int64{"abcd": 5000},
+	}
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		wantOwner string
+		wantLimit int64
+	}{
+		{name: "user with a tier", ctx: auth.WithUser(context.Background(), "alice"), wantOwner: "user:alice", wantLimit: 1000},
+		{name: "unlimited user", ctx: auth.WithUser(context.Background(), "root"), wantOwner: "user:root", wantLimit: 0},
+		{name: "user without a tier", ctx: auth.WithUser(context.Background(), "bob"), wantOwner: "user:bob", wantLimit: 100},
+		{name: "API key with a tier", ctx: auth.WithAPIKeyHash(context.Background(), "abcd"), wantOwner: "key:abcd", wantLimit: 5000},
+		{name: "API key without a tier", ctx: auth.WithAPIKeyHash(context.Background(), "ef01"), wantOwner: "key:ef01", wantLimit: 100},
+		{name: "anonymous", ctx: context.Background(), wantOwner: "", wantLimit: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			owner, limit := policy.owner(tc.ctx)
+			if owner != tc.wantOwner || limit != tc.wantLimit {
+				t.Errorf("owner() = %q, %d, want %q, %d", owner, limit, tc.wantOwner, tc.wantLimit)
+			}
+		})
+	}
+}
+
+func TestQuotaError(t *testing.T) {
+	err := quotaError(&storage.QuotaExceededError{Usage: 150, Limit: 200})
+	if connect.CodeOf(err) != connect.CodeResourceExhausted {
+		t.Errorf("quotaError() = %v, want %v", err, connect.CodeResourceExhausted)
+	}
+	if reason := apierr.ReasonOf(err); reason != filev1.ErrorReason_ERROR_REASON_QUOTA_EXCEEDED {
+		t.Errorf("quotaError() reason = %v, want %v", reason, filev1.ErrorReason_ERROR_REASON_QUOTA_EXCEEDED)
+	}
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || len(connectErr.Details()) != 1 {
+		t.Fatalf("quotaError() = %v, want one detail", err)
+	}
+	value, detailErr := connectErr.Details()[0].Value()
+	info, ok := value.(*filev1.ErrorInfo)
+	if detailErr != nil || !ok || info.GetMetadata()["usage"] != "150" || info.GetMetadata()["limit"] != "200" {
+		t.Errorf("quotaError() detail = %v, want usage 150 and limit 200", value)
+	}
+
+	if err := quotaError(errors.New("connection refused")); connect.CodeOf(err) != connect.CodeInternal {
+		t.Errorf("quotaError() of a store error = %v, want %v", err, connect.CodeInternal)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/filecrypt"
+	"github.com/fawa-io/fawa/fileservice/pkg/requestid"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+const msgQuarantined = "the file is still being scanned for viruses, try again later"
+
+var (
+	// errScanEnded fails the writes to a scanner that returned before the end of the file
+	errScanEnded = errors.New("virus scan ended before the end of the file")
+	// errUploadAborted ends the scan of an upload that failed before the end of the file
+	errUploadAborted = errors.New("upload aborted before the end of the file")
+)
+
+// Scanner scans file contents for malware, *clamav.Client implements it
+type Scanner interface {
+	// Scan returns the name of the malware found in r, empty when r is clean
+	Scan(ctx context.Context, r io.Reader) (string, error)
+}
+
+type scanResult struct {
+	virus string
+	err   error
+}
+
+// startScan scans what is written to the returned writer. Close it at the end of the file,
+// or with the error the upload failed with, then wait returns the verdict.
+func (s *FileServiceHandler) startScan(ctx context.Context) (*io.PipeWriter, func() (string, error)) {
+	pr, pw := io.Pipe()
+	result := make(chan scanResult, 1)
+	go func() {
+		virus, err := s.Scanner.Scan(ctx, pr)
+		// Never leave the upload blocked on a scanner that stopped reading
+		if err != nil {
+			_ = pr.CloseWithError(err)
+		} else {
+			_ = pr.CloseWithError(errScanEnded)
+		}
+		result <- scanResult{virus: virus, err: err}
+	}()
+	return pw, func() (string, error) {
+		r := <-result
+		return r.virus, r.err
+	}
+}
+
+// checkScan turns the verdict of the scan of an upload into its error, the object is removed
+// unless it's clean
+func checkScan(ctx context.Context, metadata *storage.FileMetadata, virus string, err error) error {
+	log := requestid.Logger(ctx)
+	if err != nil {
+		log.Errorf("Failed to scan %s for viruses: %v", metadata.StoragePath, err)
+		removeUploadedFile(metadata.Bucket, metadata.StoragePath)
+		return apierr.Unavailable("the virus scan failed, try again later")
+	}
+	if virus != "" {
+		log.Warnf("Rejected upload %s infected with %s", metadata.StoragePath, virus)
+		removeUploadedFile(metadata.Bucket, metadata.StoragePath)
+		return apierr.WithReason(apierr.FailedPrecondition(fmt.Sprintf("the file is infected with %s", virus)),
+			filev1.ErrorReason_ERROR_REASON_INFECTED, map[string]string{"virus": virus})
+	}
+	return nil
+}
+
+// scanQuarantined scans a file shared quarantined by an asynchronous scan. A clean file is
+// released, an infected one is unshared and removed. A file that couldn't be scanned stays
+// quarantined until its metadata expires.
+func (s *FileServiceHandler) scanQuarantined(log fwlog.Logger, key string, metadata *storage.FileMetadata) {
+	ctx := context.Background()
+	virus, err := s.scanObject(ctx, metadata)
+	if err != nil {
+		log.Errorf("Failed to scan %s for viruses, it stays quarantined: %v", metadata.StoragePath, err)
+		return
+	}
+	if virus != "" {
+		log.Warnf("Removing upload %s infected with %s", metadata.StoragePath, virus)
+		if err := storage.DeleteFileMeta(ctx, key, metadata); err != nil {
+			log.Errorf("Failed to unshare infected upload %s: %v", metadata.StoragePath, err)
+		}
+		removeUploadedFile(metadata.Bucket, metadata.StoragePath)
+		removeThumbnail(metadata)
+		return
+	}
+	metadata.Quarantined = false
+	if err := storage.UpdateFileMeta(ctx, key, metadata); err != nil {
+		log.Errorf("Failed to release %s from quarantine: %v", metadata.StoragePath, err)
+		return
+	}
+	log.Infof("Released %s from quarantine", metadata.StoragePath)
+}
+
+// scanObject scans the stored object of a file, decrypted
+func (s *FileServiceHandler) scanObject(ctx context.Context, metadata *storage.FileMetadata) (string, error) {
+	object, _, err := storage.GetFile(ctx, metadata.Bucket, metadata.StoragePath)
+	if err != nil {
+		return "", err
+	}
+	defer object.Close()
+
+	var content io.Reader = object
+	if metadata.EncryptionNonce != nil {
+		if content, err = filecrypt.NewDecryptingReader(s.EncryptionKey, metadata.EncryptionNonce, object); err != nil {
+			return "", err
+		}
+	}
+	return s.Scanner.Scan(ctx, content)
+}
+
+// checkQuarantine keeps files awaiting their virus scan from being downloaded
+func checkQuarantine(metadata *storage.FileMetadata) error {
+	if metadata.Quarantined {
+		return apierr.WithReason(apierr.FailedPrecondition(msgQuarantined), filev1.ErrorReason_ERROR_REASON_QUARANTINED, nil)
+	}
+	return nil
+}
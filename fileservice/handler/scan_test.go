@@ -0,0 +1,125 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+// fakeScanner finds virus in contents containing signature
+type fakeScanner struct {
+	signature string
+	virus     string
+	err       error
+}
+
+func (f *fakeScanner) Scan(_ context.Context, r io.Reader) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(string(content), f.signature) {
+		return f.virus, nil
+	}
+	return "", nil
+}
+
+func TestStartScan(t *testing.T) {
+	s := &FileServiceHandler{Scanner: &fakeScanner{signature: "EICAR", virus: "Eicar-Test-Signature"}}
+
+	testCases := []struct {
+		name     string
+		content  string
+		closeErr error
+		want     string
+		wantErr  bool
+	}{
+		{name: "clean", content: "hello world"},
+		{name: "infected", content: "hello EICAR", want: "Eicar-Test-Signature"},
+		{name: "upload failed", content: "hello", closeErr: errUploadAborted, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, wait := s.startScan(context.Background())
+			if _, err := io.WriteString(w, tc.content); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			_ = w.CloseWithError(tc.closeErr)
+
+			got, err := wait()
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Errorf("wait() = %q, %v, want %q, error %v", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStartScanFailureUnblocksWrites(t *testing.T) {
+	s := &FileServiceHandler{Scanner: &fakeScanner{err: errors.New("clamd unreachable")}}
+
+	w, wait := s.startScan(context.Background())
+	// The scanner returned without reading, the upload must fail instead of hanging
+	if _, err := io.WriteString(w, "hello"); err == nil {
+		t.Error("Write() should fail once the scan failed")
+	}
+	if _, err := wait(); err == nil {
+		t.Error("wait() should return the scan error")
+	}
+}
+
+func TestCheckScan(t *testing.T) {
+	metadata := &storage.FileMetadata{StoragePath: "a.txt"}
+
+	if err := checkScan(context.Background(), metadata, "", nil); err != nil {
+		t.Errorf("checkScan() of a clean file error = %v", err)
+	}
+	err := checkScan(context.Background(), metadata, "Eicar-Test-Signature", nil)
+	if connect.CodeOf(err) != connect.CodeFailedPrecondition || !strings.Contains(err.Error(), "Eicar-Test-Signature") {
+		t.Errorf("checkScan() of an infected file error = %v, want %v naming the detection", err, connect.CodeFailedPrecondition)
+	}
+	if reason := apierr.ReasonOf(err); reason != filev1.ErrorReason_ERROR_REASON_INFECTED {
+		t.Errorf("checkScan() of an infected file reason = %v, want %v", reason, filev1.ErrorReason_ERROR_REASON_INFECTED)
+	}
+	if err := checkScan(context.Background(), metadata, "", errors.New("timeout")); connect.CodeOf(err) != connect.CodeUnavailable {
+		t.Errorf("checkScan() of a failed scan error = %v, want %v", err, connect.CodeUnavailable)
+	}
+}
+
+func TestCheckQuarantine(t *testing.T) {
+	if err := checkQuarantine(&storage.FileMetadata{}); err != nil {
+		t.Errorf("checkQuarantine() of a scanned file error = %v", err)
+	}
+	err := checkQuarantine(&storage.FileMetadata{Quarantined: true})
+	if connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Errorf("checkQuarantine() of a quarantined file error = %v, want %v", err, connect.CodeFailedPrecondition)
+	}
+	if reason := apierr.ReasonOf(err); reason != filev1.ErrorReason_ERROR_REASON_QUARANTINED {
+		t.Errorf("checkQuarantine() of a quarantined file reason = %v, want %v", reason, filev1.ErrorReason_ERROR_REASON_QUARANTINED)
+	}
+}
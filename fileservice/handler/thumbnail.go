@@ -0,0 +1,115 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/requestid"
+	"github.com/fawa-io/fawa/fileservice/pkg/thumbnail"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+// thumbnailPrefix prefixes the download key to name the thumbnail object. Uploads can't use
+// names under it, see validObjectName, so a thumbnail can't be replaced by a user file.
+const thumbnailPrefix = ".thumbnails/"
+
+// storeThumbnail stores a thumbnail of an uploaded image next to it and returns its object name.
+// Thumbnails are best effort: images that can't be decoded, encrypted files and storage errors
+// are logged and return an empty name, the upload goes on without a thumbnail.
+func (s *FileServiceHandler) storeThumbnail(ctx context.Context, key string, metadata *storage.FileMetadata) string {
+	// A plain thumbnail would leak the content of an encrypted file
+	if s.ThumbnailSize <= 0 || metadata.EncryptionNonce != nil || !strings.HasPrefix(metadata.ContentType, "image/") {
+		return ""
+	}
+	log := requestid.Logger(ctx)
+
+	object, _, err := storage.GetFile(ctx, metadata.Bucket, metadata.StoragePath)
+	if err != nil {
+		log.Warnf("Failed to read %s for its thumbnail: %v", metadata.StoragePath, err)
+		return ""
+	}
+	defer func() {
+		if closeErr := object.Close(); closeErr != nil {
+			log.Warnf("Failed to close object %s: %v", metadata.StoragePath, closeErr)
+		}
+	}()
+
+	data, err := thumbnail.Generate(object, s.ThumbnailSize)
+	if err != nil {
+		log.Infof("Skipping the thumbnail of %s: %v", metadata.StoragePath, err)
+		return ""
+	}
+
+	path := thumbnailPrefix + key
+	if _, err := storage.UploadFile(ctx, path, bytes.NewReader(data), int64(len(data)), storage.UploadOptions{
+		Bucket:       metadata.Bucket,
+		ContentType:  thumbnail.ContentType,
+		StorageClass: metadata.StorageClass,
+	}); err != nil {
+		log.Warnf("Failed to store the thumbnail of %s: %v", metadata.StoragePath, err)
+		return ""
+	}
+	return path
+}
+
+// removeThumbnail removes the thumbnail of a file that isn't shared after all
+func removeThumbnail(metadata *storage.FileMetadata) {
+	if metadata.ThumbnailPath != "" {
+		removeUploadedFile(metadata.Bucket, metadata.ThumbnailPath)
+	}
+}
+
+// GetThumbnailURL returns a presigned URL of the thumbnail of an uploaded image.
+func (s *FileServiceHandler) GetThumbnailURL(
+	ctx context.Context,
+	req *connect.Request[filev1.GetThumbnailURLRequest],
+) (*connect.Response[filev1.GetThumbnailURLResponse], error) {
+	log := requestid.Logger(ctx)
+	randomkey := req.Msg.Randomkey
+	if randomkey == "" {
+		return nil, apierr.InvalidArgument(msgEmptyRandomkey)
+	}
+
+	metadata, err := storage.GetFileMeta(ctx, randomkey)
+	if err != nil {
+		log.Debugf("Failed to get file metadata for key %s: %v", randomkey, err)
+		return nil, errFileNotFound()
+	}
+	if err := checkOwner(ctx, metadata); err != nil {
+		return nil, err
+	}
+	if err := checkQuarantine(metadata); err != nil {
+		return nil, err
+	}
+	if metadata.ThumbnailPath == "" {
+		return nil, apierr.WithReason(apierr.NotFound("the file has no thumbnail"), filev1.ErrorReason_ERROR_REASON_NO_THUMBNAIL, nil)
+	}
+
+	// No filename, browsers display the thumbnail inline
+	presignedURL, err := storage.GetPresignedURL(ctx, metadata.Bucket, metadata.ThumbnailPath, downloadURLExpiry,
+		storage.DownloadOptions{ContentType: thumbnail.ContentType})
+	if err != nil {
+		log.Errorf("Failed to generate presigned URL for %s: %v", metadata.ThumbnailPath, err)
+		return nil, storageError(err, "could not generate thumbnail link")
+	}
+	return connect.NewResponse(&filev1.GetThumbnailURLResponse{Url: presignedURL.String()}), nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+func TestStoreThumbnailSkipped(t *testing.T) {
+	testCases := []struct {
+		name     string
+		size     int
+		metadata *storage.FileMetadata
+	}{
+		{name: "disabled", metadata: &storage.FileMetadata{StoragePath: "a.png", ContentType: "image/png"}},
+		{name: "not an image", size: 256, metadata: &storage.FileMetadata{StoragePath: "a.txt", ContentType: "text/plain"}},
+		{
+			name:     "encrypted",
+			size:     256,
+			metadata: &storage.FileMetadata{StoragePath: "a.png", ContentType: "image/png", EncryptionNonce: []byte{1}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The object store isn't initialized, reaching it would fail the test
+			s := &FileServiceHandler{ThumbnailSize: tc.size}
+			if got := s.storeThumbnail(context.Background(), "abc", tc.metadata); got != "" {
+				t.Errorf("storeThumbnail() = %q, want no thumbnail", got)
+			}
+		})
+	}
+}
+
+func TestGetThumbnailURLEmptyKey(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(&FileServiceHandler{}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+	_, err := client.GetThumbnailURL(context.Background(), connect.NewRequest(&filev1.GetThumbnailURLRequest{}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("GetThumbnailURL() error = %v, want %v", err, connect.CodeInvalidArgument)
+	}
+}
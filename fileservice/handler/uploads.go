@@ -0,0 +1,126 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+const (
+	// defaultDrainTimeout is how long Close waits for in-flight uploads
+	defaultDrainTimeout = 30 * time.Second
+	// cancelGracePeriod is how long a drain waits for cancelled uploads to clean up
+	cancelGracePeriod = 2 * time.Second
+	// cleanupTimeout bounds the removal of a partial object
+	cleanupTimeout = 10 * time.Second
+)
+
+// uploadTracker keeps track of in-flight uploads so they can be drained on shutdown
+type uploadTracker struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	draining bool
+	nextID   uint64
+	cancels  map[uint64]context.CancelFunc
+}
+
+// begin registers an upload and returns its context, cancelled when a drain gives up on it.
+// done must be called once the upload has finished.
+func (t *uploadTracker) begin(ctx context.Context) (context.Context, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return nil, nil, apierr.WithReason(apierr.Unavailable("file service is shutting down"), filev1.ErrorReason_ERROR_REASON_SHUTTING_DOWN, nil)
+	}
+	if t.cancels == nil {
+		t.cancels = make(map[uint64]context.CancelFunc)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	t.nextID++
+	id := t.nextID
+	t.cancels[id] = cancel
+	t.wg.Add(1)
+
+	done := func() {
+		t.mu.Lock()
+		delete(t.cancels, id)
+		t.mu.Unlock()
+		cancel()
+		t.wg.Done()
+	}
+	return ctx, done, nil
+}
+
+// drain rejects new uploads and waits for the in-flight ones until ctx is done,
+// then cancels the remaining ones and gives them cancelGracePeriod to clean up.
+func (t *uploadTracker) drain(ctx context.Context) error {
+	t.mu.Lock()
+	t.draining = true
+	t.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	fwlog.Warnf("Cancelling %d in-flight uploads", len(t.cancels))
+	for _, cancel := range t.cancels {
+		cancel()
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-finished:
+	case <-time.After(cancelGracePeriod):
+		fwlog.Warnf("In-flight uploads did not stop within %v", cancelGracePeriod)
+	}
+	return ctx.Err()
+}
+
+// removePartialUpload frees the parts of an upload that failed midway
+func removePartialUpload(bucketName, objectName string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if err := storage.RemoveIncompleteUpload(ctx, bucketName, objectName); err != nil {
+		fwlog.Warnf("Failed to remove partial upload of %s: %v", objectName, err)
+	}
+}
+
+// removeUploadedFile deletes an object whose upload completed but was never shared
+func removeUploadedFile(bucketName, objectName string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if err := storage.RemoveFile(ctx, bucketName, objectName); err != nil {
+		fwlog.Warnf("Failed to remove unshared upload %s: %v", objectName, err)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+	"github.com/fawa-io/fawa/fileservice/pkg/metrics"
+	"github.com/fawa-io/fawa/fileservice/storage"
+)
+
+func TestUploadTrackerDrainWaitsForUploads(t *testing.T) {
+	var tracker uploadTracker
+	_, done, err := tracker.begin(context.Background())
+	if err != nil {
+		t.Fatalf("begin() error = %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		done()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := tracker.drain(ctx); err != nil {
+		t.Fatalf("drain() error = %v", err)
+	}
+
+	if _, _, err := tracker.begin(context.Background()); connect.CodeOf(err) != connect.CodeUnavailable {
+		t.Errorf("begin() after drain error = %v, want code %v", err, connect.CodeUnavailable)
+	}
+}
+
+func TestUploadTrackerDrainCancelsUploads(t *testing.T) {
+	var tracker uploadTracker
+	uploadCtx, done, err := tracker.begin(context.Background())
+	if err != nil {
+		t.Fatalf("begin() error = %v", err)
+	}
+
+	// The upload only stops once it is cancelled
+	go func() {
+		<-uploadCtx.Done()
+		done()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tracker.drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("drain() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !errors.Is(uploadCtx.Err(), context.Canceled) {
+		t.Errorf("upload context error = %v, want %v", uploadCtx.Err(), context.Canceled)
+	}
+}
+
+// fakeBucket is an in-memory S3 bucket speaking just enough of the API for an upload
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	puts    int
+	// cleanups counts the listings of incomplete uploads done to remove them
+	cleanups int
+}
+
+func (f *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch {
+	case key == "" && r.URL.Query().Has("location"):
+		_, _ = io.WriteString(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+	case key == "" && r.URL.Query().Has("uploads"):
+		f.mu.Lock()
+		f.cleanups++
+		f.mu.Unlock()
+		_, _ = io.WriteString(w, `<ListMultipartUploadsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></ListMultipartUploadsResult>`)
+	case key == "":
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut:
+		f.mu.Lock()
+		f.puts++
+		f.mu.Unlock()
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.objects[key] = data
+		f.mu.Unlock()
+		w.Header().Set("ETag", `"etag"`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// waitFor polls cond until it holds or a few seconds passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSendFileCancelRemovesPartialUpload(t *testing.T) {
+	t.Setenv("FAWA_STORAGE_TEST_MODE", "")
+	bucket := &fakeBucket{objects: make(map[string][]byte)}
+	s3 := httptest.NewServer(bucket)
+	defer s3.Close()
+	err := storage.InitMinIO(storage.MinIOConfig{
+		Endpoint:        strings.TrimPrefix(s3.URL, "http://"),
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		BucketName:      "uploads",
+	})
+	if err != nil {
+		t.Fatalf("InitMinIO() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(&FileServiceHandler{}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	cancelled := metrics.FileUploads.Value(connect.CodeCanceled.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := filev1connect.NewFileServiceClient(srv.Client(), srv.URL).SendFile(ctx)
+	messages := []*filev1.SendFileRequest{
+		{Payload: &filev1.SendFileRequest_Info{Info: &filev1.FileInfo{Name: "partial.txt", Size: 1 << 10}}},
+		{Payload: &filev1.SendFileRequest_ChunkData{ChunkData: []byte("hello")}},
+	}
+	for _, msg := range messages {
+		if err := stream.Send(msg); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	bucketState := func(f func() bool) func() bool {
+		return func() bool {
+			bucket.mu.Lock()
+			defer bucket.mu.Unlock()
+			return f()
+		}
+	}
+	waitFor(t, "the upload to start", bucketState(func() bool { return bucket.puts > 0 }))
+
+	cancel()
+	if _, err := stream.CloseAndReceive(); connect.CodeOf(err) != connect.CodeCanceled {
+		t.Errorf("SendFile() error = %v, want %v", err, connect.CodeCanceled)
+	}
+
+	// The server side outcome is only visible through the metrics
+	waitFor(t, "the server to end the upload as cancelled", func() bool {
+		return metrics.FileUploads.Value(connect.CodeCanceled.String()) > cancelled
+	})
+	if !bucketState(func() bool { return bucket.cleanups > 0 })() {
+		t.Error("the partial upload was not removed")
+	}
+	if bucketState(func() bool { return len(bucket.objects) != 0 })() {
+		t.Error("the cancelled upload left an object in the bucket")
+	}
+}
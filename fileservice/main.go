@@ -19,16 +19,27 @@ import (
 	"errors"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"reflect"
+	"strings"
 	"time"
 
-	"github.com/fawa-io/fwpkg/cors"
+	"connectrpc.com/connect"
 	"github.com/fawa-io/fwpkg/fwlog"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/fawa-io/fawa/fileservice/config"
 	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
 	file "github.com/fawa-io/fawa/fileservice/handler"
+	"github.com/fawa-io/fawa/fileservice/pkg/auth"
+	"github.com/fawa-io/fawa/fileservice/pkg/clamav"
+	"github.com/fawa-io/fawa/fileservice/pkg/clientip"
+	"github.com/fawa-io/fawa/fileservice/pkg/filecrypt"
+	"github.com/fawa-io/fawa/fileservice/pkg/metrics"
+	"github.com/fawa-io/fawa/fileservice/pkg/ratelimit"
+	"github.com/fawa-io/fawa/fileservice/pkg/requestid"
+	"github.com/fawa-io/fawa/fileservice/server"
+	"github.com/fawa-io/fawa/fileservice/storage"
 )
 
 func main() {
@@ -45,62 +56,214 @@ func main() {
 	fwlog.SetLevel(logLevel)
 	fwlog.Infof("Logger initialized with level: %s", cfg.LogLevel)
 
-	fileSvcHdr := &file.FileServiceHandler{}
-	fileProcedure, fileHandler := filev1connect.NewFileServiceHandler(fileSvcHdr)
-
-	mux := http.NewServeMux()
-	mux.Handle(fileProcedure, fileHandler)
+	tlsConfig, err := cfg.LoadTLSConfig()
+	if err != nil {
+		fwlog.Fatalf("Invalid TLS configuration: %v", err)
+	}
 
-	fileSrv := &http.Server{
-		Addr:    cfg.Addr,
-		Handler: cors.NewCORS().Handler(mux),
+	if err := storage.InitMinIO(storage.MinIOConfig(cfg.MinIO)); err != nil {
+		fwlog.Fatalf("Failed to initialize MinIO: %v", err)
 	}
+	if err := storage.InitDragonfly(storage.DragonflyConfig(cfg.Dragonfly)); err != nil {
+		fwlog.Warnf("Failed to close the default Dragonfly client: %v", err)
+	}
+	pingCtx, cancelPing := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := storage.PingDragonfly(pingCtx); err != nil {
+		addr := cfg.Dragonfly.Addr
+		if len(cfg.Dragonfly.ClusterAddrs) > 0 {
+			addr = "cluster " + strings.Join(cfg.Dragonfly.ClusterAddrs, ",")
+		}
+		fwlog.Fatalf("Cannot reach Dragonfly at %s (db %d, tls %v), check dragonfly.addr, dragonfly.password and dragonfly.tls: %v",
+			addr, cfg.Dragonfly.DB, cfg.Dragonfly.TLS, err)
+	}
+	cancelPing()
 
-	go func() {
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-		<-sigCh
+	if cfg.PublicEndpoint != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := storage.SetPublicEndpoint(ctx, cfg.PublicEndpoint); err != nil {
+			fwlog.Fatalf("Failed to configure public endpoint: %v", err)
+		}
+		cancel()
+		fwlog.Infof("Download URLs are signed for %s", cfg.PublicEndpoint)
+	}
 
-		fwlog.Info("Shutting down server...")
+	if cfg.OrphanGCInterval > 0 {
+		go collectOrphans(cfg.OrphanGCInterval)
+	}
 
-		// Close file service
-		if err := fileSvcHdr.Close(); err != nil {
-			fwlog.Errorf("Error closing file service: %v", err)
+	var encryptionKey []byte
+	if cfg.EncryptionKey != "" {
+		if encryptionKey, err = filecrypt.ParseKey(cfg.EncryptionKey); err != nil {
+			fwlog.Fatalf("Invalid encryption key: %v", err)
 		}
+		fwlog.Infof("Encrypted uploads enabled")
+	}
 
-		// Set timeout for HTTP server shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+	proxies, err := clientip.New(cfg.TrustedProxies)
+	if err != nil {
+		fwlog.Fatalf("Invalid trusted proxies: %v", err)
+	}
 
-		if err := fileSrv.Shutdown(ctx); err != nil {
-			fwlog.Errorf("Server shutdown error: %v", err)
+	// Leave half of the shutdown budget to the storage and server shutdown
+	fileSvcHdr := &file.FileServiceHandler{
+		DrainTimeout:           cfg.ShutdownTimeout / 2,
+		ChunkSize:              cfg.ChunkSize,
+		EncryptionKey:          encryptionKey,
+		MaxConcurrentDownloads: cfg.MaxConcurrentDownloads,
+		MaxDownloadURLExpiry:   cfg.MaxDownloadURLExpiry,
+		MaxLinkTTL:             cfg.MaxLinkTTL,
+		FileTypes:              file.FileTypePolicy(cfg.FileTypes),
+		Quota:                  newQuotaPolicy(cfg.Quota),
+		ThumbnailSize:          cfg.ThumbnailSize,
+		ClientIP:               proxies.ClientIP,
+		// Presigned URLs point at the internal MinIO address without a public endpoint
+		RedirectDownloads: cfg.PublicEndpoint != "",
+	}
+	if cfg.Scan.ClamdAddr != "" {
+		fileSvcHdr.Scanner = &clamav.Client{Addr: cfg.Scan.ClamdAddr, Timeout: cfg.Scan.Timeout}
+		fileSvcHdr.ScanAsync = cfg.Scan.Mode == "async"
+	}
+	rateLimiter, setRateLimits := newRateLimiter(cfg.RateLimit)
+	rateLimiter.ClientIP = proxies.ClientIP
+	fileSvcHdr.RateLimiter = rateLimiter
+	config.OnChange(func(prev, next config.Config) {
+		if reflect.DeepEqual(prev.RateLimit, next.RateLimit) {
+			return
+		}
+		if prev.RateLimit.MaxClients != next.RateLimit.MaxClients {
+			fwlog.Warnf("Config rateLimit.maxClients changed, restart required to apply it")
+		}
+		setRateLimits(next.RateLimit)
+		fwlog.Infof("Rate limits reloaded")
+	})
+	// The request ID comes first so the other interceptors and the handlers can log it
+	interceptors := []connect.Interceptor{requestid.NewInterceptor(), rateLimiter}
+	apiKeys, err := auth.NewKeys(cfg.Auth.APIKeys, cfg.Auth.APIKeyHashes)
+	if err != nil {
+		fwlog.Fatalf("Invalid API keys: %v", err)
+	}
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.Auth.JWTPublicKeyFile != "" {
+		pemData, err := os.ReadFile(cfg.Auth.JWTPublicKeyFile)
+		if err != nil {
+			fwlog.Fatalf("Failed to read the JWT public key: %v", err)
+		}
+		if jwtVerifier, err = auth.NewJWTVerifier(pemData); err != nil {
+			fwlog.Fatalf("Invalid JWT public key: %v", err)
 		}
+	}
+	var authenticator *auth.Interceptor
+	if apiKeys.Len() > 0 || jwtVerifier != nil {
+		// After the rate limiter, which slows down key guessing
+		authenticator = auth.NewInterceptor(apiKeys, cfg.Auth.PublicProcedures...)
+		authenticator.JWT = jwtVerifier
+		interceptors = append(interceptors, authenticator)
+		fwlog.Infof("Authentication enabled with %d API keys, JWTs accepted: %v", apiKeys.Len(), jwtVerifier != nil)
+	} else {
+		fwlog.Warnf("No API keys configured, the file service accepts every request")
+	}
+	fileProcedure, fileHandler := filev1connect.NewFileServiceHandler(fileSvcHdr, connect.WithInterceptors(interceptors...))
+
+	mux := http.NewServeMux()
+	mux.Handle(fileProcedure, fileHandler)
+	// Plain HTTP uploads share the limits and credentials of SendFile
+	httpGuard := file.HTTPGuard{RateLimiter: rateLimiter, Auth: authenticator}
+	mux.Handle("/upload", httpGuard.Handler(filev1connect.FileServiceSendFileProcedure, fileSvcHdr.UploadHTTP))
+	mux.Handle("GET /download/{key}", httpGuard.Handler(filev1connect.FileServiceReceiveFileProcedure, fileSvcHdr.DownloadHTTP))
+	mux.HandleFunc("/healthz", file.Healthz)
+	mux.HandleFunc("/readyz", file.Readyz)
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/admin/objects", (&file.AdminHandler{Token: cfg.AdminToken}).ListObjects)
+
+	fileSrv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           server.NewCORS(cfg.CORS, cfg.DevMode).Handler(mux),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
 
-		fwlog.Info("Server shutdown complete")
+	shutdowner := server.NewShutdowner(cfg.ShutdownTimeout)
+	shutdowner.RegisterCloser(server.StageServices, "file service", fileSvcHdr)
+	shutdowner.RegisterServer("file server", fileSrv)
+	go func() {
+		shutdowner.WaitForSignal()
 		os.Exit(0)
 	}()
 
 	fwlog.Infof("Server starting on %v", cfg.Addr)
 
-	// Check if certificate files exist
-	if cfg.CertFile != "" && cfg.KeyFile != "" {
-		// Check if certificate files actually exist
-		if _, err := os.Stat(cfg.CertFile); err == nil {
-			if _, err := os.Stat(cfg.KeyFile); err == nil {
-				// Start the HTTPS server.
-				fwlog.Infof("Starting HTTPS server with certificates: %s, %s", cfg.CertFile, cfg.KeyFile)
-				if err := fileSrv.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
-					fwlog.Fatalf("Failed to start HTTPS server: %v", err)
-				}
-				return
-			}
+	if tlsConfig != nil {
+		// Start the HTTPS server.
+		fileSrv.TLSConfig = tlsConfig
+		fwlog.Infof("Starting HTTPS server with certificates: %s, %s", cfg.CertFile, cfg.KeyFile)
+		if err := fileSrv.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fwlog.Fatalf("Failed to start HTTPS server: %v", err)
 		}
-		fwlog.Warnf("Certificate files not found, falling back to HTTP mode")
+		return
 	}
 
-	// Start the HTTP server.
-	fwlog.Infof("Starting HTTP server")
+	// Start the HTTP server. h2c serves cleartext HTTP/2 so Connect streaming works without certificates.
+	fileSrv.Handler = h2c.NewHandler(fileSrv.Handler, &http2.Server{})
+	fwlog.Infof("Starting HTTP server (h2c enabled)")
 	if err := fileSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		fwlog.Fatalf("Failed to start HTTP server: %v", err)
 	}
 }
+
+// collectOrphans removes the objects whose metadata expired every interval, Dragonfly forgets
+// the files but MinIO keeps them until then
+func collectOrphans(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		removed, err := storage.CollectOrphans(ctx)
+		cancel()
+		for _, object := range removed {
+			fwlog.Infof("Removed orphaned object %s", object)
+		}
+		if err != nil {
+			fwlog.Warnf("Orphan collection stopped after %d objects: %v", len(removed), err)
+		}
+	}
+}
+
+// newRateLimiter limits SendFile, SendFiles and ExtendLink with the upload budget and the other file calls with the download budget.
+// The returned func applies new budgets to the running limiters, MaxClients is only read here.
+func newRateLimiter(c config.RateLimitConfig) (*ratelimit.Interceptor, func(config.RateLimitConfig)) {
+	upload := ratelimit.NewLimiter(ratelimit.Limit(c.Upload), c.MaxClients)
+	download := ratelimit.NewLimiter(ratelimit.Limit(c.Download), c.MaxClients)
+	interceptor := ratelimit.NewInterceptor(map[string]*ratelimit.Limiter{
+		filev1connect.FileServiceSendFileProcedure:        upload,
+		filev1connect.FileServiceSendFilesProcedure:       upload,
+		filev1connect.FileServiceReceiveFileProcedure:     download,
+		filev1connect.FileServiceGetDownloadURLProcedure:  download,
+		filev1connect.FileServiceGetFileInfoProcedure:     download,
+		filev1connect.FileServiceGetThumbnailURLProcedure: download,
+		filev1connect.FileServiceExtendLinkProcedure:      upload,
+	})
+	return interceptor, func(c config.RateLimitConfig) {
+		upload.SetLimit(ratelimit.Limit(c.Upload))
+		download.SetLimit(ratelimit.Limit(c.Download))
+	}
+}
+
+// newQuotaPolicy looks up the quota of each user and API key of the tiers
+func newQuotaPolicy(c config.QuotaConfig) file.QuotaPolicy {
+	policy := file.QuotaPolicy{
+		Default: c.Default,
+		Users:   make(map[string]int64),
+		APIKeys: make(map[string]int64),
+	}
+	for _, tier := range c.Tiers {
+		for _, user := range tier.Users {
+			policy.Users[user] = tier.Limit
+		}
+		for _, hash := range tier.APIKeyHashes {
+			policy.APIKeys[strings.ToLower(hash)] = tier.Limit
+		}
+	}
+	return policy
+}
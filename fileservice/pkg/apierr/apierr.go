@@ -0,0 +1,233 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apierr maps domain errors to Connect error codes so that handlers
+// report the same condition with the same code and message.
+package apierr
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+)
+
+// Domain errors. Lower layers wrap these with fmt.Errorf("...: %w", ErrX)
+// and handlers convert them with From.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrFailedPrecondition = errors.New("failed precondition")
+	ErrResourceExhausted  = errors.New("resource exhausted")
+	ErrUnauthenticated    = errors.New("unauthenticated")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrUnavailable        = errors.New("unavailable")
+)
+
+var codes = []struct {
+	err  error
+	code connect.Code
+}{
+	{ErrNotFound, connect.CodeNotFound},
+	{ErrInvalidArgument, connect.CodeInvalidArgument},
+	{ErrFailedPrecondition, connect.CodeFailedPrecondition},
+	{ErrResourceExhausted, connect.CodeResourceExhausted},
+	{ErrUnauthenticated, connect.CodeUnauthenticated},
+	{ErrPermissionDenied, connect.CodePermissionDenied},
+	{ErrUnavailable, connect.CodeUnavailable},
+}
+
+// New returns a Connect error for the domain error kind.
+// An empty msg falls back to the kind's own message.
+func New(kind error, msg string, details ...proto.Message) *connect.Error {
+	if msg == "" {
+		msg = kind.Error()
+	}
+	return WithDetails(connect.NewError(codeOf(kind), errors.New(msg)), details...)
+}
+
+// NotFound reports a missing or expired resource
+func NotFound(msg string) *connect.Error {
+	return New(ErrNotFound, msg)
+}
+
+// InvalidArgument reports a malformed request
+func InvalidArgument(msg string) *connect.Error {
+	return New(ErrInvalidArgument, msg)
+}
+
+// FailedPrecondition reports a request rejected because of the current state
+func FailedPrecondition(msg string) *connect.Error {
+	return New(ErrFailedPrecondition, msg)
+}
+
+// ResourceExhausted reports an exceeded limit or quota
+func ResourceExhausted(msg string) *connect.Error {
+	return New(ErrResourceExhausted, msg)
+}
+
+// Unauthenticated reports missing or invalid credentials
+func Unauthenticated(msg string) *connect.Error {
+	return New(ErrUnauthenticated, msg)
+}
+
+// PermissionDenied reports valid credentials lacking access
+func PermissionDenied(msg string) *connect.Error {
+	return New(ErrPermissionDenied, msg)
+}
+
+// Unavailable reports a service that can't take the request right now
+func Unavailable(msg string) *connect.Error {
+	return New(ErrUnavailable, msg)
+}
+
+// Internal wraps an unexpected error
+func Internal(err error) *connect.Error {
+	return connect.NewError(connect.CodeInternal, err)
+}
+
+// From converts any error into a Connect error.
+// Connect errors pass through unchanged, context errors keep their meaning,
+// wrapped domain errors get their mapped code and anything else is internal.
+func From(err error) *connect.Error {
+	if err == nil {
+		return nil
+	}
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		return connectErr
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return connect.NewError(connect.CodeCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return connect.NewError(connect.CodeDeadlineExceeded, err)
+	}
+	// A transport timeout, such as a backend not answering in time
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return connect.NewError(connect.CodeDeadlineExceeded, err)
+	}
+	return connect.NewError(codeOf(err), err)
+}
+
+// WithDetails attaches details to err, skipping any that cannot be marshaled
+func WithDetails(err *connect.Error, details ...proto.Message) *connect.Error {
+	for _, d := range details {
+		detail, detailErr := connect.NewErrorDetail(d)
+		if detailErr != nil {
+			continue
+		}
+		err.AddDetail(detail)
+	}
+	return err
+}
+
+// WithReason attaches an ErrorInfo detail to err so that clients can branch on reason,
+// metadata may be nil
+func WithReason(err *connect.Error, reason filev1.ErrorReason, metadata map[string]string) *connect.Error {
+	return WithDetails(err, &filev1.ErrorInfo{Reason: reason, Metadata: metadata})
+}
+
+// ReasonOf returns the reason of the ErrorInfo detail of err,
+// ERROR_REASON_UNSPECIFIED when it has none
+func ReasonOf(err error) filev1.ErrorReason {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		return filev1.ErrorReason_ERROR_REASON_UNSPECIFIED
+	}
+	for _, detail := range connectErr.Details() {
+		if value, err := detail.Value(); err == nil {
+			if info, ok := value.(*filev1.ErrorInfo); ok {
+				return info.GetReason()
+			}
+		}
+	}
+	return filev1.ErrorReason_ERROR_REASON_UNSPECIFIED
+}
+
+// httpStatus is the HTTP status of each code, as sent by Connect for unary calls
+var httpStatus = map[connect.Code]int{
+	connect.CodeCanceled:           499,
+	connect.CodeUnknown:            http.StatusInternalServerError,
+	connect.CodeInvalidArgument:    http.StatusBadRequest,
+	connect.CodeDeadlineExceeded:   http.StatusGatewayTimeout,
+	connect.CodeNotFound:           http.StatusNotFound,
+	connect.CodeAlreadyExists:      http.StatusConflict,
+	connect.CodePermissionDenied:   http.StatusForbidden,
+	connect.CodeResourceExhausted:  http.StatusTooManyRequests,
+	connect.CodeFailedPrecondition: http.StatusPreconditionFailed,
+	connect.CodeAborted:            http.StatusConflict,
+	connect.CodeOutOfRange:         http.StatusBadRequest,
+	connect.CodeUnimplemented:      http.StatusNotImplemented,
+	connect.CodeInternal:           http.StatusInternalServerError,
+	connect.CodeUnavailable:        http.StatusServiceUnavailable,
+	connect.CodeDataLoss:           http.StatusInternalServerError,
+	connect.CodeUnauthenticated:    http.StatusUnauthorized,
+}
+
+// WriteHTTP answers a plain HTTP request with err converted by From. The body is the JSON
+// {"code", "message", "details"} of Connect unary errors and the error metadata is sent as headers.
+func WriteHTTP(w http.ResponseWriter, err error) {
+	connectErr := From(err)
+	status, ok := httpStatus[connectErr.Code()]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	WriteHTTPStatus(w, connectErr, status)
+}
+
+// httpDetail is an error detail in the JSON of Connect unary errors
+type httpDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// WriteHTTPStatus is WriteHTTP answering with status instead of the one of the error code
+func WriteHTTPStatus(w http.ResponseWriter, err error, status int) {
+	connectErr := From(err)
+	for key, values := range connectErr.Meta() {
+		w.Header()[key] = values
+	}
+	var details []httpDetail
+	for _, detail := range connectErr.Details() {
+		details = append(details, httpDetail{
+			Type:  detail.Type(),
+			Value: base64.RawStdEncoding.EncodeToString(detail.Bytes()),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Code    string       `json:"code"`
+		Message string       `json:"message"`
+		Details []httpDetail `json:"details,omitempty"`
+	}{connectErr.Code().String(), connectErr.Message(), details})
+}
+
+func codeOf(err error) connect.Code {
+	for _, c := range codes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return connect.CodeInternal
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apierr
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+)
+
+func TestFrom(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want connect.Code
+	}{
+		{name: "not found", err: fmt.Errorf("key abc: %w", ErrNotFound), want: connect.CodeNotFound},
+		{name: "invalid argument", err: fmt.Errorf("bad name: %w", ErrInvalidArgument), want: connect.CodeInvalidArgument},
+		{name: "failed precondition", err: fmt.Errorf("etag: %w", ErrFailedPrecondition), want: connect.CodeFailedPrecondition},
+		{name: "resource exhausted", err: fmt.Errorf("quota: %w", ErrResourceExhausted), want: connect.CodeResourceExhausted},
+		{name: "unauthenticated", err: fmt.Errorf("token: %w", ErrUnauthenticated), want: connect.CodeUnauthenticated},
+		{name: "permission denied", err: fmt.Errorf("scope: %w", ErrPermissionDenied), want: connect.CodePermissionDenied},
+		{name: "unavailable", err: fmt.Errorf("draining: %w", ErrUnavailable), want: connect.CodeUnavailable},
+		{name: "canceled", err: fmt.Errorf("upload: %w", context.Canceled), want: connect.CodeCanceled},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, want: connect.CodeDeadlineExceeded},
+		{
+			name: "transport timeout",
+			err:  fmt.Errorf("minio upload failed: %w", &url.Error{Op: "Put", URL: "http://minio:9000/fawa/a.txt", Err: os.ErrDeadlineExceeded}),
+			want: connect.CodeDeadlineExceeded,
+		},
+		{name: "connect error passes through", err: fmt.Errorf("wrapped: %w", connect.NewError(connect.CodeAborted, errors.New("x"))), want: connect.CodeAborted},
+		{name: "unknown error", err: errors.New("boom"), want: connect.CodeInternal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := From(tc.err)
+			if got.Code() != tc.want {
+				t.Errorf("From(%v).Code() = %v, want %v", tc.err, got.Code(), tc.want)
+			}
+		})
+	}
+
+	if From(nil) != nil {
+		t.Error("From(nil) should be nil")
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  *connect.Error
+		want connect.Code
+	}{
+		{name: "NotFound", err: NotFound("file not found"), want: connect.CodeNotFound},
+		{name: "InvalidArgument", err: InvalidArgument("bad"), want: connect.CodeInvalidArgument},
+		{name: "FailedPrecondition", err: FailedPrecondition("changed"), want: connect.CodeFailedPrecondition},
+		{name: "ResourceExhausted", err: ResourceExhausted("too many"), want: connect.CodeResourceExhausted},
+		{name: "Unauthenticated", err: Unauthenticated("no token"), want: connect.CodeUnauthenticated},
+		{name: "PermissionDenied", err: PermissionDenied("no access"), want: connect.CodePermissionDenied},
+		{name: "Unavailable", err: Unavailable("shutting down"), want: connect.CodeUnavailable},
+		{name: "Internal", err: Internal(errors.New("boom")), want: connect.CodeInternal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.err.Code() != tc.want {
+				t.Errorf("Code() = %v, want %v", tc.err.Code(), tc.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	err := New(ErrNotFound, "")
+	if err.Message() != ErrNotFound.Error() {
+		t.Errorf("Message() = %q, want %q", err.Message(), ErrNotFound.Error())
+	}
+
+	err = New(ErrInvalidArgument, "name too long", wrapperspb.String("name"))
+	if err.Message() != "name too long" {
+		t.Errorf("Message() = %q, want %q", err.Message(), "name too long")
+	}
+	if len(err.Details()) != 1 {
+		t.Fatalf("len(Details()) = %d, want 1", len(err.Details()))
+	}
+	value, detailErr := err.Details()[0].Value()
+	if detailErr != nil {
+		t.Fatalf("failed to decode detail: %v", detailErr)
+	}
+	if s, ok := value.(*wrapperspb.StringValue); !ok || s.GetValue() != "name" {
+		t.Errorf("detail = %v, want %q", value, "name")
+	}
+}
+
+func TestReason(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want filev1.ErrorReason
+	}{
+		{name: "with reason", err: WithReason(NotFound("gone"), filev1.ErrorReason_ERROR_REASON_FILE_NOT_FOUND, nil), want: filev1.ErrorReason_ERROR_REASON_FILE_NOT_FOUND},
+		{name: "other details", err: New(ErrNotFound, "gone", wrapperspb.String("a")), want: filev1.ErrorReason_ERROR_REASON_UNSPECIFIED},
+		{name: "no details", err: NotFound("gone"), want: filev1.ErrorReason_ERROR_REASON_UNSPECIFIED},
+		{name: "plain error", err: errors.New("boom"), want: filev1.ErrorReason_ERROR_REASON_UNSPECIFIED},
+		{name: "nil", err: nil, want: filev1.ErrorReason_ERROR_REASON_UNSPECIFIED},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ReasonOf(tc.err); got != tc.want {
+				t.Errorf("ReasonOf() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteHTTP(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "not found", err: NotFound("file not found"), wantStatus: http.StatusNotFound, wantCode: "not_found"},
+		{name: "rate limited", err: ResourceExhausted("slow down"), wantStatus: http.StatusTooManyRequests, wantCode: "resource_exhausted"},
+		{name: "plain error", err: errors.New("boom"), wantStatus: http.StatusInternalServerError, wantCode: "internal"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			WriteHTTP(rec, tc.err)
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			var body struct{ Code, Message string }
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid body: %v", err)
+			}
+			if body.Code != tc.wantCode {
+				t.Errorf("code = %q, want %q", body.Code, tc.wantCode)
+			}
+		})
+	}
+
+	err := Unauthenticated("missing key")
+	err.Meta().Set("WWW-Authenticate", "Bearer")
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, err)
+	if got := rec.Header().Get("WWW-Authenticate"); got != "Bearer" {
+		t.Errorf("WWW-Authenticate = %q, want the error metadata", got)
+	}
+
+	rec = httptest.NewRecorder()
+	WriteHTTPStatus(rec, WithReason(NotFound("gone"), filev1.ErrorReason_ERROR_REASON_OBJECT_GONE, nil), http.StatusGone)
+	if rec.Code != http.StatusGone {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGone)
+	}
+	var body struct {
+		Details []struct{ Type, Value string }
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if len(body.Details) != 1 || body.Details[0].Type != "file.v1.ErrorInfo" {
+		t.Fatalf("details = %+v, want one file.v1.ErrorInfo", body.Details)
+	}
+	b, decodeErr := base64.RawStdEncoding.DecodeString(body.Details[0].Value)
+	if decodeErr != nil {
+		t.Fatalf("invalid detail value: %v", decodeErr)
+	}
+	var info filev1.ErrorInfo
+	if err := proto.Unmarshal(b, &info); err != nil || info.GetReason() != filev1.ErrorReason_ERROR_REASON_OBJECT_GONE {
+		t.Errorf("detail = %v (%v), want reason %v", &info, err, filev1.ErrorReason_ERROR_REASON_OBJECT_GONE)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth authenticates Connect requests with API keys or JWTs sent as bearer tokens.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+)
+
+const msgUnauthenticated = "missing or invalid API key or token"
+
+// Keys is a set of API keys. Only their SHA-256 hashes are kept, so a key store may
+// hold the hashes instead of the keys themselves.
+type Keys struct {
+	hashes map[[sha256.Size]byte]struct{}
+}
+
+// NewKeys returns the set of the plain keys and of the keys given by their hex SHA-256 hash
+func NewKeys(keys, hashes []string) (*Keys, error) {
+	k := &Keys{hashes: make(map[[sha256.Size]byte]struct{}, len(keys)+len(hashes))}
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("empty API key")
+		}
+		k.hashes[sha256.Sum256([]byte(key))] = struct{}{}
+	}
+	for _, h := range hashes {
+		b, err := hex.DecodeString(h)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("API key hash %q is not a hex SHA-256 hash", h)
+		}
+		k.hashes[[sha256.Size]byte(b)] = struct{}{}
+	}
+	return k, nil
+}
+
+// Len returns the number of keys
+func (k *Keys) Len() int {
+	return len(k.hashes)
+}
+
+// Valid reports whether key is in the set. Comparing hashes doesn't leak the keys through timing.
+func (k *Keys) Valid(key string) bool {
+	_, ok := k.hashes[sha256.Sum256([]byte(key))]
+	return ok
+}
+
+// Interceptor rejects requests without a valid "Authorization: Bearer <key>" header
+// with CodeUnauthenticated. Public procedures are let through.
+type Interceptor struct {
+	// JWT also accepts the bearer tokens it verifies, their user is put in the context.
+	// Nil accepts API keys only.
+	JWT *JWTVerifier
+
+	keys   *Keys
+	public map[string]bool
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor returns an interceptor accepting keys on every procedure but the public ones
+func NewInterceptor(keys *Keys, publicProcedures ...string) *Interceptor {
+	public := make(map[string]bool, len(publicProcedures))
+	for _, p := range publicProcedures {
+		public[p] = true
+	}
+	return &Interceptor{keys: keys, public: public}
+}
+
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		ctx, err := i.Authenticate(ctx, req.Spec().Procedure, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := i.Authenticate(ctx, conn.Spec().Procedure, conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// Authenticate checks the bearer token of a request of procedure and returns ctx with the
+// user of a JWT or the hash of an API key. Plain HTTP endpoints standing in for a procedure
+// call it themselves.
+func (i *Interceptor) Authenticate(ctx context.Context, procedure string, header http.Header) (context.Context, error) {
+	if i.public[procedure] {
+		return ctx, nil
+	}
+	token, ok := BearerToken(header)
+	if ok && i.keys.Valid(token) {
+		sum := sha256.Sum256([]byte(token))
+		return WithAPIKeyHash(ctx, hex.EncodeToString(sum[:])), nil
+	}
+	if ok && i.JWT != nil {
+		userID, err := i.JWT.Subject(token)
+		if err == nil {
+			return WithUser(ctx, userID), nil
+		}
+		fwlog.Debugf("Rejected JWT for %s: %v", procedure, err)
+	}
+	err := apierr.Unauthenticated(msgUnauthenticated)
+	err.Meta().Set("WWW-Authenticate", "Bearer")
+	return ctx, err
+}
+
+type apiKeyHashKey struct{}
+
+// WithAPIKeyHash returns a context carrying the hex SHA-256 hash of the API key a request
+// was authenticated with
+func WithAPIKeyHash(ctx context.Context, hash string) context.Context {
+	return context.WithValue(ctx, apiKeyHashKey{}, hash)
+}
+
+// APIKeyHash returns the hex SHA-256 hash of the API key a request was authenticated with,
+// as listed in the key hashes of the config. Requests authenticated with a JWT or not at all
+// have none.
+func APIKeyHash(ctx context.Context) (string, bool) {
+	hash, ok := ctx.Value(apiKeyHashKey{}).(string)
+	return hash, ok
+}
+
+// BearerToken returns the token of the Authorization header
+func BearerToken(header http.Header) (string, bool) {
+	scheme, token, ok := strings.Cut(header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+)
+
+func hashOf(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestKeys(t *testing.T) {
+	keys, err := NewKeys([]string{"plain-key"}, []string{hashOf("hashed-key")})
+	if err != nil {
+		t.Fatalf("NewKeys() failed: %v", err)
+	}
+	if keys.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", keys.Len())
+	}
+
+	testCases := []struct {
+		key  string
+		want bool
+	}{
+		{key: "plain-key", want: true},
+		{key: "hashed-key", want: true},
+		{key: hashOf("hashed-key"), want: false},
+		{key: "plain-key ", want: false},
+		{key: "", want: false},
+	}
+	for _, tc := range testCases {
+		if got := keys.Valid(tc.key); got != tc.want {
+			t.Errorf("Valid(%q) = %v, want %v", tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestNewKeysInvalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		keys   []string
+		hashes []string
+	}{
+		{name: "empty key", keys: []string{""}},
+		{name: "not hex", hashes: []string{"not-a-hash"}},
+		{name: "short hash", hashes: []string{"abcd"}},
+	}
+	for _, tc := range testCases {
+		if _, err := NewKeys(tc.keys, tc.hashes); err == nil {
+			t.Errorf("%s: NewKeys() succeeded, want an error", tc.name)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   string
+		wantOK bool
+	}{
+		{header: "Bearer abc", want: "abc", wantOK: true},
+		{header: "bearer abc", want: "abc", wantOK: true},
+		{header: "Basic abc"},
+		{header: "Bearer "},
+		{header: "abc"},
+		{header: ""},
+	}
+	for _, tc := range testCases {
+		header := http.Header{}
+		header.Set("Authorization", tc.header)
+		got, ok := BearerToken(header)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("BearerToken(%q) = %q, %v, want %q, %v", tc.header, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	keys, err := NewKeys([]string{"secret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := NewInterceptor(keys, filev1connect.FileServiceGetFileInfoProcedure)
+
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(filev1connect.UnimplementedFileServiceHandler{}, connect.WithInterceptors(interceptor)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+	ctx := context.Background()
+
+	testCases := []struct {
+		name          string
+		authorization string
+		want          connect.Code
+	}{
+		{name: "missing key", want: connect.CodeUnauthenticated},
+		{name: "wrong key", authorization: "Bearer guess", want: connect.CodeUnauthenticated},
+		{name: "valid key", authorization: "Bearer secret", want: connect.CodeUnimplemented},
+	}
+	for _, tc := range testCases {
+		req := connect.NewRequest(&filev1.GetDownloadURLRequest{})
+		if tc.authorization != "" {
+			req.Header().Set("Authorization", tc.authorization)
+		}
+		_, err := client.GetDownloadURL(ctx, req)
+		if connect.CodeOf(err) != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+		if tc.want == connect.CodeUnauthenticated {
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) || connectErr.Meta().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("%s: missing WWW-Authenticate challenge", tc.name)
+			}
+		}
+
+		stream := client.SendFile(ctx)
+		if tc.authorization != "" {
+			stream.RequestHeader().Set("Authorization", tc.authorization)
+		}
+		_, err = stream.CloseAndReceive()
+		if connect.CodeOf(err) != tc.want {
+			t.Errorf("%s: streaming call got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	// Public procedures need no key
+	_, err = client.GetFileInfo(ctx, connect.NewRequest(&filev1.GetFileInfoRequest{}))
+	if connect.CodeOf(err) != connect.CodeUnimplemented {
+		t.Errorf("public procedure: got %v, want it to reach the handler", err)
+	}
+}
+
+func TestAuthenticateAPIKeyHash(t *testing.T) {
+	keys, err := NewKeys([]string{"secret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := NewInterceptor(keys, filev1connect.FileServiceGetFileInfoProcedure)
+
+	header := http.Header{"Authorization": []string{"Bearer secret"}}
+	ctx, err := interceptor.Authenticate(context.Background(), filev1connect.FileServiceSendFileProcedure, header)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if hash, ok := APIKeyHash(ctx); !ok || hash != hashOf("secret") {
+		t.Errorf("APIKeyHash() = %q, %v, want %q", hash, ok, hashOf("secret"))
+	}
+	if _, ok := User(ctx); ok {
+		t.Error("User() is set for an API key")
+	}
+
+	// Public procedures let anonymous callers through without a key
+	ctx, err = interceptor.Authenticate(context.Background(), filev1connect.FileServiceGetFileInfoProcedure, http.Header{})
+	if err != nil {
+		t.Fatalf("Authenticate() of a public procedure error = %v", err)
+	}
+	if _, ok := APIKeyHash(ctx); ok {
+		t.Error("APIKeyHash() is set for an anonymous caller")
+	}
+}
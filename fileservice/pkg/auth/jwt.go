@@ -0,0 +1,94 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTVerifier authenticates users by JWTs signed with the private key of a public key.
+// The token must carry a sub claim naming the user and an exp claim.
+type JWTVerifier struct {
+	key     any
+	methods []string
+}
+
+// NewJWTVerifier returns a verifier of the tokens signed for the PEM encoded RSA, ECDSA or Ed25519 public key
+func NewJWTVerifier(pemData []byte) (*JWTVerifier, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM block in the JWT public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the JWT public key: %w", err)
+	}
+	// Only the algorithms of the key type are accepted, so a token can't pick a weaker one
+	var methods []string
+	switch key.(type) {
+	case *rsa.PublicKey:
+		methods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		methods = []string{"ES256", "ES384", "ES512"}
+	case ed25519.PublicKey:
+		methods = []string{"EdDSA"}
+	default:
+		return nil, fmt.Errorf("unsupported JWT public key type %T", key)
+	}
+	return &JWTVerifier{key: key, methods: methods}, nil
+}
+
+// Subject verifies the token and returns its sub claim
+func (v *JWTVerifier) Subject(token string) (string, error) {
+	parsed, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{},
+		func(*jwt.Token) (any, error) { return v.key, nil },
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", err
+	}
+	sub, err := parsed.Claims.GetSubject()
+	if err != nil {
+		return "", err
+	}
+	if sub == "" {
+		return "", errors.New("token has no sub claim")
+	}
+	return sub, nil
+}
+
+type userKey struct{}
+
+// WithUser returns a context carrying the id of the authenticated user
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// User returns the id of the user authenticated by a JWT. Requests authenticated
+// with an API key or not at all have no user.
+func User(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userKey{}).(string)
+	return userID, ok
+}
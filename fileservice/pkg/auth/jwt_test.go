@@ -0,0 +1,165 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/golang-jwt/jwt/v5"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+)
+
+// newTestKey returns a signing key and the PEM of its public key
+func newTestKey(t *testing.T) (ed25519.PrivateKey, []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func signToken(t *testing.T, key ed25519.PrivateKey, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestJWTVerifierSubject(t *testing.T) {
+	key, pemData := newTestKey(t)
+	otherKey, _ := newTestKey(t)
+	verifier, err := NewJWTVerifier(pemData)
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() failed: %v", err)
+	}
+	exp := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "alice", ExpiresAt: exp}).SignedString(pemData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", token: signToken(t, key, jwt.RegisteredClaims{Subject: "alice", ExpiresAt: exp}), want: "alice"},
+		{name: "expired", token: signToken(t, key, jwt.RegisteredClaims{Subject: "alice", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}), wantErr: true},
+		{name: "no expiry", token: signToken(t, key, jwt.RegisteredClaims{Subject: "alice"}), wantErr: true},
+		{name: "no subject", token: signToken(t, key, jwt.RegisteredClaims{ExpiresAt: exp}), wantErr: true},
+		{name: "other key", token: signToken(t, otherKey, jwt.RegisteredClaims{Subject: "alice", ExpiresAt: exp}), wantErr: true},
+		{name: "HMAC with the public key", token: hmacToken, wantErr: true},
+		{name: "garbage", token: "not.a.jwt", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := verifier.Subject(tc.token)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("%s: Subject() = %q, %v, want %q, error %v", tc.name, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestNewJWTVerifierInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pemData []byte
+	}{
+		{name: "not PEM", pemData: []byte("public key")},
+		{name: "not a public key", pemData: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("junk")})},
+	}
+	for _, tc := range testCases {
+		if _, err := NewJWTVerifier(tc.pemData); err == nil {
+			t.Errorf("%s: NewJWTVerifier() succeeded, want an error", tc.name)
+		}
+	}
+}
+
+// userEcho answers GetFileInfo with the user of the request as the file name
+type userEcho struct {
+	filev1connect.UnimplementedFileServiceHandler
+}
+
+func (userEcho) GetFileInfo(ctx context.Context, _ *connect.Request[filev1.GetFileInfoRequest]) (*connect.Response[filev1.GetFileInfoResponse], error) {
+	userID, _ := User(ctx)
+	return connect.NewResponse(&filev1.GetFileInfoResponse{Filename: userID}), nil
+}
+
+func TestInterceptorJWT(t *testing.T) {
+	key, pemData := newTestKey(t)
+	verifier, err := NewJWTVerifier(pemData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewKeys([]string{"secret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := NewInterceptor(keys)
+	interceptor.JWT = verifier
+
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(userEcho{}, connect.WithInterceptors(interceptor)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+
+	exp := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	testCases := []struct {
+		name     string
+		token    string
+		wantUser string
+		wantCode connect.Code
+	}{
+		{name: "user token", token: signToken(t, key, jwt.RegisteredClaims{Subject: "alice", ExpiresAt: exp}), wantUser: "alice"},
+		{name: "API key", token: "secret"},
+		{name: "expired token", token: signToken(t, key, jwt.RegisteredClaims{Subject: "alice", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}), wantCode: connect.CodeUnauthenticated},
+	}
+	for _, tc := range testCases {
+		req := connect.NewRequest(&filev1.GetFileInfoRequest{})
+		req.Header().Set("Authorization", "Bearer "+tc.token)
+		res, err := client.GetFileInfo(context.Background(), req)
+		if tc.wantCode != 0 {
+			if connect.CodeOf(err) != tc.wantCode {
+				t.Errorf("%s: got %v, want %v", tc.name, err, tc.wantCode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: GetFileInfo() failed: %v", tc.name, err)
+		}
+		if res.Msg.Filename != tc.wantUser {
+			t.Errorf("%s: user = %q, want %q", tc.name, res.Msg.Filename, tc.wantUser)
+		}
+	}
+}
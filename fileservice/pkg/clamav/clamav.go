@@ -0,0 +1,132 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clamav scans content with a ClamAV daemon over its INSTREAM protocol.
+package clamav
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// chunkSize is the size of the chunks streamed to clamd, well under its StreamMaxLength
+const chunkSize = 64 << 10
+
+// Client scans content with the clamd listening on Addr.
+type Client struct {
+	// Addr is the TCP address of clamd, e.g. clamav:3310
+	Addr string
+	// Timeout bounds a whole scan when ctx has no earlier deadline, zero is unlimited
+	Timeout time.Duration
+}
+
+// Scan streams r to clamd and returns the name of the malware it found, empty when r is clean.
+// Reaching clamd's StreamMaxLength is an error, not a clean result.
+func (c *Client) Scan(ctx context.Context, r io.Reader) (string, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// Unblock the reads and writes when ctx is cancelled without a deadline
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := stream(conn, r); err != nil {
+		// clamd may have closed the stream with the reason, e.g. the size limit
+		if reply, replyErr := readReply(conn); replyErr == nil {
+			if _, parseErr := parseReply(reply); parseErr != nil {
+				return "", parseErr
+			}
+		}
+		return "", wrap(ctx, err)
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return "", wrap(ctx, err)
+	}
+	return parseReply(reply)
+}
+
+// stream sends r as INSTREAM chunks, each prefixed with its big-endian length, and the
+// zero length chunk ending the stream
+func stream(w io.Writer, r io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+func readReply(r io.Reader) (string, error) {
+	reply, err := bufio.NewReader(r).ReadString(0)
+	if err != nil && (reply == "" || !errors.Is(err, io.EOF)) {
+		return "", err
+	}
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// parseReply reads the verdict of clamd, "stream: OK", "stream: <name> FOUND" or "<reason> ERROR"
+func parseReply(reply string) (string, error) {
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	case strings.HasSuffix(result, " ERROR"):
+		return "", fmt.Errorf("clamav: %s", strings.TrimSuffix(result, " ERROR"))
+	}
+	return "", fmt.Errorf("clamav: unexpected reply %q", reply)
+}
+
+func wrap(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("clamav: %w", ctxErr)
+	}
+	return fmt.Errorf("clamav: %w", err)
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd answers INSTREAM like clamd, it finds eicar and accepts up to maxLen bytes
+func fakeClamd(t *testing.T, maxLen int) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+					return
+				}
+				var content bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if content.Len()+int(size) > maxLen {
+						_, _ = io.WriteString(conn, "INSTREAM size limit exceeded. ERROR\x00")
+						// Closing with unread input would reset the connection before the reply is read
+						_, _ = io.Copy(io.Discard, r)
+						return
+					}
+					if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+						return
+					}
+				}
+				reply := "stream: OK\x00"
+				if strings.Contains(content.String(), eicar) {
+					reply = "stream: Eicar-Test-Signature FOUND\x00"
+				}
+				_, _ = io.WriteString(conn, reply)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestScan(t *testing.T) {
+	addr := fakeClamd(t, 1<<20)
+
+	testCases := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{name: "clean", content: "hello world"},
+		{name: "empty"},
+		{name: "infected", content: eicar, want: "Eicar-Test-Signature"},
+		{name: "infected past the first chunk", content: strings.Repeat("x", chunkSize+10) + eicar, want: "Eicar-Test-Signature"},
+		{name: "too large", content: strings.Repeat("x", 2<<20), wantErr: "size limit exceeded"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Client{Addr: addr, Timeout: 5 * time.Second}
+			got, err := c.Scan(context.Background(), strings.NewReader(tc.content))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Scan() error = %v, want it to mention %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Scan() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScanUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	c := &Client{Addr: addr}
+	if _, err := c.Scan(context.Background(), strings.NewReader("hello")); err == nil {
+		t.Error("Scan() should fail when clamd is unreachable")
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	testCases := []struct {
+		reply   string
+		want    string
+		wantErr bool
+	}{
+		{reply: "stream: OK"},
+		{reply: "stream: Win.Test.EICAR_HDB-1 FOUND", want: "Win.Test.EICAR_HDB-1"},
+		{reply: "INSTREAM size limit exceeded. ERROR", wantErr: true},
+		{reply: "UNKNOWN COMMAND", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := parseReply(tc.reply)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("parseReply(%q) = %q, %v, want %q, error %v", tc.reply, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
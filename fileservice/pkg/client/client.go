@@ -0,0 +1,229 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client wraps the file service RPCs with file based upload and download helpers.
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+)
+
+// DefaultChunkSize is the size of uploaded chunks and requested download chunks
+const DefaultChunkSize = 64 << 10
+
+// Client uploads and downloads files through a file service
+type Client struct {
+	// ChunkSize is the size of upload chunks and the download chunk size asked from the server,
+	// defaults to DefaultChunkSize. The server may clamp the download chunk size.
+	ChunkSize int
+	// Progress, when set, is called after every chunk sent or received with the bytes
+	// transferred so far and the size of the file
+	Progress func(done, total int64)
+
+	rpc filev1connect.FileServiceClient
+}
+
+// New returns a client of the file service at baseURL
+func New(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) *Client {
+	return &Client{rpc: filev1connect.NewFileServiceClient(httpClient, baseURL, opts...)}
+}
+
+// RPC returns the underlying Connect client for the calls the helpers don't cover
+func (c *Client) RPC() filev1connect.FileServiceClient {
+	return c.rpc
+}
+
+func (c *Client) chunkSize() int {
+	if c.ChunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return c.ChunkSize
+}
+
+func (c *Client) progress(done, total int64) {
+	if c.Progress != nil {
+		c.Progress(done, total)
+	}
+}
+
+// UploadFile uploads the file at path under its base name and returns its download key
+func (c *Client) UploadFile(ctx context.Context, path string) (key string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	stat, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !stat.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", path)
+	}
+	return c.Upload(ctx, filepath.Base(path), f, stat.Size())
+}
+
+// Upload uploads size bytes of r as name and returns its download key
+func (c *Client) Upload(ctx context.Context, name string, r io.Reader, size int64) (string, error) {
+	stream := c.rpc.SendFile(ctx)
+	err := stream.Send(&filev1.SendFileRequest{
+		Payload: &filev1.SendFileRequest_Info{Info: &filev1.FileInfo{Name: name, Size: size}},
+	})
+	// A failed send is reported by CloseAndReceive with the server's error
+	if err == nil {
+		err = c.sendChunks(ctx, stream, r, size)
+	}
+	resp, closeErr := stream.CloseAndReceive()
+	if closeErr != nil && !errors.Is(closeErr, io.EOF) {
+		return "", closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || !resp.Msg.GetSuccess() {
+		return "", errors.New("upload was not accepted")
+	}
+	return resp.Msg.GetRandomkey(), nil
+}
+
+func (c *Client) sendChunks(ctx context.Context, stream *connect.ClientStreamForClient[filev1.SendFileRequest, filev1.SendFileResponse], r io.Reader, size int64) error {
+	buf := make([]byte, c.chunkSize())
+	var sent int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if sendErr := stream.Send(&filev1.SendFileRequest{
+				Payload: &filev1.SendFileRequest_ChunkData{ChunkData: buf[:n]},
+			}); sendErr != nil {
+				// The server ended the stream, CloseAndReceive has the reason
+				if errors.Is(sendErr, io.EOF) {
+					return nil
+				}
+				return sendErr
+			}
+			sent += int64(n)
+			c.progress(sent, size)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// DownloadFile downloads the file shared under key to destPath.
+// The file is written next to destPath first and only renamed into place once complete,
+// so a failed download never leaves a partial file at destPath.
+func (c *Client) DownloadFile(ctx context.Context, key, destPath string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(destPath), "."+filepath.Base(destPath)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = c.Download(ctx, key, tmp); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), destPath)
+}
+
+// Download writes the file shared under key to w and returns its name.
+// The stream starts with the file size, the content is checked against it
+// and against the checksum the server ends the stream with.
+func (c *Client) Download(ctx context.Context, key string, w io.Writer) (filename string, err error) {
+	stream, err := c.rpc.ReceiveFile(ctx, connect.NewRequest(&filev1.ReceiveFileRequest{
+		Randomkey: key,
+		ChunkSize: uint32(c.chunkSize()),
+	}))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = stream.Close() }()
+
+	size := int64(-1)
+	var written int64
+	checksum := sha256.New()
+	w = io.MultiWriter(w, checksum)
+	for stream.Receive() {
+		msg := stream.Msg()
+		if msg.GetCompression() != filev1.Compression_COMPRESSION_NONE {
+			return "", fmt.Errorf("unexpected %v stream", msg.GetCompression())
+		}
+		switch payload := msg.GetPayload().(type) {
+		case *filev1.ReceiveFileResponse_FileSize:
+			if size >= 0 {
+				return "", errors.New("file size sent twice")
+			}
+			size = payload.FileSize
+		case *filev1.ReceiveFileResponse_ChunkData:
+			if size < 0 {
+				return "", errors.New("chunk received before the file size")
+			}
+			if msg.GetFilename() != "" {
+				filename = msg.GetFilename()
+			}
+			n, err := w.Write(payload.ChunkData)
+			written += int64(n)
+			if err != nil {
+				return "", err
+			}
+			if written > size {
+				return "", fmt.Errorf("received more than the announced %d bytes", size)
+			}
+			c.progress(written, size)
+		case *filev1.ReceiveFileResponse_Sha256:
+			if written != size {
+				return "", fmt.Errorf("checksum received after %d of %d bytes", written, size)
+			}
+			if sum := hex.EncodeToString(checksum.Sum(nil)); sum != payload.Sha256 {
+				return "", fmt.Errorf("received content has SHA-256 %s, want %s", sum, payload.Sha256)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return "", err
+	}
+	if size < 0 {
+		return "", errors.New("file size was never sent")
+	}
+	if written != size {
+		return "", fmt.Errorf("received %d bytes, want %d", written, size)
+	}
+	return filename, nil
+}
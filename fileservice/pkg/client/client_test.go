@@ -0,0 +1,231 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+)
+
+type storedFile struct {
+	name string
+	data []byte
+	// sha256 is sent at the end of downloads
+	sha256 string
+}
+
+// fakeFileService keeps uploads in memory and serves them back in the requested chunk size
+type fakeFileService struct {
+	filev1connect.UnimplementedFileServiceHandler
+
+	mu         sync.Mutex
+	files      map[string]storedFile
+	chunkSizes []int
+}
+
+func (f *fakeFileService) SendFile(ctx context.Context, stream *connect.ClientStream[filev1.SendFileRequest]) (*connect.Response[filev1.SendFileResponse], error) {
+	if !stream.Receive() {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("missing info"))
+	}
+	info := stream.Msg().GetInfo()
+	var data []byte
+	for stream.Receive() {
+		chunk := stream.Msg().GetChunkData()
+		f.mu.Lock()
+		f.chunkSizes = append(f.chunkSizes, len(chunk))
+		f.mu.Unlock()
+		data = append(data, chunk...)
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != info.GetSize() {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("got %d bytes, want %d", len(data), info.GetSize()))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := fmt.Sprintf("key%d", len(f.files))
+	sum := sha256.Sum256(data)
+	f.files[key] = storedFile{name: info.GetName(), data: data, sha256: hex.EncodeToString(sum[:])}
+	return connect.NewResponse(&filev1.SendFileResponse{Success: true, Randomkey: key}), nil
+}
+
+func (f *fakeFileService) ReceiveFile(ctx context.Context, req *connect.Request[filev1.ReceiveFileRequest], stream *connect.ServerStream[filev1.ReceiveFileResponse]) error {
+	f.mu.Lock()
+	file, ok := f.files[req.Msg.GetRandomkey()]
+	f.mu.Unlock()
+	if !ok {
+		return connect.NewError(connect.CodeNotFound, errors.New("file not found"))
+	}
+	if err := stream.Send(&filev1.ReceiveFileResponse{
+		Payload: &filev1.ReceiveFileResponse_FileSize{FileSize: int64(len(file.data))},
+	}); err != nil {
+		return err
+	}
+	data, size := file.data, int(req.Msg.GetChunkSize())
+	for len(data) > 0 {
+		n := min(size, len(data))
+		if err := stream.Send(&filev1.ReceiveFileResponse{
+			Filename: file.name,
+			Payload:  &filev1.ReceiveFileResponse_ChunkData{ChunkData: data[:n]},
+		}); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return stream.Send(&filev1.ReceiveFileResponse{
+		Payload: &filev1.ReceiveFileResponse_Sha256{Sha256: file.sha256},
+	})
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeFileService) {
+	t.Helper()
+	fake := &fakeFileService{files: make(map[string]storedFile)}
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(fake))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return New(srv.Client(), srv.URL), fake
+}
+
+func TestUploadDownloadRoundTrip(t *testing.T) {
+	c, fake := newTestClient(t)
+	c.ChunkSize = 1000
+
+	dir := t.TempDir()
+	content := make([]byte, 4321)
+	if _, err := rand.Read(content); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "report.bin")
+	if err := os.WriteFile(src, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := c.UploadFile(context.Background(), src)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if got := fake.files[key].name; got != "report.bin" {
+		t.Errorf("uploaded name = %q, want report.bin", got)
+	}
+	for i, size := range fake.chunkSizes {
+		if size > c.ChunkSize {
+			t.Errorf("chunk %d is %d bytes, want at most %d", i, size, c.ChunkSize)
+		}
+	}
+
+	dest := filepath.Join(dir, "copy.bin")
+	if err := c.DownloadFile(context.Background(), key, dest); err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("downloaded %d bytes differ from the uploaded %d", len(got), len(content))
+	}
+}
+
+func TestDownloadFileFailureLeavesNoFile(t *testing.T) {
+	c, _ := newTestClient(t)
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "missing.bin")
+
+	err := c.DownloadFile(context.Background(), "nope", dest)
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Fatalf("DownloadFile() error = %v, want %v", err, connect.CodeNotFound)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("failed download left %d files behind", len(entries))
+	}
+}
+
+func TestUploadCancelled(t *testing.T) {
+	c, fake := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.Upload(ctx, "a.txt", bytes.NewReader([]byte("data")), 4)
+	if !errors.Is(err, context.Canceled) && connect.CodeOf(err) != connect.CodeCanceled {
+		t.Fatalf("Upload() error = %v, want context.Canceled", err)
+	}
+	if len(fake.files) != 0 {
+		t.Errorf("cancelled upload stored %d files", len(fake.files))
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	c, fake := newTestClient(t)
+	key, err := c.Upload(context.Background(), "a.txt", bytes.NewReader([]byte("data")), 4)
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	file := fake.files[key]
+	file.sha256 = strings.Repeat("0", 64)
+	fake.files[key] = file
+
+	var buf bytes.Buffer
+	if _, err := c.Download(context.Background(), key, &buf); err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Errorf("Download() error = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	c, _ := newTestClient(t)
+	c.ChunkSize = 3
+	var calls [][2]int64
+	c.Progress = func(done, total int64) { calls = append(calls, [2]int64{done, total}) }
+
+	key, err := c.Upload(context.Background(), "a.txt", bytes.NewReader([]byte("abcdefg")), 7)
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	want := [][2]int64{{3, 7}, {6, 7}, {7, 7}}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("upload progress = %v, want %v", calls, want)
+	}
+
+	calls = nil
+	if _, err := c.Download(context.Background(), key, io.Discard); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("download progress = %v, want %v", calls, want)
+	}
+}
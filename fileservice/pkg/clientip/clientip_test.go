@@ -0,0 +1,129 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientip
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	r, err := New([]string{"10.0.0.0/8", "2001:db8:ffff::/48", "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		peer   string
+		header http.Header
+		want   string
+	}{
+		{
+			name: "direct connection",
+			peer: "203.0.113.7:51234",
+			want: "203.0.113.7",
+		},
+		{
+			name:   "spoofed header from untrusted peer",
+			peer:   "203.0.113.7:51234",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			want:   "203.0.113.7",
+		},
+		{
+			name:   "spoofed Forwarded from untrusted peer",
+			peer:   "203.0.113.7:51234",
+			header: http.Header{"Forwarded": {"for=198.51.100.1"}},
+			want:   "203.0.113.7",
+		},
+		{
+			name:   "trusted proxy",
+			peer:   "10.1.2.3:8080",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "chain of trusted proxies",
+			peer:   "10.1.2.3:8080",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1, 192.0.2.1", "10.9.9.9"}},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "client prepends a spoofed hop",
+			peer:   "10.1.2.3:8080",
+			header: http.Header{"X-Forwarded-For": {"1.1.1.1, 198.51.100.1"}},
+			want:   "198.51.100.1",
+		},
+		{
+			name: "trusted proxy without header",
+			peer: "10.1.2.3:8080",
+			want: "10.1.2.3",
+		},
+		{
+			name:   "Forwarded takes precedence",
+			peer:   "10.1.2.3:8080",
+			header: http.Header{"Forwarded": {`for="[2001:db8:cafe::17]:4711";proto=https, for=10.7.7.7`}, "X-Forwarded-For": {"198.51.100.1"}},
+			want:   "2001:db8:cafe::17",
+		},
+		{
+			name:   "obfuscated hop",
+			peer:   "10.1.2.3:8080",
+			header: http.Header{"Forwarded": {"for=_hidden, for=10.7.7.7"}},
+			want:   "10.7.7.7",
+		},
+		{
+			name:   "IPv6 trusted proxy",
+			peer:   "[2001:db8:ffff::1]:443",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			want:   "198.51.100.1",
+		},
+		{
+			name:   "IPv4-mapped peer",
+			peer:   "[::ffff:10.1.2.3]:8080",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			want:   "198.51.100.1",
+		},
+		{
+			name: "non IP peer",
+			peer: "@",
+			want: "@",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := r.ClientIP(tc.peer, tc.header); got != tc.want {
+				t.Errorf("ClientIP(%q) = %q, want %q", tc.peer, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNoTrustedProxies(t *testing.T) {
+	var r *Resolver
+	got := r.ClientIP("10.1.2.3:8080", http.Header{"X-Forwarded-For": {"198.51.100.1"}})
+	if got != "10.1.2.3" {
+		t.Errorf("ClientIP() = %q, want the peer address", got)
+	}
+}
+
+func TestNewInvalidProxy(t *testing.T) {
+	if _, err := New([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("New() should reject an invalid CIDR")
+	}
+	if _, err := New([]string{"proxy.local"}); err == nil {
+		t.Error("New() should reject a host name")
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filecrypt encrypts file streams at rest with AES-256-GCM.
+//
+// Every file is encrypted with its own key, derived from the master key and a random
+// per-file nonce. The stream is cut into segments of SegmentSize bytes that are sealed
+// separately, so neither side has to buffer the whole file. Segment nonces hold the
+// segment index and a final-segment flag, so reordered, dropped or truncated segments
+// fail authentication like any modified byte does.
+package filecrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// KeySize is the size of the master key in bytes
+	KeySize = 32
+	// NonceSize is the size of the per-file nonce in bytes
+	NonceSize = 16
+	// SegmentSize is the plaintext size of every segment but the last
+	SegmentSize = 64 << 10
+
+	tagSize  = 16
+	keyLabel = "fawa file encryption v1"
+)
+
+// ErrAuthentication is returned when an encrypted stream was modified, truncated
+// or is decrypted with the wrong key
+var ErrAuthentication = errors.New("filecrypt: message authentication failed")
+
+// ParseKey decodes a base64 master key
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("filecrypt: master key is not valid base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("filecrypt: master key is %d bytes, want %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// NewNonce returns a random per-file nonce
+func NewNonce() ([]byte, error) {
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+// EncryptedSize returns the size of the encryption of size plaintext bytes
+func EncryptedSize(size int64) int64 {
+	return size + (size/SegmentSize+1)*tagSize
+}
+
+// NewEncryptingReader returns a reader of the encryption of r
+func NewEncryptingReader(masterKey, nonce []byte, r io.Reader) (io.Reader, error) {
+	aead, err := newAEAD(masterKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptingReader{stream: stream{aead: aead}, src: r, plain: make([]byte, SegmentSize)}, nil
+}
+
+// NewDecryptingReader returns a reader of the decryption of r.
+// Reads fail with ErrAuthentication as soon as a segment doesn't authenticate,
+// no plaintext of that segment is returned.
+func NewDecryptingReader(masterKey, nonce []byte, r io.Reader) (io.Reader, error) {
+	aead, err := newAEAD(masterKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingReader{stream: stream{aead: aead}, src: r, sealed: make([]byte, SegmentSize+tagSize+1)}, nil
+}
+
+func newAEAD(masterKey, nonce []byte) (cipher.AEAD, error) {
+	if len(masterKey) != KeySize {
+		return nil, fmt.Errorf("filecrypt: master key is %d bytes, want %d", len(masterKey), KeySize)
+	}
+	if len(nonce) != NonceSize {
+		return nil, fmt.Errorf("filecrypt: nonce is %d bytes, want %d", len(nonce), NonceSize)
+	}
+	key, err := hkdf.Key(sha256.New, masterKey, nonce, keyLabel, KeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// stream tracks the segment nonces of an encrypted stream
+type stream struct {
+	aead    cipher.AEAD
+	counter uint64
+	nonce   [12]byte
+	out     []byte
+	done    bool
+}
+
+func (s *stream) segmentNonce(final bool) []byte {
+	binary.BigEndian.PutUint64(s.nonce[4:], s.counter)
+	s.nonce[0] = 0
+	if final {
+		s.nonce[0] = 1
+	}
+	s.counter++
+	return s.nonce[:]
+}
+
+func (s *stream) read(p []byte) int {
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n
+}
+
+type encryptingReader struct {
+	stream
+	src   io.Reader
+	plain []byte
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.src, r.plain)
+		final := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !final {
+			return 0, err
+		}
+		r.out = r.aead.Seal(r.out[:0], r.segmentNonce(final), r.plain[:n], nil)
+		r.done = final
+	}
+	return r.read(p), nil
+}
+
+type decryptingReader struct {
+	stream
+	src io.Reader
+	// sealed has room for one byte past a full segment to tell the final segment apart
+	sealed []byte
+	// next holds that extra byte, the start of the following segment
+	next []byte
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		k := copy(r.sealed, r.next)
+		n, err := io.ReadFull(r.src, r.sealed[k:])
+		n += k
+		switch {
+		case err == nil:
+			// A full segment followed by more data
+			r.next = append(r.next[:0], r.sealed[n-1])
+			n--
+		case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+			r.done = true
+		default:
+			return 0, err
+		}
+		final := r.done && n < SegmentSize+tagSize
+		if r.done && !final {
+			// The stream ends on a full segment, the final one was dropped
+			return 0, ErrAuthentication
+		}
+		plain, openErr := r.aead.Open(r.out[:0], r.segmentNonce(final), r.sealed[:n], nil)
+		if openErr != nil {
+			r.done = true
+			return 0, ErrAuthentication
+		}
+		r.out = plain
+	}
+	return r.read(p), nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filecrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+)
+
+func testKeyAndNonce(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, nonce
+}
+
+func encrypt(t *testing.T, key, nonce, plain []byte) []byte {
+	t.Helper()
+	r, err := NewEncryptingReader(key, nonce, bytes.NewReader(plain))
+	if err != nil {
+		t.Fatalf("NewEncryptingReader() error = %v", err)
+	}
+	sealed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("encrypting failed: %v", err)
+	}
+	return sealed
+}
+
+func decrypt(key, nonce, sealed []byte) ([]byte, error) {
+	r, err := NewDecryptingReader(key, nonce, bytes.NewReader(sealed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	key, nonce := testKeyAndNonce(t)
+
+	for _, size := range []int{0, 1, SegmentSize - 1, SegmentSize, SegmentSize + 1, 3*SegmentSize + 7} {
+		plain := make([]byte, size)
+		if _, err := rand.Read(plain); err != nil {
+			t.Fatal(err)
+		}
+
+		sealed := encrypt(t, key, nonce, plain)
+		if int64(len(sealed)) != EncryptedSize(int64(size)) {
+			t.Errorf("size %d: encrypted to %d bytes, EncryptedSize() = %d", size, len(sealed), EncryptedSize(int64(size)))
+		}
+		if size > 0 && bytes.Contains(sealed, plain) {
+			t.Errorf("size %d: ciphertext contains the plaintext", size)
+		}
+
+		got, err := decrypt(key, nonce, sealed)
+		if err != nil {
+			t.Fatalf("size %d: decrypting failed: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: round trip returned %d different bytes", size, len(got))
+		}
+	}
+}
+
+func TestDecryptDetectsTampering(t *testing.T) {
+	key, nonce := testKeyAndNonce(t)
+	plain := bytes.Repeat([]byte("fawa"), SegmentSize/2)
+	sealed := encrypt(t, key, nonce, plain)
+	segment := SegmentSize + tagSize
+
+	otherKey, otherNonce := testKeyAndNonce(t)
+	flipped := bytes.Clone(sealed)
+	flipped[10] ^= 1
+	swapped := append(bytes.Clone(sealed[segment:2*segment]), sealed[:segment]...)
+	swapped = append(swapped, sealed[2*segment:]...)
+
+	testCases := []struct {
+		name   string
+		key    []byte
+		nonce  []byte
+		sealed []byte
+	}{
+		{name: "flipped bit", key: key, nonce: nonce, sealed: flipped},
+		{name: "dropped final segment", key: key, nonce: nonce, sealed: sealed[:2*segment]},
+		{name: "truncated segment", key: key, nonce: nonce, sealed: sealed[:len(sealed)-1]},
+		{name: "swapped segments", key: key, nonce: nonce, sealed: swapped},
+		{name: "appended data", key: key, nonce: nonce, sealed: append(bytes.Clone(sealed), 0)},
+		{name: "empty stream", key: key, nonce: nonce, sealed: nil},
+		{name: "wrong key", key: otherKey, nonce: nonce, sealed: sealed},
+		{name: "wrong nonce", key: key, nonce: otherNonce, sealed: sealed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decrypt(tc.key, tc.nonce, tc.sealed); !errors.Is(err, ErrAuthentication) {
+				t.Errorf("decrypt() error = %v, want %v", err, ErrAuthentication)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, KeySize)
+	got, err := ParseKey(base64.StdEncoding.EncodeToString(key))
+	if err != nil || !bytes.Equal(got, key) {
+		t.Errorf("ParseKey() = %x, %v, want %x", got, err, key)
+	}
+
+	for _, s := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(key[:16])} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics holds every metric of the file service and serves them in the Prometheus text format.
+// Metrics are declared in this package only so that Describe lists the complete catalog.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type is the Prometheus type of a metric
+type Type string
+
+const (
+	Counter Type = "counter"
+	Gauge   Type = "gauge"
+)
+
+// Desc describes a metric of the catalog
+type Desc struct {
+	Name   string
+	Help   string
+	Type   Type
+	Labels []string
+}
+
+// The file service metrics
+var (
+	FileUploads = newVec(Desc{
+		Name:   "fawa_file_uploads_total",
+		Help:   "SendFile calls by result code.",
+		Type:   Counter,
+		Labels: []string{"code"},
+	})
+	FileUploadBytes = newVec(Desc{
+		Name: "fawa_file_upload_bytes_total",
+		Help: "Bytes received by SendFile, before decompression.",
+		Type: Counter,
+	})
+	FileUploadsInFlight = newVec(Desc{
+		Name: "fawa_file_uploads_in_flight",
+		Help: "SendFile calls in progress.",
+		Type: Gauge,
+	})
+	RateLimited = newVec(Desc{
+		Name:   "fawa_rate_limited_requests_total",
+		Help:   "Requests rejected by the rate limiter by procedure.",
+		Type:   Counter,
+		Labels: []string{"procedure"},
+	})
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Vec{}
+)
+
+// Vec is a metric with one value per combination of label values
+type Vec struct {
+	desc Desc
+
+	mu     sync.Mutex
+	values map[string]*sample
+}
+
+type sample struct {
+	labels []string
+	value  float64
+}
+
+func newVec(desc Desc) *Vec {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[desc.Name]; ok {
+		panic(fmt.Sprintf("metrics: %s registered twice", desc.Name))
+	}
+	v := &Vec{desc: desc, values: map[string]*sample{}}
+	registry[desc.Name] = v
+	return v
+}
+
+// Add adds delta to the value of the given label values, which must match the metric labels
+func (v *Vec) Add(delta float64, labelValues ...string) {
+	if len(labelValues) != len(v.desc.Labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.desc.Name, len(v.desc.Labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.values[key]
+	if !ok {
+		s = &sample{labels: slices.Clone(labelValues)}
+		v.values[key] = s
+	}
+	s.value += delta
+}
+
+// Inc adds one to the value of the given label values
+func (v *Vec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+// Value returns the value of the given label values
+func (v *Vec) Value(labelValues ...string) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.values[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// Describe returns the catalog of registered metrics sorted by name
+func Describe() []Desc {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	descs := make([]Desc, 0, len(registry))
+	for _, v := range registry {
+		descs = append(descs, v.desc)
+	}
+	slices.SortFunc(descs, func(a, b Desc) int { return strings.Compare(a.Name, b.Name) })
+	return descs
+}
+
+// Handler serves the metrics in the Prometheus text exposition format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = write(w)
+	})
+}
+
+func write(w io.Writer) error {
+	for _, desc := range Describe() {
+		registryMu.RLock()
+		v := registry[desc.Name]
+		registryMu.RUnlock()
+
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", desc.Name, desc.Help, desc.Name, desc.Type); err != nil {
+			return err
+		}
+		for _, line := range v.lines() {
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lines returns the exposition lines of the metric values sorted by labels.
+// A metric without labels is always exposed, its value defaulting to zero.
+func (v *Vec) lines() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.desc.Labels) == 0 && len(v.values) == 0 {
+		return []string{v.desc.Name + " 0\n"}
+	}
+	lines := make([]string, 0, len(v.values))
+	for _, s := range v.values {
+		var b strings.Builder
+		b.WriteString(v.desc.Name)
+		if len(s.labels) > 0 {
+			b.WriteByte('{')
+			for i, value := range s.labels {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				fmt.Fprintf(&b, `%s="%s"`, v.desc.Labels[i], labelEscaper.Replace(value))
+			}
+			b.WriteByte('}')
+		}
+		fmt.Fprintf(&b, " %s\n", strconv.FormatFloat(s.value, 'g', -1, 64))
+		lines = append(lines, b.String())
+	}
+	slices.Sort(lines)
+	return lines
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// assertCatalog fails the test unless every wanted metric is registered with exactly the wanted labels
+func assertCatalog(t *testing.T, want map[string][]string) {
+	t.Helper()
+	got := map[string][]string{}
+	for _, desc := range Describe() {
+		got[desc.Name] = desc.Labels
+	}
+	for name, labels := range want {
+		gotLabels, ok := got[name]
+		if !ok {
+			t.Errorf("metric %s is not registered", name)
+			continue
+		}
+		if !slices.Equal(gotLabels, labels) {
+			t.Errorf("metric %s has labels %v, want %v", name, gotLabels, labels)
+		}
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	assertCatalog(t, map[string][]string{
+		"fawa_file_uploads_total":          {"code"},
+		"fawa_file_upload_bytes_total":     nil,
+		"fawa_file_uploads_in_flight":      nil,
+		"fawa_rate_limited_requests_total": {"procedure"},
+	})
+}
+
+func TestCatalogNaming(t *testing.T) {
+	name := regexp.MustCompile(`^fawa_[a-z0-9_]+$`)
+	label := regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	for _, desc := range Describe() {
+		if !name.MatchString(desc.Name) {
+			t.Errorf("metric %s is not a fawa_ snake case name", desc.Name)
+		}
+		if isCounter, hasSuffix := desc.Type == Counter, strings.HasSuffix(desc.Name, "_total"); isCounter != hasSuffix {
+			t.Errorf("metric %s: only counters end with _total", desc.Name)
+		}
+		if desc.Help == "" {
+			t.Errorf("metric %s has no help", desc.Name)
+		}
+		for _, l := range desc.Labels {
+			if !label.MatchString(l) {
+				t.Errorf("metric %s has invalid label %q", desc.Name, l)
+			}
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	v := newVec(Desc{Name: "fawa_test_events_total", Help: "Test events.", Type: Counter, Labels: []string{"kind"}})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, v.desc.Name)
+		registryMu.Unlock()
+	})
+	v.Inc("b")
+	v.Add(2.5, "a")
+	v.Inc(`quote"d`)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	want := `# HELP fawa_test_events_total Test events.
+# TYPE fawa_test_events_total counter
+fawa_test_events_total{kind="a"} 2.5
+fawa_test_events_total{kind="b"} 1
+fawa_test_events_total{kind="quote\"d"} 1
+`
+	if !strings.Contains(body, want) {
+		t.Errorf("exposition is missing the test metric:\n%s", body)
+	}
+	if !strings.Contains(body, "fawa_file_uploads_in_flight 0\n") {
+		t.Errorf("metrics without labels should be exposed before their first update:\n%s", body)
+	}
+}
+
+func TestAddWrongLabels(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Add() with missing label values should panic")
+		}
+	}()
+	FileUploads.Inc()
+}
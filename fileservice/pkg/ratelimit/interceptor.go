@@ -0,0 +1,100 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/metrics"
+)
+
+const msgRateLimited = "rate limit exceeded, retry later"
+
+// Interceptor rejects requests from clients over their limit with CodeResourceExhausted.
+// Limiters are looked up by procedure, procedures without one are not limited.
+type Interceptor struct {
+	// ClientIP returns the key of the client sending a request, the peer address
+	// without its port when nil. Set it to account requests behind a proxy to the real client.
+	ClientIP func(peerAddr string, header http.Header) string
+
+	limiters map[string]*Limiter
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor returns an interceptor applying limiters keyed by procedure.
+// Several procedures may share a limiter to share a budget.
+func NewInterceptor(limiters map[string]*Limiter) *Interceptor {
+	return &Interceptor{limiters: limiters}
+}
+
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		if err := i.Allow(req.Spec().Procedure, req.Peer(), req.Header()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := i.Allow(conn.Spec().Procedure, conn.Peer(), conn.RequestHeader()); err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// Allow takes a request of procedure from the budget of its client, it returns a
+// CodeResourceExhausted error when the budget is spent. Plain HTTP endpoints sharing the
+// budget of a procedure call it themselves.
+func (i *Interceptor) Allow(procedure string, peer connect.Peer, header http.Header) error {
+	limiter, ok := i.limiters[procedure]
+	if !ok {
+		return nil
+	}
+	key := clientIP(peer.Addr)
+	if i.ClientIP != nil {
+		key = i.ClientIP(peer.Addr, header)
+	}
+	if limiter.Allow(key) {
+		return nil
+	}
+	metrics.RateLimited.Inc(procedure)
+	return apierr.WithReason(apierr.ResourceExhausted(msgRateLimited), filev1.ErrorReason_ERROR_REASON_RATE_LIMITED, nil)
+}
+
+// clientIP strips the port from a peer address so that every connection of a client shares its bucket
+func clientIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
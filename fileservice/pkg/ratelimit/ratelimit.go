@@ -0,0 +1,131 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit throttles requests per client IP with token buckets.
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultMaxClients bounds the number of client buckets kept in memory
+const DefaultMaxClients = 10000
+
+// Limit is a token bucket refilled at RPS tokens per second holding at most Burst tokens.
+// A non-positive RPS disables the limit.
+type Limit struct {
+	RPS   float64
+	Burst int
+}
+
+// Limiter keeps one token bucket per key.
+// Once maxKeys buckets are tracked the least recently used one is evicted,
+// which at worst hands a full bucket back to an idle client.
+type Limiter struct {
+	limit   Limit
+	maxKeys int
+	now     func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	buckets map[string]*list.Element
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter for limit keeping at most maxKeys buckets.
+// A non-positive maxKeys falls back to DefaultMaxClients.
+func NewLimiter(limit Limit, maxKeys int) *Limiter {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxClients
+	}
+	return &Limiter{
+		limit:   normalize(limit),
+		maxKeys: maxKeys,
+		now:     time.Now,
+		order:   list.New(),
+		buckets: make(map[string]*list.Element),
+	}
+}
+
+// SetLimit replaces the limit of every bucket. Tracked clients keep their tokens,
+// capped to the new burst on their next request.
+func (l *Limiter) SetLimit(limit Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = normalize(limit)
+}
+
+// Allow takes a token from key's bucket and reports whether one was available
+func (l *Limiter) Allow(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit.RPS <= 0 {
+		return true
+	}
+	now := l.now()
+	b := l.bucket(key, now)
+	b.tokens += now.Sub(b.last).Seconds() * l.limit.RPS
+	if burst := float64(l.limit.Burst); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Len returns the number of tracked buckets
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// normalize raises the burst of limit to 1 so a bucket can ever hold a token
+func normalize(limit Limit) Limit {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return limit
+}
+
+// bucket returns key's bucket, creating a full one and evicting the oldest if needed
+func (l *Limiter) bucket(key string, now time.Time) *bucket {
+	if elem, ok := l.buckets[key]; ok {
+		l.order.MoveToFront(elem)
+		return elem.Value.(*bucket)
+	}
+	for l.order.Len() >= l.maxKeys {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.buckets, oldest.Value.(*bucket).key)
+	}
+	b := &bucket{key: key, tokens: float64(l.limit.Burst), last: now}
+	l.buckets[key] = l.order.PushFront(b)
+	return b
+}
@@ -0,0 +1,235 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+	"github.com/fawa-io/fawa/fileservice/pkg/clientip"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestLimiter(limit Limit, maxKeys int) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	l := NewLimiter(limit, maxKeys)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestLimiterAllow(t *testing.T) {
+	l, clock := newTestLimiter(Limit{RPS: 2, Burst: 3}, 0)
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow("1.2.3.4") {
+			t.Fatalf("request %d within burst was rejected", i)
+		}
+	}
+	if l.Allow("1.2.3.4") {
+		t.Fatal("request over burst was allowed")
+	}
+	if !l.Allow("5.6.7.8") {
+		t.Fatal("other client shares the exhausted bucket")
+	}
+
+	clock.t = clock.t.Add(500 * time.Millisecond)
+	if !l.Allow("1.2.3.4") {
+		t.Fatal("refilled token was not available")
+	}
+	if l.Allow("1.2.3.4") {
+		t.Fatal("bucket refilled faster than RPS")
+	}
+
+	// A long idle period refills to the burst, not beyond
+	clock.t = clock.t.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if !l.Allow("1.2.3.4") {
+			t.Fatalf("request %d after idle period was rejected", i)
+		}
+	}
+	if l.Allow("1.2.3.4") {
+		t.Fatal("bucket grew beyond burst while idle")
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l, _ := newTestLimiter(Limit{}, 0)
+	for i := 0; i < 100; i++ {
+		if !l.Allow("1.2.3.4") {
+			t.Fatal("disabled limiter rejected a request")
+		}
+	}
+	if l.Len() != 0 {
+		t.Errorf("disabled limiter tracks %d clients, want 0", l.Len())
+	}
+
+	var nilLimiter *Limiter
+	if !nilLimiter.Allow("1.2.3.4") {
+		t.Error("nil limiter rejected a request")
+	}
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	l, clock := newTestLimiter(Limit{RPS: 1, Burst: 5}, 0)
+	for i := 0; i < 5; i++ {
+		l.Allow("1.2.3.4")
+	}
+
+	l.SetLimit(Limit{})
+	if !l.Allow("1.2.3.4") {
+		t.Fatal("request rejected after the limit was disabled")
+	}
+
+	l.SetLimit(Limit{RPS: 10, Burst: 2})
+	clock.t = clock.t.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if !l.Allow("1.2.3.4") {
+			t.Fatalf("request %d within the new burst was rejected", i)
+		}
+	}
+	if l.Allow("1.2.3.4") {
+		t.Fatal("bucket kept the old burst after SetLimit")
+	}
+	clock.t = clock.t.Add(100 * time.Millisecond)
+	if !l.Allow("1.2.3.4") {
+		t.Fatal("bucket did not refill at the new rate")
+	}
+}
+
+func TestLimiterBounded(t *testing.T) {
+	l, _ := newTestLimiter(Limit{RPS: 1, Burst: 1}, 3)
+
+	if !l.Allow("keep") {
+		t.Fatal("first request was rejected")
+	}
+	for i := 0; i < 10; i++ {
+		l.Allow(fmt.Sprintf("10.0.0.%d", i))
+		// Keep the first client recently used so it survives eviction
+		l.Allow("keep")
+	}
+	if l.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", l.Len())
+	}
+	if l.Allow("keep") {
+		t.Error("recently used client was evicted and got a fresh bucket")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	testCases := []struct {
+		addr string
+		want string
+	}{
+		{addr: "1.2.3.4:5678", want: "1.2.3.4"},
+		{addr: "[2001:db8::1]:443", want: "2001:db8::1"},
+		{addr: "1.2.3.4", want: "1.2.3.4"},
+		{addr: "", want: ""},
+	}
+
+	for _, tc := range testCases {
+		if got := clientIP(tc.addr); got != tc.want {
+			t.Errorf("clientIP(%q) = %q, want %q", tc.addr, got, tc.want)
+		}
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	limiter := NewLimiter(Limit{RPS: 0.001, Burst: 2}, 0)
+	interceptor := NewInterceptor(map[string]*Limiter{
+		filev1connect.FileServiceGetFileInfoProcedure: limiter,
+		filev1connect.FileServiceSendFileProcedure:    limiter,
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(filev1connect.UnimplementedFileServiceHandler{}, connect.WithInterceptors(interceptor)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetFileInfo(ctx, connect.NewRequest(&filev1.GetFileInfoRequest{}))
+		if connect.CodeOf(err) != connect.CodeUnimplemented {
+			t.Fatalf("request %d: got %v, want it to reach the handler", i, err)
+		}
+	}
+	_, err := client.GetFileInfo(ctx, connect.NewRequest(&filev1.GetFileInfoRequest{}))
+	if connect.CodeOf(err) != connect.CodeResourceExhausted {
+		t.Fatalf("request over limit: got %v, want %v", err, connect.CodeResourceExhausted)
+	}
+	if reason := apierr.ReasonOf(err); reason != filev1.ErrorReason_ERROR_REASON_RATE_LIMITED {
+		t.Errorf("request over limit: reason = %v, want %v", reason, filev1.ErrorReason_ERROR_REASON_RATE_LIMITED)
+	}
+
+	// Procedures without a limiter are not limited
+	_, err = client.GetDownloadURL(ctx, connect.NewRequest(&filev1.GetDownloadURLRequest{}))
+	if connect.CodeOf(err) != connect.CodeUnimplemented {
+		t.Fatalf("unlimited procedure: got %v", err)
+	}
+
+	// Streaming calls share the exhausted budget
+	stream := client.SendFile(ctx)
+	_, err = stream.CloseAndReceive()
+	if connect.CodeOf(err) != connect.CodeResourceExhausted {
+		t.Fatalf("streaming request over limit: got %v, want %v", err, connect.CodeResourceExhausted)
+	}
+}
+
+func TestInterceptorClientIP(t *testing.T) {
+	resolver, err := clientip.New([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := NewInterceptor(map[string]*Limiter{
+		filev1connect.FileServiceGetFileInfoProcedure: NewLimiter(Limit{RPS: 0.001, Burst: 1}, 0),
+	})
+	interceptor.ClientIP = resolver.ClientIP
+
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(filev1connect.UnimplementedFileServiceHandler{}, connect.WithInterceptors(interceptor)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+	getFileInfo := func(forwardedFor string) error {
+		req := connect.NewRequest(&filev1.GetFileInfoRequest{})
+		req.Header().Set("X-Forwarded-For", forwardedFor)
+		_, err := client.GetFileInfo(context.Background(), req)
+		return err
+	}
+
+	// Clients behind the trusted proxy get a bucket each
+	for _, ip := range []string{"198.51.100.1", "198.51.100.2"} {
+		if err := getFileInfo(ip); connect.CodeOf(err) != connect.CodeUnimplemented {
+			t.Fatalf("first request from %s: got %v, want it to reach the handler", ip, err)
+		}
+	}
+	if err := getFileInfo("198.51.100.1"); connect.CodeOf(err) != connect.CodeResourceExhausted {
+		t.Fatalf("second request: got %v, want %v", err, connect.CodeResourceExhausted)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package requestid tags every request with an ID, taken from the X-Request-ID header or
+// generated, so the log lines of a request can be correlated.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/fawa-io/fwpkg/fwlog"
+)
+
+// Header carries the request ID both ways
+const Header = "X-Request-ID"
+
+// maxLength bounds the IDs accepted from clients, longer ones are replaced
+const maxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID of ctx, empty when it has none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New returns a random request ID
+func New() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// valid reports whether a client supplied ID is short printable ASCII, safe to log and echo
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Interceptor puts the request ID in the context of the handlers and echoes it in the response header
+type Interceptor struct{}
+
+var _ connect.Interceptor = Interceptor{}
+
+// NewInterceptor returns the request ID interceptor, install it first so every log line carries the ID
+func NewInterceptor() Interceptor {
+	return Interceptor{}
+}
+
+// ID returns the request ID sent by the client in header, or a new one when it sent none or an invalid one
+func ID(header http.Header) string {
+	if id := header.Get(Header); valid(id) {
+		return id
+	}
+	return New()
+}
+
+func (Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		id := ID(req.Header())
+		res, err := next(NewContext(ctx, id), req)
+		if err != nil {
+			var connectErr *connect.Error
+			if errors.As(err, &connectErr) {
+				connectErr.Meta().Set(Header, id)
+			}
+			return res, err
+		}
+		res.Header().Set(Header, id)
+		return res, nil
+	}
+}
+
+func (Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		id := ID(conn.RequestHeader())
+		// The headers go out with the first message, or with the error when there is none
+		conn.ResponseHeader().Set(Header, id)
+		return next(NewContext(ctx, id), conn)
+	}
+}
+
+// Logger returns a logger prefixing every line with the request ID of ctx,
+// the default logger when ctx has none
+func Logger(ctx context.Context) fwlog.Logger {
+	id := FromContext(ctx)
+	if id == "" {
+		return fwlog.DefaultLogger()
+	}
+	return prefixLogger{Logger: fwlog.DefaultLogger(), prefix: "[" + id + "] "}
+}
+
+// prefixLogger prefixes the lines of Logger. Its methods call Logger directly, like the fwlog
+// functions do, so the logged caller is still the line calling them.
+// The prefix is passed as an argument, the ID comes from the client and may hold verbs.
+type prefixLogger struct {
+	fwlog.Logger
+	prefix string
+}
+
+func (l prefixLogger) Debugf(format string, v ...any) {
+	l.Logger.Debugf("%s"+format, append([]any{l.prefix}, v...)...)
+}
+
+func (l prefixLogger) Infof(format string, v ...any) {
+	l.Logger.Infof("%s"+format, append([]any{l.prefix}, v...)...)
+}
+
+func (l prefixLogger) Warnf(format string, v ...any) {
+	l.Logger.Warnf("%s"+format, append([]any{l.prefix}, v...)...)
+}
+
+func (l prefixLogger) Errorf(format string, v ...any) {
+	l.Logger.Errorf("%s"+format, append([]any{l.prefix}, v...)...)
+}
+
+func (l prefixLogger) Fatalf(format string, v ...any) {
+	l.Logger.Fatalf("%s"+format, append([]any{l.prefix}, v...)...)
+}
+
+func (l prefixLogger) Debug(v ...any) { l.Logger.Debug(append([]any{l.prefix}, v...)...) }
+func (l prefixLogger) Info(v ...any)  { l.Logger.Info(append([]any{l.prefix}, v...)...) }
+func (l prefixLogger) Warn(v ...any)  { l.Logger.Warn(append([]any{l.prefix}, v...)...) }
+func (l prefixLogger) Error(v ...any) { l.Logger.Error(append([]any{l.prefix}, v...)...) }
+func (l prefixLogger) Fatal(v ...any) { l.Logger.Fatal(append([]any{l.prefix}, v...)...) }
@@ -0,0 +1,154 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/fawa-io/fwpkg/fwlog"
+
+	filev1 "github.com/fawa-io/fawa/fileservice/gen/file/v1"
+	"github.com/fawa-io/fawa/fileservice/gen/file/v1/filev1connect"
+)
+
+func TestValid(t *testing.T) {
+	testCases := []struct {
+		id   string
+		want bool
+	}{
+		{id: "abc-123", want: true},
+		{id: strings.Repeat("a", maxLength), want: true},
+		{id: ""},
+		{id: strings.Repeat("a", maxLength+1)},
+		{id: "with space"},
+		{id: "new\nline"},
+		{id: "ünicode"},
+	}
+	for _, tc := range testCases {
+		if got := valid(tc.id); got != tc.want {
+			t.Errorf("valid(%q) = %v, want %v", tc.id, got, tc.want)
+		}
+	}
+}
+
+// idEcho answers with the request ID of the handler context as the file name,
+// and fails SendFile to check the ID comes back with errors too
+type idEcho struct {
+	filev1connect.UnimplementedFileServiceHandler
+}
+
+func (idEcho) GetFileInfo(ctx context.Context, _ *connect.Request[filev1.GetFileInfoRequest]) (*connect.Response[filev1.GetFileInfoResponse], error) {
+	return connect.NewResponse(&filev1.GetFileInfoResponse{Filename: FromContext(ctx)}), nil
+}
+
+func (idEcho) ReceiveFile(ctx context.Context, _ *connect.Request[filev1.ReceiveFileRequest], stream *connect.ServerStream[filev1.ReceiveFileResponse]) error {
+	return stream.Send(&filev1.ReceiveFileResponse{Filename: FromContext(ctx)})
+}
+
+func TestInterceptor(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(filev1connect.NewFileServiceHandler(idEcho{}, connect.WithInterceptors(NewInterceptor())))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := filev1connect.NewFileServiceClient(srv.Client(), srv.URL)
+	ctx := context.Background()
+
+	// A valid client ID is kept
+	req := connect.NewRequest(&filev1.GetFileInfoRequest{})
+	req.Header().Set(Header, "client-id-1")
+	res, err := client.GetFileInfo(ctx, req)
+	if err != nil {
+		t.Fatalf("GetFileInfo() failed: %v", err)
+	}
+	if res.Msg.Filename != "client-id-1" || res.Header().Get(Header) != "client-id-1" {
+		t.Errorf("handler saw %q and response header is %q, want the client ID", res.Msg.Filename, res.Header().Get(Header))
+	}
+
+	// An invalid one is replaced
+	req = connect.NewRequest(&filev1.GetFileInfoRequest{})
+	req.Header().Set(Header, "bad id")
+	res, err = client.GetFileInfo(ctx, req)
+	if err != nil {
+		t.Fatalf("GetFileInfo() failed: %v", err)
+	}
+	if id := res.Header().Get(Header); id == "bad id" || !valid(id) || id != res.Msg.Filename {
+		t.Errorf("response ID = %q, handler saw %q, want a new valid ID", id, res.Msg.Filename)
+	}
+
+	// Errors carry the ID
+	_, err = client.GetDownloadURL(ctx, connect.NewRequest(&filev1.GetDownloadURLRequest{}))
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || !valid(connectErr.Meta().Get(Header)) {
+		t.Errorf("error %v has no request ID", err)
+	}
+
+	// Streams too
+	stream, err := client.ReceiveFile(ctx, connect.NewRequest(&filev1.ReceiveFileRequest{}))
+	if err != nil {
+		t.Fatalf("ReceiveFile() failed: %v", err)
+	}
+	defer stream.Close()
+	if !stream.Receive() {
+		t.Fatalf("ReceiveFile() sent nothing: %v", stream.Err())
+	}
+	if id := stream.ResponseHeader().Get(Header); !valid(id) || id != stream.Msg().GetFilename() {
+		t.Errorf("stream response ID = %q, handler saw %q", id, stream.Msg().GetFilename())
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	fwlog.SetOutput(&buf)
+	t.Cleanup(func() { fwlog.SetOutput(io.Discard) })
+
+	Logger(NewContext(context.Background(), "req-42")).Infof("hello %s", "world")
+	Logger(context.Background()).Info("no id")
+	out := buf.String()
+	if !strings.Contains(out, "[req-42] hello world") {
+		t.Errorf("log %q lacks the prefixed line", out)
+	}
+	if !strings.Contains(out, `"no id"`) {
+		t.Errorf("log %q lacks the line without ID", out)
+	}
+	if !strings.Contains(out, "requestid_test.go") {
+		t.Errorf("log %q doesn't name the calling file", out)
+	}
+}
+
+// Client IDs may hold format verbs, they must not take the arguments of the line
+func TestLoggerIDWithVerbs(t *testing.T) {
+	var buf bytes.Buffer
+	fwlog.SetOutput(&buf)
+	t.Cleanup(func() { fwlog.SetOutput(io.Discard) })
+
+	header := http.Header{}
+	header.Set(Header, "a%s%d")
+	id := ID(header)
+	if id != "a%s%d" {
+		t.Fatalf("ID() = %q, want the client's", id)
+	}
+	Logger(NewContext(context.Background(), id)).Infof("hello %s %d", "world", 42)
+	if out := buf.String(); !strings.Contains(out, "[a%s%d] hello world 42") {
+		t.Errorf("log %q lacks the line with its arguments", out)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package thumbnail scales images down to JPEG thumbnails.
+//
+// PNG, JPEG and GIF images are supported. The scaling averages the source pixels under each
+// thumbnail pixel, which is all a thumbnail needs, and transparency is flattened on white.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	_ "image/png" // register the PNG decoder
+	"io"
+)
+
+const (
+	// MaxPixels bounds the size of the decoded images, so a small file declaring a huge
+	// image can't exhaust the memory
+	MaxPixels = 25_000_000
+	// ContentType is the type of the generated thumbnails
+	ContentType = "image/jpeg"
+
+	quality = 80
+)
+
+// ErrTooLarge is returned for images of more than MaxPixels pixels
+var ErrTooLarge = errors.New("thumbnail: image too large")
+
+// Generate decodes the image read from r and returns a JPEG thumbnail fitting in a size×size
+// square, keeping the aspect ratio. Images already fitting aren't enlarged.
+func Generate(r io.Reader, size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("thumbnail: invalid size %d", size)
+	}
+
+	// Check the dimensions from the header before decoding the pixels
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, fmt.Errorf("thumbnail: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("thumbnail: invalid dimensions %dx%d", config.Width, config.Height)
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	src, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, fmt.Errorf("thumbnail: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scale(flatten(src), size), &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// flatten draws img on a white background
+func flatten(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// scale shrinks src to fit in a size×size square, each pixel is the average of the source
+// pixels it covers
+func scale(src *image.RGBA, size int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw <= size && sh <= size {
+		return src
+	}
+	w, h := size, size
+	if sw >= sh {
+		h = max(1, sh*size/sw)
+	} else {
+		w = max(1, sw*size/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for dy := range h {
+		y0, y1 := span(dy, h, sh)
+		for dx := range w {
+			x0, x1 := span(dx, w, sw)
+			var r, g, b, a, n int
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride+x0*4 : y*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += int(row[i])
+					g += int(row[i+1])
+					b += int(row[i+2])
+					a += int(row[i+3])
+					n++
+				}
+			}
+			i := dy*dst.Stride + dx*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// span returns the source range [lo, hi) covered by pixel i of n scaled from srcLen, never empty
+func span(i, n, srcLen int) (int, int) {
+	lo := i * srcLen / n
+	hi := (i + 1) * srcLen / n
+	return lo, max(hi, lo+1)
+}
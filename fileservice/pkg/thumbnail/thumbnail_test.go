@@ -0,0 +1,108 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		width, height int
+		size          int
+		wantW, wantH  int
+	}{
+		{name: "landscape", width: 800, height: 400, size: 200, wantW: 200, wantH: 100},
+		{name: "portrait", width: 300, height: 900, size: 150, wantW: 50, wantH: 150},
+		{name: "square", width: 512, height: 512, size: 128, wantW: 128, wantH: 128},
+		{name: "not enlarged", width: 64, height: 32, size: 256, wantW: 64, wantH: 32},
+		{name: "thin", width: 1000, height: 1, size: 100, wantW: 100, wantH: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := Generate(bytes.NewReader(encodePNG(t, tc.width, tc.height, color.NRGBA{R: 200, A: 255})), tc.size)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			thumb, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("the thumbnail isn't a JPEG: %v", err)
+			}
+			if b := thumb.Bounds(); b.Dx() != tc.wantW || b.Dy() != tc.wantH {
+				t.Errorf("thumbnail is %dx%d, want %dx%d", b.Dx(), b.Dy(), tc.wantW, tc.wantH)
+			}
+		})
+	}
+}
+
+func TestGenerateFlattensTransparency(t *testing.T) {
+	data, err := Generate(bytes.NewReader(encodePNG(t, 100, 100, color.NRGBA{})), 10)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("the thumbnail isn't a JPEG: %v", err)
+	}
+	// JPEG is lossy, transparent pixels only have to come out close to white
+	if r, g, b, _ := thumb.At(5, 5).RGBA(); r>>8 < 240 || g>>8 < 240 || b>>8 < 240 {
+		t.Errorf("transparent pixel = %v, want white", thumb.At(5, 5))
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	// A tiny GIF declaring a huge screen
+	var small bytes.Buffer
+	if err := gif.Encode(&small, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil); err != nil {
+		t.Fatalf("gif.Encode() error = %v", err)
+	}
+	huge := bytes.Clone(small.Bytes())
+	huge[6], huge[7], huge[8], huge[9] = 0xff, 0xff, 0xff, 0xff
+
+	if _, err := Generate(bytes.NewReader(huge), 100); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Generate() of a huge image error = %v, want %v", err, ErrTooLarge)
+	}
+	if _, err := Generate(strings.NewReader("not an image"), 100); err == nil {
+		t.Error("Generate() of text should fail")
+	}
+	if _, err := Generate(bytes.NewReader(small.Bytes()), 0); err == nil {
+		t.Error("Generate() with a zero size should fail")
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/cors"
+
+	"github.com/fawa-io/fawa/fileservice/config"
+)
+
+var (
+	// defaultAllowedMethods are allowed when the config lists no methods
+	defaultAllowedMethods = []string{
+		http.MethodHead,
+		http.MethodGet,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+	}
+	// defaultAllowedHeaders are allowed when the config lists no headers
+	defaultAllowedHeaders = []string{"*"}
+	// exposedHeaders are the Connect and gRPC-Web headers browsers must be able to read
+	exposedHeaders = []string{
+		// Content-Type is in the default safelist.
+		"Accept",
+		"Accept-Encoding",
+		"Accept-Post",
+		"Connect-Accept-Encoding",
+		"Connect-Content-Encoding",
+		"Content-Encoding",
+		"Grpc-Accept-Encoding",
+		"Grpc-Encoding",
+		"Grpc-Message",
+		"Grpc-Status",
+		"Grpc-Status-Details-Bin",
+		// X-Request-ID identifies the request in the server logs
+		"X-Request-ID",
+	}
+)
+
+// NewCORS builds the CORS middleware from the configured allowlist.
+// Every origin is allowed in dev mode, otherwise only the listed ones are.
+func NewCORS(c config.CORSConfig, devMode bool) *cors.Cors {
+	methods := c.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultAllowedMethods
+	}
+	headers := c.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultAllowedHeaders
+	}
+	return cors.New(cors.Options{
+		AllowedMethods:  methods,
+		AllowOriginFunc: OriginAllowed(c, devMode),
+		AllowedHeaders:  headers,
+		ExposedHeaders:  exposedHeaders,
+		// Let browsers cache CORS information for longer, which reduces the number
+		// of preflight requests. FF caps this value at 24h, and modern Chrome caps it at 2h.
+		MaxAge: int(2 * time.Hour / time.Second),
+	})
+}
+
+// OriginAllowed returns a matcher for the configured allowlist.
+// An entry is an exact origin such as https://fawa.example.com, "*" for any origin,
+// or a wildcard subdomain such as https://*.example.com.
+func OriginAllowed(c config.CORSConfig, devMode bool) func(origin string) bool {
+	origins := make([]string, len(c.AllowedOrigins))
+	for i, o := range c.AllowedOrigins {
+		origins[i] = strings.ToLower(strings.TrimSuffix(o, "/"))
+	}
+	return func(origin string) bool {
+		if devMode || slices.Contains(origins, "*") {
+			return true
+		}
+		origin = strings.ToLower(origin)
+		for _, allowed := range origins {
+			if allowed == origin {
+				return true
+			}
+			if prefix, suffix, ok := strings.Cut(allowed, "*"); ok &&
+				len(origin) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// CheckOrigin adapts the allowlist to WebSocket and WebTransport upgrades.
+// Requests without an Origin header don't come from a browser and are accepted.
+func CheckOrigin(c config.CORSConfig, devMode bool) func(r *http.Request) bool {
+	allowed := OriginAllowed(c, devMode)
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || allowed(origin)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fawa-io/fawa/fileservice/config"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	cfg := config.CORSConfig{AllowedOrigins: []string{"https://fawa.example.com/", "https://*.cdn.example.com"}}
+
+	testCases := []struct {
+		name    string
+		cfg     config.CORSConfig
+		devMode bool
+		origin  string
+		want    bool
+	}{
+		{name: "listed origin", cfg: cfg, origin: "https://fawa.example.com", want: true},
+		{name: "case insensitive", cfg: cfg, origin: "https://FAWA.example.com", want: true},
+		{name: "wildcard subdomain", cfg: cfg, origin: "https://eu.cdn.example.com", want: true},
+		{name: "wildcard needs a subdomain", cfg: cfg, origin: "https://.cdn.example.com", want: false},
+		{name: "other scheme", cfg: cfg, origin: "http://fawa.example.com", want: false},
+		{name: "unlisted origin", cfg: cfg, origin: "https://evil.example.com", want: false},
+		{name: "empty allowlist", origin: "https://fawa.example.com", want: false},
+		{name: "empty allowlist in dev mode", devMode: true, origin: "https://fawa.example.com", want: true},
+		{name: "star", cfg: config.CORSConfig{AllowedOrigins: []string{"*"}}, origin: "https://any.example.com", want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := OriginAllowed(tc.cfg, tc.devMode)(tc.origin); got != tc.want {
+				t.Errorf("OriginAllowed(%q) = %v, want %v", tc.origin, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewCORS(t *testing.T) {
+	cfg := config.CORSConfig{AllowedOrigins: []string{"https://fawa.example.com"}}
+	h := NewCORS(cfg, false).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for origin, want := range map[string]string{
+		"https://fawa.example.com": "https://fawa.example.com",
+		"https://evil.example.com": "",
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/file.v1.FileService/GetFileInfo", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("preflight from %s: Access-Control-Allow-Origin = %q, want %q", origin, got, want)
+		}
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	check := CheckOrigin(config.CORSConfig{AllowedOrigins: []string{"https://fawa.example.com"}}, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/ws/canva", nil)
+	if !check(req) {
+		t.Error("a request without Origin should be allowed")
+	}
+	req.Header.Set("Origin", "https://evil.example.com")
+	if check(req) {
+		t.Error("an unlisted origin should be rejected")
+	}
+	req.Header.Set("Origin", "https://fawa.example.com")
+	if !check(req) {
+		t.Error("a listed origin should be allowed")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fawa-io/fwpkg/fwlog"
+)
+
+// Stage orders the shutdown steps, all steps of a stage run before the next stage starts
+type Stage int
+
+const (
+	// StageDrain stops accepting new work and drains in-flight streams
+	StageDrain Stage = iota
+	// StageServices closes services and their storage connections
+	StageServices
+	// StageServers shuts down the HTTP servers
+	StageServers
+
+	stageCount
+)
+
+type step struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Shutdowner coordinates the graceful shutdown of servers and services.
+// Steps run in stage order, then in registration order, under a single deadline.
+type Shutdowner struct {
+	timeout time.Duration
+
+	mu    sync.Mutex
+	steps [stageCount][]step
+}
+
+// NewShutdowner creates a Shutdowner whose steps must all finish within timeout
+func NewShutdowner(timeout time.Duration) *Shutdowner {
+	return &Shutdowner{timeout: timeout}
+}
+
+// Register adds a shutdown step to the given stage
+func (s *Shutdowner) Register(stage Stage, name string, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps[stage] = append(s.steps[stage], step{name: name, fn: fn})
+}
+
+// RegisterCloser adds an io.Closer to the given stage
+func (s *Shutdowner) RegisterCloser(stage Stage, name string, c io.Closer) {
+	s.Register(stage, name, func(context.Context) error {
+		return c.Close()
+	})
+}
+
+// RegisterServer adds an HTTP server to the StageServers stage
+func (s *Shutdowner) RegisterServer(name string, srv *http.Server) {
+	s.Register(StageServers, name, srv.Shutdown)
+}
+
+// Shutdown runs all registered steps in order.
+// A step that doesn't return before the deadline is abandoned and the remaining steps are skipped.
+func (s *Shutdowner) Shutdown(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	s.mu.Lock()
+	var steps []step
+	for _, stageSteps := range s.steps {
+		steps = append(steps, stageSteps...)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, st := range steps {
+		if err := ctx.Err(); err != nil {
+			fwlog.Errorf("Skipping shutdown of %s: %v", st.name, err)
+			errs = append(errs, fmt.Errorf("%s: skipped: %w", st.name, err))
+			continue
+		}
+		fwlog.Infof("Shutting down %s...", st.name)
+		if err := runStep(ctx, st); err != nil {
+			fwlog.Errorf("Error shutting down %s: %v", st.name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", st.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runStep runs a step, giving up when the context is done
+func runStep(ctx context.Context, st step) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- st.fn(ctx)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitForSignal blocks until SIGINT or SIGTERM is received, then runs Shutdown
+func (s *Shutdowner) WaitForSignal() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	<-sigCh
+
+	fwlog.Info("Shutting down server...")
+	if err := s.Shutdown(context.Background()); err != nil {
+		fwlog.Errorf("Server shutdown error: %v", err)
+	}
+	fwlog.Info("Server shutdown complete")
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestShutdownerRunsStepsInOrder(t *testing.T) {
+	s := NewShutdowner(time.Second)
+
+	var mu sync.Mutex
+	var got []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, name)
+	}
+
+	// Register out of stage order on purpose.
+	s.RegisterServer("http", &http.Server{})
+	s.Register(StageServers, "http3", func(context.Context) error {
+		record("http3")
+		return nil
+	})
+	s.RegisterCloser(StageServices, "storage", closerFunc(func() error {
+		record("storage")
+		return nil
+	}))
+	s.Register(StageDrain, "streams", func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("step context has no deadline")
+		}
+		record("streams")
+		return nil
+	})
+	s.RegisterCloser(StageServices, "service", closerFunc(func() error {
+		record("service")
+		return nil
+	}))
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	want := []string{"streams", "storage", "service", "http3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("steps ran in order %v, want %v", got, want)
+	}
+}
+
+func TestShutdownerDeadline(t *testing.T) {
+	s := NewShutdowner(50 * time.Millisecond)
+
+	skipped := true
+	s.Register(StageDrain, "stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	s.RegisterCloser(StageServices, "service", closerFunc(func() error {
+		skipped = false
+		return nil
+	}))
+
+	start := time.Now()
+	err := s.Shutdown(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Shutdown() took %v, want it bounded by the deadline", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want deadline exceeded", err)
+	}
+	if !skipped {
+		t.Error("step after the deadline should be skipped")
+	}
+}
+
+func TestShutdownerJoinsErrors(t *testing.T) {
+	s := NewShutdowner(time.Second)
+
+	closeErr := errors.New("close failed")
+	ran := false
+	s.RegisterCloser(StageServices, "failing", closerFunc(func() error {
+		return closeErr
+	}))
+	s.RegisterCloser(StageServices, "next", closerFunc(func() error {
+		ran = true
+		return nil
+	}))
+
+	err := s.Shutdown(context.Background())
+	if !errors.Is(err, closeErr) {
+		t.Errorf("Shutdown() error = %v, want it to include %v", err, closeErr)
+	}
+	if !ran {
+		t.Error("a failing step should not stop the following ones")
+	}
+}
@@ -16,29 +16,105 @@ package storage
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
-	"os"
 	"time"
 
 	"github.com/fawa-io/fawa/pkg/fwlog"
 	"github.com/redis/go-redis/v9"
 )
 
-var dragon *DragonflyStorage
+const (
+	// metadataTTL is how long a shared file stays downloadable
+	metadataTTL = 25 * time.Minute
+	// downloadCountSuffix is appended to a download key to count its downloads
+	downloadCountSuffix = ":downloads"
+	// activeDownloadsSuffix is appended to a download key to count its downloads in progress
+	activeDownloadsSuffix = ":active"
+	// objectRefPrefix prefixes the key recording that metadata still references an object,
+	// it expires with the metadata so the orphan collector knows the object may go
+	objectRefPrefix = "objectref:"
+)
 
-func init() {
-	addr := os.Getenv("DRAGONFLY_ADDR")
-	if addr == "" {
-		addr = "localhost:6379"
-	}
+// ErrTooManyDownloads is returned when a file already has the maximum number of downloads in progress
+var ErrTooManyDownloads = errors.New("too many concurrent downloads")
+
+// defaultDragonflyAddr is the metadata store address until InitDragonfly configures another
+const defaultDragonflyAddr = "localhost:6379"
+
+// dragon connects lazily, so the default store costs nothing until it's used
+var dragon = newDragonflyStorage(DragonflyConfig{})
+
+// DragonflyConfig is the connection of the metadata store
+type DragonflyConfig struct {
+	Addr     string
+	DB       int
+	Password string
+	// TLS connects over TLS, verifying the server certificate against the host of Addr
+	TLS bool
+	// ClusterAddrs are the seed nodes of a Redis cluster, setting them replaces Addr.
+	// A cluster only has database 0, so DB must be left at 0.
+	ClusterAddrs []string
+	// MaxRetries is how many times a command failing on a connection error or a failover
+	// (LOADING, READONLY, TRYAGAIN...) is retried, 0 disables retries. redis.Nil and the
+	// other replies are never retried.
+	MaxRetries int
+	// MinRetryBackoff and MaxRetryBackoff bound the exponential backoff between retries,
+	// zero picks the go-redis defaults of 8ms and 512ms
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+}
 
-	dragon = &DragonflyStorage{
-		client: redis.NewClient(&redis.Options{
-			Addr: addr,
-			DB:   0,
-		}),
+func newDragonflyStorage(cfg DragonflyConfig) *DragonflyStorage {
+	var tlsConfig *tls.Config
+	if cfg.TLS {
+		// The server name is taken from the address of each node when dialing
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
 	}
+	// go-redis retries by default, -1 is how it's turned off
+	maxRetries := cfg.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = -1
+	}
+	if len(cfg.ClusterAddrs) > 0 {
+		return &DragonflyStorage{client: redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:           cfg.ClusterAddrs,
+			Password:        cfg.Password,
+			TLSConfig:       tlsConfig,
+			MaxRetries:      maxRetries,
+			MinRetryBackoff: cfg.MinRetryBackoff,
+			MaxRetryBackoff: cfg.MaxRetryBackoff,
+		})}
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = defaultDragonflyAddr
+	}
+	return &DragonflyStorage{client: redis.NewClient(&redis.Options{
+		Addr:            cfg.Addr,
+		DB:              cfg.DB,
+		Password:        cfg.Password,
+		TLSConfig:       tlsConfig,
+		MaxRetries:      maxRetries,
+		MinRetryBackoff: cfg.MinRetryBackoff,
+		MaxRetryBackoff: cfg.MaxRetryBackoff,
+	})}
+}
+
+// InitDragonfly points the metadata store at cfg, an empty address is localhost:6379.
+// The connection is established on first use, check it with PingDragonfly.
+func InitDragonfly(cfg DragonflyConfig) error {
+	prev := dragon
+	dragon = newDragonflyStorage(cfg)
+	return prev.close()
+}
+
+// SetDragonflyClient makes the metadata store use client until the returned func restores the
+// previous store. It lets the tests of other packages replace Dragonfly by a mock.
+func SetDragonflyClient(client redis.Cmdable) (restore func()) {
+	prev := dragon
+	dragon = &DragonflyStorage{client: client}
+	return func() { dragon = prev }
 }
 
 // DragonflyStorage implements the Storage interface using Dragonfly/Redis.
@@ -46,7 +122,30 @@ type DragonflyStorage struct {
 	client redis.Cmdable
 }
 
-func (dragon *DragonflyStorage) saveFileMeta(key string, metadata *FileMetadata) error {
+var _ Storage = (*DragonflyStorage)(nil)
+
+// SaveFileMeta saves the metadata and the references to its objects, expiring after metadataTTL.
+// The keys expire at the absolute time recorded in metadata.ExpiresAt, so it matches the TTL Dragonfly reports.
+func (dragon *DragonflyStorage) SaveFileMeta(ctx context.Context, key string, metadata *FileMetadata) error {
+	if metadata == nil {
+		return errors.New("metadata cannot be nil")
+	}
+	// EXAT has a one second resolution
+	metadata.ExpiresAt = now().Add(metadataTTL).Truncate(time.Second).UTC()
+	jsonMetadata, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	pipe := dragon.client.TxPipeline()
+	pipe.SetArgs(ctx, key, jsonMetadata, redis.SetArgs{ExpireAt: metadata.ExpiresAt})
+	refObjects(ctx, pipe, key, metadata, metadata.ExpiresAt)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// UpdateFileMeta replaces the metadata saved under key, keeping its TTL.
+// It returns redis.Nil when the metadata expired rather than saving it again.
+func (dragon *DragonflyStorage) UpdateFileMeta(ctx context.Context, key string, metadata *FileMetadata) error {
 	if metadata == nil {
 		return errors.New("metadata cannot be nil")
 	}
@@ -54,12 +153,98 @@ func (dragon *DragonflyStorage) saveFileMeta(key string, metadata *FileMetadata)
 	if err != nil {
 		return err
 	}
-	ttl := 25 * time.Minute
-	return dragon.client.Set(context.Background(), key, jsonMetadata, ttl).Err()
+	return dragon.client.SetArgs(ctx, key, jsonMetadata, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+}
+
+// ExtendFileMeta makes the metadata saved under key expire ttl from now, or metadataTTL when ttl is 0,
+// along with its download counters and its quota usage. The references to its objects are extended
+// to the new expiry, but never shortened.
+// metadata.ExpiresAt is set to the new expiry. It returns redis.Nil when the metadata already expired.
+func (dragon *DragonflyStorage) ExtendFileMeta(ctx context.Context, key string, metadata *FileMetadata, ttl time.Duration) error {
+	if metadata == nil {
+		return errors.New("metadata cannot be nil")
+	}
+	if ttl <= 0 {
+		ttl = metadataTTL
+	}
+	extended := *metadata
+	extended.ExpiresAt = now().Add(ttl).Truncate(time.Second).UTC()
+	jsonMetadata, err := json.Marshal(&extended)
+	if err != nil {
+		return err
+	}
+	pipe := dragon.client.TxPipeline()
+	// The metadata is rewritten rather than EXPIREd so that it records its new expiry,
+	// XX keeps an expired link from coming back
+	set := pipe.SetArgs(ctx, key, jsonMetadata, redis.SetArgs{Mode: "XX", ExpireAt: extended.ExpiresAt})
+	refObjects(ctx, pipe, key, metadata, extended.ExpiresAt)
+	pipe.ExpireAt(ctx, key+downloadCountSuffix, extended.ExpiresAt)
+	pipe.ExpireAt(ctx, key+activeDownloadsSuffix, extended.ExpiresAt)
+	if metadata.QuotaOwner != "" {
+		quota := quotaKey(metadata.QuotaOwner)
+		pipe.ZAddXX(ctx, quota, redis.Z{Score: float64(extended.ExpiresAt.Unix()), Member: quotaMember(key, metadata.Size)})
+		// The set must outlive its longest member, GT never shortens it
+		pipe.ExpireGT(ctx, quota, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if err := set.Err(); err != nil {
+		return err
+	}
+	metadata.ExpiresAt = extended.ExpiresAt
+	return nil
+}
+
+// DeleteFileMeta removes the metadata saved under key, its download counters, the references to
+// its objects and its quota usage
+func (dragon *DragonflyStorage) DeleteFileMeta(ctx context.Context, key string, metadata *FileMetadata) error {
+	pipe := dragon.client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.Del(ctx, key+downloadCountSuffix)
+	pipe.Del(ctx, key+activeDownloadsSuffix)
+	for _, object := range metadata.objects() {
+		pipe.Del(ctx, objectRefKey(metadata.Bucket, object))
+	}
+	if metadata.QuotaOwner != "" {
+		pipe.ZRem(ctx, quotaKey(metadata.QuotaOwner), quotaMember(key, metadata.Size))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// refObjects records in pipe that the metadata saved under key references its objects until
+// expiresAt. A reference is one key per object that any link may have set, so an existing one is
+// only ever lengthened: shortening it would let the orphan collector remove an object another
+// link still shares.
+func refObjects(ctx context.Context, pipe redis.Pipeliner, key string, metadata *FileMetadata, expiresAt time.Time) {
+	for _, object := range metadata.objects() {
+		ref := objectRefKey(metadata.Bucket, object)
+		pipe.SetNX(ctx, ref, key, 0)
+		// NX gives a reference just created its expiry, GT lengthens an existing one
+		pipe.Do(ctx, "expireat", ref, expiresAt.Unix(), "nx")
+		pipe.Do(ctx, "expireat", ref, expiresAt.Unix(), "gt")
+	}
+}
+
+// objectRefKey is the key recording that metadata references the object, the bucket name is
+// resolved so that the primary bucket has one key whether it's named or left empty
+func objectRefKey(bucketName, objectName string) string {
+	if fileStore != nil {
+		bucketName = fileStore.bucket(bucketName)
+	}
+	return objectRefPrefix + bucketName + "/" + objectName
+}
+
+// isReferenced reports whether unexpired metadata references the object
+func (dragon *DragonflyStorage) isReferenced(ctx context.Context, bucketName, objectName string) (bool, error) {
+	n, err := dragon.client.Exists(ctx, objectRefKey(bucketName, objectName)).Result()
+	return n > 0, err
 }
 
-func (dragon *DragonflyStorage) getFileMeta(key string) (*FileMetadata, error) {
-	val, err := dragon.client.Get(context.Background(), key).Result()
+// GetFileMeta returns the metadata saved under key, redis.Nil when there is none
+func (dragon *DragonflyStorage) GetFileMeta(ctx context.Context, key string) (*FileMetadata, error) {
+	val, err := dragon.client.Get(ctx, key).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -71,16 +256,132 @@ func (dragon *DragonflyStorage) getFileMeta(key string) (*FileMetadata, error) {
 	return &metadata, nil
 }
 
-func SaveFileMeta(key string, metadata *FileMetadata) error {
-	return dragon.saveFileMeta(key, metadata)
+func (dragon *DragonflyStorage) getFileTTL(key string) (time.Duration, error) {
+	ttl, err := dragon.client.TTL(context.Background(), key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// A negative TTL means the key doesn't exist (-2) or never expires (-1).
+	if ttl == -2 {
+		return 0, redis.Nil
+	}
+	return max(ttl, 0), nil
+}
+
+func (dragon *DragonflyStorage) incrDownloadCount(key string) (int64, error) {
+	ctx := context.Background()
+	countKey := key + downloadCountSuffix
+	pipe := dragon.client.TxPipeline()
+	incr := pipe.Incr(ctx, countKey)
+	pipe.Expire(ctx, countKey, metadataTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (dragon *DragonflyStorage) getDownloadCount(key string) (int64, error) {
+	count, err := dragon.client.Get(context.Background(), key+downloadCountSuffix).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return count, err
+}
+
+// acquireDownload counts a download in progress, unless limit are already running.
+// The counter expires with the metadata so that slots leaked by a crashed server don't block the file forever.
+func (dragon *DragonflyStorage) acquireDownload(key string, limit int64) error {
+	ctx := context.Background()
+	activeKey := key + activeDownloadsSuffix
+	pipe := dragon.client.TxPipeline()
+	incr := pipe.Incr(ctx, activeKey)
+	pipe.Expire(ctx, activeKey, metadataTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if incr.Val() > limit {
+		if err := dragon.releaseDownload(key); err != nil {
+			fwlog.Warnf("Failed to release download slot of %s: %v", key, err)
+		}
+		return ErrTooManyDownloads
+	}
+	return nil
+}
+
+func (dragon *DragonflyStorage) releaseDownload(key string) error {
+	return dragon.client.Decr(context.Background(), key+activeDownloadsSuffix).Err()
+}
+
+// Ping checks that Dragonfly answers, without touching any key
+func (dragon *DragonflyStorage) Ping(ctx context.Context) error {
+	return dragon.client.Ping(ctx).Err()
+}
+
+// SaveFileMeta saves the metadata of a shared file under its download key. Connection errors
+// are retried with backoff until the dragonfly.maxRetries policy or ctx gives up.
+func SaveFileMeta(ctx context.Context, key string, metadata *FileMetadata) error {
+	return dragon.SaveFileMeta(ctx, key, metadata)
+}
+
+// GetFileMeta returns the metadata saved under the download key, redis.Nil when there is none.
+// It's retried like SaveFileMeta.
+func GetFileMeta(ctx context.Context, key string) (*FileMetadata, error) {
+	return dragon.GetFileMeta(ctx, key)
+}
+
+// UpdateFileMeta replaces the metadata of a shared file without extending the time it stays shared
+func UpdateFileMeta(ctx context.Context, key string, metadata *FileMetadata) error {
+	return dragon.UpdateFileMeta(ctx, key, metadata)
+}
+
+// ExtendFileMeta keeps a file shared for ttl from now, as long as a new upload when ttl is 0.
+// It returns redis.Nil when the file is no longer shared.
+func ExtendFileMeta(ctx context.Context, key string, metadata *FileMetadata, ttl time.Duration) error {
+	return dragon.ExtendFileMeta(ctx, key, metadata, ttl)
 }
 
-func GetFileMeta(key string) (*FileMetadata, error) {
-	return dragon.getFileMeta(key)
+// DeleteFileMeta unshares a file, its object is left to the caller
+func DeleteFileMeta(ctx context.Context, key string, metadata *FileMetadata) error {
+	return dragon.DeleteFileMeta(ctx, key, metadata)
+}
+
+// GetFileTTL returns how long the file metadata has left before it expires
+func GetFileTTL(key string) (time.Duration, error) {
+	return dragon.getFileTTL(key)
+}
+
+// IncrDownloadCount records a download of the file and returns the new count
+func IncrDownloadCount(key string) (int64, error) {
+	return dragon.incrDownloadCount(key)
+}
+
+// GetDownloadCount returns how many times the file has been downloaded
+func GetDownloadCount(key string) (int64, error) {
+	return dragon.getDownloadCount(key)
+}
+
+// AcquireDownload counts a download of the file in progress, it returns ErrTooManyDownloads
+// when limit downloads are already running. Call ReleaseDownload once the download is over.
+func AcquireDownload(key string, limit int64) error {
+	return dragon.acquireDownload(key, limit)
+}
+
+// ReleaseDownload ends a download counted by AcquireDownload
+func ReleaseDownload(key string) error {
+	return dragon.releaseDownload(key)
+}
+
+// PingDragonfly checks that the metadata store is reachable
+func PingDragonfly(ctx context.Context) error {
+	return dragon.Ping(ctx)
 }
 
 // Close closes storage connections
 func Close() error {
+	return dragon.close()
+}
+
+func (dragon *DragonflyStorage) close() error {
 	if dragon != nil {
 		// Try to cast to redis.Client type
 		if client, ok := dragon.client.(*redis.Client); ok {
@@ -28,8 +28,30 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// expectRefObject expects the commands of refObjects for one object. created is whether the
+// reference is new, lengthened whether an existing one expired earlier.
+func expectRefObject(mock redismock.ClientMock, ref, key string, expiresAt time.Time, created, lengthened bool) {
+	mock.ExpectSetNX(ref, key, 0).SetVal(created)
+	mock.ExpectDo("expireat", ref, expiresAt.Unix(), "nx").SetVal(boolInt(created))
+	mock.ExpectDo("expireat", ref, expiresAt.Unix(), "gt").SetVal(boolInt(lengthened))
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func TestDragonflyStorage_SaveFileMeta(t *testing.T) {
 	client, mock := redismock.NewClientMock()
+	start := time.Date(2025, 6, 1, 12, 0, 0, 500, time.UTC)
+	prev := now
+	now = func() time.Time { return start }
+	t.Cleanup(func() { now = prev })
+	expiresAt := time.Date(2025, 6, 1, 12, 25, 0, 0, time.UTC)
+	expiry := redis.SetArgs{ExpireAt: expiresAt}
+	thumbnailJSON, _ := json.Marshal(&FileMetadata{Filename: "a.png", StoragePath: "k/a.png", ThumbnailPath: ".thumbnails/k", ExpiresAt: expiresAt})
 
 	storage := &DragonflyStorage{client: client}
 
@@ -53,11 +75,27 @@ func TestDragonflyStorage_SaveFileMeta(t *testing.T) {
 					Filename:    "test.txt",
 					Size:        123,
 					StoragePath: "/path/to/file",
+					ExpiresAt:   expiresAt,
 				})
-				mock.ExpectSet("test-key", metadataJSON, 25*time.Minute).SetVal("OK")
+				mock.ExpectTxPipeline()
+				mock.ExpectSetArgs("test-key", metadataJSON, expiry).SetVal("OK")
+				expectRefObject(mock, "objectref://path/to/file", "test-key", expiresAt, true, false)
+				mock.ExpectTxPipelineExec()
 			},
 			wantErr: false,
 		},
+		{
+			name:     "thumbnail",
+			key:      "k",
+			metadata: &FileMetadata{Filename: "a.png", StoragePath: "k/a.png", ThumbnailPath: ".thumbnails/k"},
+			mocker: func() {
+				mock.ExpectTxPipeline()
+				mock.ExpectSetArgs("k", thumbnailJSON, expiry).SetVal("OK")
+				expectRefObject(mock, "objectref:/k/a.png", "k", expiresAt, true, false)
+				expectRefObject(mock, "objectref:/.thumbnails/k", "k", expiresAt, true, false)
+				mock.ExpectTxPipelineExec()
+			},
+		},
 		{
 			name:     "nil metadata",
 			key:      "nil-key",
@@ -72,8 +110,9 @@ func TestDragonflyStorage_SaveFileMeta(t *testing.T) {
 				Filename: "error.txt",
 			},
 			mocker: func() {
-				metadataJSON, _ := json.Marshal(&FileMetadata{Filename: "error.txt"})
-				mock.ExpectSet("error-key", metadataJSON, 25*time.Minute).SetErr(errors.New("redis error"))
+				metadataJSON, _ := json.Marshal(&FileMetadata{Filename: "error.txt", ExpiresAt: expiresAt})
+				mock.ExpectTxPipeline()
+				mock.ExpectSetArgs("error-key", metadataJSON, expiry).SetErr(errors.New("redis error"))
 			},
 			wantErr: true,
 		},
@@ -82,10 +121,13 @@ func TestDragonflyStorage_SaveFileMeta(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.mocker()
-			err := storage.saveFileMeta(tc.key, tc.metadata)
+			err := storage.SaveFileMeta(context.Background(), tc.key, tc.metadata)
 			if (err != nil) != tc.wantErr {
 				t.Errorf("SaveFileMeta() error = %v, wantErr %v", err, tc.wantErr)
 			}
+			if tc.metadata != nil && !tc.metadata.ExpiresAt.Equal(expiresAt) {
+				t.Errorf("SaveFileMeta() set ExpiresAt = %v, want %v", tc.metadata.ExpiresAt, expiresAt)
+			}
 			if err := mock.ExpectationsWereMet(); err != nil {
 				t.Errorf("there were unfulfilled expectations: %s", err)
 			}
@@ -147,7 +189,7 @@ func TestDragonflyStorage_GetFileMeta(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.mocker()
-			got, err := storage.getFileMeta(tc.key)
+			got, err := storage.GetFileMeta(context.Background(), tc.key)
 			if (err != nil) != tc.wantErr {
 				t.Errorf("GetFileMeta() error = %v, wantErr %v", err, tc.wantErr)
 				return
@@ -162,6 +204,350 @@ func TestDragonflyStorage_GetFileMeta(t *testing.T) {
 	}
 }
 
+func TestDragonflyStorage_GetFileTTL(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+
+	storage := &DragonflyStorage{client: client}
+
+	testCases := []struct {
+		name    string
+		key     string
+		mocker  func()
+		want    time.Duration
+		wantErr bool
+	}{
+		{
+			name: "success",
+			key:  "test-key",
+			mocker: func() {
+				mock.ExpectTTL("test-key").SetVal(10 * time.Minute)
+			},
+			want: 10 * time.Minute,
+		},
+		{
+			name: "key not found",
+			key:  "not-found-key",
+			mocker: func() {
+				mock.ExpectTTL("not-found-key").SetVal(-2)
+			},
+			wantErr: true,
+		},
+		{
+			name: "redis error",
+			key:  "error-key",
+			mocker: func() {
+				mock.ExpectTTL("error-key").SetErr(errors.New("redis error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.mocker()
+			got, err := storage.getFileTTL(tc.key)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("GetFileTTL() error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			if got != tc.want {
+				t.Errorf("GetFileTTL() got = %v, want %v", got, tc.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestDragonflyStorage_UpdateFileMeta(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+
+	storage := &DragonflyStorage{client: client}
+	metadata := &FileMetadata{Filename: "test.txt", StoragePath: "test.txt"}
+	metadataJSON, _ := json.Marshal(metadata)
+
+	mock.ExpectSetArgs("test-key", metadataJSON, redis.SetArgs{Mode: "XX", KeepTTL: true}).SetVal("OK")
+	if err := storage.UpdateFileMeta(context.Background(), "test-key", metadata); err != nil {
+		t.Errorf("UpdateFileMeta() error = %v", err)
+	}
+
+	// Expired metadata isn't saved again
+	mock.ExpectSetArgs("expired-key", metadataJSON, redis.SetArgs{Mode: "XX", KeepTTL: true}).RedisNil()
+	if err := storage.UpdateFileMeta(context.Background(), "expired-key", metadata); !errors.Is(err, redis.Nil) {
+		t.Errorf("UpdateFileMeta() error = %v, want %v", err, redis.Nil)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDragonflyStorage_ExtendFileMeta(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	storage := &DragonflyStorage{client: client}
+	start := time.Date(2025, 6, 1, 12, 0, 0, 500, time.UTC)
+	prev := now
+	now = func() time.Time { return start }
+	t.Cleanup(func() { now = prev })
+
+	tests := []struct {
+		name      string
+		metadata  FileMetadata
+		ttl       time.Duration
+		expiresAt time.Time
+		mocker    func(metadataJSON []byte, expiresAt time.Time)
+		wantErr   error
+	}{
+		{
+			name:      "requested ttl",
+			metadata:  FileMetadata{Filename: "a.txt", StoragePath: "a.txt"},
+			ttl:       2 * time.Hour,
+			expiresAt: time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC),
+			mocker: func(metadataJSON []byte, expiresAt time.Time) {
+				mock.ExpectTxPipeline()
+				mock.ExpectSetArgs("key", metadataJSON, redis.SetArgs{Mode: "XX", ExpireAt: expiresAt}).SetVal("OK")
+				expectRefObject(mock, "objectref:/a.txt", "key", expiresAt, false, true)
+				mock.ExpectExpireAt("key:downloads", expiresAt).SetVal(false)
+				mock.ExpectExpireAt("key:active", expiresAt).SetVal(false)
+				mock.ExpectTxPipelineExec()
+			},
+		},
+		{
+			name:      "default ttl with quota",
+			metadata:  FileMetadata{Filename: "a.txt", StoragePath: "a.txt", Size: 30, QuotaOwner: "user:alice"},
+			expiresAt: time.Date(2025, 6, 1, 12, 25, 0, 0, time.UTC),
+			mocker: func(metadataJSON []byte, expiresAt time.Time) {
+				mock.ExpectTxPipeline()
+				mock.ExpectSetArgs("key", metadataJSON, redis.SetArgs{Mode: "XX", ExpireAt: expiresAt}).SetVal("OK")
+				expectRefObject(mock, "objectref:/a.txt", "key", expiresAt, false, true)
+				mock.ExpectExpireAt("key:downloads", expiresAt).SetVal(true)
+				mock.ExpectExpireAt("key:active", expiresAt).SetVal(false)
+				mock.ExpectZAddXX("quota:user:alice", redis.Z{Score: float64(expiresAt.Unix()), Member: "key:30"}).SetVal(0)
+				mock.ExpectExpireGT("quota:user:alice", 25*time.Minute).SetVal(true)
+				mock.ExpectTxPipelineExec()
+			},
+		},
+		{
+			name:      "expired",
+			metadata:  FileMetadata{Filename: "a.txt", StoragePath: "a.txt"},
+			ttl:       time.Hour,
+			expiresAt: time.Date(2025, 6, 1, 13, 0, 0, 0, time.UTC),
+			mocker: func(metadataJSON []byte, expiresAt time.Time) {
+				mock.ExpectTxPipeline()
+				mock.ExpectSetArgs("key", metadataJSON, redis.SetArgs{Mode: "XX", ExpireAt: expiresAt}).RedisNil()
+			},
+			wantErr: redis.Nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extended := tt.metadata
+			extended.ExpiresAt = tt.expiresAt
+			metadataJSON, _ := json.Marshal(&extended)
+			tt.mocker(metadataJSON, tt.expiresAt)
+
+			metadata := tt.metadata
+			err := storage.ExtendFileMeta(context.Background(), "key", &metadata, tt.ttl)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExtendFileMeta() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !metadata.ExpiresAt.Equal(tt.expiresAt) {
+				t.Errorf("ExpiresAt = %v, want %v", metadata.ExpiresAt, tt.expiresAt)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+// An upload sharing the object of a link extended further must not shorten its reference
+func TestObjectRefOnlyLengthened(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	storage := &DragonflyStorage{client: client}
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	prev := now
+	now = func() time.Time { return start }
+	t.Cleanup(func() { now = prev })
+
+	extendedUntil := start.Add(24 * time.Hour)
+	first := &FileMetadata{Filename: "report.pdf", StoragePath: "report.pdf"}
+	extendedJSON, _ := json.Marshal(&FileMetadata{Filename: "report.pdf", StoragePath: "report.pdf", ExpiresAt: extendedUntil})
+	mock.ExpectTxPipeline()
+	mock.ExpectSetArgs("first", extendedJSON, redis.SetArgs{Mode: "XX", ExpireAt: extendedUntil}).SetVal("OK")
+	expectRefObject(mock, "objectref:/report.pdf", "first", extendedUntil, false, true)
+	mock.ExpectExpireAt("first:downloads", extendedUntil).SetVal(false)
+	mock.ExpectExpireAt("first:active", extendedUntil).SetVal(false)
+	mock.ExpectTxPipelineExec()
+	if err := storage.ExtendFileMeta(context.Background(), "first", first, 24*time.Hour); err != nil {
+		t.Fatalf("ExtendFileMeta() error = %v", err)
+	}
+
+	// The reference exists and expires later, so neither SETNX nor EXPIREAT change it
+	savedUntil := start.Add(metadataTTL)
+	second := &FileMetadata{Filename: "report.pdf", StoragePath: "report.pdf"}
+	savedJSON, _ := json.Marshal(&FileMetadata{Filename: "report.pdf", StoragePath: "report.pdf", ExpiresAt: savedUntil})
+	mock.ExpectTxPipeline()
+	mock.ExpectSetArgs("second", savedJSON, redis.SetArgs{ExpireAt: savedUntil}).SetVal("OK")
+	expectRefObject(mock, "objectref:/report.pdf", "second", savedUntil, false, false)
+	mock.ExpectTxPipelineExec()
+	if err := storage.SaveFileMeta(context.Background(), "second", second); err != nil {
+		t.Fatalf("SaveFileMeta() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDragonflyStorage_DeleteFileMeta(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+
+	storage := &DragonflyStorage{client: client}
+
+	mock.ExpectTxPipeline()
+	mock.ExpectDel("test-key").SetVal(1)
+	mock.ExpectDel("test-key:downloads").SetVal(0)
+	mock.ExpectDel("test-key:active").SetVal(0)
+	mock.ExpectDel("objectref:/test.txt").SetVal(1)
+	mock.ExpectDel("objectref:/.thumbnails/test-key").SetVal(1)
+	mock.ExpectTxPipelineExec()
+	metadata := &FileMetadata{StoragePath: "test.txt", ThumbnailPath: ".thumbnails/test-key"}
+	if err := storage.DeleteFileMeta(context.Background(), "test-key", metadata); err != nil {
+		t.Errorf("DeleteFileMeta() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDragonflyStorage_DownloadCount(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+
+	storage := &DragonflyStorage{client: client}
+
+	mock.ExpectGet("test-key:downloads").SetErr(redis.Nil)
+	got, err := storage.getDownloadCount("test-key")
+	if err != nil || got != 0 {
+		t.Errorf("getDownloadCount() = %d, %v, want 0, nil", got, err)
+	}
+
+	mock.ExpectTxPipeline()
+	mock.ExpectIncr("test-key:downloads").SetVal(1)
+	mock.ExpectExpire("test-key:downloads", 25*time.Minute).SetVal(true)
+	mock.ExpectTxPipelineExec()
+	got, err = storage.incrDownloadCount("test-key")
+	if err != nil || got != 1 {
+		t.Errorf("incrDownloadCount() = %d, %v, want 1, nil", got, err)
+	}
+
+	mock.ExpectGet("test-key:downloads").SetVal("1")
+	got, err = storage.getDownloadCount("test-key")
+	if err != nil || got != 1 {
+		t.Errorf("getDownloadCount() = %d, %v, want 1, nil", got, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFileMetadataJSON(t *testing.T) {
+	// Records already in Dragonfly are decoded with these names, renaming a field loses its value
+	metadata := &FileMetadata{
+		Filename:        "a.txt",
+		Size:            1,
+		StoragePath:     "a.txt",
+		Bucket:          "public",
+		ContentType:     "text/plain",
+		Compression:     "gzip",
+		StorageClass:    "STANDARD",
+		EncryptionNonce: []byte{1},
+		StorageTags:     map[string]string{"k": "v"},
+		ThumbnailPath:   ".thumbnails/abc",
+		SHA256:          "ab",
+		Quarantined:     true,
+		ExpiresAt:       time.Date(2025, 6, 1, 12, 25, 0, 0, time.UTC),
+	}
+	want := `{"filename":"a.txt","size":1,"storagePath":"a.txt","bucket":"public","contentType":"text/plain",` +
+		`"compression":"gzip","storageClass":"STANDARD","encryptionNonce":"AQ==","storageTags":{"k":"v"},"thumbnailPath":".thumbnails/abc",` +
+		`"sha256":"ab","quarantined":true,"expiresAt":"2025-06-01T12:25:00Z"}`
+
+	got, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestDragonflyStorage_ConcurrentDownloads(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+
+	storage := &DragonflyStorage{client: client}
+
+	// Fill the two slots of the key
+	for i := int64(1); i <= 2; i++ {
+		mock.ExpectTxPipeline()
+		mock.ExpectIncr("test-key:active").SetVal(i)
+		mock.ExpectExpire("test-key:active", 25*time.Minute).SetVal(true)
+		mock.ExpectTxPipelineExec()
+		if err := storage.acquireDownload("test-key", 2); err != nil {
+			t.Fatalf("acquireDownload() #%d error = %v", i, err)
+		}
+	}
+
+	// The next download is throttled and gives its slot back
+	mock.ExpectTxPipeline()
+	mock.ExpectIncr("test-key:active").SetVal(3)
+	mock.ExpectExpire("test-key:active", 25*time.Minute).SetVal(true)
+	mock.ExpectTxPipelineExec()
+	mock.ExpectDecr("test-key:active").SetVal(2)
+	if err := storage.acquireDownload("test-key", 2); !errors.Is(err, ErrTooManyDownloads) {
+		t.Fatalf("acquireDownload() over limit error = %v, want %v", err, ErrTooManyDownloads)
+	}
+
+	// A finished download frees a slot
+	mock.ExpectDecr("test-key:active").SetVal(1)
+	if err := storage.releaseDownload("test-key"); err != nil {
+		t.Fatalf("releaseDownload() error = %v", err)
+	}
+	mock.ExpectTxPipeline()
+	mock.ExpectIncr("test-key:active").SetVal(2)
+	mock.ExpectExpire("test-key:active", 25*time.Minute).SetVal(true)
+	mock.ExpectTxPipelineExec()
+	if err := storage.acquireDownload("test-key", 2); err != nil {
+		t.Fatalf("acquireDownload() after release error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDragonflyStorage_Ping(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+
+	storage := &DragonflyStorage{client: client}
+
+	mock.ExpectPing().SetVal("PONG")
+	if err := storage.Ping(context.Background()); err != nil {
+		t.Errorf("ping() error = %v", err)
+	}
+
+	mock.ExpectPing().SetErr(errors.New("connection refused"))
+	if err := storage.Ping(context.Background()); err == nil {
+		t.Error("ping() should fail when the server is unreachable")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 // setupRealDragonfly creates a real client and skips tests if the service is unavailable.
 func setupRealDragonfly(b *testing.B) *DragonflyStorage {
 	client := redis.NewClient(&redis.Options{
@@ -190,7 +576,7 @@ func BenchmarkGetFileMeta(b *testing.B) {
 	}
 	// Pre-populate data for the benchmark to fetch.
 	key := "benchmark-get-key"
-	err := storage.saveFileMeta(key, metadata)
+	err := storage.SaveFileMeta(context.Background(), key, metadata)
 	if err != nil {
 		b.Fatalf("failed to set up benchmark data: %v", err)
 	}
@@ -199,7 +585,7 @@ func BenchmarkGetFileMeta(b *testing.B) {
 	b.Run("Low-Concurrency-1", func(b *testing.B) {
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			_, err := storage.getFileMeta(key)
+			_, err := storage.GetFileMeta(context.Background(), key)
 			if err != nil {
 				b.Error(err)
 			}
@@ -215,7 +601,7 @@ func BenchmarkGetFileMeta(b *testing.B) {
 			b.ResetTimer()
 			b.RunParallel(func(pb *testing.PB) {
 				for pb.Next() {
-					_, err := storage.getFileMeta(key)
+					_, err := storage.GetFileMeta(context.Background(), key)
 					if err != nil {
 						b.Error(err)
 					}
@@ -229,7 +615,7 @@ func BenchmarkGetFileMeta(b *testing.B) {
 		b.ResetTimer()
 		b.RunParallel(func(pb *testing.PB) {
 			for pb.Next() {
-				_, err := storage.getFileMeta(key)
+				_, err := storage.GetFileMeta(context.Background(), key)
 				if err != nil {
 					b.Error(err)
 				}
@@ -246,7 +632,7 @@ func BenchmarkGetFileMeta(b *testing.B) {
 		b.ResetTimer()
 		b.RunParallel(func(pb *testing.PB) {
 			for pb.Next() {
-				_, err := storage.getFileMeta(key)
+				_, err := storage.GetFileMeta(context.Background(), key)
 				if err != nil {
 					b.Error(err)
 				}
@@ -271,7 +657,7 @@ func BenchmarkSaveFileMeta(b *testing.B) {
 	b.Run("Low-Concurrency-1", func(b *testing.B) {
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			err := storage.saveFileMeta(key, metadata)
+			err := storage.SaveFileMeta(context.Background(), key, metadata)
 			if err != nil {
 				b.Error(err)
 			}
@@ -287,7 +673,7 @@ func BenchmarkSaveFileMeta(b *testing.B) {
 			b.ResetTimer()
 			b.RunParallel(func(pb *testing.PB) {
 				for pb.Next() {
-					err := storage.saveFileMeta(key, metadata)
+					err := storage.SaveFileMeta(context.Background(), key, metadata)
 					if err != nil {
 						b.Error(err)
 					}
@@ -301,7 +687,7 @@ func BenchmarkSaveFileMeta(b *testing.B) {
 		b.ResetTimer()
 		b.RunParallel(func(pb *testing.PB) {
 			for pb.Next() {
-				err := storage.saveFileMeta(key, metadata)
+				err := storage.SaveFileMeta(context.Background(), key, metadata)
 				if err != nil {
 					b.Error(err)
 				}
@@ -318,7 +704,7 @@ func BenchmarkSaveFileMeta(b *testing.B) {
 		b.ResetTimer()
 		b.RunParallel(func(pb *testing.PB) {
 			for pb.Next() {
-				err := storage.saveFileMeta(key, metadata)
+				err := storage.SaveFileMeta(context.Background(), key, metadata)
 				if err != nil {
 					b.Error(err)
 				}
@@ -326,3 +712,55 @@ func BenchmarkSaveFileMeta(b *testing.B) {
 		})
 	})
 }
+
+func TestInitDragonfly(t *testing.T) {
+	prev := dragon
+	t.Cleanup(func() { dragon = prev })
+
+	if err := InitDragonfly(DragonflyConfig{Addr: "dragonfly:6379", DB: 2, Password: "secret"}); err != nil {
+		t.Fatalf("InitDragonfly() error = %v", err)
+	}
+	opts := dragon.client.(*redis.Client).Options()
+	if opts.Addr != "dragonfly:6379" || opts.DB != 2 || opts.Password != "secret" {
+		t.Errorf("client options = %s db %d password %q, want the config", opts.Addr, opts.DB, opts.Password)
+	}
+	if opts.TLSConfig != nil {
+		t.Error("TLS should be off unless configured")
+	}
+	if opts.MaxRetries != 0 {
+		t.Errorf("MaxRetries = %d, want retries disabled", opts.MaxRetries)
+	}
+
+	if err := InitDragonfly(DragonflyConfig{MaxRetries: 5, MinRetryBackoff: 10 * time.Millisecond, MaxRetryBackoff: time.Second}); err != nil {
+		t.Fatalf("InitDragonfly() error = %v", err)
+	}
+	opts = dragon.client.(*redis.Client).Options()
+	if opts.MaxRetries != 5 || opts.MinRetryBackoff != 10*time.Millisecond || opts.MaxRetryBackoff != time.Second {
+		t.Errorf("retry options = %d %v %v, want 5 10ms 1s", opts.MaxRetries, opts.MinRetryBackoff, opts.MaxRetryBackoff)
+	}
+
+	if err := InitDragonfly(DragonflyConfig{Addr: "redis.example.com:6380", TLS: true}); err != nil {
+		t.Fatalf("InitDragonfly() error = %v", err)
+	}
+	if dragon.client.(*redis.Client).Options().TLSConfig == nil {
+		t.Error("TLS should be on when configured")
+	}
+
+	if err := InitDragonfly(DragonflyConfig{ClusterAddrs: []string{"node1:6379", "node2:6379"}, Password: "secret"}); err != nil {
+		t.Fatalf("InitDragonfly() error = %v", err)
+	}
+	cluster, ok := dragon.client.(*redis.ClusterClient)
+	if !ok {
+		t.Fatalf("client = %T, want a cluster client", dragon.client)
+	}
+	if clusterOpts := cluster.Options(); !reflect.DeepEqual(clusterOpts.Addrs, []string{"node1:6379", "node2:6379"}) || clusterOpts.Password != "secret" {
+		t.Errorf("cluster options = %v password %q, want the config", clusterOpts.Addrs, clusterOpts.Password)
+	}
+
+	if err := InitDragonfly(DragonflyConfig{}); err != nil {
+		t.Fatalf("InitDragonfly() error = %v", err)
+	}
+	if addr := dragon.client.(*redis.Client).Options().Addr; addr != defaultDragonflyAddr {
+		t.Errorf("default address = %s, want %s", addr, defaultDragonflyAddr)
+	}
+}
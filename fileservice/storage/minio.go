@@ -17,110 +17,536 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
-	"log"
+	"mime"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
 	"github.com/fawa-io/fawa/pkg/fwlog"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/tags"
 )
 
 // minioFileStore holds the client and configuration for MinIO file operations.
 type minioFileStore struct {
-	client     *minio.Client
+	client *minio.Client
+	// bucketName is the primary bucket, used by uploads that don't select one
 	bucketName string
+	// buckets are the buckets uploads may select, including the primary one
+	buckets []string
+	// publicClient signs download URLs for the public endpoint, nil when MinIO is reached directly
+	publicClient *minio.Client
+	// publicPathPrefix is the path the reverse proxy serves MinIO under, e.g. /minio
+	publicPathPrefix string
+	// opTimeout bounds the calls that don't stream a file, zero is unlimited
+	opTimeout time.Duration
+	// offlineUntil is the UnixNano time until which MinIO is considered down
+	offlineUntil atomic.Int64
+}
+
+// offlineWindow is how long MinIO is considered down after a call failed to reach it.
+// The first call after the window probes it again.
+const offlineWindow = 5 * time.Second
+
+// ErrOffline is returned without calling MinIO while it's considered down,
+// so requests fail fast instead of piling up on a degraded server
+var ErrOffline = fmt.Errorf("MinIO is offline: %w", apierr.ErrUnavailable)
+
+// offline reports whether a recent call failed to reach MinIO
+func (s *minioFileStore) offline() bool {
+	return time.Now().UnixNano() < s.offlineUntil.Load()
+}
+
+// observe records the outcome of a call that doesn't stream a file and returns its error.
+// Network errors and timeouts take MinIO offline for offlineWindow. Streaming calls aren't
+// observed: a client aborting its upload fails them just like a network error.
+func (s *minioFileStore) observe(err error) error {
+	if err != nil && minio.IsNetworkOrHostDown(err, false) {
+		s.offlineUntil.Store(time.Now().Add(offlineWindow).UnixNano())
+	}
+	return err
+}
+
+// op prepares a MinIO call that doesn't stream a file, bounded by the operation timeout.
+// Cancel the returned context once the call returned, and pass its error to observe.
+func (s *minioFileStore) op(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if s.offline() {
+		return nil, nil, ErrOffline
+	}
+	if s.opTimeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.opTimeout)
+	return ctx, cancel, nil
 }
 
 var fileStore *minioFileStore
 
-// init initializes the MinIO client and bucket from environment variables.
-func init() {
-	endpoint := os.Getenv("MINIO_ENDPOINT")
-	accessKeyID := os.Getenv("MINIO_ACCESS_KEY_ID")
-	secretAccessKey := os.Getenv("MINIO_SECRET_ACCESS_KEY")
-	bucketName := os.Getenv("MINIO_BUCKET_NAME")
-	useSSL := os.Getenv("MINIO_USE_SSL") == "true"
+// testModeEnv turns the MinIO initialization into a no-op, so tests and local runs
+// that don't touch object storage work without a MinIO server.
+const testModeEnv = "FAWA_STORAGE_TEST_MODE"
+
+// storageTestMode reports whether testModeEnv is set to a true value
+func storageTestMode() bool {
+	enabled, err := strconv.ParseBool(os.Getenv(testModeEnv))
+	return err == nil && enabled
+}
+
+// MinIOConfig is the connection and bucket configuration of the object store
+type MinIOConfig struct {
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	// BucketName is the primary bucket, used by uploads that don't select one
+	BucketName string
+	// Buckets are the buckets uploads may select besides the primary one
+	Buckets []string
+	UseSSL  bool
+	// OpTimeout bounds each MinIO call that doesn't stream a file, and the wait for the response
+	// headers of those that do. Zero keeps the client defaults.
+	OpTimeout time.Duration
+}
+
+// InitMinIO connects to MinIO and creates the configured buckets that don't exist yet.
+// An incomplete configuration leaves object storage disabled, its operations then fail.
+func InitMinIO(cfg MinIOConfig) error {
+	if storageTestMode() {
+		fwlog.Infof("%s is set, skipping MinIO client initialization.", testModeEnv)
+		return nil
+	}
 
 	fwlog.Debugf("Initializing MinIO with the following configuration:")
-	fwlog.Debugf("  MINIO_ENDPOINT: %s", endpoint)
-	fwlog.Debugf("  MINIO_ACCESS_KEY_ID: %s", accessKeyID)
-	fwlog.Debugf("  MINIO_BUCKET_NAME: %s", bucketName)
-	fwlog.Debugf("  MINIO_USE_SSL: %v", useSSL)
+	fwlog.Debugf("  endpoint: %s", cfg.Endpoint)
+	fwlog.Debugf("  accessKeyID: %s", cfg.AccessKeyID)
+	fwlog.Debugf("  bucketName: %s", cfg.BucketName)
+	fwlog.Debugf("  buckets: %v", cfg.Buckets)
+	fwlog.Debugf("  useSSL: %v", cfg.UseSSL)
 
-	if endpoint == "" || accessKeyID == "" || secretAccessKey == "" || bucketName == "" {
-		fwlog.Info("MinIO environment variables for file storage not set, skipping client initialization.")
-		return
+	if cfg.Endpoint == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" || cfg.BucketName == "" {
+		fwlog.Info("MinIO settings for file storage not set, skipping client initialization.")
+		return nil
 	}
 
-	client, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
-		Secure: useSSL,
+	transport, err := minio.DefaultTransport(cfg.UseSSL)
+	if err != nil {
+		return fmt.Errorf("failed to initialize MinIO transport: %w", err)
+	}
+	// Uploads and downloads stream for as long as the file takes, only a silent server is cut off
+	if cfg.OpTimeout > 0 {
+		transport.ResponseHeaderTimeout = cfg.OpTimeout
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure:    cfg.UseSSL,
+		Transport: transport,
 	})
 	if err != nil {
-		log.Fatalf("Failed to initialize MinIO client: %v", err)
+		return fmt.Errorf("failed to initialize MinIO client: %w", err)
 	}
 
-	fileStore = &minioFileStore{
+	store := &minioFileStore{
 		client:     client,
-		bucketName: bucketName,
+		bucketName: cfg.BucketName,
+		buckets:    parseBuckets(cfg.BucketName, cfg.Buckets),
+		opTimeout:  cfg.OpTimeout,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	for _, bucket := range store.buckets {
+		if err := ensureBucket(ctx, client, bucket); err != nil {
+			return err
+		}
+	}
+	fileStore = store
+	return nil
+}
+
+// parseBuckets returns the primary bucket followed by the extra buckets, without blanks and duplicates
+func parseBuckets(primary string, extra []string) []string {
+	buckets := []string{primary}
+	for _, bucket := range extra {
+		bucket = strings.TrimSpace(bucket)
+		if bucket != "" && !slices.Contains(buckets, bucket) {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets
+}
+
+// ensureBucket creates the bucket if it doesn't exist yet
+func ensureBucket(ctx context.Context, client *minio.Client, bucketName string) error {
 	exists, err := client.BucketExists(ctx, bucketName)
 	if err != nil {
-		log.Fatalf("Failed to check if MinIO bucket '%s' exists: %v", bucketName, err)
+		return fmt.Errorf("failed to check if MinIO bucket '%s' exists: %w", bucketName, err)
 	}
 	if !exists {
 		err = client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
 		if err != nil {
-			log.Fatalf("Failed to create MinIO bucket '%s': %v", bucketName, err)
+			return fmt.Errorf("failed to create MinIO bucket '%s': %w", bucketName, err)
 		}
-		log.Printf("Successfully created MinIO bucket: %s", bucketName)
+		fwlog.Infof("Successfully created MinIO bucket: %s", bucketName)
+	}
+	return nil
+}
+
+// ErrInvalidBucket is returned for a bucket outside of the configured ones
+var ErrInvalidBucket = errors.New("invalid bucket")
+
+// ValidateBucket checks that an upload may select the bucket.
+// An empty bucket is the primary bucket and is always valid.
+func ValidateBucket(bucketName string) error {
+	if bucketName == "" {
+		return nil
+	}
+	if fileStore == nil || !slices.Contains(fileStore.bucketList(), bucketName) {
+		return fmt.Errorf("%w %q", ErrInvalidBucket, bucketName)
+	}
+	return nil
+}
+
+// bucket returns the bucket to use for bucketName, the primary bucket when empty
+func (s *minioFileStore) bucket(bucketName string) string {
+	if bucketName == "" {
+		return s.bucketName
+	}
+	return bucketName
+}
+
+// ErrInvalidStorageClass is returned for a storage class outside of the allowed set
+var ErrInvalidStorageClass = errors.New("invalid storage class")
+
+// StorageClassStandard is the storage class of uploads that don't ask for another
+const StorageClassStandard = "STANDARD"
+
+// storageClasses are the storage classes an upload may ask for.
+// MinIO only knows STANDARD and REDUCED_REDUNDANCY, the others are for S3 backends.
+var storageClasses = []string{
+	StorageClassStandard,
+	"REDUCED_REDUNDANCY",
+	"STANDARD_IA",
+	"ONEZONE_IA",
+	"INTELLIGENT_TIERING",
+	"GLACIER_IR",
+}
+
+// ParseStorageClass validates a requested storage class, case-insensitively.
+// An empty class is StorageClassStandard.
+func ParseStorageClass(class string) (string, error) {
+	if class == "" {
+		return StorageClassStandard, nil
+	}
+	class = strings.ToUpper(class)
+	if !slices.Contains(storageClasses, class) {
+		return "", fmt.Errorf("%w %q, want one of %s", ErrInvalidStorageClass, class, strings.Join(storageClasses, ", "))
 	}
+	return class, nil
+}
+
+// ErrInvalidTags is returned for object tags MinIO would not accept
+var ErrInvalidTags = errors.New("invalid object tags")
+
+// ValidateTags checks tags against the object tagging limits of MinIO and S3
+func ValidateTags(objectTags map[string]string) error {
+	if _, err := tags.MapToObjectTags(objectTags); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTags, err)
+	}
+	return nil
+}
+
+// UploadOptions holds the optional settings of an upload
+type UploadOptions struct {
+	// Bucket is the bucket to upload to, validate it with ValidateBucket first. Empty is the primary bucket.
+	Bucket      string
+	ContentType string
+	// StorageClass is passed to the object store as is, empty leaves the choice to the store
+	StorageClass string
+	// Tags are set on the object, validate them with ValidateTags first as invalid tags are dropped
+	Tags map[string]string
 }
 
 // UploadFile uploads a file to MinIO.
 // objectName is the full path/name of the object in the bucket.
 // reader is the file content stream.
 // size is the total size of the file.
-func UploadFile(ctx context.Context, objectName string, reader io.Reader, size int64) (minio.UploadInfo, error) {
+func UploadFile(ctx context.Context, objectName string, reader io.Reader, size int64, opts UploadOptions) (minio.UploadInfo, error) {
 	if fileStore == nil {
 		return minio.UploadInfo{}, errors.New("MinIO client is not initialized")
 	}
 
-	return fileStore.client.PutObject(ctx, fileStore.bucketName, objectName, reader, size, minio.PutObjectOptions{
-		ContentType: "application/octet-stream", // Generic content type
+	if fileStore.offline() {
+		return minio.UploadInfo{}, ErrOffline
+	}
+
+	return fileStore.client.PutObject(ctx, fileStore.bucket(opts.Bucket), objectName, reader, size, minio.PutObjectOptions{
+		ContentType:  opts.ContentType,
+		StorageClass: opts.StorageClass,
+		UserTags:     opts.Tags,
+	})
+}
+
+// ErrObjectNotFound is returned when the object of a file does not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// GetFile opens an uploaded object for streaming and returns it with its size.
+// An empty bucketName is the primary bucket. The caller must close the returned reader.
+func GetFile(ctx context.Context, bucketName, objectName string) (io.ReadCloser, int64, error) {
+	if fileStore == nil {
+		return nil, 0, errors.New("MinIO client is not initialized")
+	}
+
+	if fileStore.offline() {
+		return nil, 0, ErrOffline
+	}
+	obj, err := fileStore.client.GetObject(ctx, fileStore.bucket(bucketName), objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		_ = obj.Close()
+		if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+			return nil, 0, fmt.Errorf("%w: %s", ErrObjectNotFound, objectName)
+		}
+		return nil, 0, err
+	}
+	return obj, info.Size, nil
+}
+
+// StatFile returns the size of an uploaded object, ErrObjectNotFound when it doesn't exist.
+// An empty bucketName is the primary bucket.
+func StatFile(ctx context.Context, bucketName, objectName string) (int64, error) {
+	if fileStore == nil {
+		return 0, errors.New("MinIO client is not initialized")
+	}
+
+	ctx, cancel, err := fileStore.op(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+	info, err := fileStore.client.StatObject(ctx, fileStore.bucket(bucketName), objectName, minio.StatObjectOptions{})
+	if fileStore.observe(err) != nil {
+		if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+			return 0, fmt.Errorf("%w: %s", ErrObjectNotFound, objectName)
+		}
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+// RemoveFile deletes an uploaded object from MinIO, an empty bucketName is the primary bucket.
+func RemoveFile(ctx context.Context, bucketName, objectName string) error {
+	if fileStore == nil {
+		return errors.New("MinIO client is not initialized")
+	}
+
+	ctx, cancel, err := fileStore.op(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return fileStore.observe(fileStore.client.RemoveObject(ctx, fileStore.bucket(bucketName), objectName, minio.RemoveObjectOptions{}))
+}
+
+// RemoveIncompleteUpload aborts an unfinished multipart upload and frees its parts.
+func RemoveIncompleteUpload(ctx context.Context, bucketName, objectName string) error {
+	if fileStore == nil {
+		return errors.New("MinIO client is not initialized")
+	}
+
+	ctx, cancel, err := fileStore.op(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return fileStore.observe(fileStore.client.RemoveIncompleteUpload(ctx, fileStore.bucket(bucketName), objectName))
+}
+
+// CheckBucket verifies that MinIO is reachable and the buckets exist.
+func CheckBucket(ctx context.Context) error {
+	if fileStore == nil {
+		return errors.New("MinIO client is not initialized")
+	}
+
+	ctx, cancel, err := fileStore.op(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	for _, bucket := range fileStore.bucketList() {
+		exists, err := fileStore.client.BucketExists(ctx, bucket)
+		if fileStore.observe(err) != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		}
+	}
+	return nil
+}
+
+// bucketList returns the configured buckets, the primary one first
+func (s *minioFileStore) bucketList() []string {
+	if len(s.buckets) == 0 {
+		return []string{s.bucketName}
+	}
+	return s.buckets
+}
+
+// SetPublicEndpoint makes presigned URLs point at the externally reachable address of MinIO,
+// e.g. https://files.example.com/minio when it sits behind a reverse proxy or CDN.
+// The host is part of the signature, so URLs are signed for the public host instead of being rewritten.
+// A path prefix is added after signing since the proxy strips it before the request reaches MinIO.
+// An empty endpoint signs URLs for the MinIO endpoint again.
+func SetPublicEndpoint(ctx context.Context, endpoint string) error {
+	if fileStore == nil {
+		return errors.New("MinIO client is not initialized")
+	}
+	if endpoint == "" {
+		fileStore.publicClient = nil
+		fileStore.publicPathPrefix = ""
+		return nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid public endpoint %q: %w", endpoint, err)
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid public endpoint %q: want http(s)://host[:port][/path]", endpoint)
+	}
+
+	creds, err := fileStore.client.GetCreds()
+	if err != nil {
+		return fmt.Errorf("failed to get MinIO credentials: %w", err)
+	}
+	// Presigning needs the bucket region, resolve it through the internal endpoint
+	// so the public client never has to reach MinIO. All buckets are expected in the region of the primary one.
+	region, err := fileStore.client.GetBucketLocation(ctx, fileStore.bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to get location of bucket %s: %w", fileStore.bucketName, err)
+	}
+
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken),
+		Secure:       u.Scheme == "https",
+		Region:       region,
+		BucketLookup: minio.BucketLookupPath,
 	})
+	if err != nil {
+		return fmt.Errorf("failed to create MinIO client for public endpoint: %w", err)
+	}
+
+	fileStore.publicClient = client
+	fileStore.publicPathPrefix = strings.TrimSuffix(u.Path, "/")
+	return nil
+}
+
+// DownloadOptions are the response headers MinIO sends with a presigned download
+type DownloadOptions struct {
+	// Filename is the name the browser saves the download under, the object name when empty
+	Filename string
+	// ContentType overrides the stored content type when set
+	ContentType string
+}
+
+// ContentDisposition is the Content-Disposition saving a download under filename,
+// non-ASCII names are encoded as RFC 2231 asks
+func ContentDisposition(filename string) string {
+	if v := mime.FormatMediaType("attachment", map[string]string{"filename": filename}); v != "" {
+		return v
+	}
+	return "attachment"
 }
 
 // GetPresignedURL generates a temporary, presigned URL for downloading a file.
-func GetPresignedURL(ctx context.Context, objectName string, expires time.Duration) (*url.URL, error) {
+// An empty bucketName is the primary bucket.
+func GetPresignedURL(ctx context.Context, bucketName, objectName string, expires time.Duration, opts DownloadOptions) (*url.URL, error) {
 	if fileStore == nil {
 		return nil, errors.New("MinIO client is not initialized")
 	}
 
-	return fileStore.client.PresignedGetObject(ctx, fileStore.bucketName, objectName, expires, nil)
+	// The response-* parameters are signed, so MinIO sends them back as headers
+	reqParams := url.Values{}
+	if opts.Filename != "" {
+		reqParams.Set("response-content-disposition", ContentDisposition(opts.Filename))
+	}
+	if opts.ContentType != "" {
+		reqParams.Set("response-content-type", opts.ContentType)
+	}
+
+	// Signing may look up the bucket region
+	ctx, cancel, err := fileStore.op(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	bucket := fileStore.bucket(bucketName)
+	if fileStore.publicClient == nil {
+		u, err := fileStore.client.PresignedGetObject(ctx, bucket, objectName, expires, reqParams)
+		return u, fileStore.observe(err)
+	}
+
+	u, err := fileStore.publicClient.PresignedGetObject(ctx, bucket, objectName, expires, reqParams)
+	if err != nil {
+		return nil, err
+	}
+	if prefix := fileStore.publicPathPrefix; prefix != "" {
+		u.Path = prefix + u.Path
+		if u.RawPath != "" {
+			u.RawPath = prefix + u.RawPath
+		}
+	}
+	return u, nil
+}
+
+// ObjectInfo is a stored object as listed by ListObjects
+type ObjectInfo struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
 }
 
-// ListObjects lists all objects in the bucket for debugging purposes.
-func ListObjects(ctx context.Context) ([]string, error) {
+// ListObjects lists, in name order, up to limit objects of the bucket named after startAfter
+// and starting with prefix. more reports whether objects are left, the next page starts after
+// the last object returned. It's meant for reconciling the bucket with the metadata store.
+func ListObjects(ctx context.Context, bucketName, prefix, startAfter string, limit int) (objects []ObjectInfo, more bool, err error) {
 	if fileStore == nil {
-		return nil, errors.New("MinIO client is not initialized")
+		return nil, false, errors.New("MinIO client is not initialized")
 	}
 
-	var objectNames []string
-	objectCh := fileStore.client.ListObjects(ctx, fileStore.bucketName, minio.ListObjectsOptions{})
+	// Canceling stops the listing when the page is full
+	ctx, cancel, err := fileStore.op(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cancel()
+	objectCh := fileStore.client.ListObjects(ctx, fileStore.bucket(bucketName), minio.ListObjectsOptions{
+		Prefix:     prefix,
+		StartAfter: startAfter,
+		Recursive:  true,
+		MaxKeys:    limit + 1,
+	})
 	for object := range objectCh {
 		if object.Err != nil {
-			return nil, object.Err
+			return nil, false, fileStore.observe(object.Err)
+		}
+		if len(objects) == limit {
+			return objects, true, nil
 		}
-		objectNames = append(objectNames, object.Key)
+		objects = append(objects, ObjectInfo{Name: object.Key, Size: object.Size, LastModified: object.LastModified})
 	}
-	return objectNames, nil
+	return objects, false, nil
 }
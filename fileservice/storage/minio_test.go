@@ -0,0 +1,753 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+)
+
+const testBucket = "fawa-test"
+
+type fakeObject struct {
+	data     []byte
+	etag     string
+	header   http.Header
+	modified time.Time
+}
+
+// fakeS3 is an in-memory object store speaking just enough of the S3 API for the tests
+// Objects of testBucket are keyed by name, those of the other buckets by bucket/name.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	puts    int
+	// buckets are the buckets existing besides testBucket
+	buckets        []string
+	bucketsCreated int
+	// hang makes every request wait for the client to give up, like a degraded server
+	hang bool
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	if f.hang {
+		f.mu.Unlock()
+		<-r.Context().Done()
+		return
+	}
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if key == "" {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Has("location"):
+			_, _ = io.WriteString(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			f.list(w, bucket, r.URL.Query())
+		case r.Method == http.MethodPut:
+			f.buckets = append(f.buckets, bucket)
+			f.bucketsCreated++
+		case bucket == testBucket || slices.Contains(f.buckets, bucket):
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+	if bucket != testBucket {
+		key = bucket + "/" + key
+	}
+
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range obj.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", `"`+obj.etag+`"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		sum := md5.Sum(data)
+		obj := &fakeObject{data: data, etag: hex.EncodeToString(sum[:]), header: http.Header{}, modified: time.Now()}
+		for k, v := range r.Header {
+			if strings.HasPrefix(strings.ToLower(k), "x-amz-") || k == "Content-Type" {
+				obj.header[k] = v
+			}
+		}
+		f.objects[key] = obj
+		f.puts++
+		w.Header().Set("ETag", `"`+obj.etag+`"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// list answers a ListObjectsV2 request with every matching object of bucket, never truncated
+func (f *fakeS3) list(w http.ResponseWriter, bucket string, query url.Values) {
+	keyPrefix := ""
+	if bucket != testBucket {
+		keyPrefix = bucket + "/"
+	}
+	var names []string
+	for key := range f.objects {
+		name, ok := strings.CutPrefix(key, keyPrefix)
+		if !ok || (keyPrefix == "" && slices.ContainsFunc(f.buckets, func(b string) bool { return strings.HasPrefix(key, b+"/") })) {
+			continue
+		}
+		if strings.HasPrefix(name, query.Get("prefix")) && name > query.Get("start-after") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>%s</Name><KeyCount>%d</KeyCount><IsTruncated>false</IsTruncated>`, bucket, len(names))
+	for _, name := range names {
+		obj := f.objects[keyPrefix+name]
+		fmt.Fprintf(&b, `<Contents><Key>%s</Key><Size>%d</Size><ETag>"%s"</ETag><LastModified>%s</LastModified></Contents>`, name, len(obj.data), obj.etag, obj.modified.UTC().Format(time.RFC3339))
+	}
+	b.WriteString(`</ListBucketResult>`)
+	_, _ = io.WriteString(w, b.String())
+}
+
+// put stores an object directly in the fake and returns its ETag
+func (f *fakeS3) put(key string, data []byte) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sum := md5.Sum(data)
+	obj := &fakeObject{data: data, etag: hex.EncodeToString(sum[:]), header: http.Header{}, modified: time.Now()}
+	f.objects[key] = obj
+	return obj.etag
+}
+
+// get returns a stored object
+func (f *fakeS3) get(key string) (*fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[key]
+	return obj, ok
+}
+
+// setupFakeMinIO points the package file store at a fresh fake S3 server
+func setupFakeMinIO(t *testing.T) *fakeS3 {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string]*fakeObject)}
+	srv := httptest.NewTLSServer(fake)
+	t.Cleanup(srv.Close)
+
+	client, err := minio.New(strings.TrimPrefix(srv.URL, "https://"), &minio.Options{
+		Creds:        credentials.NewStaticV4("access", "secret", ""),
+		Secure:       true,
+		Transport:    srv.Client().Transport,
+		Region:       "us-east-1",
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		t.Fatalf("failed to create MinIO client: %v", err)
+	}
+
+	prev := fileStore
+	fileStore = &minioFileStore{client: client, bucketName: testBucket}
+	t.Cleanup(func() { fileStore = prev })
+	return fake
+}
+
+func TestUploadFileStorageClass(t *testing.T) {
+	fake := setupFakeMinIO(t)
+
+	data := []byte("cold data")
+	_, err := UploadFile(context.Background(), "cold.txt", bytes.NewReader(data), int64(len(data)), UploadOptions{
+		ContentType:  "text/plain",
+		StorageClass: "REDUCED_REDUNDANCY",
+	})
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	obj, ok := fake.get("cold.txt")
+	if !ok {
+		t.Fatal("cold.txt was not stored")
+	}
+	if got := obj.header.Get("X-Amz-Storage-Class"); got != "REDUCED_REDUNDANCY" {
+		t.Errorf("X-Amz-Storage-Class = %q, want REDUCED_REDUNDANCY", got)
+	}
+}
+
+func TestUploadFileTags(t *testing.T) {
+	fake := setupFakeMinIO(t)
+
+	data := []byte("scratch")
+	_, err := UploadFile(context.Background(), "scratch.txt", bytes.NewReader(data), int64(len(data)), UploadOptions{
+		Tags: map[string]string{"class": "temp"},
+	})
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	obj, ok := fake.get("scratch.txt")
+	if !ok {
+		t.Fatal("scratch.txt was not stored")
+	}
+	if got := obj.header.Get("X-Amz-Tagging"); got != "class=temp" {
+		t.Errorf("X-Amz-Tagging = %q, want class=temp", got)
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i < 11; i++ {
+		tooMany[fmt.Sprintf("k%d", i)] = "v"
+	}
+
+	testCases := []struct {
+		name    string
+		tags    map[string]string
+		wantErr error
+	}{
+		{name: "none"},
+		{name: "valid", tags: map[string]string{"class": "temp", "cost-center": "42"}},
+		{name: "empty key", tags: map[string]string{"": "v"}, wantErr: ErrInvalidTags},
+		{name: "long key", tags: map[string]string{strings.Repeat("k", 129): "v"}, wantErr: ErrInvalidTags},
+		{name: "long value", tags: map[string]string{"k": strings.Repeat("v", 257)}, wantErr: ErrInvalidTags},
+		{name: "invalid character", tags: map[string]string{"k": "a&b"}, wantErr: ErrInvalidTags},
+		{name: "too many", tags: tooMany, wantErr: ErrInvalidTags},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidateTags(tc.tags); !errors.Is(err, tc.wantErr) {
+				t.Errorf("ValidateTags() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseStorageClass(t *testing.T) {
+	testCases := []struct {
+		class   string
+		want    string
+		wantErr error
+	}{
+		{class: "", want: StorageClassStandard},
+		{class: "STANDARD", want: "STANDARD"},
+		{class: "reduced_redundancy", want: "REDUCED_REDUNDANCY"},
+		{class: "STANDARD_IA", want: "STANDARD_IA"},
+		{class: "DEEP_FREEZER", wantErr: ErrInvalidStorageClass},
+	}
+
+	for _, tc := range testCases {
+		got, err := ParseStorageClass(tc.class)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("ParseStorageClass(%q) error = %v, want %v", tc.class, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("ParseStorageClass(%q) = %q, want %q", tc.class, got, tc.want)
+		}
+	}
+}
+
+func TestRemoveFile(t *testing.T) {
+	fake := setupFakeMinIO(t)
+	fake.put("orphan.txt", []byte("data"))
+
+	if err := RemoveFile(context.Background(), "", "orphan.txt"); err != nil {
+		t.Fatalf("RemoveFile() error = %v", err)
+	}
+	if _, ok := fake.get("orphan.txt"); ok {
+		t.Error("orphan.txt should have been removed")
+	}
+}
+
+func TestListObjects(t *testing.T) {
+	fake := setupFakeMinIO(t)
+	fake.put("a.txt", []byte("a"))
+	fake.put("b.txt", []byte("bb"))
+	fake.put("c.txt", []byte("ccc"))
+	fake.put("logs/d.txt", []byte("dddd"))
+
+	testCases := []struct {
+		name       string
+		prefix     string
+		startAfter string
+		limit      int
+		want       []ObjectInfo
+		wantMore   bool
+	}{
+		{
+			name:  "everything",
+			limit: 10,
+			want:  []ObjectInfo{{Name: "a.txt", Size: 1}, {Name: "b.txt", Size: 2}, {Name: "c.txt", Size: 3}, {Name: "logs/d.txt", Size: 4}},
+		},
+		{
+			name:     "first page",
+			limit:    2,
+			want:     []ObjectInfo{{Name: "a.txt", Size: 1}, {Name: "b.txt", Size: 2}},
+			wantMore: true,
+		},
+		{
+			name:       "last page",
+			startAfter: "b.txt",
+			limit:      2,
+			want:       []ObjectInfo{{Name: "c.txt", Size: 3}, {Name: "logs/d.txt", Size: 4}},
+		},
+		{
+			name:   "prefix",
+			prefix: "logs/",
+			limit:  10,
+			want:   []ObjectInfo{{Name: "logs/d.txt", Size: 4}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, more, err := ListObjects(context.Background(), "", tc.prefix, tc.startAfter, tc.limit)
+			if err != nil {
+				t.Fatalf("ListObjects() error = %v", err)
+			}
+			for i := range got {
+				got[i].LastModified = time.Time{}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("ListObjects() = %v, want %v", got, tc.want)
+			}
+			if more != tc.wantMore {
+				t.Errorf("ListObjects() more = %v, want %v", more, tc.wantMore)
+			}
+		})
+	}
+}
+
+func TestStatFile(t *testing.T) {
+	fake := setupFakeMinIO(t)
+	fake.put("report.txt", []byte("quarterly numbers"))
+
+	size, err := StatFile(context.Background(), "", "report.txt")
+	if err != nil || size != int64(len("quarterly numbers")) {
+		t.Errorf("StatFile() = %d, %v, want %d", size, err, len("quarterly numbers"))
+	}
+	if _, err := StatFile(context.Background(), "", "missing.txt"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("StatFile() error = %v, want %v", err, ErrObjectNotFound)
+	}
+}
+
+func TestGetFile(t *testing.T) {
+	fake := setupFakeMinIO(t)
+	fake.put("report.txt", []byte("quarterly numbers"))
+	fake.put("archive/old.txt", []byte("archived"))
+
+	testCases := []struct {
+		name     string
+		bucket   string
+		object   string
+		want     string
+		notFound bool
+	}{
+		{name: "primary bucket", object: "report.txt", want: "quarterly numbers"},
+		{name: "other bucket", bucket: "archive", object: "old.txt", want: "archived"},
+		{name: "missing object", object: "missing.txt", notFound: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, size, err := GetFile(context.Background(), tc.bucket, tc.object)
+			if tc.notFound {
+				if !errors.Is(err, ErrObjectNotFound) {
+					t.Fatalf("GetFile() error = %v, want %v", err, ErrObjectNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetFile() error = %v", err)
+			}
+			defer func() { _ = r.Close() }()
+			data, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(data) != tc.want || size != int64(len(tc.want)) {
+				t.Errorf("GetFile() = %q of size %d, want %q of size %d", data, size, tc.want, len(tc.want))
+			}
+		})
+	}
+}
+
+func TestCheckBucket(t *testing.T) {
+	setupFakeMinIO(t)
+	if err := CheckBucket(context.Background()); err != nil {
+		t.Errorf("CheckBucket() error = %v", err)
+	}
+
+	fileStore.buckets = []string{testBucket, "missing"}
+	if err := CheckBucket(context.Background()); err == nil {
+		t.Error("CheckBucket() should fail for a missing extra bucket")
+	}
+
+	fileStore.buckets = nil
+	fileStore.bucketName = "missing"
+	if err := CheckBucket(context.Background()); err == nil {
+		t.Error("CheckBucket() should fail for a missing bucket")
+	}
+}
+
+func TestUploadFileBucket(t *testing.T) {
+	fake := setupFakeMinIO(t)
+	fake.buckets = []string{"fawa-public"}
+	fileStore.buckets = []string{testBucket, "fawa-public"}
+	ctx := context.Background()
+
+	if err := CheckBucket(ctx); err != nil {
+		t.Fatalf("CheckBucket() error = %v", err)
+	}
+
+	data := []byte("public data")
+	_, err := UploadFile(ctx, "pub.txt", bytes.NewReader(data), int64(len(data)), UploadOptions{Bucket: "fawa-public"})
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if _, ok := fake.get("fawa-public/pub.txt"); !ok {
+		t.Fatal("pub.txt was not stored in fawa-public")
+	}
+	if _, ok := fake.get("pub.txt"); ok {
+		t.Fatal("pub.txt was stored in the primary bucket")
+	}
+
+	u, err := GetPresignedURL(ctx, "fawa-public", "pub.txt", time.Minute, DownloadOptions{})
+	if err != nil {
+		t.Fatalf("GetPresignedURL() error = %v", err)
+	}
+	if u.Path != "/fawa-public/pub.txt" {
+		t.Errorf("presigned URL path = %s, want /fawa-public/pub.txt", u.Path)
+	}
+
+	if err := RemoveFile(ctx, "fawa-public", "pub.txt"); err != nil {
+		t.Fatalf("RemoveFile() error = %v", err)
+	}
+	if _, ok := fake.get("fawa-public/pub.txt"); ok {
+		t.Error("pub.txt should have been removed from fawa-public")
+	}
+}
+
+func TestValidateBucket(t *testing.T) {
+	setupFakeMinIO(t)
+	fileStore.buckets = []string{testBucket, "fawa-public"}
+
+	testCases := []struct {
+		bucket  string
+		wantErr bool
+	}{
+		{bucket: ""},
+		{bucket: testBucket},
+		{bucket: "fawa-public"},
+		{bucket: "fawa-private", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateBucket(tc.bucket)
+		if tc.wantErr != (err != nil) {
+			t.Errorf("ValidateBucket(%q) error = %v, wantErr %v", tc.bucket, err, tc.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidBucket) {
+			t.Errorf("ValidateBucket(%q) error = %v, want ErrInvalidBucket", tc.bucket, err)
+		}
+	}
+}
+
+func TestParseBuckets(t *testing.T) {
+	got := parseBuckets("fawa", []string{" fawa-public", " ", "fawa", "fawa-private"})
+	want := []string{"fawa", "fawa-public", "fawa-private"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseBuckets() = %v, want %v", got, want)
+	}
+}
+
+func TestGetPresignedURLPublicEndpoint(t *testing.T) {
+	setupFakeMinIO(t)
+	ctx := context.Background()
+
+	if err := SetPublicEndpoint(ctx, "https://files.example.com/minio/"); err != nil {
+		t.Fatalf("SetPublicEndpoint() error = %v", err)
+	}
+	t.Cleanup(func() { _ = SetPublicEndpoint(ctx, "") })
+
+	// A client talking to the public host directly computes the signature MinIO expects
+	// once the proxy has stripped the path prefix.
+	direct, err := minio.New("files.example.com", &minio.Options{
+		Creds:        credentials.NewStaticV4("access", "secret", ""),
+		Secure:       true,
+		Region:       "us-east-1",
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		t.Fatalf("failed to create MinIO client: %v", err)
+	}
+
+	// Both URLs must be signed within the same second to be comparable
+	for attempt := 0; ; attempt++ {
+		got, err := GetPresignedURL(ctx, "", "dir/file.txt", 5*time.Minute, DownloadOptions{})
+		if err != nil {
+			t.Fatalf("GetPresignedURL() error = %v", err)
+		}
+		want, err := direct.PresignedGetObject(ctx, testBucket, "dir/file.txt", 5*time.Minute, nil)
+		if err != nil {
+			t.Fatalf("PresignedGetObject() error = %v", err)
+		}
+		if got.Query().Get("X-Amz-Date") != want.Query().Get("X-Amz-Date") && attempt < 3 {
+			continue
+		}
+
+		if got.Scheme != "https" || got.Host != "files.example.com" {
+			t.Errorf("URL = %s, want it on https://files.example.com", got)
+		}
+		if got.Path != "/minio/"+testBucket+"/dir/file.txt" {
+			t.Errorf("path = %s, want /minio/%s/dir/file.txt", got.Path, testBucket)
+		}
+		if got.Query().Get("X-Amz-Signature") != want.Query().Get("X-Amz-Signature") {
+			t.Errorf("signature = %s, want %s", got.Query().Get("X-Amz-Signature"), want.Query().Get("X-Amz-Signature"))
+		}
+		break
+	}
+}
+
+func TestSetPublicEndpointInvalid(t *testing.T) {
+	setupFakeMinIO(t)
+	for _, endpoint := range []string{"files.example.com", "ftp://files.example.com", "https://"} {
+		if err := SetPublicEndpoint(context.Background(), endpoint); err == nil {
+			t.Errorf("SetPublicEndpoint(%q) should fail", endpoint)
+		}
+	}
+}
+
+func TestInitMinIOTestMode(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	t.Setenv(testModeEnv, "true")
+
+	prev := fileStore
+	fileStore = nil
+	t.Cleanup(func() { fileStore = prev })
+
+	cfg := MinIOConfig{
+		Endpoint:        strings.TrimPrefix(srv.URL, "http://"),
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		BucketName:      testBucket,
+	}
+	if err := InitMinIO(cfg); err != nil {
+		t.Fatalf("InitMinIO() error = %v", err)
+	}
+
+	if fileStore != nil {
+		t.Error("fileStore should stay nil in test mode")
+	}
+	if requests != 0 {
+		t.Errorf("MinIO received %d requests in test mode, want 0", requests)
+	}
+	if _, err := UploadFile(context.Background(), "a.txt", strings.NewReader("a"), 1, UploadOptions{}); err == nil {
+		t.Error("UploadFile() should fail without a MinIO client")
+	}
+}
+
+func TestInitMinIO(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string]*fakeObject)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	prev := fileStore
+	t.Cleanup(func() { fileStore = prev })
+	fileStore = nil
+
+	cfg := MinIOConfig{
+		Endpoint:        strings.TrimPrefix(srv.URL, "http://"),
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		BucketName:      testBucket,
+		Buckets:         []string{"fawa-public"},
+	}
+	if err := InitMinIO(cfg); err != nil {
+		t.Fatalf("InitMinIO() error = %v", err)
+	}
+	if fileStore == nil {
+		t.Fatal("fileStore should be initialized")
+	}
+	// Only the missing bucket is created
+	if fake.bucketsCreated != 1 || !slices.Contains(fake.buckets, "fawa-public") {
+		t.Errorf("created %d buckets, want fawa-public only", fake.bucketsCreated)
+	}
+}
+
+func TestInitMinIOErrors(t *testing.T) {
+	prev := fileStore
+	t.Cleanup(func() { fileStore = prev })
+
+	// Missing settings leave object storage disabled
+	fileStore = nil
+	if err := InitMinIO(MinIOConfig{Endpoint: "localhost:9000"}); err != nil {
+		t.Errorf("InitMinIO() with an incomplete config error = %v", err)
+	}
+	if fileStore != nil {
+		t.Error("fileStore should stay nil with an incomplete config")
+	}
+
+	// A store refusing the bucket check is reported instead of killing the process
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+	err := InitMinIO(MinIOConfig{
+		Endpoint:        strings.TrimPrefix(srv.URL, "http://"),
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		BucketName:      testBucket,
+	})
+	if err == nil {
+		t.Error("InitMinIO() should fail when the bucket can't be checked")
+	}
+	if fileStore != nil {
+		t.Error("fileStore should stay nil when the initialization fails")
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	testCases := []struct {
+		filename string
+		want     string
+	}{
+		{filename: "report.pdf", want: "attachment; filename=report.pdf"},
+		{filename: "my report.pdf", want: `attachment; filename="my report.pdf"`},
+		{filename: "报告.pdf", want: "attachment; filename*=utf-8''%E6%8A%A5%E5%91%8A.pdf"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.filename, func(t *testing.T) {
+			if got := ContentDisposition(tc.filename); got != tc.want {
+				t.Errorf("ContentDisposition(%q) = %q, want %q", tc.filename, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetPresignedURLResponseHeaders(t *testing.T) {
+	setupFakeMinIO(t)
+
+	u, err := GetPresignedURL(context.Background(), "", "dir/abc", time.Minute, DownloadOptions{Filename: "report.pdf", ContentType: "application/pdf"})
+	if err != nil {
+		t.Fatalf("GetPresignedURL() error = %v", err)
+	}
+	if got, want := u.Query().Get("response-content-disposition"), `attachment; filename=report.pdf`; got != want {
+		t.Errorf("response-content-disposition = %q, want %q", got, want)
+	}
+	if got := u.Query().Get("response-content-type"); got != "application/pdf" {
+		t.Errorf("response-content-type = %q, want application/pdf", got)
+	}
+
+	u, err = GetPresignedURL(context.Background(), "", "dir/abc", time.Minute, DownloadOptions{})
+	if err != nil {
+		t.Fatalf("GetPresignedURL() error = %v", err)
+	}
+	if u.Query().Has("response-content-disposition") || u.Query().Has("response-content-type") {
+		t.Errorf("URL = %s, want no response-* parameters", u)
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	fake := setupFakeMinIO(t)
+	fake.put("a.txt", []byte("a"))
+	fake.mu.Lock()
+	fake.hang = true
+	fake.mu.Unlock()
+	fileStore.opTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	_, err := StatFile(context.Background(), "", "a.txt")
+	if code := apierr.From(err).Code(); code != connect.CodeDeadlineExceeded {
+		t.Errorf("StatFile() error = %v, want %v", err, connect.CodeDeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("StatFile() returned after %v, want it bounded by the operation timeout", elapsed)
+	}
+}
+
+func TestOperationsFailFastWhenOffline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	client, err := minio.New(addr, &minio.Options{Creds: credentials.NewStaticV4("access", "secret", "")})
+	if err != nil {
+		t.Fatalf("failed to create MinIO client: %v", err)
+	}
+	prev := fileStore
+	fileStore = &minioFileStore{client: client, bucketName: testBucket}
+	t.Cleanup(func() { fileStore = prev })
+
+	// The first call can't reach the closed port and takes MinIO offline
+	if _, err := StatFile(context.Background(), "", "a.txt"); err == nil || errors.Is(err, ErrOffline) {
+		t.Fatalf("first StatFile() error = %v, want a network error", err)
+	}
+	if _, err := StatFile(context.Background(), "", "a.txt"); !errors.Is(err, ErrOffline) {
+		t.Errorf("StatFile() error = %v, want %v", err, ErrOffline)
+	}
+	if _, err := UploadFile(context.Background(), "a.txt", strings.NewReader("a"), 1, UploadOptions{}); !errors.Is(err, ErrOffline) {
+		t.Errorf("UploadFile() error = %v, want %v", err, ErrOffline)
+	}
+	if code := apierr.From(ErrOffline).Code(); code != connect.CodeUnavailable {
+		t.Errorf("ErrOffline code = %v, want %v", code, connect.CodeUnavailable)
+	}
+}
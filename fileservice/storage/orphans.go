@@ -0,0 +1,90 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// orphanPageSize is how many objects CollectOrphans lists at once
+const orphanPageSize = 1000
+
+// CollectOrphans removes the objects that no unexpired metadata references anymore and returns
+// their bucket/name. Objects modified within metadataTTL are kept: their upload may not have
+// saved its metadata yet, or it predates the object references.
+func CollectOrphans(ctx context.Context) ([]string, error) {
+	if fileStore == nil {
+		return nil, errors.New("MinIO client is not initialized")
+	}
+
+	var removed []string
+	for _, bucket := range fileStore.bucketList() {
+		after := ""
+		for {
+			objects, more, err := ListObjects(ctx, bucket, "", after, orphanPageSize)
+			if err != nil {
+				return removed, fmt.Errorf("list bucket %s: %w", bucket, err)
+			}
+			for _, object := range objects {
+				orphan, err := collectOrphan(ctx, bucket, object)
+				if err != nil {
+					return removed, fmt.Errorf("collect %s/%s: %w", bucket, object.Name, err)
+				}
+				if orphan {
+					removed = append(removed, bucket+"/"+object.Name)
+				}
+			}
+			if !more {
+				break
+			}
+			after = objects[len(objects)-1].Name
+		}
+	}
+	return removed, nil
+}
+
+// collectOrphan removes the listed object if it's an orphan and reports whether it was one
+func collectOrphan(ctx context.Context, bucket string, object ObjectInfo) (bool, error) {
+	if time.Since(object.LastModified) < metadataTTL {
+		return false, nil
+	}
+	referenced, err := dragon.isReferenced(ctx, bucket, object.Name)
+	if err != nil || referenced {
+		return false, err
+	}
+	ctx, cancel, err := fileStore.op(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+	// An upload may have replaced the object since it was listed, its new metadata is then saved
+	// or about to be. Listings have a finer precision than object headers.
+	info, err := fileStore.client.StatObject(ctx, bucket, object.Name, minio.StatObjectOptions{})
+	if fileStore.observe(err) != nil {
+		if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+			return false, nil
+		}
+		return false, err
+	}
+	if !info.LastModified.Truncate(time.Second).Equal(object.LastModified.Truncate(time.Second)) {
+		return false, nil
+	}
+	return true, fileStore.observe(fileStore.client.RemoveObject(ctx, bucket, object.Name, minio.RemoveObjectOptions{}))
+}
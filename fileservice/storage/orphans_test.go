@@ -0,0 +1,111 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCollectOrphans(t *testing.T) {
+	fake := setupFakeMinIO(t)
+	client, mock := redismock.NewClientMock()
+	prev := dragon
+	dragon = &DragonflyStorage{client: client}
+	t.Cleanup(func() { dragon = prev })
+
+	fake.put("fresh.txt", []byte("uploading"))
+	fake.put("kept.txt", []byte("shared"))
+	fake.put("orphan.txt", []byte("expired"))
+	old := time.Now().Add(-2 * metadataTTL)
+	fake.objects["kept.txt"].modified = old
+	fake.objects["orphan.txt"].modified = old
+
+	mock.ExpectExists("objectref:" + testBucket + "/kept.txt").SetVal(1)
+	mock.ExpectExists("objectref:" + testBucket + "/orphan.txt").SetVal(0)
+
+	removed, err := CollectOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("CollectOrphans() error = %v", err)
+	}
+	if want := []string{testBucket + "/orphan.txt"}; !slices.Equal(removed, want) {
+		t.Errorf("CollectOrphans() = %v, want %v", removed, want)
+	}
+	for _, name := range []string{"fresh.txt", "kept.txt"} {
+		if _, ok := fake.get(name); !ok {
+			t.Errorf("%s should have been kept", name)
+		}
+	}
+	if _, ok := fake.get("orphan.txt"); ok {
+		t.Error("orphan.txt should have been removed")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCollectOrphansKeepsExtendedThumbnail(t *testing.T) {
+	fake := setupFakeMinIO(t)
+	client, mock := redismock.NewClientMock()
+	prev := dragon
+	dragon = &DragonflyStorage{client: client}
+	t.Cleanup(func() { dragon = prev })
+
+	// Both objects are older than metadataTTL, only their references keep them
+	fake.put("k/a.png", []byte("image"))
+	fake.put(".thumbnails/k", []byte("thumbnail"))
+	old := time.Now().Add(-2 * metadataTTL)
+	fake.objects["k/a.png"].modified = old
+	fake.objects[".thumbnails/k"].modified = old
+
+	metadata := &FileMetadata{Filename: "a.png", StoragePath: "k/a.png", ThumbnailPath: ".thumbnails/k"}
+	expiresAt := now().Add(24 * time.Hour).Truncate(time.Second).UTC()
+	extended := *metadata
+	extended.ExpiresAt = expiresAt
+	metadataJSON, _ := json.Marshal(&extended)
+	mock.ExpectTxPipeline()
+	mock.ExpectSetArgs("k", metadataJSON, redis.SetArgs{Mode: "XX", ExpireAt: expiresAt}).SetVal("OK")
+	expectRefObject(mock, "objectref:"+testBucket+"/k/a.png", "k", expiresAt, false, true)
+	expectRefObject(mock, "objectref:"+testBucket+"/.thumbnails/k", "k", expiresAt, false, true)
+	mock.ExpectExpireAt("k:downloads", expiresAt).SetVal(false)
+	mock.ExpectExpireAt("k:active", expiresAt).SetVal(false)
+	mock.ExpectTxPipelineExec()
+	if err := ExtendFileMeta(context.Background(), "k", metadata, 24*time.Hour); err != nil {
+		t.Fatalf("ExtendFileMeta() error = %v", err)
+	}
+
+	// Listed in name order
+	mock.ExpectExists("objectref:" + testBucket + "/.thumbnails/k").SetVal(1)
+	mock.ExpectExists("objectref:" + testBucket + "/k/a.png").SetVal(1)
+	removed, err := CollectOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("CollectOrphans() error = %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("CollectOrphans() removed %v", removed)
+	}
+	if _, ok := fake.get(".thumbnails/k"); !ok {
+		t.Error("the thumbnail of the extended link was removed")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
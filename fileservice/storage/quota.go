@@ -0,0 +1,132 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fawa-io/fawa/pkg/fwlog"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+)
+
+// quotaPrefix prefixes the sorted set of the files an owner stores. Its members are
+// "<download key>:<size>" scored by the Unix time the file's metadata expires, so
+// expired files stop counting without anything having to remove them.
+const quotaPrefix = "quota:"
+
+// now is the clock of the metadata expiry and quota accounting, replaced by the tests
+var now = time.Now
+
+// QuotaExceededError is returned when storing a file would take its owner over their quota.
+// It wraps apierr.ErrResourceExhausted.
+type QuotaExceededError struct {
+	// Usage is the bytes the owner stores, the rejected file excluded
+	Usage int64
+	Limit int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("storage quota exceeded: %d of %d bytes used", e.Usage, e.Limit)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return apierr.ErrResourceExhausted
+}
+
+func quotaKey(owner string) string {
+	return quotaPrefix + owner
+}
+
+func quotaMember(key string, size int64) string {
+	return key + ":" + strconv.FormatInt(size, 10)
+}
+
+// quotaUsage sums the sizes of the quota members
+func quotaUsage(members []string) int64 {
+	var usage int64
+	for _, member := range members {
+		i := strings.LastIndexByte(member, ':')
+		size, err := strconv.ParseInt(member[i+1:], 10, 64)
+		if err != nil {
+			fwlog.Warnf("Ignoring malformed quota member %q", member)
+			continue
+		}
+		usage += size
+	}
+	return usage
+}
+
+// QuotaUsage returns the bytes of the unexpired files counted against owner
+func (dragon *DragonflyStorage) QuotaUsage(ctx context.Context, owner string) (int64, error) {
+	key := quotaKey(owner)
+	pipe := dragon.client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now().Unix(), 10))
+	members := pipe.ZRange(ctx, key, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return quotaUsage(members.Val()), nil
+}
+
+// ReserveQuota counts the file of size bytes saved under key against owner until its metadata
+// expires, unless that takes the owner over limit. Like acquireDownload it adds the file first
+// and takes it back when over, so concurrent uploads can't both slip under the limit.
+func (dragon *DragonflyStorage) ReserveQuota(ctx context.Context, owner, key string, size, limit int64) error {
+	quota := quotaKey(owner)
+	t := now()
+	pipe := dragon.client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, quota, "-inf", strconv.FormatInt(t.Unix(), 10))
+	pipe.ZAdd(ctx, quota, redis.Z{Score: float64(t.Add(metadataTTL).Unix()), Member: quotaMember(key, size)})
+	// The set must outlive its longest member, which ExtendFileMeta may have pushed past
+	// metadataTTL: NX gives a new set its expiry and GT only ever lengthens it
+	pipe.ExpireNX(ctx, quota, metadataTTL)
+	pipe.ExpireGT(ctx, quota, metadataTTL)
+	members := pipe.ZRange(ctx, quota, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if usage := quotaUsage(members.Val()); usage > limit {
+		if err := dragon.ReleaseQuota(ctx, owner, key, size); err != nil {
+			fwlog.Warnf("Failed to release the quota of %s: %v", key, err)
+		}
+		return &QuotaExceededError{Usage: usage - size, Limit: limit}
+	}
+	return nil
+}
+
+// ReleaseQuota stops counting the file of size bytes saved under key against owner
+func (dragon *DragonflyStorage) ReleaseQuota(ctx context.Context, owner, key string, size int64) error {
+	return dragon.client.ZRem(ctx, quotaKey(owner), quotaMember(key, size)).Err()
+}
+
+// QuotaUsage returns the bytes stored by owner, see DragonflyStorage.QuotaUsage
+func QuotaUsage(ctx context.Context, owner string) (int64, error) {
+	return dragon.QuotaUsage(ctx, owner)
+}
+
+// ReserveQuota counts a file against the quota of owner, see DragonflyStorage.ReserveQuota
+func ReserveQuota(ctx context.Context, owner, key string, size, limit int64) error {
+	return dragon.ReserveQuota(ctx, owner, key, size, limit)
+}
+
+// ReleaseQuota takes back a file counted with ReserveQuota
+func ReleaseQuota(ctx context.Context, owner, key string, size int64) error {
+	return dragon.ReleaseQuota(ctx, owner, key, size)
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/fawa-io/fawa/fileservice/pkg/apierr"
+)
+
+func TestDragonflyStorage_Quota(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	storage := &DragonflyStorage{client: client}
+	start := time.Unix(1000, 0)
+	prev := now
+	now = func() time.Time { return start }
+	t.Cleanup(func() { now = prev })
+	expires := float64(start.Add(metadataTTL).Unix())
+
+	mock.ExpectTxPipeline()
+	mock.ExpectZRemRangeByScore("quota:user:alice", "-inf", "1000").SetVal(1)
+	mock.ExpectZRange("quota:user:alice", 0, -1).SetVal([]string{"abc:100", "def:50"})
+	mock.ExpectTxPipelineExec()
+	if usage, err := storage.QuotaUsage(context.Background(), "user:alice"); err != nil || usage != 150 {
+		t.Errorf("QuotaUsage() = %d, %v, want 150, nil", usage, err)
+	}
+
+	mock.ExpectTxPipeline()
+	mock.ExpectZRemRangeByScore("quota:user:alice", "-inf", "1000").SetVal(0)
+	mock.ExpectZAdd("quota:user:alice", redis.Z{Score: expires, Member: "ghi:30"}).SetVal(1)
+	mock.ExpectExpireNX("quota:user:alice", metadataTTL).SetVal(false)
+	mock.ExpectExpireGT("quota:user:alice", metadataTTL).SetVal(false)
+	mock.ExpectZRange("quota:user:alice", 0, -1).SetVal([]string{"abc:100", "def:50", "ghi:30"})
+	mock.ExpectTxPipelineExec()
+	if err := storage.ReserveQuota(context.Background(), "user:alice", "ghi", 30, 200); err != nil {
+		t.Errorf("ReserveQuota() under the limit error = %v", err)
+	}
+
+	// Over the limit the file is taken back
+	mock.ExpectTxPipeline()
+	mock.ExpectZRemRangeByScore("quota:user:alice", "-inf", "1000").SetVal(0)
+	mock.ExpectZAdd("quota:user:alice", redis.Z{Score: expires, Member: "jkl:80"}).SetVal(1)
+	mock.ExpectExpireNX("quota:user:alice", metadataTTL).SetVal(false)
+	mock.ExpectExpireGT("quota:user:alice", metadataTTL).SetVal(false)
+	mock.ExpectZRange("quota:user:alice", 0, -1).SetVal([]string{"abc:100", "def:50", "ghi:30", "jkl:80"})
+	mock.ExpectTxPipelineExec()
+	mock.ExpectZRem("quota:user:alice", "jkl:80").SetVal(1)
+	err := storage.ReserveQuota(context.Background(), "user:alice", "jkl", 80, 200)
+	var quotaErr *QuotaExceededError
+	if !errors.As(err, &quotaErr) || quotaErr.Usage != 180 || quotaErr.Limit != 200 {
+		t.Errorf("ReserveQuota() over the limit error = %v, want usage 180 of 200", err)
+	}
+	if !errors.Is(err, apierr.ErrResourceExhausted) {
+		t.Errorf("ReserveQuota() error = %v, want it to wrap %v", err, apierr.ErrResourceExhausted)
+	}
+
+	// Deleting the file releases its bytes
+	mock.ExpectTxPipeline()
+	mock.ExpectDel("ghi").SetVal(1)
+	mock.ExpectDel("ghi:downloads").SetVal(0)
+	mock.ExpectDel("ghi:active").SetVal(0)
+	mock.ExpectDel("objectref:/c.txt").SetVal(1)
+	mock.ExpectZRem("quota:user:alice", "ghi:30").SetVal(1)
+	mock.ExpectTxPipelineExec()
+	metadata := &FileMetadata{StoragePath: "c.txt", Size: 30, QuotaOwner: "user:alice"}
+	if err := storage.DeleteFileMeta(context.Background(), "ghi", metadata); err != nil {
+		t.Errorf("DeleteFileMeta() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// An upload after a link was extended must not expire the set before the extended file
+func TestReserveQuotaKeepsExtendedFiles(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	storage := &DragonflyStorage{client: client}
+	start := time.Unix(1000, 0)
+	prev := now
+	now = func() time.Time { return start }
+	t.Cleanup(func() { now = prev })
+
+	ttl := 24 * time.Hour
+	extendedUntil := start.Add(ttl).UTC()
+	metadata := &FileMetadata{Filename: "a.txt", StoragePath: "abc/a.txt", Size: 100, QuotaOwner: "user:alice"}
+	extended := *metadata
+	extended.ExpiresAt = extendedUntil
+	metadataJSON, _ := json.Marshal(&extended)
+	mock.ExpectTxPipeline()
+	mock.ExpectSetArgs("abc", metadataJSON, redis.SetArgs{Mode: "XX", ExpireAt: extendedUntil}).SetVal("OK")
+	expectRefObject(mock, "objectref:/abc/a.txt", "abc", extendedUntil, false, true)
+	mock.ExpectExpireAt("abc:downloads", extendedUntil).SetVal(false)
+	mock.ExpectExpireAt("abc:active", extendedUntil).SetVal(false)
+	mock.ExpectZAddXX("quota:user:alice", redis.Z{Score: float64(extendedUntil.Unix()), Member: "abc:100"}).SetVal(0)
+	mock.ExpectExpireGT("quota:user:alice", ttl).SetVal(true)
+	mock.ExpectTxPipelineExec()
+	if err := storage.ExtendFileMeta(context.Background(), "abc", metadata, ttl); err != nil {
+		t.Fatalf("ExtendFileMeta() error = %v", err)
+	}
+
+	// The set already expires after metadataTTL, neither NX nor GT change its expiry
+	mock.ExpectTxPipeline()
+	mock.ExpectZRemRangeByScore("quota:user:alice", "-inf", "1000").SetVal(0)
+	mock.ExpectZAdd("quota:user:alice", redis.Z{Score: float64(start.Add(metadataTTL).Unix()), Member: "def:50"}).SetVal(1)
+	mock.ExpectExpireNX("quota:user:alice", metadataTTL).SetVal(false)
+	mock.ExpectExpireGT("quota:user:alice", metadataTTL).SetVal(false)
+	mock.ExpectZRange("quota:user:alice", 0, -1).SetVal([]string{"abc:100", "def:50"})
+	mock.ExpectTxPipelineExec()
+	if err := storage.ReserveQuota(context.Background(), "user:alice", "def", 50, 200); err != nil {
+		t.Fatalf("ReserveQuota() error = %v", err)
+	}
+
+	// Past the original TTL only the extended file still counts
+	now = func() time.Time { return start.Add(metadataTTL + time.Second) }
+	mock.ExpectTxPipeline()
+	mock.ExpectZRemRangeByScore("quota:user:alice", "-inf", strconv.FormatInt(start.Add(metadataTTL+time.Second).Unix(), 10)).SetVal(1)
+	mock.ExpectZRange("quota:user:alice", 0, -1).SetVal([]string{"abc:100"})
+	mock.ExpectTxPipelineExec()
+	if usage, err := storage.QuotaUsage(context.Background(), "user:alice"); err != nil || usage != 100 {
+		t.Errorf("QuotaUsage() = %d, %v, want 100, nil", usage, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQuotaUsage(t *testing.T) {
+	testCases := []struct {
+		name    string
+		members []string
+		want    int64
+	}{
+		{name: "empty", members: nil, want: 0},
+		{name: "sizes", members: []string{"a:1", "b:2"}, want: 3},
+		{name: "malformed skipped", members: []string{"a:1", "b", "c:x"}, want: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := quotaUsage(tc.members); got != tc.want {
+				t.Errorf("quotaUsage() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -14,20 +14,79 @@
 
 package storage
 
+import (
+	"context"
+	"time"
+)
+
 // FileMetadata defines the structure for storing file information.
-// This is the canonical definition used across the application.
+// This is the canonical definition used across the application. It is stored as JSON in Dragonfly
+// under the download key, so the JSON names must stay stable for records written by older versions.
 type FileMetadata struct {
 	Filename    string `json:"filename"`
 	Size        int64  `json:"size"`
 	StoragePath string `json:"storagePath"`
+	// Bucket holds the object, empty for the primary bucket
+	Bucket      string `json:"bucket,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	// Compression is the algorithm the upload was transferred with, the object itself is stored uncompressed
+	Compression string `json:"compression,omitempty"`
+	// StorageClass is the object storage class the file was uploaded with
+	StorageClass string `json:"storageClass,omitempty"`
+	// EncryptionNonce is set for files stored encrypted, it derives the file key from the master key
+	EncryptionNonce []byte `json:"encryptionNonce,omitempty"`
+	// StorageTags are the tags set on the stored object
+	StorageTags map[string]string `json:"storageTags,omitempty"`
+	// OwnerID is the user who uploaded the file with a JWT, empty for anonymous uploads
+	OwnerID string `json:"ownerID,omitempty"`
+	// ThumbnailPath is the object holding the JPEG thumbnail of an image, in the same bucket
+	ThumbnailPath string `json:"thumbnailPath,omitempty"`
+	// SHA256 is the hex SHA-256 of the file content, empty for files uploaded before it was recorded
+	SHA256 string `json:"sha256,omitempty"`
+	// QuotaOwner is the user or API key whose quota the file counts against, empty when it counts against none
+	QuotaOwner string `json:"quotaOwner,omitempty"`
+	// Quarantined is set while the file awaits its virus scan, it can't be downloaded until then
+	Quarantined bool `json:"quarantined,omitempty"`
+	// ExpiresAt is when the metadata expires and the link stops working, set by SaveFileMeta.
+	// It is zero for files saved before it was recorded.
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+}
+
+// objects returns the names of the objects of the file, which must outlive its metadata
+func (m *FileMetadata) objects() []string {
+	if m.ThumbnailPath == "" {
+		return []string{m.StoragePath}
+	}
+	return []string{m.StoragePath, m.ThumbnailPath}
 }
 
 // Storage defines the interface for all data storage operations.
 // This allows for decoupling the business logic from the concrete storage implementation.
 type Storage interface {
-	// SaveFileMeta saves the file metadata with a given key and TTL.
-	SaveFileMeta(key string, metadata *FileMetadata) error
+	// SaveFileMeta saves the file metadata with a given key and TTL, recording the expiry in metadata.ExpiresAt.
+	SaveFileMeta(ctx context.Context, key string, metadata *FileMetadata) error
 
 	// GetFileMeta retrieves file metadata by its key.
-	GetFileMeta(key string) (*FileMetadata, error)
+	GetFileMeta(ctx context.Context, key string) (*FileMetadata, error)
+
+	// UpdateFileMeta replaces existing file metadata without extending its TTL.
+	UpdateFileMeta(ctx context.Context, key string, metadata *FileMetadata) error
+
+	// ExtendFileMeta resets the TTL of existing file metadata, recording the new expiry in metadata.ExpiresAt.
+	ExtendFileMeta(ctx context.Context, key string, metadata *FileMetadata, ttl time.Duration) error
+
+	// DeleteFileMeta removes the file metadata and everything recorded along with it.
+	DeleteFileMeta(ctx context.Context, key string, metadata *FileMetadata) error
+
+	// QuotaUsage returns the bytes of the unexpired files counted against owner.
+	QuotaUsage(ctx context.Context, owner string) (int64, error)
+
+	// ReserveQuota counts a file against owner unless that takes them over limit.
+	ReserveQuota(ctx context.Context, owner, key string, size, limit int64) error
+
+	// ReleaseQuota stops counting a file against owner.
+	ReleaseQuota(ctx context.Context, owner, key string, size int64) error
+
+	// Ping reports whether the backend is usable, for the readiness probe. It must not write.
+	Ping(ctx context.Context) error
 }
@@ -15,9 +15,17 @@
 package config
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 
@@ -26,11 +34,44 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Config is the service configuration. Fields tagged reload:"live" take effect when the
+// config file changes, the others only on restart.
 type Config struct {
 	Addr     string `mapstructure:"addr"`
 	CertFile string `mapstructure:"certFile"`
 	KeyFile  string `mapstructure:"keyFile"`
-	LogLevel string `mapstructure:"logLevel"`
+	LogLevel string `mapstructure:"logLevel" reload:"live"`
+	// ShutdownTimeout bounds the whole graceful shutdown sequence
+	ShutdownTimeout time.Duration `mapstructure:"shutdownTimeout"`
+	// DevMode relaxes production safeguards, every CORS origin is allowed
+	DevMode bool `mapstructure:"devMode"`
+	// CORS restricts which browser origins may call the service
+	CORS CORSConfig `mapstructure:"cors"`
+	// HTTP bounds how long the server waits on a client connection
+	HTTP HTTPConfig `mapstructure:"http"`
+	// IdleTimeout closes a bidi stream that receives no message for that long, zero disables it
+	IdleTimeout time.Duration `mapstructure:"idleTimeout"`
+}
+
+// HTTPConfig are the http.Server timeouts, zero disables one. Streaming RPCs and long lived
+// connections outlast any sensible request deadline, so ReadTimeout and WriteTimeout are off by default.
+type HTTPConfig struct {
+	// ReadHeaderTimeout bounds reading the request headers, which is what slowloris clients stall
+	ReadHeaderTimeout time.Duration `mapstructure:"readHeaderTimeout"`
+	// ReadTimeout bounds reading the whole request, body included
+	ReadTimeout time.Duration `mapstructure:"readTimeout"`
+	// WriteTimeout bounds writing the response, streams included
+	WriteTimeout time.Duration `mapstructure:"writeTimeout"`
+	// IdleTimeout closes keep-alive connections without a request for that long
+	IdleTimeout time.Duration `mapstructure:"idleTimeout"`
+}
+
+// CORSConfig is the allowlist of cross-origin requests, empty lists fall back to the defaults
+// except for AllowedOrigins, which allows no cross-origin request unless DevMode is set.
+type CORSConfig struct {
+	AllowedOrigins []string `mapstructure:"allowedOrigins"`
+	AllowedMethods []string `mapstructure:"allowedMethods"`
+	AllowedHeaders []string `mapstructure:"allowedHeaders"`
 }
 
 var (
@@ -39,6 +80,8 @@ var (
 	mu sync.RWMutex
 
 	config Config
+	// onChange are the callbacks registered with OnChange, guarded by mu
+	onChange []func(old, new Config)
 )
 
 func InitConfig() error {
@@ -55,21 +98,43 @@ func Get() Config {
 	return config
 }
 
+// OnChange registers fn to be called with the previous and the new config whenever a reload
+// changes it. Since only fields tagged reload:"live" are reloaded, those are the only ones that
+// can differ. fn runs on the file watcher goroutine once Get returns the new config, so it may
+// call Get but must not block.
+func OnChange(fn func(old, new Config)) {
+	mu.Lock()
+	defer mu.Unlock()
+	onChange = append(onChange, fn)
+}
+
+// LoadAndWatch loads the config and reloads it when the config file changes.
+// A setting is taken from the first of: a command line flag, its FAWA_ environment variable,
+// the config file, the default.
 func LoadAndWatch() error {
-	pflag.String("addr", "", "List of HTTP service address (e.g., '127.0.0.1:9090')")
+	pflag.String("addr", "127.0.0.1:8080", "List of HTTP service address (e.g., '127.0.0.1:9090')")
+	pflag.String("logLevel", "info", "Minimum level logged: debug, info, warn, error or fatal.")
 	pflag.String("uploadDir", "", "Upload files dir")
 	pflag.String("certFile", "", "Path to the TLS certificate file.")
 	pflag.String("keyFile", "", "Path to the TLS private key file.")
+	pflag.Duration("shutdownTimeout", 10*time.Second, "Maximum time allowed for the graceful shutdown.")
+	pflag.Duration("http.readHeaderTimeout", 10*time.Second, "Maximum time to read the request headers, 0 disables it.")
+	pflag.Duration("http.readTimeout", 0, "Maximum time to read a whole request, 0 disables it so client streams may run long.")
+	pflag.Duration("http.writeTimeout", 0, "Maximum time to write a response, 0 disables it so server streams may run long.")
+	pflag.Duration("http.idleTimeout", 2*time.Minute, "Close keep-alive connections idle for this long, 0 disables it.")
+	pflag.Duration("idleTimeout", 5*time.Minute, "Close a bidi stream after this long without a message, 0 disables it.")
+	pflag.Bool("devMode", false, "Allow every CORS origin, for local development only.")
+	pflag.String("config", "", "Path to the config file, the format is detected from its extension (yaml, json or toml).")
 	pflag.Parse()
 
 	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
 		return fmt.Errorf("failed to bind pflags: %w", err)
 	}
+	if err := bindEnv(viper.GetViper()); err != nil {
+		return err
+	}
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("/etc/fawa/")
+	setConfigFile(viper.GetViper(), viper.GetString("config"))
 
 	if err := viper.ReadInConfig(); err != nil {
 		var configFileNotFoundError viper.ConfigFileNotFoundError
@@ -86,32 +151,191 @@ func LoadAndWatch() error {
 		return fmt.Errorf("the initial configuration cannot be decoded into the struct: %w", err)
 	}
 	mu.Unlock()
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
 
 	viper.SetDefault("addr", "127.0.0.1:8080")
 	viper.SetDefault("uploadDir", "./upload")
 	viper.SetDefault("certFile", "")
 	viper.SetDefault("keyFile", "")
 	viper.SetDefault("logLevel", "info")
+	viper.SetDefault("shutdownTimeout", "10s")
+	viper.SetDefault("devMode", false)
+	viper.SetDefault("idleTimeout", "5m")
 
 	viper.OnConfigChange(func(e fsnotify.Event) {
 		fwlog.Infof("the Profile HasChanged: %s。reloading...", e.Name)
 
-		mu.Lock()
-		defer mu.Unlock()
-
-		if err := viper.Unmarshal(&config); err != nil {
+		var next Config
+		if err := viper.Unmarshal(&next); err != nil {
 			fwlog.Errorf("Error while reloading config: %v", err)
-		} else {
-			newLogLevel, err := fwlog.ParseLevel(config.LogLevel)
-			if err != nil {
-				fwlog.Warnf("New log level in config is invalid: %v. Keeping previous level.", err)
-			} else {
-				fwlog.SetLevel(newLogLevel)
-				fwlog.Infof("Log level reloaded successfully to: %s", config.LogLevel)
-			}
+			return
 		}
+		if err := next.Validate(); err != nil {
+			fwlog.Errorf("Ignoring invalid config, keeping the running one:\n%v", err)
+			return
+		}
+		reload(next)
 	})
 	viper.WatchConfig()
 
 	return nil
 }
+
+// envPrefix prefixes the environment variables overriding config keys, e.g. FAWA_ADDR
+const envPrefix = "FAWA"
+
+// bindEnv lets FAWA_ prefixed environment variables override the config file. The key is
+// upper-cased with dots replaced by underscores, rateLimit.upload.rps is FAWA_RATELIMIT_UPLOAD_RPS.
+func bindEnv(v *viper.Viper) error {
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// Unmarshal only decodes the keys viper knows about, bind those without a flag or default too
+	return bindEnvKeys(v, "", reflect.TypeOf(Config{}))
+}
+
+// bindEnvKeys binds the key of every leaf field of the struct type t to its environment variable
+func bindEnvKeys(v *viper.Viper, prefix string, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		if field.Type.Kind() == reflect.Struct {
+			if err := bindEnvKeys(v, key+".", field.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := v.BindEnv(key); err != nil {
+			return fmt.Errorf("failed to bind env: %w", err)
+		}
+	}
+	return nil
+}
+
+// reload makes next the current config and notifies the OnChange callbacks.
+// Only fields tagged reload:"live" are applied, the running servers were built from the others,
+// which keep their previous value until a restart.
+func reload(next Config) {
+	mu.Lock()
+	prev := config
+	var pending []string
+	config, pending = applyReload(config, next)
+	current, callbacks := config, onChange
+	mu.Unlock()
+
+	for _, name := range pending {
+		fwlog.Warnf("Config %s changed, restart required to apply it", name)
+	}
+
+	newLogLevel, err := fwlog.ParseLevel(current.LogLevel)
+	if err != nil {
+		fwlog.Warnf("New log level in config is invalid: %v. Keeping previous level.", err)
+	} else {
+		fwlog.SetLevel(newLogLevel)
+		fwlog.Infof("Log level reloaded successfully to: %s", current.LogLevel)
+	}
+
+	// The callbacks run without mu held so they can read the config back with Get
+	if reflect.DeepEqual(prev, current) {
+		return
+	}
+	for _, fn := range callbacks {
+		fn(prev, current)
+	}
+}
+
+// applyReload returns next with the fields not tagged reload:"live" reset to their value in current,
+// along with the names of those that differ.
+func applyReload(current, next Config) (Config, []string) {
+	cur := reflect.ValueOf(current)
+	nxt := reflect.ValueOf(&next).Elem()
+	var pending []string
+	for i := 0; i < nxt.NumField(); i++ {
+		field := nxt.Type().Field(i)
+		if field.Tag.Get("reload") == "live" {
+			continue
+		}
+		if !reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			pending = append(pending, field.Tag.Get("mapstructure"))
+			nxt.Field(i).Set(cur.Field(i))
+		}
+	}
+	return next, pending
+}
+
+// LoadTLSConfig loads the key pair named by CertFile and KeyFile.
+// It returns a nil config when both are empty so the caller serves plain HTTP,
+// and an error naming the offending file when only one is set or the pair can't be loaded.
+func (c Config) LoadTLSConfig() (*tls.Config, error) {
+	if c.CertFile == "" && c.KeyFile == "" {
+		return nil, nil
+	}
+	if c.CertFile == "" {
+		return nil, fmt.Errorf("keyFile %q is set but certFile is empty", c.KeyFile)
+	}
+	if c.KeyFile == "" {
+		return nil, fmt.Errorf("certFile %q is set but keyFile is empty", c.CertFile)
+	}
+	for _, file := range []string{c.CertFile, c.KeyFile} {
+		if _, err := os.Stat(file); err != nil {
+			return nil, fmt.Errorf("cannot read TLS file %q: %w", file, err)
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS key pair (certFile %q, keyFile %q): %w", c.CertFile, c.KeyFile, err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// Validate reports every invalid setting of c at once, so a misconfigured service refuses
+// to start with the full list instead of failing on the first request that needs one.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Addr == "" {
+		errs = append(errs, errors.New("addr is required"))
+	} else if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("addr %q must be host:port: %w", c.Addr, err))
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("certFile %q and keyFile %q must be set together or both left empty", c.CertFile, c.KeyFile))
+	}
+	if _, err := fwlog.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel %q is invalid, use debug, info, warn, error or fatal", c.LogLevel))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdownTimeout %v must be positive", c.ShutdownTimeout))
+	}
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"http.readHeaderTimeout", c.HTTP.ReadHeaderTimeout},
+		{"http.readTimeout", c.HTTP.ReadTimeout},
+		{"http.writeTimeout", c.HTTP.WriteTimeout},
+		{"http.idleTimeout", c.HTTP.IdleTimeout},
+	} {
+		if timeout.value < 0 {
+			errs = append(errs, fmt.Errorf("%s %v must not be negative", timeout.key, timeout.value))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setConfigFile tells v where to find the config file.
+// The format is detected from the file extension, YAML is assumed when it can't be detected.
+// Without an explicit path, config.{yaml,json,toml} is searched in the working directory and /etc/fawa/.
+func setConfigFile(v *viper.Viper, path string) {
+	if path == "" {
+		v.SetConfigName("config")
+		v.AddConfigPath(".")
+		v.AddConfigPath("/etc/fawa/")
+		return
+	}
+	v.SetConfigFile(path)
+	if ext := filepath.Ext(path); ext == "" || !slices.Contains(viper.SupportedExts, ext[1:]) {
+		v.SetConfigType("yaml")
+	}
+}
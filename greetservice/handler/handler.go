@@ -20,40 +20,83 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"connectrpc.com/connect"
-	"github.com/fawa-io/fwpkg/fwlog"
 
 	greetv1 "github.com/fawa-io/fawa/greetservice/gen/greet/v1"
+	"github.com/fawa-io/fawa/greetservice/pkg/requestid"
 )
 
-type GreetServiceHandler struct{}
+const (
+	// defaultGreetStreamCount is the number of greetings GreetStream sends when the request has no count
+	defaultGreetStreamCount = 10
+	// maxGreetStreamCount and maxGreetStreamInterval bound a GreetStream to under three hours
+	maxGreetStreamCount    = 1000
+	maxGreetStreamInterval = 10 * time.Second
+)
+
+// errIdleTimeout ends a bidi stream that received nothing within the idle timeout
+var errIdleTimeout = errors.New("stream closed after idle timeout")
+
+type GreetServiceHandler struct {
+	// IdleTimeout closes a bidi stream that receives no message for that long, zero disables it
+	IdleTimeout time.Duration
+}
 
 func (s *GreetServiceHandler) SayHello(
 	ctx context.Context,
 	req *connect.Request[greetv1.SayHelloRequest],
 ) (*connect.Response[greetv1.SayHelloResponse], error) {
-	fwlog.Debugf("Request headers: %v", req.Header())
+	log := requestid.Logger(ctx)
+	log.Debugf("Request headers: %v", req.Header())
+	g := greetingFor(req.Msg.Locale, req.Header())
 	res := connect.NewResponse(&greetv1.SayHelloResponse{
-		Resp: fmt.Sprintf("Hello, %s!", req.Msg.Name),
+		Resp: fmt.Sprintf(g.hello, req.Msg.Name),
 	})
 	res.Header().Set("Greet-Version", "v1")
 	return res, nil
 }
 
 // GreetStream implements the server-streaming RPC.
+// It sends Count greetings IntervalMs apart and stops as soon as the client goes away.
 func (s *GreetServiceHandler) GreetStream(
 	ctx context.Context,
 	req *connect.Request[greetv1.GreetStreamRequest],
 	stream *connect.ServerStream[greetv1.GreetStreamResponse],
 ) error {
+	count := int(req.Msg.Count)
+	if count == 0 {
+		count = defaultGreetStreamCount
+	}
+	if count < 0 || count > maxGreetStreamCount {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("count %d must be between 1 and %d", count, maxGreetStreamCount))
+	}
+	interval := time.Duration(req.Msg.IntervalMs) * time.Millisecond
+	if interval < 0 || interval > maxGreetStreamInterval {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("interval_ms %d must be between 0 and %d", req.Msg.IntervalMs, maxGreetStreamInterval.Milliseconds()))
+	}
+
+	g := greetingFor(req.Msg.Locale, req.Header())
 	name := req.Msg.Name
 	if name == "" {
-		name = "World"
+		name = g.world
 	}
-	for i := 0; i < 10; i++ {
+	for i := 0; i < count; i++ {
+		if i > 0 && interval > 0 {
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := stream.Send(&greetv1.GreetStreamResponse{
-			Part: fmt.Sprintf("Hello, %s! (part %d)", name, i+1),
+			Part: fmt.Sprintf(g.hello, name) + fmt.Sprintf(g.part, i+1),
 		}); err != nil {
 			return err
 		}
@@ -65,45 +108,93 @@ func (s *GreetServiceHandler) GreetClientStream(
 	ctx context.Context,
 	stream *connect.ClientStream[greetv1.GreetClientStreamRequest],
 ) (*connect.Response[greetv1.GreetClientStreamResponse], error) {
+	log := requestid.Logger(ctx)
 	var names []string
+	// The first message naming a locale sets the language of the summary
+	var locale string
 	for stream.Receive() {
-		fwlog.Debugf("cilent stream receive: %v", stream.Msg().Name)
+		log.Debugf("cilent stream receive: %v", stream.Msg().Name)
 		names = append(names, stream.Msg().Name)
+		if locale == "" {
+			locale = stream.Msg().Locale
+		}
 	}
 
 	if err := stream.Err(); err != nil {
-		fwlog.Errorf("Stream ended with an error: %v", err)
+		log.Errorf("Stream ended with an error: %v", err)
 		return nil, connect.NewError(connect.CodeUnknown, err)
 	}
 
-	fwlog.Debugf("Stream finished successfully. Received names: %v", names)
+	log.Debugf("Stream finished successfully. Received names: %v", names)
 
+	g := greetingFor(locale, stream.RequestHeader())
 	resp := connect.NewResponse(&greetv1.GreetClientStreamResponse{
-		Summary: fmt.Sprintf("Hello, %s!", strings.Join(names, ", ")),
+		Summary: fmt.Sprintf(g.hello, strings.Join(names, g.sep)),
 	})
 	return resp, nil
 }
 
 // GreetBidiStream implements the bidirectional-streaming RPC.
+// A stream idle for longer than IdleTimeout ends with CodeDeadlineExceeded,
+// which tells it apart from a stream the client closed or that failed.
 func (s *GreetServiceHandler) GreetBidiStream(
 	ctx context.Context,
 	stream *connect.BidiStream[greetv1.GreetBidiStreamRequest, greetv1.GreetBidiStreamResponse],
 ) error {
+	log := requestid.Logger(ctx)
+	type received struct {
+		req *greetv1.GreetBidiStreamRequest
+		err error
+	}
+	// Receive blocks, read in the background so the idle timer can fire.
+	// The goroutine exits once the handler returns and cancels ctx.
+	receives := make(chan received)
+	go func() {
+		for {
+			req, err := stream.Receive()
+			select {
+			case receives <- received{req: req, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var timer *time.Timer
+	var idle <-chan time.Time
+	if s.IdleTimeout > 0 {
+		timer = time.NewTimer(s.IdleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+
 	for {
-		if err := ctx.Err(); err != nil {
-			return err
+		var r received
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-idle:
+			log.Infof("bidi stream idle for %v, closing", s.IdleTimeout)
+			return connect.NewError(connect.CodeDeadlineExceeded, errIdleTimeout)
+		case r = <-receives:
+		}
+		if timer != nil {
+			timer.Reset(s.IdleTimeout)
 		}
-		req, err := stream.Receive()
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				fwlog.Debug("bidi stream finished successfully.")
+		if r.err != nil {
+			if errors.Is(r.err, io.EOF) {
+				log.Debug("bidi stream finished successfully.")
 				return nil
 			}
-			return err
+			return r.err
 		}
-		fwlog.Debugf("bidi stream receive: %v", req.Name)
+		log.Debugf("bidi stream receive: %v", r.req.Name)
+		g := greetingFor(r.req.Locale, stream.RequestHeader())
 		if err := stream.Send(&greetv1.GreetBidiStreamResponse{
-			Echo: fmt.Sprintf("Hello, %s!", req.Name),
+			Echo: fmt.Sprintf(g.hello, r.req.Name),
 		}); err != nil {
 			return err
 		}
@@ -0,0 +1,186 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+
+	greetv1 "github.com/fawa-io/fawa/greetservice/gen/greet/v1"
+	"github.com/fawa-io/fawa/greetservice/gen/greet/v1/greetv1connect"
+)
+
+// newTestClient serves h over HTTP/2, which bidi streams require
+func newTestClient(t *testing.T, h greetv1connect.GreetServiceHandler) greetv1connect.GreetServiceClient {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(greetv1connect.NewGreetServiceHandler(h))
+	srv := httptest.NewUnstartedServer(mux)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return greetv1connect.NewGreetServiceClient(srv.Client(), srv.URL)
+}
+
+func TestGreetBidiStreamIdleTimeout(t *testing.T) {
+	const idleTimeout = 100 * time.Millisecond
+	client := newTestClient(t, &GreetServiceHandler{IdleTimeout: idleTimeout})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream := client.GreetBidiStream(ctx)
+	defer func() { _ = stream.CloseResponse() }()
+
+	// Messages keep the stream open past the timeout
+	for _, name := range []string{"a", "b", "c"} {
+		if err := stream.Send(&greetv1.GreetBidiStreamRequest{Name: name}); err != nil {
+			t.Fatalf("Send(%q) failed: %v", name, err)
+		}
+		resp, err := stream.Receive()
+		if err != nil {
+			t.Fatalf("Receive() after %q failed: %v", name, err)
+		}
+		if want := "Hello, " + name + "!"; resp.Echo != want {
+			t.Errorf("Echo = %q, want %q", resp.Echo, want)
+		}
+		time.Sleep(idleTimeout / 2)
+	}
+
+	// Then the client goes idle
+	start := time.Now()
+	_, err := stream.Receive()
+	if connect.CodeOf(err) != connect.CodeDeadlineExceeded {
+		t.Fatalf("idle stream ended with %v, want %v", err, connect.CodeDeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > idleTimeout*10 {
+		t.Errorf("idle stream closed after %v, want about %v", elapsed, idleTimeout)
+	}
+}
+
+func TestGreetBidiStreamClientClose(t *testing.T) {
+	client := newTestClient(t, &GreetServiceHandler{IdleTimeout: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream := client.GreetBidiStream(ctx)
+	defer func() { _ = stream.CloseResponse() }()
+
+	if err := stream.Send(&greetv1.GreetBidiStreamRequest{Name: "fawa"}); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if _, err := stream.Receive(); err != nil {
+		t.Fatalf("Receive() failed: %v", err)
+	}
+	if err := stream.CloseRequest(); err != nil {
+		t.Fatalf("CloseRequest() failed: %v", err)
+	}
+	if _, err := stream.Receive(); !errors.Is(err, io.EOF) {
+		t.Fatalf("closed stream ended with %v, want io.EOF", err)
+	}
+}
+
+func TestGreetStreamCountAndInterval(t *testing.T) {
+	testCases := []struct {
+		name       string
+		req        *greetv1.GreetStreamRequest
+		wantParts  int
+		minElapsed time.Duration
+		wantCode   connect.Code
+	}{
+		{name: "defaults", req: &greetv1.GreetStreamRequest{}, wantParts: defaultGreetStreamCount},
+		{name: "count and interval", req: &greetv1.GreetStreamRequest{Count: 3, IntervalMs: 30}, wantParts: 3, minElapsed: 60 * time.Millisecond},
+		{name: "negative count", req: &greetv1.GreetStreamRequest{Count: -1}, wantCode: connect.CodeInvalidArgument},
+		{name: "count over cap", req: &greetv1.GreetStreamRequest{Count: maxGreetStreamCount + 1}, wantCode: connect.CodeInvalidArgument},
+		{name: "negative interval", req: &greetv1.GreetStreamRequest{IntervalMs: -1}, wantCode: connect.CodeInvalidArgument},
+		{name: "interval over cap", req: &greetv1.GreetStreamRequest{IntervalMs: 10001}, wantCode: connect.CodeInvalidArgument},
+	}
+
+	client := newTestClient(t, &GreetServiceHandler{})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			stream, err := client.GreetStream(context.Background(), connect.NewRequest(tc.req))
+			if err != nil {
+				t.Fatalf("GreetStream() failed: %v", err)
+			}
+			defer func() { _ = stream.Close() }()
+			parts := 0
+			for stream.Receive() {
+				parts++
+			}
+			var code connect.Code
+			if err := stream.Err(); err != nil {
+				code = connect.CodeOf(err)
+			}
+			if code != tc.wantCode {
+				t.Fatalf("stream ended with %v, want code %v", stream.Err(), tc.wantCode)
+			}
+			if parts != tc.wantParts {
+				t.Errorf("received %d parts, want %d", parts, tc.wantParts)
+			}
+			if elapsed := time.Since(start); elapsed < tc.minElapsed {
+				t.Errorf("stream took %v, want at least %v", elapsed, tc.minElapsed)
+			}
+		})
+	}
+}
+
+// greetStreamResult reports when GreetStream returns
+type greetStreamResult struct {
+	*GreetServiceHandler
+	done chan error
+}
+
+func (h greetStreamResult) GreetStream(
+	ctx context.Context,
+	req *connect.Request[greetv1.GreetStreamRequest],
+	stream *connect.ServerStream[greetv1.GreetStreamResponse],
+) error {
+	err := h.GreetServiceHandler.GreetStream(ctx, req, stream)
+	h.done <- err
+	return err
+}
+
+func TestGreetStreamStopsOnCancel(t *testing.T) {
+	h := greetStreamResult{GreetServiceHandler: &GreetServiceHandler{}, done: make(chan error, 1)}
+	client := newTestClient(t, h)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.GreetStream(ctx, connect.NewRequest(&greetv1.GreetStreamRequest{Count: maxGreetStreamCount, IntervalMs: 10000}))
+	if err != nil {
+		t.Fatalf("GreetStream() failed: %v", err)
+	}
+	if !stream.Receive() {
+		t.Fatalf("GreetStream() sent nothing: %v", stream.Err())
+	}
+	cancel()
+
+	select {
+	case err := <-h.done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("GreetStream() returned %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GreetStream() kept running after the client went away")
+	}
+}
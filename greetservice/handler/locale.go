@@ -0,0 +1,103 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultLocale is used when neither the request nor Accept-Language names a known language
+const defaultLocale = "en"
+
+// greeting is the wording of the greetings in a language
+type greeting struct {
+	// hello greets a name, %s is replaced by the name
+	hello string
+	// world is greeted when no name is given
+	world string
+	// part numbers a greeting of GreetStream, %d is replaced by the part
+	part string
+	// sep joins the names greeted at once
+	sep string
+}
+
+// greetings are the built-in languages, keyed by their primary language subtag
+var greetings = map[string]greeting{
+	"en": {hello: "Hello, %s!", world: "World", part: " (part %d)", sep: ", "},
+	"es": {hello: "¡Hola, %s!", world: "Mundo", part: " (parte %d)", sep: ", "},
+	"fr": {hello: "Bonjour, %s !", world: "le monde", part: " (partie %d)", sep: ", "},
+	"zh": {hello: "你好，%s！", world: "世界", part: "（第 %d 部分）", sep: "、"},
+	"ja": {hello: "こんにちは、%s！", world: "世界", part: "（パート %d）", sep: "、"},
+}
+
+// greetingFor returns the greeting in the requested locale. The locale field of the
+// request wins over the Accept-Language header, English is the fallback.
+func greetingFor(locale string, header http.Header) greeting {
+	if g, ok := greetings[baseLanguage(locale)]; ok {
+		return g
+	}
+	for _, tag := range acceptLanguages(header.Get("Accept-Language")) {
+		if g, ok := greetings[baseLanguage(tag)]; ok {
+			return g
+		}
+	}
+	return greetings[defaultLocale]
+}
+
+// baseLanguage returns the primary language subtag of a BCP 47 tag, e.g. "zh" for "zh-Hant-TW"
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	base, _, _ = strings.Cut(base, "_")
+	return strings.ToLower(strings.TrimSpace(base))
+}
+
+// acceptLanguages returns the tags of an Accept-Language header by decreasing preference,
+// leaving out the ones refused with q=0
+func acceptLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 The fawa Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	greetv1 "github.com/fawa-io/fawa/greetservice/gen/greet/v1"
+)
+
+func TestGreetingFor(t *testing.T) {
+	testCases := []struct {
+		locale         string
+		acceptLanguage string
+		want           string
+	}{
+		{want: "Hello, %s!"},
+		{locale: "fr", want: "Bonjour, %s !"},
+		{locale: "zh-CN", want: "你好，%s！"},
+		{locale: "ES_mx", want: "¡Hola, %s!"},
+		{locale: "ja", acceptLanguage: "fr", want: "こんにちは、%s！"},
+		{locale: "de", acceptLanguage: "es", want: "¡Hola, %s!"},
+		{locale: "de", want: "Hello, %s!"},
+		{acceptLanguage: "de-DE, fr;q=0.5, ja;q=0.8", want: "こんにちは、%s！"},
+		{acceptLanguage: "ja;q=0, zh-Hant-TW;q=0.1", want: "你好，%s！"},
+		{acceptLanguage: "*, ja;q=bad", want: "Hello, %s!"},
+	}
+
+	for _, tc := range testCases {
+		header := http.Header{}
+		if tc.acceptLanguage != "" {
+			header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		if got := greetingFor(tc.locale, header).hello; got != tc.want {
+			t.Errorf("greetingFor(%q, %q) = %q, want %q", tc.locale, tc.acceptLanguage, got, tc.want)
+		}
+	}
+}
+
+func TestLocalizedGreetings(t *testing.T) {
+	client := newTestClient(t, &GreetServiceHandler{})
+	ctx := context.Background()
+
+	req := connect.NewRequest(&greetv1.SayHelloRequest{Name: "Ana"})
+	req.Header().Set("Accept-Language", "es-ES,en;q=0.5")
+	hello, err := client.SayHello(ctx, req)
+	if err != nil {
+		t.Fatalf("SayHello() failed: %v", err)
+	}
+	if want := "¡Hola, Ana!"; hello.Msg.Resp != want {
+		t.Errorf("SayHello() = %q, want %q", hello.Msg.Resp, want)
+	}
+
+	server, err := client.GreetStream(ctx, connect.NewRequest(&greetv1.GreetStreamRequest{Locale: "zh"}))
+	if err != nil {
+		t.Fatalf("GreetStream() failed: %v", err)
+	}
+	if !server.Receive() {
+		t.Fatalf("GreetStream() sent nothing: %v", server.Err())
+	}
+	if want := "你好，世界！（第 1 部分）"; server.Msg().Part != want {
+		t.Errorf("GreetStream() part = %q, want %q", server.Msg().Part, want)
+	}
+	_ = server.Close()
+
+	clientStream := client.GreetClientStream(ctx)
+	for _, msg := range []*greetv1.GreetClientStreamRequest{{Name: "Léa", Locale: "fr"}, {Name: "Hugo", Locale: "ja"}} {
+		if err := clientStream.Send(msg); err != nil {
+			t.Fatalf("Send() failed: %v", err)
+		}
+	}
+	summary, err := clientStream.CloseAndReceive()
+	if err != nil {
+		t.Fatalf("GreetClientStream() failed: %v", err)
+	}
+	if want := "Bonjour, Léa, Hugo !"; summary.Msg.Summary != want {
+		t.Errorf("GreetClientStream() = %q, want %q", summary.Msg.Summary, want)
+	}
+
+	bidi := client.GreetBidiStream(ctx)
+	bidi.RequestHeader().Set("Accept-Language", "ja")
+	var echoes []string
+	for _, msg := range []*greetv1.GreetBidiStreamRequest{{Name: "Ken"}, {Name: "Kim", Locale: "en"}} {
+		if err := bidi.Send(msg); err != nil {
+			t.Fatalf("Send() failed: %v", err)
+		}
+		resp, err := bidi.Receive()
+		if err != nil {
+			t.Fatalf("Receive() failed: %v", err)
+		}
+		echoes = append(echoes, resp.Echo)
+	}
+	_ = bidi.CloseRequest()
+	_ = bidi.CloseResponse()
+	if want := []string{"こんにちは、Ken！", "Hello, Kim!"}; !slices.Equal(echoes, want) {
+		t.Errorf("GreetBidiStream() echoes = %q, want %q", echoes, want)
+	}
+}
@@ -15,20 +15,20 @@
 package main
 
 import (
-	"context"
 	"errors"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	"github.com/fawa-io/fwpkg/cors"
+	"connectrpc.com/connect"
 	"github.com/fawa-io/fwpkg/fwlog"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/fawa-io/fawa/greetservice/config"
 	"github.com/fawa-io/fawa/greetservice/gen/greet/v1/greetv1connect"
 	greet "github.com/fawa-io/fawa/greetservice/handler"
+	"github.com/fawa-io/fawa/greetservice/pkg/requestid"
+	"github.com/fawa-io/fawa/greetservice/server"
 )
 
 func main() {
@@ -45,56 +45,48 @@ func main() {
 	fwlog.SetLevel(logLevel)
 	fwlog.Infof("Logger initialized with level: %s", cfg.LogLevel)
 
-	greetSvcHdr := &greet.GreetServiceHandler{}
-	greetProcedure, greetHandler := greetv1connect.NewGreetServiceHandler(greetSvcHdr)
+	tlsConfig, err := cfg.LoadTLSConfig()
+	if err != nil {
+		fwlog.Fatalf("Invalid TLS configuration: %v", err)
+	}
+
+	greetSvcHdr := &greet.GreetServiceHandler{IdleTimeout: cfg.IdleTimeout}
+	greetProcedure, greetHandler := greetv1connect.NewGreetServiceHandler(greetSvcHdr, connect.WithInterceptors(requestid.NewInterceptor()))
 
 	mux := http.NewServeMux()
 	mux.Handle(greetProcedure, greetHandler)
 
 	greetSrv := &http.Server{
-		Addr:    cfg.Addr,
-		Handler: cors.NewCORS().Handler(mux),
+		Addr:              cfg.Addr,
+		Handler:           server.NewCORS(cfg.CORS, cfg.DevMode).Handler(mux),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
 	}
 
+	shutdowner := server.NewShutdowner(cfg.ShutdownTimeout)
+	shutdowner.RegisterServer("greet server", greetSrv)
 	go func() {
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-		<-sigCh
-
-		fwlog.Info("Shutting down server...")
-
-		// Set timeout for HTTP server shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		if err := greetSrv.Shutdown(ctx); err != nil {
-			fwlog.Errorf("Server shutdown error: %v", err)
-		}
-
-		fwlog.Info("Server shutdown complete")
+		shutdowner.WaitForSignal()
 		os.Exit(0)
 	}()
 
 	fwlog.Infof("Server starting on %v", cfg.Addr)
 
-	// Check if certificate files exist
-	if cfg.CertFile != "" && cfg.KeyFile != "" {
-		// Check if certificate files actually exist
-		if _, err := os.Stat(cfg.CertFile); err == nil {
-			if _, err := os.Stat(cfg.KeyFile); err == nil {
-				// Start the HTTPS server.
-				fwlog.Infof("Starting HTTPS server with certificates: %s, %s", cfg.CertFile, cfg.KeyFile)
-				if err := greetSrv.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
-					fwlog.Fatalf("Failed to start HTTPS server: %v", err)
-				}
-				return
-			}
+	if tlsConfig != nil {
+		// Start the HTTPS server.
+		greetSrv.TLSConfig = tlsConfig
+		fwlog.Infof("Starting HTTPS server with certificates: %s, %s", cfg.CertFile, cfg.KeyFile)
+		if err := greetSrv.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fwlog.Fatalf("Failed to start HTTPS server: %v", err)
 		}
-		fwlog.Warnf("Certificate files not found, falling back to HTTP mode")
+		return
 	}
 
-	// Start the HTTP server.
-	fwlog.Infof("Starting HTTP server")
+	// Start the HTTP server. h2c serves cleartext HTTP/2 so Connect streaming works without certificates.
+	greetSrv.Handler = h2c.NewHandler(greetSrv.Handler, &http2.Server{})
+	fwlog.Infof("Starting HTTP server (h2c enabled)")
 	if err := greetSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		fwlog.Fatalf("Failed to start HTTP server: %v", err)
 	}